@@ -69,8 +69,13 @@ func run() {
 		}
 	}()
 
-	tgHandler := telegram.NewBotHandler(l, bot, svc)
+	states := telegram.NewPostgresStateStore(repo, tgCfg.ConversationStateTTL)
+
+	tgHandler := telegram.NewBotHandler(l, bot, svc, states)
+	handlerDone := make(chan struct{})
 	go func() {
+		defer close(handlerDone)
+
 		if err = tgHandler.Start(ctx); err != nil {
 			l.Error("failed to start tg handler service", logger.ErrAttr(err))
 			stop()
@@ -85,5 +90,8 @@ func run() {
 
 	stop()
 
+	// wait for the handler to remove the webhook
+	<-handlerDone
+
 	l.Info("notifier service stopped gracefully")
 }
@@ -16,9 +16,12 @@ import (
 )
 
 type Config struct {
-	LogLevel        string        `envconfig:"LOG_LEVEL" default:"info"`
-	ScraperInterval time.Duration `envconfig:"SCRAPER_INTERVAL" default:"10m"`
-	Workers         int           `envconfig:"SCRAPER_WORKERS_COUNT" default:"5"`
+	LogLevel           string        `envconfig:"LOG_LEVEL" default:"info"`
+	ScraperInterval    time.Duration `envconfig:"SCRAPER_INTERVAL" default:"10m"`
+	Workers            int           `envconfig:"SCRAPER_WORKERS_COUNT" default:"5"`
+	FullScrapeInterval time.Duration `envconfig:"SCRAPER_FULL_SCRAPE_INTERVAL" default:"6h"`
+	MissingScrapes     int           `envconfig:"SCRAPER_MISSING_SCRAPES" default:"3"`
+	NotifyRemoved      bool          `envconfig:"SCRAPER_NOTIFY_REMOVED" default:"true"`
 }
 
 func main() {
@@ -58,6 +61,12 @@ func run() {
 
 	fetch := fetcher.NewService(l, paCli)
 
+	removal := scraper.RemovalConfig{
+		FullScrapeInterval: cfg.FullScrapeInterval,
+		MissingScrapes:     cfg.MissingScrapes,
+		Notify:             cfg.NotifyRemoved,
+	}
+
 	svc := scraper.NewService(
 		l,
 		repo,
@@ -65,6 +74,7 @@ func run() {
 		fetch,
 		cfg.ScraperInterval,
 		cfg.Workers,
+		removal,
 	)
 
 	go func() {
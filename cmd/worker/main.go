@@ -18,6 +18,9 @@ import (
 type Config struct {
 	LogLevel                   string        `envconfig:"LOG_LEVEL" default:"info"`
 	WorkerNotificationInterval time.Duration `envconfig:"WORKER_NOTIFICATION_INTERVAL" default:"20m"`
+	WorkerMaxAttempts          int           `envconfig:"WORKER_MAX_ATTEMPTS" default:"8"`
+	WorkerRetryBaseDelay       time.Duration `envconfig:"WORKER_RETRY_BASE_DELAY" default:"5m"`
+	WorkerRetryMaxDelay        time.Duration `envconfig:"WORKER_RETRY_MAX_DELAY" default:"12h"`
 }
 
 func main() {
@@ -56,7 +59,14 @@ func run() {
 		return
 	}
 
-	svc := worker.NewService(lg, repo, bot, cfg.WorkerNotificationInterval)
+	retry := worker.RetryConfig{
+		MaxAttempts: cfg.WorkerMaxAttempts,
+		BaseDelay:   cfg.WorkerRetryBaseDelay,
+		MaxDelay:    cfg.WorkerRetryMaxDelay,
+	}
+
+	// the worker sends the listings in bulk, so the messages are kept within the telegram rate limits
+	svc := worker.NewService(lg, repo, tgCli.NewRateLimitedBot(bot), cfg.WorkerNotificationInterval, retry)
 
 	go func() {
 		if err = svc.Start(ctx); err != nil {
@@ -0,0 +1,10 @@
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS fuel_type,
+    DROP COLUMN IF EXISTS power_kw,
+    DROP COLUMN IF EXISTS power_hp,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS drive,
+    DROP COLUMN IF EXISTS doors,
+    DROP COLUMN IF EXISTS registered_until,
+    DROP COLUMN IF EXISTS damage_status,
+    DROP COLUMN IF EXISTS seller_type;
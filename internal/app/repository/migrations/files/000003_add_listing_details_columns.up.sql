@@ -0,0 +1,10 @@
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS fuel_type        VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS power_kw         INTEGER      DEFAULT 0  NOT NULL,
+    ADD COLUMN IF NOT EXISTS power_hp         INTEGER      DEFAULT 0  NOT NULL,
+    ADD COLUMN IF NOT EXISTS color            VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS drive            VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS doors            VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS registered_until VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS damage_status    VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS seller_type      VARCHAR(256) DEFAULT '' NOT NULL;
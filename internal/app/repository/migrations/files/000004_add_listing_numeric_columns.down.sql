@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_listings_price_eur;
+
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS price_eur,
+    DROP COLUMN IF EXISTS new_price_eur,
+    DROP COLUMN IF EXISTS mileage_km,
+    DROP COLUMN IF EXISTS engine_volume_cm3,
+    DROP COLUMN IF EXISTS year;
@@ -0,0 +1,17 @@
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS price_eur         NUMERIC(12, 2) NULL,
+    ADD COLUMN IF NOT EXISTS new_price_eur     NUMERIC(12, 2) NULL,
+    ADD COLUMN IF NOT EXISTS mileage_km        INTEGER        NULL,
+    ADD COLUMN IF NOT EXISTS engine_volume_cm3 INTEGER        NULL,
+    ADD COLUMN IF NOT EXISTS year              INTEGER        NULL;
+
+-- backfill numeric values from the existing free-text columns,
+-- e.g. "2.400 €" -> 2400, "150.000 km" -> 150000, "1995 cm3" -> 1995.
+-- year was never stored, so it is filled by the scraper on the next upsert.
+UPDATE listings
+SET price_eur         = NULLIF(regexp_replace(price, '[^0-9]', '', 'g'), '')::NUMERIC,
+    new_price_eur     = NULLIF(regexp_replace(new_price, '[^0-9]', '', 'g'), '')::NUMERIC,
+    mileage_km        = NULLIF(regexp_replace(substring(mileage FROM '^[0-9. ]+'), '[^0-9]', '', 'g'), '')::INTEGER,
+    engine_volume_cm3 = NULLIF(regexp_replace(substring(engine_volume FROM '^[0-9. ]+'), '[^0-9]', '', 'g'), '')::INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_listings_price_eur ON listings (price_eur);
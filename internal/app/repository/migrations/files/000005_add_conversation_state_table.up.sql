@@ -0,0 +1,11 @@
+-- Create conversation_state table for the unfinished bot conversations
+CREATE TABLE IF NOT EXISTS conversation_state
+(
+    user_id    BIGINT PRIMARY KEY,
+    state      JSONB                   NOT NULL,
+    expires_at TIMESTAMP               NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_state_expires_at ON conversation_state (expires_at);
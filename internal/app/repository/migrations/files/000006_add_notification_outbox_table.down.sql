@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS notification_outbox;
+
+-- Enum values can not be dropped, so the type is recreated without the dead-letter status
+UPDATE notifications SET status = 'FAILED' WHERE status = 'DEAD';
+
+ALTER TYPE status RENAME TO status_old;
+CREATE TYPE status AS ENUM ('SENT', 'FAILED');
+ALTER TABLE notifications ALTER COLUMN status TYPE status USING status::text::status;
+DROP TYPE status_old;
@@ -0,0 +1,19 @@
+-- Add dead-letter status for notifications which will not be retried anymore
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'DEAD';
+
+-- Create notification_outbox table for the pending notifications and their retries
+CREATE TABLE IF NOT EXISTS notification_outbox
+(
+    id              UUID      DEFAULT gen_random_uuid() PRIMARY KEY,
+    listing_id      VARCHAR(256)                                         NOT NULL,
+    subscription_id UUID REFERENCES subscriptions (id) ON DELETE CASCADE NOT NULL,
+    attempts        INTEGER   DEFAULT 0                                  NOT NULL,
+    next_attempt_at TIMESTAMP                                            NOT NULL,
+    last_error      TEXT      DEFAULT ''                                 NOT NULL,
+    created_at      TIMESTAMP DEFAULT now()                              NOT NULL,
+    updated_at      TIMESTAMP DEFAULT now()                              NOT NULL,
+
+    CONSTRAINT notification_outbox_listing_id_subscription_id_unique UNIQUE (listing_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_next_attempt_at ON notification_outbox (next_attempt_at);
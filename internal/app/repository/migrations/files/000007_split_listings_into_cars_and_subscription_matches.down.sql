@@ -0,0 +1,81 @@
+-- Recreate listings table with a copy of the car for every subscription
+CREATE TABLE IF NOT EXISTS listings
+(
+    id                UUID      DEFAULT gen_random_uuid() PRIMARY KEY,
+    listing_id        VARCHAR(256)            NOT NULL,
+    subscription_id   UUID REFERENCES subscriptions (id),
+    title             VARCHAR(256)            NOT NULL,
+    price             VARCHAR(256)            NOT NULL,
+    new_price         VARCHAR(256)            NULL,
+    engine_volume     VARCHAR(256)            NOT NULL,
+    transmission      VARCHAR(256)            NOT NULL,
+    body_type         VARCHAR(256)            NOT NULL,
+    mileage           VARCHAR(256)            NOT NULL,
+    location          VARCHAR(256)            NOT NULL,
+    link              TEXT                    NOT NULL,
+    date              TIMESTAMP               NOT NULL,
+    is_need_send      BOOLEAN   DEFAULT FALSE NOT NULL,
+    fuel_type         VARCHAR(256) DEFAULT '' NOT NULL,
+    power_kw          INTEGER      DEFAULT 0  NOT NULL,
+    power_hp          INTEGER      DEFAULT 0  NOT NULL,
+    color             VARCHAR(256) DEFAULT '' NOT NULL,
+    drive             VARCHAR(256) DEFAULT '' NOT NULL,
+    doors             VARCHAR(256) DEFAULT '' NOT NULL,
+    registered_until  VARCHAR(256) DEFAULT '' NOT NULL,
+    damage_status     VARCHAR(256) DEFAULT '' NOT NULL,
+    seller_type       VARCHAR(256) DEFAULT '' NOT NULL,
+    price_eur         NUMERIC(12, 2)          NULL,
+    new_price_eur     NUMERIC(12, 2)          NULL,
+    mileage_km        INTEGER                 NULL,
+    engine_volume_cm3 INTEGER                 NULL,
+    year              INTEGER                 NULL,
+    created_at        TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at        TIMESTAMP DEFAULT now() NOT NULL,
+
+    CONSTRAINT listings_listing_id_subscription_id_unique UNIQUE (listing_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_listings_subscription_id ON listings (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_listings_is_need_send ON listings (is_need_send);
+CREATE INDEX IF NOT EXISTS idx_listings_price_eur ON listings (price_eur);
+
+INSERT INTO listings (id, listing_id, subscription_id, title, price, new_price, engine_volume, transmission, body_type,
+                      mileage, location, link, date, is_need_send, fuel_type, power_kw, power_hp, color, drive, doors,
+                      registered_until, damage_status, seller_type, price_eur, new_price_eur, mileage_km,
+                      engine_volume_cm3, year, created_at, updated_at)
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id;
+
+DROP TABLE IF EXISTS subscription_matches;
+
+DROP TABLE IF EXISTS cars;
@@ -0,0 +1,101 @@
+-- Create cars table, one row per Polovni Automobili listing shared by all matching subscriptions
+CREATE TABLE IF NOT EXISTS cars
+(
+    listing_id        VARCHAR(256) PRIMARY KEY,
+    title             VARCHAR(256)            NOT NULL,
+    price             VARCHAR(256)            NOT NULL,
+    engine_volume     VARCHAR(256)            NOT NULL,
+    transmission      VARCHAR(256)            NOT NULL,
+    body_type         VARCHAR(256)            NOT NULL,
+    mileage           VARCHAR(256)            NOT NULL,
+    location          VARCHAR(256)            NOT NULL,
+    link              TEXT                    NOT NULL,
+    date              TIMESTAMP               NOT NULL,
+    fuel_type         VARCHAR(256) DEFAULT '' NOT NULL,
+    power_kw          INTEGER      DEFAULT 0  NOT NULL,
+    power_hp          INTEGER      DEFAULT 0  NOT NULL,
+    color             VARCHAR(256) DEFAULT '' NOT NULL,
+    drive             VARCHAR(256) DEFAULT '' NOT NULL,
+    doors             VARCHAR(256) DEFAULT '' NOT NULL,
+    registered_until  VARCHAR(256) DEFAULT '' NOT NULL,
+    damage_status     VARCHAR(256) DEFAULT '' NOT NULL,
+    seller_type       VARCHAR(256) DEFAULT '' NOT NULL,
+    price_eur         NUMERIC(12, 2)          NULL,
+    mileage_km        INTEGER                 NULL,
+    engine_volume_cm3 INTEGER                 NULL,
+    year              INTEGER                 NULL,
+    created_at        TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at        TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cars_price_eur ON cars (price_eur);
+
+-- Create subscription_matches table with the send state of a car for a subscription,
+-- price is the price the subscriber knows and new_price is the changed price which is not sent yet
+CREATE TABLE IF NOT EXISTS subscription_matches
+(
+    id              UUID      DEFAULT gen_random_uuid() PRIMARY KEY,
+    listing_id      VARCHAR(256) REFERENCES cars (listing_id)            NOT NULL,
+    subscription_id UUID REFERENCES subscriptions (id) ON DELETE CASCADE NOT NULL,
+    price           VARCHAR(256)                                         NOT NULL,
+    new_price       VARCHAR(256)                                         NULL,
+    price_eur       NUMERIC(12, 2)                                       NULL,
+    new_price_eur   NUMERIC(12, 2)                                       NULL,
+    is_need_send    BOOLEAN   DEFAULT FALSE                              NOT NULL,
+    created_at      TIMESTAMP DEFAULT now()                              NOT NULL,
+    updated_at      TIMESTAMP DEFAULT now()                              NOT NULL,
+
+    CONSTRAINT subscription_matches_listing_id_subscription_id_unique UNIQUE (listing_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_matches_subscription_id ON subscription_matches (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_subscription_matches_is_need_send ON subscription_matches (is_need_send);
+
+-- the latest scraped copy of a listing becomes the car, its current price is the new price if there is one
+INSERT INTO cars (listing_id, title, price, engine_volume, transmission, body_type, mileage, location, link, date,
+                  fuel_type, power_kw, power_hp, color, drive, doors, registered_until, damage_status, seller_type,
+                  price_eur, mileage_km, engine_volume_cm3, year, created_at, updated_at)
+SELECT DISTINCT ON (listing_id) listing_id,
+                                title,
+                                COALESCE(new_price, price),
+                                engine_volume,
+                                transmission,
+                                body_type,
+                                mileage,
+                                location,
+                                link,
+                                date,
+                                fuel_type,
+                                power_kw,
+                                power_hp,
+                                color,
+                                drive,
+                                doors,
+                                registered_until,
+                                damage_status,
+                                seller_type,
+                                COALESCE(new_price_eur, price_eur),
+                                mileage_km,
+                                engine_volume_cm3,
+                                year,
+                                created_at,
+                                updated_at
+FROM listings
+ORDER BY listing_id, updated_at DESC;
+
+INSERT INTO subscription_matches (id, listing_id, subscription_id, price, new_price, price_eur, new_price_eur,
+                                  is_need_send, created_at, updated_at)
+SELECT id,
+       listing_id,
+       subscription_id,
+       price,
+       new_price,
+       price_eur,
+       new_price_eur,
+       is_need_send,
+       created_at,
+       updated_at
+FROM listings
+WHERE subscription_id IS NOT NULL;
+
+DROP TABLE IF EXISTS listings;
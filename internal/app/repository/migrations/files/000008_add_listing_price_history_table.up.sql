@@ -0,0 +1,18 @@
+-- Create listing_price_history table with the observed prices of the cars
+CREATE TABLE IF NOT EXISTS listing_price_history
+(
+    id          UUID      DEFAULT gen_random_uuid() PRIMARY KEY,
+    listing_id  VARCHAR(256) REFERENCES cars (listing_id) ON DELETE CASCADE NOT NULL,
+    price       VARCHAR(256)                                             NOT NULL,
+    price_eur   NUMERIC(12, 2)                                           NULL,
+    observed_at TIMESTAMP DEFAULT now()                                  NOT NULL,
+    created_at  TIMESTAMP DEFAULT now()                                  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_listing_price_history_listing_id_observed_at
+    ON listing_price_history (listing_id, observed_at);
+
+-- the current price of the existing cars is their first observation
+INSERT INTO listing_price_history (listing_id, price, price_eur, observed_at, created_at)
+SELECT listing_id, price, price_eur, updated_at, now()
+FROM cars;
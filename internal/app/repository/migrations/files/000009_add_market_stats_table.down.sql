@@ -0,0 +1,10 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS min_deal_percent;
+
+DROP TABLE IF EXISTS market_stats;
+
+DROP INDEX IF EXISTS idx_cars_brand_model_year;
+
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS brand,
+    DROP COLUMN IF EXISTS model;
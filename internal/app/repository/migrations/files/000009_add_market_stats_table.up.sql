@@ -0,0 +1,32 @@
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS brand VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS model VARCHAR(256) DEFAULT '' NOT NULL;
+
+-- backfill the brand of the existing cars from the subscriptions they matched,
+-- the model is only known if the subscription has a single model, otherwise it is filled by the scraper
+UPDATE cars c
+SET brand = s.brand,
+    model = CASE WHEN cardinality(s.model) = 1 THEN s.model[1] ELSE c.model END
+FROM subscription_matches m
+         JOIN subscriptions s ON s.id = m.subscription_id
+WHERE m.listing_id = c.listing_id;
+
+CREATE INDEX IF NOT EXISTS idx_cars_brand_model_year ON cars (brand, model, year);
+
+-- Create market_stats table with the price percentiles of the cars grouped by brand, model, year and mileage band
+CREATE TABLE IF NOT EXISTS market_stats
+(
+    brand            VARCHAR(256)            NOT NULL,
+    model            VARCHAR(256)            NOT NULL,
+    year             INTEGER                 NOT NULL,
+    mileage_band     INTEGER                 NOT NULL,
+    sample_size      INTEGER                 NOT NULL,
+    p25_price_eur    NUMERIC(12, 2)          NOT NULL,
+    median_price_eur NUMERIC(12, 2)          NOT NULL,
+    p75_price_eur    NUMERIC(12, 2)          NOT NULL,
+    updated_at       TIMESTAMP DEFAULT now() NOT NULL,
+    PRIMARY KEY (brand, model, year, mileage_band)
+);
+
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS min_deal_percent INTEGER NULL;
@@ -0,0 +1,21 @@
+DELETE
+FROM notification_outbox
+WHERE kind = 'REMOVED';
+
+ALTER TABLE notification_outbox
+    DROP CONSTRAINT IF EXISTS notification_outbox_listing_id_subscription_id_kind_unique,
+    ADD CONSTRAINT notification_outbox_listing_id_subscription_id_unique UNIQUE (listing_id, subscription_id),
+    DROP COLUMN IF EXISTS kind;
+
+DROP TYPE IF EXISTS notification_kind;
+
+ALTER TABLE market_stats
+    DROP COLUMN IF EXISTS removed_count,
+    DROP COLUMN IF EXISTS median_days_on_market;
+
+DROP INDEX IF EXISTS idx_cars_last_seen_at;
+
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS missing_count,
+    DROP COLUMN IF EXISTS removed_at;
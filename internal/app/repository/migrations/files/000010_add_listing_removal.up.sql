@@ -0,0 +1,24 @@
+-- last_seen_at is the last time the car was found in the search results, missing_count is the number of the full
+-- scrapes in a row the car was not found in and removed_at is set when the removal is confirmed by the detail page
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS last_seen_at  TIMESTAMP DEFAULT now() NOT NULL,
+    ADD COLUMN IF NOT EXISTS missing_count INTEGER   DEFAULT 0     NOT NULL,
+    ADD COLUMN IF NOT EXISTS removed_at    TIMESTAMP               NULL;
+
+UPDATE cars
+SET last_seen_at = updated_at;
+
+CREATE INDEX IF NOT EXISTS idx_cars_last_seen_at ON cars (last_seen_at);
+
+-- the time on market of the removed cars of the group
+ALTER TABLE market_stats
+    ADD COLUMN IF NOT EXISTS removed_count         INTEGER DEFAULT 0 NOT NULL,
+    ADD COLUMN IF NOT EXISTS median_days_on_market NUMERIC(8, 1)     NULL;
+
+-- Create enum type for the kind of the outbox notification, a new listing or a removed one
+CREATE TYPE notification_kind AS ENUM ('LISTING', 'REMOVED');
+
+ALTER TABLE notification_outbox
+    ADD COLUMN IF NOT EXISTS kind notification_kind DEFAULT 'LISTING' NOT NULL,
+    DROP CONSTRAINT IF EXISTS notification_outbox_listing_id_subscription_id_unique,
+    ADD CONSTRAINT notification_outbox_listing_id_subscription_id_kind_unique UNIQUE (listing_id, subscription_id, kind);
@@ -0,0 +1,6 @@
+ALTER TABLE subscription_matches
+    DROP COLUMN IF EXISTS relisted_from,
+    DROP COLUMN IF EXISTS relisted_price;
+
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS image_hash;
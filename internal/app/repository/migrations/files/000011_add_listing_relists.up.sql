@@ -0,0 +1,9 @@
+-- the difference hash of the first photo of the listing, it's used to detect the cars relisted under a new listing ID
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS image_hash BIGINT NULL;
+
+-- relisted_from is the previous listing of the same car seen by the subscription
+-- and relisted_price is the price of the previous listing the subscriber knows
+ALTER TABLE subscription_matches
+    ADD COLUMN IF NOT EXISTS relisted_from  VARCHAR(256) NULL,
+    ADD COLUMN IF NOT EXISTS relisted_price VARCHAR(256) NULL;
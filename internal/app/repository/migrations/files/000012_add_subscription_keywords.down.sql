@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS include_keywords,
+    DROP COLUMN IF EXISTS exclude_keywords;
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS include_keywords TEXT[] DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS exclude_keywords TEXT[] DEFAULT '{}' NOT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS fuel,
+    DROP COLUMN IF EXISTS gearbox,
+    DROP COLUMN IF EXISTS mileage_to,
+    DROP COLUMN IF EXISTS power_from,
+    DROP COLUMN IF EXISTS power_to,
+    DROP COLUMN IF EXISTS doors,
+    DROP COLUMN IF EXISTS seats,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS air_condition,
+    DROP COLUMN IF EXISTS damage;
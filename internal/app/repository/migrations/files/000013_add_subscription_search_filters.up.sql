@@ -0,0 +1,12 @@
+-- the options are stored by their names, the same as the chassis
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS fuel          TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS gearbox       TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS mileage_to    VARCHAR(256) DEFAULT ''   NOT NULL,
+    ADD COLUMN IF NOT EXISTS power_from    VARCHAR(256) DEFAULT ''   NOT NULL,
+    ADD COLUMN IF NOT EXISTS power_to      VARCHAR(256) DEFAULT ''   NOT NULL,
+    ADD COLUMN IF NOT EXISTS doors         TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS seats         TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS color         TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS air_condition TEXT[]       DEFAULT '{}' NOT NULL,
+    ADD COLUMN IF NOT EXISTS damage        TEXT[]       DEFAULT '{}' NOT NULL;
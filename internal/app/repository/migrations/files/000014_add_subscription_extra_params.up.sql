@@ -0,0 +1,3 @@
+-- the query parameters of a pasted search link the subscription has no fields for, they are passed to the search as is
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS extra_params JSONB DEFAULT '{}' NOT NULL;
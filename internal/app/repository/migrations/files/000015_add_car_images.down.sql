@@ -0,0 +1,3 @@
+ALTER TABLE cars
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS gallery_urls;
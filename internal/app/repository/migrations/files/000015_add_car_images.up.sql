@@ -0,0 +1,4 @@
+-- the photos of the listing sent with the notification, the gallery is fetched from the listing page
+ALTER TABLE cars
+    ADD COLUMN IF NOT EXISTS image_url    TEXT   DEFAULT ''   NOT NULL,
+    ADD COLUMN IF NOT EXISTS gallery_urls TEXT[] DEFAULT '{}' NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS muted_until,
+    DROP COLUMN IF EXISTS price_only;
+
+DROP TABLE IF EXISTS hidden_listings;
+DROP TABLE IF EXISTS watchlist;
@@ -0,0 +1,25 @@
+-- Create watchlist table for the cars the users saved from the notifications
+CREATE TABLE IF NOT EXISTS watchlist
+(
+    user_id    BIGINT REFERENCES users (id) ON DELETE CASCADE              NOT NULL,
+    listing_id VARCHAR(256) REFERENCES cars (listing_id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP DEFAULT now()                                     NOT NULL,
+
+    PRIMARY KEY (user_id, listing_id)
+);
+
+-- Create hidden_listings table for the cars the users don't want to be notified about
+CREATE TABLE IF NOT EXISTS hidden_listings
+(
+    user_id    BIGINT REFERENCES users (id) ON DELETE CASCADE              NOT NULL,
+    listing_id VARCHAR(256) REFERENCES cars (listing_id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP DEFAULT now()                                     NOT NULL,
+
+    PRIMARY KEY (user_id, listing_id)
+);
+
+-- the notifications of a muted subscription are dropped until muted_until,
+-- a price only subscription notifies only about the price changes of the cars the user already got
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP             NULL,
+    ADD COLUMN IF NOT EXISTS price_only  BOOLEAN DEFAULT FALSE NOT NULL;
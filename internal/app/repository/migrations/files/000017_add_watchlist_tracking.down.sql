@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_watchlist_listing_id;
+
+ALTER TABLE watchlist
+    DROP COLUMN IF EXISTS notified_price,
+    DROP COLUMN IF EXISTS notified_price_eur,
+    DROP COLUMN IF EXISTS notified_removed_at;
@@ -0,0 +1,15 @@
+-- notified_price is the price of the watched car the user knows, a change of the known price is notified,
+-- notified_removed_at is the removal of the car the user was notified about
+ALTER TABLE watchlist
+    ADD COLUMN IF NOT EXISTS notified_price      VARCHAR(256) DEFAULT '' NOT NULL,
+    ADD COLUMN IF NOT EXISTS notified_price_eur  NUMERIC(12, 2)          NULL,
+    ADD COLUMN IF NOT EXISTS notified_removed_at TIMESTAMP               NULL;
+
+UPDATE watchlist w
+SET notified_price      = c.price,
+    notified_price_eur  = c.price_eur,
+    notified_removed_at = c.removed_at
+FROM cars c
+WHERE c.listing_id = w.listing_id;
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_listing_id ON watchlist (listing_id);
@@ -0,0 +1,7 @@
+-- the listings of a digest subscription are collected and sent together every hour or every day at digest_hour,
+-- which is the hour in the time zone of the user (users.time_zone),
+-- digest_sent_at is when the last digest was sent, it's reset when the delivery mode or the digest hour is changed
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS delivery_mode  VARCHAR(16) DEFAULT 'instant' NOT NULL,
+    ADD COLUMN IF NOT EXISTS digest_hour    SMALLINT    DEFAULT 9         NOT NULL,
+    ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP                     NULL;
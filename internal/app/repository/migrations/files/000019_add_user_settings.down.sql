@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS time_zone,
+    DROP COLUMN IF EXISTS quiet_hours_from,
+    DROP COLUMN IF EXISTS quiet_hours_to,
+    DROP COLUMN IF EXISTS mute_weekends;
@@ -0,0 +1,8 @@
+-- the notifications are held during the quiet hours and on the weekends if they are muted,
+-- quiet_hours_from and quiet_hours_to are the hours in the time zone of the user, NULL if there are no quiet hours
+-- the digest_hour of the subscriptions is in the time zone of the user as well
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS time_zone        VARCHAR(64) DEFAULT 'UTC' NOT NULL,
+    ADD COLUMN IF NOT EXISTS quiet_hours_from SMALLINT                  NULL,
+    ADD COLUMN IF NOT EXISTS quiet_hours_to   SMALLINT                  NULL,
+    ADD COLUMN IF NOT EXISTS mute_weekends    BOOLEAN     DEFAULT FALSE NOT NULL;
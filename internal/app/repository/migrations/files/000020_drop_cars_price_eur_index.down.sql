@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_cars_price_eur ON cars (price_eur);
@@ -0,0 +1,4 @@
+-- idx_cars_price_eur was created with the cars table, but no query filters or orders the cars by price_eur alone:
+-- the market stats group the cars by brand, model, year and mileage band and aggregate their prices,
+-- so the index is only a cost for every upsert of a car.
+DROP INDEX IF EXISTS idx_cars_price_eur;
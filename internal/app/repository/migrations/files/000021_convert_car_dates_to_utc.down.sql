@@ -0,0 +1,2 @@
+UPDATE cars
+SET date = (date AT TIME ZONE 'UTC') AT TIME ZONE 'Europe/Belgrade';
@@ -0,0 +1,4 @@
+-- the renew dates of the cars were stored as the wall clock time of the site in Belgrade,
+-- they are converted to UTC as the scraper stores them now
+UPDATE cars
+SET date = (date AT TIME ZONE 'Europe/Belgrade') AT TIME ZONE 'UTC';
@@ -0,0 +1,4 @@
+-- photos_sent is set when the photos of the listing were sent but the text following them failed,
+-- so the retry sends only the text
+ALTER TABLE notification_outbox
+    ADD COLUMN IF NOT EXISTS photos_sent BOOLEAN DEFAULT FALSE NOT NULL;
@@ -6,25 +6,31 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq" // Register PostgreSQL driver
 	pkgerrors "github.com/pkg/errors"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/app/repository/migrations"
 	psql "github.com/gudimz/polovni-auto-alert/internal/app/repository/psql/db/sqlc_gen"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
+var _ repository.DB = (*Repository)(nil)
+
 type Repository struct {
 	l       *logger.Logger
 	cfg     *Config
 	pool    *pgxpool.Pool
+	tx      pgx.Tx
 	queries *psql.Queries
 }
 
@@ -47,6 +53,7 @@ func NewRepo(ctx context.Context, l *logger.Logger, cfg *Config) (*Repository, e
 		l:       l,
 		cfg:     cfg,
 		pool:    pool,
+		tx:      nil,
 		queries: psql.New(pool),
 	}, nil
 }
@@ -103,6 +110,54 @@ func (r *Repository) Close() {
 	}
 }
 
+// WithTx runs fn with a repository bound to a new transaction, the transaction is committed if fn returns nil
+// and rolled back otherwise. A call on a repository which is already in a transaction starts a savepoint.
+func (r *Repository) WithTx(ctx context.Context, fn func(repo repository.DB) error) (err error) {
+	var tx pgx.Tx
+
+	if r.tx != nil {
+		tx, err = r.tx.Begin(ctx)
+	} else {
+		tx, err = r.pool.BeginTx(ctx, pgx.TxOptions{}) //nolint:exhaustruct,nolintlint
+	}
+
+	if err != nil {
+		return pkgerrors.Wrap(err, "failed to begin transaction")
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+
+		if err == nil {
+			return
+		}
+
+		rbErr := tx.Rollback(context.WithoutCancel(ctx))
+		if rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			r.l.Error("failed to rollback transaction", logger.ErrAttr(rbErr))
+		}
+	}()
+
+	if err = fn(&Repository{
+		l:       r.l,
+		cfg:     r.cfg,
+		pool:    r.pool,
+		tx:      tx,
+		queries: r.queries.WithTx(tx),
+	}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return pkgerrors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
 func (r *Repository) UpsertUser(ctx context.Context, request ds.UserRequest) (ds.UserResponse, error) {
 	row, err := r.queries.UpsertUser(ctx, userToDB(request))
 	if err != nil {
@@ -112,6 +167,34 @@ func (r *Repository) UpsertUser(ctx context.Context, request ds.UserRequest) (ds
 	return userFromDB(row), nil
 }
 
+func (r *Repository) GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error) {
+	row, err := r.queries.GetUserSettings(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.UserSettingsResponse{}, ds.ErrNotFound
+		}
+
+		return ds.UserSettingsResponse{}, pkgerrors.Wrap(err, "failed to get user settings from DB")
+	}
+
+	return userSettingsFromDB(row), nil
+}
+
+func (r *Repository) UpdateUserSettings(
+	ctx context.Context, request ds.UpdateUserSettingsRequest,
+) (ds.UserSettingsResponse, error) {
+	row, err := r.queries.UpdateUserSettings(ctx, updateUserSettingsToDB(request))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.UserSettingsResponse{}, ds.ErrNotFound
+		}
+
+		return ds.UserSettingsResponse{}, pkgerrors.Wrap(err, "failed to update user settings in DB")
+	}
+
+	return userSettingsFromDB(psql.GetUserSettingsRow(row)), nil
+}
+
 func (r *Repository) GetAllSubscriptions(ctx context.Context) ([]ds.SubscriptionResponse, error) {
 	rows, err := r.queries.GetAllSubscriptions(ctx)
 	if err != nil {
@@ -142,7 +225,12 @@ func (r *Repository) GetAllSubscriptions(ctx context.Context) ([]ds.Subscription
 func (r *Repository) CreateSubscription(
 	ctx context.Context, sub ds.SubscriptionRequest,
 ) (ds.SubscriptionResponse, error) {
-	row, err := r.queries.CreateSubscription(ctx, subscriptionToDB(sub))
+	req, err := subscriptionToDB(sub)
+	if err != nil {
+		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to convert subscription to DB")
+	}
+
+	row, err := r.queries.CreateSubscription(ctx, req)
 	if err != nil {
 		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to create subscription to DB")
 	}
@@ -150,14 +238,38 @@ func (r *Repository) CreateSubscription(
 	return subscriptionFromDB(row)
 }
 
-func (r *Repository) UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error {
-	req, err := listingToDB(listing)
+func (r *Repository) UpdateSubscription(
+	ctx context.Context, sub ds.UpdateSubscriptionRequest,
+) (ds.SubscriptionResponse, error) {
+	req, err := updateSubscriptionToDB(sub)
 	if err != nil {
-		return pkgerrors.Wrap(err, "failed to convert listing to DB")
+		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to convert subscription to DB")
 	}
 
-	if err = r.queries.UpsertListing(ctx, req); err != nil {
-		return pkgerrors.Wrap(err, "failed to upsert listing to DB")
+	row, err := r.queries.UpdateSubscription(ctx, req)
+	if err != nil {
+		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to update subscription in DB")
+	}
+
+	return subscriptionFromDB(row)
+}
+
+func (r *Repository) UpsertCar(ctx context.Context, car ds.UpsertCarRequest) error {
+	if err := r.queries.UpsertCar(ctx, carToDB(car)); err != nil {
+		return pkgerrors.Wrap(err, "failed to upsert car to DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error {
+	req, err := subscriptionMatchToDB(match)
+	if err != nil {
+		return pkgerrors.Wrap(err, "failed to convert subscription match to DB")
+	}
+
+	if err = r.queries.UpsertSubscriptionMatch(ctx, req); err != nil {
+		return pkgerrors.Wrap(err, "failed to upsert subscription match to DB")
 	}
 
 	return nil
@@ -220,6 +332,212 @@ func (r *Repository) GetListingsByIsNeedSend(ctx context.Context, isNeedSend boo
 	return listings, nil
 }
 
+func (r *Repository) GetListing(ctx context.Context, listingID, subscriptionID string) (ds.ListingResponse, error) {
+	pgUUID, err := stringToPgUUID(subscriptionID)
+	if err != nil {
+		return ds.ListingResponse{}, err
+	}
+
+	row, err := r.queries.GetListing(ctx, psql.GetListingParams{
+		ListingID:      listingID,
+		SubscriptionID: pgUUID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.ListingResponse{}, ds.ErrNotFound
+		}
+
+		return ds.ListingResponse{}, pkgerrors.Wrap(err, "failed to get listing from DB")
+	}
+
+	return listingFromListingDB(row)
+}
+
+func (r *Repository) AddListingPrice(ctx context.Context, price ds.ListingPriceRequest) error {
+	if err := r.queries.AddListingPrice(ctx, psql.AddListingPriceParams{
+		ListingID:   price.ListingID,
+		Price:       price.Price,
+		PriceEur:    decimalToPgNumeric(price.PriceEUR),
+		ObservedAt:  timeToPgTimestamp(price.ObservedAt),
+		StaleBefore: timeToPgTimestamp(price.StaleBefore),
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to add listing price to DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error) {
+	rows, err := r.queries.GetListingPriceHistory(ctx, listingID)
+	if err != nil {
+		return []ds.ListingPriceResponse{}, pkgerrors.Wrap(err, "failed to get listing price history from DB")
+	}
+
+	prices := make([]ds.ListingPriceResponse, 0, len(rows))
+
+	for _, row := range rows {
+		var price ds.ListingPriceResponse
+
+		price, err = listingPriceFromDB(row)
+		if err != nil {
+			r.l.Warn("failed to convert listing price from DB", logger.ErrAttr(err))
+			continue
+		}
+
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+func (r *Repository) GetMedianPricesBySubscriptionID(
+	ctx context.Context, subscriptionID string, observedAfter time.Time,
+) ([]ds.MedianPriceResponse, error) {
+	pgUUID, err := stringToPgUUID(subscriptionID)
+	if err != nil {
+		return []ds.MedianPriceResponse{}, err
+	}
+
+	rows, err := r.queries.GetMedianPricesBySubscriptionID(ctx, psql.GetMedianPricesBySubscriptionIDParams{
+		SubscriptionID: pgUUID,
+		ObservedAfter:  timeToPgTimestamp(observedAfter),
+	})
+	if err != nil {
+		return []ds.MedianPriceResponse{}, pkgerrors.Wrap(err, "failed to get median prices by subscription ID from DB")
+	}
+
+	prices := make([]ds.MedianPriceResponse, 0, len(rows))
+
+	for _, row := range rows {
+		price := pgNumericToDecimal(row.MedianPriceEur)
+		if !price.Valid {
+			continue
+		}
+
+		prices = append(prices, ds.MedianPriceResponse{
+			Day:      row.Day.Time,
+			PriceEUR: price.Decimal,
+		})
+	}
+
+	return prices, nil
+}
+
+func (r *Repository) RefreshMarketStats(ctx context.Context, request ds.RefreshMarketStatsRequest) (int64, error) {
+	rows, err := r.queries.RefreshMarketStats(ctx, psql.RefreshMarketStatsParams{
+		MileageBandKm: int32(request.MileageBandKM), //nolint:gosec,nolintlint
+		SeenAfter:     timeToPgTimestamp(request.SeenAfter),
+	})
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "failed to refresh market stats in DB")
+	}
+
+	return rows, nil
+}
+
+func (r *Repository) GetMarketStats(
+	ctx context.Context, request ds.MarketStatsRequest,
+) (ds.MarketStatsResponse, error) {
+	row, err := r.queries.GetMarketStats(ctx, psql.GetMarketStatsParams{
+		Brand:       request.Brand,
+		Model:       request.Model,
+		Year:        int32(request.Year),        //nolint:gosec,nolintlint
+		MileageBand: int32(request.MileageBand), //nolint:gosec,nolintlint
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.MarketStatsResponse{}, ds.ErrNotFound
+		}
+
+		return ds.MarketStatsResponse{}, pkgerrors.Wrap(err, "failed to get market stats from DB")
+	}
+
+	return marketStatsFromDB(row), nil
+}
+
+func (r *Repository) UpdateSubscriptionDigestSentAt(ctx context.Context, id string, sentAt time.Time) error {
+	pgUUID, err := stringToPgUUID(id)
+	if err != nil {
+		return err
+	}
+
+	if err = r.queries.UpdateSubscriptionDigestSentAt(ctx, psql.UpdateSubscriptionDigestSentAtParams{
+		ID:           pgUUID,
+		DigestSentAt: timeToPgTimestamp(sentAt),
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to update subscription digest sent at in DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) MarkCarsSeen(ctx context.Context, request ds.MarkCarsSeenRequest) error {
+	if err := r.queries.MarkCarsSeen(ctx, psql.MarkCarsSeenParams{
+		SeenAt:     timeToPgTimestamp(request.SeenAt),
+		ListingIds: request.ListingIDs,
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to mark cars as seen in DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) MarkCarsMissing(
+	ctx context.Context, request ds.MarkCarsMissingRequest,
+) ([]ds.MissingCarResponse, error) {
+	subscriptionIDs := make([]pgtype.UUID, 0, len(request.SubscriptionIDs))
+
+	for _, id := range request.SubscriptionIDs {
+		pgUUID, err := stringToPgUUID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptionIDs = append(subscriptionIDs, pgUUID)
+	}
+
+	rows, err := r.queries.MarkCarsMissing(ctx, psql.MarkCarsMissingParams{
+		SeenListingIds:  request.SeenListingIDs,
+		SubscriptionIds: subscriptionIDs,
+	})
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "failed to mark cars as missing in DB")
+	}
+
+	cars := make([]ds.MissingCarResponse, 0, len(rows))
+	for _, row := range rows {
+		cars = append(cars, ds.MissingCarResponse{
+			ListingID:    row.ListingID,
+			Link:         row.Link,
+			MissingCount: int(row.MissingCount),
+		})
+	}
+
+	return cars, nil
+}
+
+func (r *Repository) MarkCarRemoved(ctx context.Context, request ds.MarkCarRemovedRequest) (int64, error) {
+	rows, err := r.queries.MarkCarRemoved(ctx, psql.MarkCarRemovedParams{
+		RemovedAt: timeToPgTimestamp(request.RemovedAt),
+		ListingID: request.ListingID,
+		Notify:    request.Notify,
+	})
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "failed to mark car as removed in DB")
+	}
+
+	return rows, nil
+}
+
+func (r *Repository) DeleteOrphanCars(ctx context.Context, seenBefore time.Time) (int64, error) {
+	rows, err := r.queries.DeleteOrphanCars(ctx, timeToPgTimestamp(seenBefore))
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "failed to delete orphan cars from DB")
+	}
+
+	return rows, nil
+}
+
 func (r *Repository) CreateNotification(
 	ctx context.Context, notification ds.CreateNotificationRequest,
 ) (ds.NotificationResponse, error) {
@@ -324,3 +642,295 @@ func (r *Repository) DeleteSubscriptionByID(ctx context.Context, id string) erro
 
 	return nil
 }
+
+func (r *Repository) GetConversationState(
+	ctx context.Context, userID int64, now time.Time,
+) (ds.ConversationStateResponse, error) {
+	row, err := r.queries.GetConversationState(ctx, psql.GetConversationStateParams{
+		UserID:    userID,
+		ExpiresAt: timeToPgTimestamp(now),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.ConversationStateResponse{}, ds.ErrNotFound
+		}
+
+		return ds.ConversationStateResponse{}, pkgerrors.Wrap(err, "failed to get conversation state from DB")
+	}
+
+	return conversationStateFromDB(row), nil
+}
+
+func (r *Repository) UpsertConversationState(ctx context.Context, state ds.ConversationStateRequest) error {
+	if err := r.queries.UpsertConversationState(ctx, psql.UpsertConversationStateParams{
+		UserID:    state.UserID,
+		State:     state.State,
+		ExpiresAt: timeToPgTimestamp(state.ExpiresAt),
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to upsert conversation state to DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteConversationState(ctx context.Context, userID int64) error {
+	if err := r.queries.DeleteConversationState(ctx, userID); err != nil {
+		return pkgerrors.Wrap(err, "failed to delete conversation state from DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteExpiredConversationStates(ctx context.Context, now time.Time) (int64, error) {
+	count, err := r.queries.DeleteExpiredConversationStates(ctx, timeToPgTimestamp(now))
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "failed to delete expired conversation states from DB")
+	}
+
+	return count, nil
+}
+
+func (r *Repository) EnqueueNotifications(ctx context.Context, now time.Time) (int64, error) {
+	count, err := r.queries.EnqueueNotifications(ctx, timeToPgTimestamp(now))
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "failed to enqueue notifications to DB")
+	}
+
+	return count, nil
+}
+
+func (r *Repository) GetDueOutboxNotifications(
+	ctx context.Context, now time.Time,
+) ([]ds.OutboxNotificationResponse, error) {
+	rows, err := r.queries.GetDueOutboxNotifications(ctx, timeToPgTimestamp(now))
+	if err != nil {
+		return []ds.OutboxNotificationResponse{}, pkgerrors.Wrap(err, "failed to get due outbox notifications from DB")
+	}
+
+	notifications := make([]ds.OutboxNotificationResponse, 0, len(rows))
+
+	for _, row := range rows {
+		var notification ds.OutboxNotificationResponse
+
+		notification, err = outboxNotificationFromDB(row)
+		if err != nil {
+			r.l.Warn("failed to convert outbox notification from DB", logger.ErrAttr(err))
+			continue
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (r *Repository) UpdateOutboxNotification(ctx context.Context, request ds.UpdateOutboxNotificationRequest) error {
+	pgUUID, err := stringToPgUUID(request.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.queries.UpdateOutboxNotification(ctx, psql.UpdateOutboxNotificationParams{
+		ID:            pgUUID,
+		Attempts:      int32(request.Attempts), //nolint:gosec,nolintlint
+		NextAttemptAt: timeToPgTimestamp(request.NextAttemptAt),
+		LastError:     request.LastError,
+		PhotosSent:    request.PhotosSent,
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to update outbox notification in DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteOutboxNotification(ctx context.Context, id string) error {
+	pgUUID, err := stringToPgUUID(id)
+	if err != nil {
+		return err
+	}
+
+	if err = r.queries.DeleteOutboxNotification(ctx, pgUUID); err != nil {
+		return pkgerrors.Wrap(err, "failed to delete outbox notification from DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) AddToWatchlist(ctx context.Context, userID int64, listingID string) error {
+	if err := r.queries.AddToWatchlist(ctx, psql.AddToWatchlistParams{
+		UserID:    userID,
+		ListingID: listingID,
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to add listing to watchlist in DB")
+	}
+
+	return nil
+}
+
+// RemoveFromWatchlist removes the car from the watchlist of the user, ds.ErrNotFound is returned
+// if the car is not on the watchlist.
+func (r *Repository) RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error {
+	rows, err := r.queries.RemoveFromWatchlist(ctx, psql.RemoveFromWatchlistParams{
+		UserID:    userID,
+		ListingID: listingID,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "failed to remove listing from watchlist in DB")
+	}
+
+	if rows == 0 {
+		return ds.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) GetWatchlistByUserID(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error) {
+	rows, err := r.queries.GetWatchlistByUserID(ctx, userID)
+	if err != nil {
+		return []ds.WatchlistItemResponse{}, pkgerrors.Wrap(err, "failed to get watchlist by user id from DB")
+	}
+
+	items := make([]ds.WatchlistItemResponse, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, watchlistItemFromDB(row))
+	}
+
+	return items, nil
+}
+
+// GetWatchedCars returns the cars of all the watchlists which are not removed from the site.
+func (r *Repository) GetWatchedCars(ctx context.Context) ([]ds.WatchedCarResponse, error) {
+	rows, err := r.queries.GetWatchedCars(ctx)
+	if err != nil {
+		return []ds.WatchedCarResponse{}, pkgerrors.Wrap(err, "failed to get watched cars from DB")
+	}
+
+	cars := make([]ds.WatchedCarResponse, 0, len(rows))
+	for _, row := range rows {
+		cars = append(cars, ds.WatchedCarResponse{
+			ListingID: row.ListingID,
+			Link:      row.Link,
+		})
+	}
+
+	return cars, nil
+}
+
+func (r *Repository) UpdateCarPrice(ctx context.Context, request ds.UpdateCarPriceRequest) error {
+	if err := r.queries.UpdateCarPrice(ctx, psql.UpdateCarPriceParams{
+		ListingID:  request.ListingID,
+		Price:      request.Price,
+		PriceEur:   decimalToPgNumeric(request.PriceEUR),
+		LastSeenAt: timeToPgTimestamp(request.SeenAt),
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to update car price in DB")
+	}
+
+	return nil
+}
+
+// GetWatchlistChanges returns the watched cars whose price or removal the users were not notified about yet.
+func (r *Repository) GetWatchlistChanges(ctx context.Context) ([]ds.WatchlistChangeResponse, error) {
+	rows, err := r.queries.GetWatchlistChanges(ctx)
+	if err != nil {
+		return []ds.WatchlistChangeResponse{}, pkgerrors.Wrap(err, "failed to get watchlist changes from DB")
+	}
+
+	changes := make([]ds.WatchlistChangeResponse, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, watchlistChangeFromDB(row))
+	}
+
+	return changes, nil
+}
+
+// UpdateWatchlistNotified records the price and the removal of the watched car the user was notified about.
+func (r *Repository) UpdateWatchlistNotified(ctx context.Context, request ds.UpdateWatchlistNotifiedRequest) error {
+	if err := r.queries.UpdateWatchlistNotified(ctx, psql.UpdateWatchlistNotifiedParams{
+		UserID:            request.UserID,
+		ListingID:         request.ListingID,
+		NotifiedPrice:     request.Price,
+		NotifiedPriceEur:  decimalToPgNumeric(request.PriceEUR),
+		NotifiedRemovedAt: timeToPgTimestamp(request.RemovedAt.ValueOrZero()),
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to update watchlist notified in DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) HideListing(ctx context.Context, userID int64, listingID string) error {
+	if err := r.queries.HideListing(ctx, psql.HideListingParams{
+		UserID:    userID,
+		ListingID: listingID,
+	}); err != nil {
+		return pkgerrors.Wrap(err, "failed to hide listing in DB")
+	}
+
+	return nil
+}
+
+func (r *Repository) IsListingHidden(ctx context.Context, userID int64, listingID string) (bool, error) {
+	hidden, err := r.queries.IsListingHidden(ctx, psql.IsListingHiddenParams{
+		UserID:    userID,
+		ListingID: listingID,
+	})
+	if err != nil {
+		return false, pkgerrors.Wrap(err, "failed to check if listing is hidden in DB")
+	}
+
+	return hidden, nil
+}
+
+// MuteSubscription mutes the subscription of the user until the time, ds.ErrNotFound is returned if the user
+// has no such subscription.
+func (r *Repository) MuteSubscription(
+	ctx context.Context, userID int64, id string, until time.Time,
+) (ds.SubscriptionResponse, error) {
+	pgUUID, err := stringToPgUUID(id)
+	if err != nil {
+		return ds.SubscriptionResponse{}, err
+	}
+
+	row, err := r.queries.MuteSubscription(ctx, psql.MuteSubscriptionParams{
+		ID:         pgUUID,
+		UserID:     userID,
+		MutedUntil: timeToPgTimestamp(until),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.SubscriptionResponse{}, ds.ErrNotFound
+		}
+
+		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to mute subscription in DB")
+	}
+
+	return subscriptionFromDB(row)
+}
+
+// SetSubscriptionPriceOnly switches the subscription of the user to the notifications about the price changes
+// only or about all the listings, ds.ErrNotFound is returned if the user has no such subscription.
+func (r *Repository) SetSubscriptionPriceOnly(
+	ctx context.Context, userID int64, id string, priceOnly bool,
+) (ds.SubscriptionResponse, error) {
+	pgUUID, err := stringToPgUUID(id)
+	if err != nil {
+		return ds.SubscriptionResponse{}, err
+	}
+
+	row, err := r.queries.SetSubscriptionPriceOnly(ctx, psql.SetSubscriptionPriceOnlyParams{
+		ID:        pgUUID,
+		UserID:    userID,
+		PriceOnly: priceOnly,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ds.SubscriptionResponse{}, ds.ErrNotFound
+		}
+
+		return ds.SubscriptionResponse{}, pkgerrors.Wrap(err, "failed to set subscription price only in DB")
+	}
+
+	return subscriptionFromDB(row)
+}
@@ -0,0 +1,834 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/marketstats"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// The tests run against the database from the DB_* environment variables, see Config.
+// Run them with: make test-integration
+
+var errCommon = errors.New("common error")
+
+type RepositoryTestSuite struct {
+	suite.Suite
+	repo *Repository
+}
+
+func (s *RepositoryTestSuite) SetupSuite() {
+	var err error
+
+	s.repo, err = NewRepo(context.Background(), logger.NewLogger(), NewConfig())
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Migrate())
+}
+
+func (s *RepositoryTestSuite) TearDownSuite() {
+	s.repo.Close()
+}
+
+// createUserWithListing creates a user with a subscription which has a listing.
+func (s *RepositoryTestSuite) createUserWithListing(ctx context.Context) (int64, ds.SubscriptionResponse) {
+	userID := time.Now().UnixNano()
+
+	_, err := s.repo.UpsertUser(ctx, ds.UserRequest{ID: userID, Username: "test"}) //nolint:exhaustruct,nolintlint
+	s.Require().NoError(err)
+
+	sub, err := s.repo.CreateSubscription(ctx, ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+		UserID: userID,
+		Brand:  "bmw",
+		Model:  []string{"m3"},
+	})
+	s.Require().NoError(err)
+
+	listingID := uuid.NewString()
+
+	err = s.repo.UpsertCar(ctx, ds.UpsertCarRequest{ //nolint:exhaustruct,nolintlint
+		ListingID: listingID,
+		Title:     "Best bmw",
+		Price:     "2400€",
+		Date:      time.Now(),
+	})
+	s.Require().NoError(err)
+
+	err = s.repo.UpsertSubscriptionMatch(ctx, ds.UpsertSubscriptionMatchRequest{ //nolint:exhaustruct,nolintlint
+		ListingID:      listingID,
+		SubscriptionID: sub.ID,
+		Price:          "2400€",
+	})
+	s.Require().NoError(err)
+
+	return userID, sub
+}
+
+// requireUserData checks if the user still has the subscription and its listing.
+func (s *RepositoryTestSuite) requireUserData(ctx context.Context, userID int64, subID string, exists bool) {
+	subscriptions, err := s.repo.GetSubscriptionsByUserID(ctx, userID)
+	s.Require().NoError(err)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, subID)
+	s.Require().NoError(err)
+
+	if exists {
+		s.Require().Len(subscriptions, 1)
+		s.Require().Len(listings, 1)
+
+		return
+	}
+
+	s.Require().Empty(subscriptions)
+	s.Require().Empty(listings)
+}
+
+// deleteUser deletes the user with all the data the same way the services do.
+func deleteUser(ctx context.Context, repo repository.DB, userID int64, subID string) error {
+	if err := repo.DeleteListingsBySubscriptionIDs(ctx, []string{subID}); err != nil {
+		return err
+	}
+
+	if err := repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	return repo.DeleteUserByID(ctx, userID)
+}
+
+func (s *RepositoryTestSuite) TestRepository_WithTx() {
+	testCases := []struct {
+		name      string
+		fn        func(ctx context.Context, repo repository.DB, userID int64, subID string) error
+		expectErr error
+		exists    bool
+	}{
+		{
+			name:   "commit",
+			fn:     deleteUser,
+			exists: false,
+		},
+		{
+			name: "rollback on error",
+			fn: func(ctx context.Context, repo repository.DB, userID int64, subID string) error {
+				if err := repo.DeleteListingsBySubscriptionIDs(ctx, []string{subID}); err != nil {
+					return err
+				}
+
+				if err := repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
+					return err
+				}
+
+				return errCommon
+			},
+			expectErr: errCommon,
+			exists:    true,
+		},
+		{
+			name: "savepoint is rolled back, transaction is committed",
+			fn: func(ctx context.Context, repo repository.DB, userID int64, subID string) error {
+				err := repo.WithTx(ctx, func(txRepo repository.DB) error {
+					if err := deleteUser(ctx, txRepo, userID, subID); err != nil {
+						return err
+					}
+
+					return errCommon
+				})
+				if !errors.Is(err, errCommon) {
+					return err
+				}
+
+				return nil
+			},
+			exists: true,
+		},
+		{
+			name: "savepoint is committed, transaction is rolled back",
+			fn: func(ctx context.Context, repo repository.DB, userID int64, subID string) error {
+				if err := repo.WithTx(ctx, func(txRepo repository.DB) error {
+					return deleteUser(ctx, txRepo, userID, subID)
+				}); err != nil {
+					return err
+				}
+
+				return errCommon
+			},
+			expectErr: errCommon,
+			exists:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx := context.Background()
+			userID, sub := s.createUserWithListing(ctx)
+
+			err := s.repo.WithTx(ctx, func(repo repository.DB) error {
+				return tc.fn(ctx, repo, userID, sub.ID)
+			})
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().ErrorIs(err, tc.expectErr)
+			default:
+				s.Require().NoError(err)
+			}
+
+			s.requireUserData(ctx, userID, sub.ID, tc.exists)
+		})
+	}
+}
+
+func (s *RepositoryTestSuite) TestRepository_WithTx_Panic() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	s.Require().Panics(func() {
+		_ = s.repo.WithTx(ctx, func(repo repository.DB) error {
+			if err := deleteUser(ctx, repo, userID, sub.ID); err != nil {
+				return err
+			}
+
+			panic(errCommon)
+		})
+	})
+
+	s.requireUserData(ctx, userID, sub.ID, true)
+}
+
+func (s *RepositoryTestSuite) TestRepository_UpdateSubscription_Keywords() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	defer func() {
+		s.Require().NoError(deleteUser(ctx, s.repo, userID, sub.ID))
+	}()
+
+	// the subscription is created without the keywords
+	s.Require().Empty(sub.IncludeKeywords)
+	s.Require().Empty(sub.ExcludeKeywords)
+
+	updated, err := s.repo.UpdateSubscription(ctx, ds.UpdateSubscriptionRequest{ //nolint:exhaustruct,nolintlint
+		ID:              sub.ID,
+		UserID:          userID,
+		Model:           sub.Model,
+		Chassis:         []string{},
+		Region:          []string{},
+		IncludeKeywords: []string{"xdrive", "m paket"},
+		ExcludeKeywords: []string{"oštećen"},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"xdrive", "m paket"}, updated.IncludeKeywords)
+	s.Require().Equal([]string{"oštećen"}, updated.ExcludeKeywords)
+
+	got, err := s.repo.GetSubscriptionByID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(updated.IncludeKeywords, got.IncludeKeywords)
+	s.Require().Equal(updated.ExcludeKeywords, got.ExcludeKeywords)
+}
+
+func (s *RepositoryTestSuite) TestRepository_UpdateSubscription_SearchFilters() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	defer func() {
+		s.Require().NoError(deleteUser(ctx, s.repo, userID, sub.ID))
+	}()
+
+	// the subscription is created without the search filters
+	s.Require().Empty(sub.Fuel)
+	s.Require().Empty(sub.MileageTo)
+	s.Require().Empty(sub.ExtraParams)
+
+	updated, err := s.repo.UpdateSubscription(ctx, ds.UpdateSubscriptionRequest{ //nolint:exhaustruct,nolintlint
+		ID:          sub.ID,
+		UserID:      userID,
+		Model:       sub.Model,
+		Chassis:     []string{},
+		Region:      []string{},
+		Fuel:        []string{"Dizel", "Benzin"},
+		Gearbox:     []string{"Automatski / poluautomatski"},
+		MileageTo:   "150000",
+		PowerFrom:   "100",
+		Damage:      []string{"Nije oštećen"},
+		ExtraParams: map[string]string{"showOldNew": "old", "without_price": "1"},
+	})
+	s.Require().NoError(err)
+
+	got, err := s.repo.GetSubscriptionByID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(updated, got)
+	s.Require().Equal([]string{"Dizel", "Benzin"}, got.Fuel)
+	s.Require().Equal([]string{"Automatski / poluautomatski"}, got.Gearbox)
+	s.Require().Equal("150000", got.MileageTo)
+	s.Require().Equal("100", got.PowerFrom)
+	s.Require().Empty(got.PowerTo)
+	s.Require().Empty(got.Color)
+	s.Require().Equal([]string{"Nije oštećen"}, got.Damage)
+	s.Require().Equal(map[string]string{"showOldNew": "old", "without_price": "1"}, got.ExtraParams)
+}
+
+func (s *RepositoryTestSuite) TestRepository_UpsertCar_SharedBySubscriptions() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	other, err := s.repo.CreateSubscription(ctx, ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+		UserID: userID,
+		Brand:  "bmw",
+	})
+	s.Require().NoError(err)
+
+	err = s.repo.UpsertSubscriptionMatch(ctx, ds.UpsertSubscriptionMatchRequest{ //nolint:exhaustruct,nolintlint
+		ListingID:      listings[0].ListingID,
+		SubscriptionID: other.ID,
+		Price:          "2400€",
+		IsNeedSend:     true,
+	})
+	s.Require().NoError(err)
+
+	// the car changes once for all subscriptions, the send state stays per subscription
+	err = s.repo.UpsertCar(ctx, ds.UpsertCarRequest{ //nolint:exhaustruct,nolintlint
+		ListingID: listings[0].ListingID,
+		Title:     "Best bmw m3",
+		Price:     "2400€",
+		Date:      time.Now(),
+	})
+	s.Require().NoError(err)
+
+	for _, tc := range []struct {
+		subID      string
+		isNeedSend bool
+	}{
+		{subID: sub.ID, isNeedSend: false},
+		{subID: other.ID, isNeedSend: true},
+	} {
+		listing, err := s.repo.GetListing(ctx, listings[0].ListingID, tc.subID)
+		s.Require().NoError(err)
+		s.Require().Equal("Best bmw m3", listing.Title)
+		s.Require().Equal(tc.isNeedSend, listing.IsNeedSend)
+	}
+}
+
+func (s *RepositoryTestSuite) TestRepository_UpsertSubscriptionMatch_Relist() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	listingID := uuid.NewString()
+
+	err = s.repo.UpsertCar(ctx, ds.UpsertCarRequest{ //nolint:exhaustruct,nolintlint
+		ListingID: listingID,
+		Title:     "Best bmw",
+		Price:     "2200€",
+		Date:      time.Now(),
+		ImageHash: null.IntFrom(-42),
+		ImageURL:  "https://images.polovniautomobili.com/thumbs/1.jpg",
+		Details: ds.ListingDetails{ //nolint:exhaustruct,nolintlint
+			GalleryURLs: []string{"https://images.polovniautomobili.com/big/1.jpg"},
+		},
+	})
+	s.Require().NoError(err)
+
+	err = s.repo.UpsertSubscriptionMatch(ctx, ds.UpsertSubscriptionMatchRequest{ //nolint:exhaustruct,nolintlint
+		ListingID:      listingID,
+		SubscriptionID: sub.ID,
+		Price:          "2200€",
+		IsNeedSend:     true,
+		RelistedFrom:   null.StringFrom(listings[0].ListingID),
+		RelistedPrice:  null.StringFrom("2400€"),
+	})
+	s.Require().NoError(err)
+
+	// the relist and the photos are kept when the listing is updated without them
+	err = s.repo.UpsertCar(ctx, ds.UpsertCarRequest{ //nolint:exhaustruct,nolintlint
+		ListingID: listingID,
+		Title:     "Best bmw",
+		Price:     "2200€",
+		Date:      time.Now(),
+	})
+	s.Require().NoError(err)
+
+	err = s.repo.UpsertSubscriptionMatch(ctx, ds.UpsertSubscriptionMatchRequest{ //nolint:exhaustruct,nolintlint
+		ListingID:      listingID,
+		SubscriptionID: sub.ID,
+		Price:          "2200€",
+	})
+	s.Require().NoError(err)
+
+	listing, err := s.repo.GetListing(ctx, listingID, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(null.IntFrom(-42), listing.ImageHash)
+	s.Require().Equal("https://images.polovniautomobili.com/thumbs/1.jpg", listing.ImageURL)
+	s.Require().Equal([]string{"https://images.polovniautomobili.com/big/1.jpg"}, listing.Details.GalleryURLs)
+	s.Require().Equal(null.StringFrom(listings[0].ListingID), listing.RelistedFrom)
+	s.Require().Equal(null.StringFrom("2400€"), listing.RelistedPrice)
+
+	s.Require().NoError(deleteUser(ctx, s.repo, userID, sub.ID))
+}
+
+func (s *RepositoryTestSuite) TestRepository_AddListingPrice() {
+	ctx := context.Background()
+	_, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	listingID := listings[0].ListingID
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	price := func(raw string, eur decimal.NullDecimal, observedAt time.Time) ds.ListingPriceRequest {
+		return ds.ListingPriceRequest{
+			ListingID:   listingID,
+			Price:       raw,
+			PriceEUR:    eur,
+			ObservedAt:  observedAt,
+			StaleBefore: observedAt.Add(-24 * time.Hour),
+		}
+	}
+
+	for _, req := range []ds.ListingPriceRequest{
+		price("2400€", decimal.NewNullDecimal(decimal.NewFromInt(2400)), now.Add(-72*time.Hour)),
+		// the same typed price is skipped
+		price("2.400 €", decimal.NewNullDecimal(decimal.NewFromInt(2400)), now.Add(-71*time.Hour)),
+		// the same price is recorded again when the last observation is stale
+		price("2400€", decimal.NewNullDecimal(decimal.NewFromInt(2400)), now.Add(-24*time.Hour)),
+		price("2000€", decimal.NewNullDecimal(decimal.NewFromInt(2000)), now.Add(-time.Hour)),
+		// the same raw price is skipped
+		price("2000€", decimal.NullDecimal{}, now),
+	} {
+		s.Require().NoError(s.repo.AddListingPrice(ctx, req))
+	}
+
+	history, err := s.repo.GetListingPriceHistory(ctx, listingID)
+	s.Require().NoError(err)
+	s.Require().Len(history, 3)
+	s.Require().Equal("2400€", history[0].Price)
+	s.Require().Equal("2400€", history[1].Price)
+	s.Require().Equal("2000€", history[2].Price)
+
+	// the median of a day with the only listing is its price
+	median, err := s.repo.GetMedianPricesBySubscriptionID(ctx, sub.ID, now.Add(-30*time.Hour))
+	s.Require().NoError(err)
+	s.Require().Len(median, 2)
+	s.Require().True(median[0].PriceEUR.Equal(decimal.NewFromInt(2400)))
+	s.Require().True(median[1].PriceEUR.Equal(decimal.NewFromInt(2000)))
+}
+
+func (s *RepositoryTestSuite) TestRepository_RefreshMarketStats() {
+	ctx := context.Background()
+	_, sub := s.createUserWithListing(ctx)
+
+	// the cars of one group, the first one is out of the mileage band
+	for i, car := range []struct {
+		price   int64
+		mileage int64
+	}{
+		{price: 9000, mileage: 50000},
+		{price: 10000, mileage: 10000},
+		{price: 12000, mileage: 20000},
+		{price: 14000, mileage: 30000},
+	} {
+		listingID := fmt.Sprintf("market-%s-%d", sub.ID, i)
+
+		err := s.repo.UpsertCar(ctx, ds.UpsertCarRequest{ //nolint:exhaustruct,nolintlint
+			ListingID: listingID,
+			Title:     "bmw 320",
+			Price:     fmt.Sprintf("%d€", car.price),
+			Date:      time.Now(),
+			PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(car.price)),
+			MileageKM: null.IntFrom(car.mileage),
+			Year:      null.IntFrom(2015),
+			Brand:     "bmw",
+			Model:     "320-" + sub.ID,
+		})
+		s.Require().NoError(err)
+	}
+
+	_, err := s.repo.RefreshMarketStats(ctx, ds.RefreshMarketStatsRequest{
+		MileageBandKM: marketstats.MileageBandKM,
+		SeenAfter:     time.Now().Add(-time.Hour),
+	})
+	s.Require().NoError(err)
+
+	stats, err := s.repo.GetMarketStats(ctx, ds.MarketStatsRequest{
+		Brand:       "bmw",
+		Model:       "320-" + sub.ID,
+		Year:        2015,
+		MileageBand: 0,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(3, stats.SampleSize)
+	s.Require().True(stats.MedianPriceEUR.Equal(decimal.NewFromInt(12000)))
+	s.Require().True(stats.P25PriceEUR.Equal(decimal.NewFromInt(11000)))
+	s.Require().True(stats.P75PriceEUR.Equal(decimal.NewFromInt(13000)))
+
+	// the groups without recently seen cars are removed
+	_, err = s.repo.RefreshMarketStats(ctx, ds.RefreshMarketStatsRequest{
+		MileageBandKM: marketstats.MileageBandKM,
+		SeenAfter:     time.Now().Add(time.Hour),
+	})
+	s.Require().NoError(err)
+
+	_, err = s.repo.GetMarketStats(ctx, ds.MarketStatsRequest{
+		Brand:       "bmw",
+		Model:       "320-" + sub.ID,
+		Year:        2015,
+		MileageBand: 1,
+	})
+	s.Require().ErrorIs(err, ds.ErrNotFound)
+}
+
+func (s *RepositoryTestSuite) TestRepository_MarkCarRemoved() {
+	ctx := context.Background()
+	_, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	listingID := listings[0].ListingID
+
+	// the listing was sent, so the subscriber is notified about its removal
+	_, err = s.repo.CreateNotification(ctx, ds.CreateNotificationRequest{
+		SubscriptionID: sub.ID,
+		ListingID:      listingID,
+		Status:         ds.StatusSent,
+		Reason:         "",
+	})
+	s.Require().NoError(err)
+
+	for i := 1; i <= 2; i++ {
+		missing, err := s.repo.MarkCarsMissing(ctx, ds.MarkCarsMissingRequest{
+			SubscriptionIDs: []string{sub.ID},
+			SeenListingIDs:  []string{},
+		})
+		s.Require().NoError(err)
+		s.Require().Equal([]ds.MissingCarResponse{{ListingID: listingID, Link: "", MissingCount: i}}, missing)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	enqueued, err := s.repo.MarkCarRemoved(ctx, ds.MarkCarRemovedRequest{
+		ListingID: listingID,
+		RemovedAt: now,
+		Notify:    true,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(1), enqueued)
+
+	listing, err := s.repo.GetListing(ctx, listingID, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(null.TimeFrom(now), listing.RemovedAt)
+
+	notifications, err := s.repo.GetDueOutboxNotifications(ctx, now)
+	s.Require().NoError(err)
+
+	idx := slices.IndexFunc(notifications, func(n ds.OutboxNotificationResponse) bool {
+		return n.ListingID == listingID && n.Kind == ds.NotificationKindRemoved
+	})
+	s.Require().NotEqual(-1, idx)
+	s.Require().Equal(sub.ID, notifications[idx].SubscriptionID)
+	s.Require().False(notifications[idx].PhotosSent)
+
+	// the retry of the notification remembers the photos sent before the failure
+	s.Require().NoError(s.repo.UpdateOutboxNotification(ctx, ds.UpdateOutboxNotificationRequest{
+		ID:            notifications[idx].ID,
+		Attempts:      1,
+		NextAttemptAt: now,
+		LastError:     "failed to send text",
+		PhotosSent:    true,
+	}))
+
+	notifications, err = s.repo.GetDueOutboxNotifications(ctx, now)
+	s.Require().NoError(err)
+
+	idx = slices.IndexFunc(notifications, func(n ds.OutboxNotificationResponse) bool {
+		return n.ListingID == listingID && n.Kind == ds.NotificationKindRemoved
+	})
+	s.Require().NotEqual(-1, idx)
+	s.Require().True(notifications[idx].PhotosSent)
+	s.Require().Equal(1, notifications[idx].Attempts)
+
+	// the removed car is not counted as missing and is not removed again
+	missing, err := s.repo.MarkCarsMissing(ctx, ds.MarkCarsMissingRequest{
+		SubscriptionIDs: []string{sub.ID},
+		SeenListingIDs:  []string{},
+	})
+	s.Require().NoError(err)
+	s.Require().Empty(missing)
+
+	enqueued, err = s.repo.MarkCarRemoved(ctx, ds.MarkCarRemovedRequest{
+		ListingID: listingID,
+		RemovedAt: now,
+		Notify:    true,
+	})
+	s.Require().NoError(err)
+	s.Require().Zero(enqueued)
+
+	// the car is on the market again after it was seen
+	err = s.repo.MarkCarsSeen(ctx, ds.MarkCarsSeenRequest{ListingIDs: []string{listingID}, SeenAt: now})
+	s.Require().NoError(err)
+
+	listing, err = s.repo.GetListing(ctx, listingID, sub.ID)
+	s.Require().NoError(err)
+	s.Require().False(listing.RemovedAt.Valid)
+}
+
+func (s *RepositoryTestSuite) TestRepository_DeleteOrphanCars() {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// createOrphanCar creates a car with a price whose match is deleted, as if its subscription was removed
+	createOrphanCar := func() (int64, string) {
+		userID, sub := s.createUserWithListing(ctx)
+
+		listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+		s.Require().NoError(err)
+		s.Require().Len(listings, 1)
+
+		listingID := listings[0].ListingID
+
+		s.Require().NoError(s.repo.AddListingPrice(ctx, ds.ListingPriceRequest{ //nolint:exhaustruct,nolintlint
+			ListingID:   listingID,
+			Price:       "2400€",
+			ObservedAt:  now,
+			StaleBefore: now.Add(-24 * time.Hour),
+		}))
+		s.Require().NoError(s.repo.DeleteListingsBySubscriptionIDs(ctx, []string{sub.ID}))
+
+		return userID, listingID
+	}
+
+	// requireCar checks if the car is kept by its price history, which is deleted with the car
+	requireCar := func(listingID string, exists bool) {
+		history, err := s.repo.GetListingPriceHistory(ctx, listingID)
+		s.Require().NoError(err)
+		s.Require().Equal(exists, len(history) == 1)
+	}
+
+	_, orphanID := createOrphanCar()
+	watcherID, watchedID := createOrphanCar()
+	s.Require().NoError(s.repo.AddToWatchlist(ctx, watcherID, watchedID))
+
+	// the cars seen recently are kept for the market stats
+	_, err := s.repo.DeleteOrphanCars(ctx, now.Add(-time.Hour))
+	s.Require().NoError(err)
+	requireCar(orphanID, true)
+
+	deleted, err := s.repo.DeleteOrphanCars(ctx, now.Add(time.Hour))
+	s.Require().NoError(err)
+	s.Require().Positive(deleted)
+	requireCar(orphanID, false)
+	requireCar(watchedID, true)
+}
+
+func (s *RepositoryTestSuite) TestRepository_ListingActions() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	listingID := listings[0].ListingID
+
+	// the buttons can be tapped several times
+	for range 2 {
+		s.Require().NoError(s.repo.AddToWatchlist(ctx, userID, listingID))
+		s.Require().NoError(s.repo.HideListing(ctx, userID, listingID))
+	}
+
+	hidden, err := s.repo.IsListingHidden(ctx, userID, listingID)
+	s.Require().NoError(err)
+	s.Require().True(hidden)
+
+	hidden, err = s.repo.IsListingHidden(ctx, userID+1, listingID)
+	s.Require().NoError(err)
+	s.Require().False(hidden)
+
+	until := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Microsecond)
+
+	muted, err := s.repo.MuteSubscription(ctx, userID, sub.ID, until)
+	s.Require().NoError(err)
+	s.Require().Equal(null.TimeFrom(until), muted.MutedUntil)
+
+	// the price only button can be tapped several times too
+	for range 2 {
+		priceOnly, err := s.repo.SetSubscriptionPriceOnly(ctx, userID, sub.ID, true)
+		s.Require().NoError(err)
+		s.Require().True(priceOnly.PriceOnly)
+	}
+
+	allListings, err := s.repo.SetSubscriptionPriceOnly(ctx, userID, sub.ID, false)
+	s.Require().NoError(err)
+	s.Require().False(allListings.PriceOnly)
+
+	// the subscription of another user is not changed
+	_, err = s.repo.MuteSubscription(ctx, userID+1, sub.ID, until)
+	s.Require().ErrorIs(err, ds.ErrNotFound)
+
+	_, err = s.repo.SetSubscriptionPriceOnly(ctx, userID+1, sub.ID, true)
+	s.Require().ErrorIs(err, ds.ErrNotFound)
+}
+
+func (s *RepositoryTestSuite) TestRepository_Watchlist() {
+	ctx := context.Background()
+	userID, sub := s.createUserWithListing(ctx)
+
+	listings, err := s.repo.GetListingsBySubscriptionID(ctx, sub.ID)
+	s.Require().NoError(err)
+	s.Require().Len(listings, 1)
+
+	listingID := listings[0].ListingID
+
+	s.Require().NoError(s.repo.AddToWatchlist(ctx, userID, listingID))
+
+	items, err := s.repo.GetWatchlistByUserID(ctx, userID)
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Require().Equal(listingID, items[0].ListingID)
+	s.Require().Equal("2400€", items[0].Price)
+
+	watched, err := s.repo.GetWatchedCars(ctx)
+	s.Require().NoError(err)
+	s.Require().True(slices.ContainsFunc(watched, func(car ds.WatchedCarResponse) bool {
+		return car.ListingID == listingID
+	}))
+
+	// findChange finds the change of the car of the user, the other tests share the database
+	findChange := func() (ds.WatchlistChangeResponse, bool) {
+		changes, err := s.repo.GetWatchlistChanges(ctx)
+		s.Require().NoError(err)
+
+		idx := slices.IndexFunc(changes, func(c ds.WatchlistChangeResponse) bool {
+			return c.UserID == userID && c.ListingID == listingID
+		})
+		if idx == -1 {
+			return ds.WatchlistChangeResponse{}, false
+		}
+
+		return changes[idx], true
+	}
+
+	_, found := findChange()
+	s.Require().False(found)
+
+	priceEUR := decimal.NewNullDecimal(decimal.NewFromInt(2200))
+
+	err = s.repo.UpdateCarPrice(ctx, ds.UpdateCarPriceRequest{
+		ListingID: listingID,
+		Price:     "2200€",
+		PriceEUR:  priceEUR,
+		SeenAt:    time.Now().UTC(),
+	})
+	s.Require().NoError(err)
+
+	change, found := findChange()
+	s.Require().True(found)
+	s.Require().Equal("2200€", change.Price)
+	s.Require().Equal("2400€", change.NotifiedPrice)
+
+	err = s.repo.UpdateWatchlistNotified(ctx, ds.UpdateWatchlistNotifiedRequest{
+		UserID:    userID,
+		ListingID: listingID,
+		Price:     change.Price,
+		PriceEUR:  change.PriceEUR,
+		RemovedAt: change.RemovedAt,
+	})
+	s.Require().NoError(err)
+
+	_, found = findChange()
+	s.Require().False(found)
+
+	// the removal of the car is a change too, and the removed car is not checked anymore
+	_, err = s.repo.MarkCarRemoved(ctx, ds.MarkCarRemovedRequest{
+		ListingID: listingID,
+		RemovedAt: time.Now().UTC(),
+		Notify:    false,
+	})
+	s.Require().NoError(err)
+
+	change, found = findChange()
+	s.Require().True(found)
+	s.Require().True(change.RemovedAt.Valid)
+
+	watched, err = s.repo.GetWatchedCars(ctx)
+	s.Require().NoError(err)
+	s.Require().False(slices.ContainsFunc(watched, func(car ds.WatchedCarResponse) bool {
+		return car.ListingID == listingID
+	}))
+
+	s.Require().NoError(s.repo.RemoveFromWatchlist(ctx, userID, listingID))
+	s.Require().ErrorIs(s.repo.RemoveFromWatchlist(ctx, userID, listingID), ds.ErrNotFound)
+
+	items, err = s.repo.GetWatchlistByUserID(ctx, userID)
+	s.Require().NoError(err)
+	s.Require().Empty(items)
+}
+
+func (s *RepositoryTestSuite) TestRepository_UserSettings() {
+	ctx := context.Background()
+	userID, _ := s.createUserWithListing(ctx)
+
+	settings, err := s.repo.GetUserSettings(ctx, userID)
+	s.Require().NoError(err)
+	s.Require().Equal(ds.UserSettingsResponse{UserID: userID, TimeZone: "UTC"}, settings) //nolint:exhaustruct,nolintlint
+
+	request := ds.UpdateUserSettingsRequest{
+		UserID:         userID,
+		TimeZone:       "Europe/Belgrade",
+		QuietHoursFrom: null.IntFrom(22),
+		QuietHoursTo:   null.IntFrom(8),
+		MuteWeekends:   true,
+	}
+
+	updated, err := s.repo.UpdateUserSettings(ctx, request)
+	s.Require().NoError(err)
+
+	settings, err = s.repo.GetUserSettings(ctx, userID)
+	s.Require().NoError(err)
+	s.Require().Equal(updated, settings)
+	s.Require().Equal(null.IntFrom(22), settings.QuietHoursFrom)
+	s.Require().Equal(null.IntFrom(8), settings.QuietHoursTo)
+	s.Require().True(settings.MuteWeekends)
+
+	// the user data is kept when the user starts the bot again
+	_, err = s.repo.UpsertUser(ctx, ds.UserRequest{ID: userID, Username: "test"}) //nolint:exhaustruct,nolintlint
+	s.Require().NoError(err)
+
+	settings, err = s.repo.GetUserSettings(ctx, userID)
+	s.Require().NoError(err)
+	s.Require().Equal("Europe/Belgrade", settings.TimeZone)
+
+	_, err = s.repo.GetUserSettings(ctx, -userID)
+	s.Require().ErrorIs(err, ds.ErrNotFound)
+
+	request.UserID = -userID
+	_, err = s.repo.UpdateUserSettings(ctx, request)
+	s.Require().ErrorIs(err, ds.ErrNotFound)
+}
+
+func TestRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(RepositoryTestSuite))
+}
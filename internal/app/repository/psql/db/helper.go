@@ -1,12 +1,14 @@
 package db
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/guregu/null"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
 
 	psql "github.com/gudimz/polovni-auto-alert/internal/app/repository/psql/db/sqlc_gen"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
@@ -38,6 +40,28 @@ func userFromDB(input psql.User) ds.UserResponse {
 	}
 }
 
+// userSettingsFromDB converts a psql.GetUserSettingsRow to a ds.UserSettingsResponse.
+func userSettingsFromDB(input psql.GetUserSettingsRow) ds.UserSettingsResponse {
+	return ds.UserSettingsResponse{
+		UserID:         input.ID,
+		TimeZone:       input.TimeZone,
+		QuietHoursFrom: pgInt2ToNullInt(input.QuietHoursFrom),
+		QuietHoursTo:   pgInt2ToNullInt(input.QuietHoursTo),
+		MuteWeekends:   input.MuteWeekends,
+	}
+}
+
+// updateUserSettingsToDB converts a ds.UpdateUserSettingsRequest to a psql.UpdateUserSettingsParams.
+func updateUserSettingsToDB(input ds.UpdateUserSettingsRequest) psql.UpdateUserSettingsParams {
+	return psql.UpdateUserSettingsParams{
+		ID:             input.UserID,
+		TimeZone:       input.TimeZone,
+		QuietHoursFrom: nullIntToPgInt2(input.QuietHoursFrom),
+		QuietHoursTo:   nullIntToPgInt2(input.QuietHoursTo),
+		MuteWeekends:   input.MuteWeekends,
+	}
+}
+
 // subscriptionFromDB converts a psql.Subscription to a ds.SubscriptionResponse.
 func subscriptionFromDB(input psql.Subscription) (ds.SubscriptionResponse, error) {
 	id, err := pgUUIDToString(input.ID)
@@ -45,58 +69,181 @@ func subscriptionFromDB(input psql.Subscription) (ds.SubscriptionResponse, error
 		return ds.SubscriptionResponse{}, err
 	}
 
+	extraParams, err := extraParamsFromDB(input.ExtraParams)
+	if err != nil {
+		return ds.SubscriptionResponse{}, err
+	}
+
 	return ds.SubscriptionResponse{
-		ID:        id,
-		UserID:    input.UserID,
-		Brand:     input.Brand,
-		Model:     input.Model,
-		Chassis:   input.Chassis,
-		PriceFrom: input.PriceFrom,
-		PriceTo:   input.PriceTo,
-		YearFrom:  input.YearFrom,
-		YearTo:    input.YearTo,
-		Region:    input.Region,
-		CreatedAt: input.CreatedAt.Time,
-		UpdatedAt: input.UpdatedAt.Time,
+		ID:              id,
+		UserID:          input.UserID,
+		Brand:           input.Brand,
+		Model:           input.Model,
+		Chassis:         input.Chassis,
+		PriceFrom:       input.PriceFrom,
+		PriceTo:         input.PriceTo,
+		YearFrom:        input.YearFrom,
+		YearTo:          input.YearTo,
+		Region:          input.Region,
+		MinDealPercent:  pgInt4ToNullInt(input.MinDealPercent),
+		IncludeKeywords: input.IncludeKeywords,
+		ExcludeKeywords: input.ExcludeKeywords,
+		Fuel:            input.Fuel,
+		Gearbox:         input.Gearbox,
+		MileageTo:       input.MileageTo,
+		PowerFrom:       input.PowerFrom,
+		PowerTo:         input.PowerTo,
+		Doors:           input.Doors,
+		Seats:           input.Seats,
+		Color:           input.Color,
+		AirCondition:    input.AirCondition,
+		Damage:          input.Damage,
+		ExtraParams:     extraParams,
+		MutedUntil:      pgTimestampToNullTime(input.MutedUntil),
+		PriceOnly:       input.PriceOnly,
+		DeliveryMode:    ds.DeliveryMode(input.DeliveryMode),
+		DigestHour:      int(input.DigestHour),
+		DigestSentAt:    pgTimestampToNullTime(input.DigestSentAt),
+		CreatedAt:       input.CreatedAt.Time,
+		UpdatedAt:       input.UpdatedAt.Time,
 	}, nil
 }
 
 // subscriptionToDB converts a ds.SubscriptionRequest to a psql.CreateSubscriptionParams.
-func subscriptionToDB(input ds.SubscriptionRequest) psql.CreateSubscriptionParams {
+func subscriptionToDB(input ds.SubscriptionRequest) (psql.CreateSubscriptionParams, error) {
+	extraParams, err := extraParamsToDB(input.ExtraParams)
+	if err != nil {
+		return psql.CreateSubscriptionParams{}, err
+	}
+
 	return psql.CreateSubscriptionParams{
-		UserID:    input.UserID,
-		Brand:     input.Brand,
-		Model:     input.Model,
-		Chassis:   input.Chassis,
-		PriceFrom: input.PriceFrom,
-		PriceTo:   input.PriceTo,
-		YearFrom:  input.YearFrom,
-		YearTo:    input.YearTo,
-		Region:    input.Region,
+		UserID:          input.UserID,
+		Brand:           input.Brand,
+		Model:           input.Model,
+		Chassis:         input.Chassis,
+		PriceFrom:       input.PriceFrom,
+		PriceTo:         input.PriceTo,
+		YearFrom:        input.YearFrom,
+		YearTo:          input.YearTo,
+		Region:          input.Region,
+		MinDealPercent:  nullIntToPgInt4(input.MinDealPercent),
+		IncludeKeywords: nonNilStrings(input.IncludeKeywords),
+		ExcludeKeywords: nonNilStrings(input.ExcludeKeywords),
+		Fuel:            nonNilStrings(input.Fuel),
+		Gearbox:         nonNilStrings(input.Gearbox),
+		MileageTo:       input.MileageTo,
+		PowerFrom:       input.PowerFrom,
+		PowerTo:         input.PowerTo,
+		Doors:           nonNilStrings(input.Doors),
+		Seats:           nonNilStrings(input.Seats),
+		Color:           nonNilStrings(input.Color),
+		AirCondition:    nonNilStrings(input.AirCondition),
+		Damage:          nonNilStrings(input.Damage),
+		ExtraParams:     extraParams,
+	}, nil
+}
+
+// updateSubscriptionToDB converts a ds.UpdateSubscriptionRequest to a psql.UpdateSubscriptionParams.
+func updateSubscriptionToDB(input ds.UpdateSubscriptionRequest) (psql.UpdateSubscriptionParams, error) {
+	id, err := stringToPgUUID(input.ID)
+	if err != nil {
+		return psql.UpdateSubscriptionParams{}, err
+	}
+
+	extraParams, err := extraParamsToDB(input.ExtraParams)
+	if err != nil {
+		return psql.UpdateSubscriptionParams{}, err
+	}
+
+	return psql.UpdateSubscriptionParams{
+		ID:              id,
+		UserID:          input.UserID,
+		Model:           input.Model,
+		Chassis:         input.Chassis,
+		PriceFrom:       input.PriceFrom,
+		PriceTo:         input.PriceTo,
+		YearFrom:        input.YearFrom,
+		YearTo:          input.YearTo,
+		Region:          input.Region,
+		MinDealPercent:  nullIntToPgInt4(input.MinDealPercent),
+		IncludeKeywords: nonNilStrings(input.IncludeKeywords),
+		ExcludeKeywords: nonNilStrings(input.ExcludeKeywords),
+		Fuel:            nonNilStrings(input.Fuel),
+		Gearbox:         nonNilStrings(input.Gearbox),
+		MileageTo:       input.MileageTo,
+		PowerFrom:       input.PowerFrom,
+		PowerTo:         input.PowerTo,
+		Doors:           nonNilStrings(input.Doors),
+		Seats:           nonNilStrings(input.Seats),
+		Color:           nonNilStrings(input.Color),
+		AirCondition:    nonNilStrings(input.AirCondition),
+		Damage:          nonNilStrings(input.Damage),
+		ExtraParams:     extraParams,
+		DeliveryMode:    string(deliveryModeToDB(input.DeliveryMode)),
+		DigestHour:      int16(input.DigestHour), //nolint:gosec,nolintlint
+	}, nil
+}
+
+// deliveryModeToDB returns the delivery mode to store, the subscription is instant unless the mode is set.
+func deliveryModeToDB(mode ds.DeliveryMode) ds.DeliveryMode {
+	if mode == "" {
+		return ds.DeliveryModeInstant
+	}
+
+	return mode
+}
+
+// carToDB converts a ds.UpsertCarRequest to psql.UpsertCarParams.
+func carToDB(input ds.UpsertCarRequest) psql.UpsertCarParams {
+	return psql.UpsertCarParams{
+		ListingID:       input.ListingID,
+		Title:           input.Title,
+		Price:           input.Price,
+		EngineVolume:    input.EngineVolume,
+		Transmission:    input.Transmission,
+		BodyType:        input.BodyType,
+		Mileage:         input.Mileage,
+		Location:        input.Location,
+		Link:            input.Link,
+		Date:            timeToPgTimestamp(input.Date),
+		FuelType:        input.Details.FuelType,
+		PowerKw:         int32(input.Details.PowerKW), //nolint:gosec,nolintlint
+		PowerHp:         int32(input.Details.PowerHP), //nolint:gosec,nolintlint
+		Color:           input.Details.Color,
+		Drive:           input.Details.Drive,
+		Doors:           input.Details.Doors,
+		RegisteredUntil: input.Details.RegisteredUntil,
+		DamageStatus:    input.Details.DamageStatus,
+		SellerType:      input.Details.SellerType,
+		PriceEur:        decimalToPgNumeric(input.PriceEUR),
+		MileageKm:       nullIntToPgInt4(input.MileageKM),
+		EngineVolumeCm3: nullIntToPgInt4(input.EngineVolumeCM3),
+		Year:            nullIntToPgInt4(input.Year),
+		Brand:           input.Brand,
+		Model:           input.Model,
+		ImageHash:       pgtype.Int8{Int64: input.ImageHash.Int64, Valid: input.ImageHash.Valid},
+		ImageUrl:        input.ImageURL,
+		GalleryUrls:     nonNilStrings(input.Details.GalleryURLs),
 	}
 }
 
-// listingToDB converts a ds.UpsertListingRequest to psql.UpsertListingParams.
-func listingToDB(input ds.UpsertListingRequest) (psql.UpsertListingParams, error) {
+// subscriptionMatchToDB converts a ds.UpsertSubscriptionMatchRequest to psql.UpsertSubscriptionMatchParams.
+func subscriptionMatchToDB(input ds.UpsertSubscriptionMatchRequest) (psql.UpsertSubscriptionMatchParams, error) {
 	subscriptionID, err := stringToPgUUID(input.SubscriptionID)
 	if err != nil {
-		return psql.UpsertListingParams{}, err
+		return psql.UpsertSubscriptionMatchParams{}, err
 	}
 
-	return psql.UpsertListingParams{
+	return psql.UpsertSubscriptionMatchParams{
 		ListingID:      input.ListingID,
 		SubscriptionID: subscriptionID,
-		Title:          input.Title,
 		Price:          input.Price,
 		NewPrice:       pgtype.Text{String: input.NewPrice.String, Valid: input.NewPrice.Valid},
-		EngineVolume:   input.EngineVolume,
-		Transmission:   input.Transmission,
-		BodyType:       input.BodyType,
-		Mileage:        input.Mileage,
-		Location:       input.Location,
-		Link:           input.Link,
-		Date:           timeToPgTimestamp(input.Date),
+		PriceEur:       decimalToPgNumeric(input.PriceEUR),
+		NewPriceEur:    decimalToPgNumeric(input.NewPriceEUR),
 		IsNeedSend:     input.IsNeedSend,
+		RelistedFrom:   pgtype.Text{String: input.RelistedFrom.String, Valid: input.RelistedFrom.Valid},
+		RelistedPrice:  pgtype.Text{String: input.RelistedPrice.String, Valid: input.RelistedPrice.Valid},
 	}, nil
 }
 
@@ -129,8 +276,34 @@ func listingFromListingsBySubscriptionIDDB(input psql.GetListingsBySubscriptionI
 		Link:           input.Link,
 		Date:           input.Date.Time,
 		IsNeedSend:     input.IsNeedSend,
-		CreatedAt:      input.CreatedAt.Time,
-		UpdatedAt:      input.UpdatedAt.Time,
+		Details: ds.ListingDetails{
+			FuelType:        input.FuelType,
+			PowerKW:         int(input.PowerKw),
+			PowerHP:         int(input.PowerHp),
+			Color:           input.Color,
+			Drive:           input.Drive,
+			Doors:           input.Doors,
+			RegisteredUntil: input.RegisteredUntil,
+			DamageStatus:    input.DamageStatus,
+			SellerType:      input.SellerType,
+			GalleryURLs:     input.GalleryUrls,
+		},
+		PriceEUR:        pgNumericToDecimal(input.PriceEur),
+		NewPriceEUR:     pgNumericToDecimal(input.NewPriceEur),
+		MileageKM:       pgInt4ToNullInt(input.MileageKm),
+		EngineVolumeCM3: pgInt4ToNullInt(input.EngineVolumeCm3),
+		Year:            pgInt4ToNullInt(input.Year),
+		Brand:           input.Brand,
+		Model:           input.Model,
+		FirstSeenAt:     input.FirstSeenAt.Time,
+		RemovedAt:       pgTimestampToNullTime(input.RemovedAt),
+		LastSeenAt:      input.LastSeenAt.Time,
+		ImageHash:       null.NewInt(input.ImageHash.Int64, input.ImageHash.Valid),
+		ImageURL:        input.ImageUrl,
+		RelistedFrom:    null.NewString(input.RelistedFrom.String, input.RelistedFrom.Valid),
+		RelistedPrice:   null.NewString(input.RelistedPrice.String, input.RelistedPrice.Valid),
+		CreatedAt:       input.CreatedAt.Time,
+		UpdatedAt:       input.UpdatedAt.Time,
 	}, nil
 }
 
@@ -163,11 +336,42 @@ func listingFromListingsByIsNeedSendDB(input psql.GetListingsByIsNeedSendRow) (d
 		Link:           input.Link,
 		Date:           input.Date.Time,
 		IsNeedSend:     input.IsNeedSend,
-		CreatedAt:      input.CreatedAt.Time,
-		UpdatedAt:      input.UpdatedAt.Time,
+		Details: ds.ListingDetails{
+			FuelType:        input.FuelType,
+			PowerKW:         int(input.PowerKw),
+			PowerHP:         int(input.PowerHp),
+			Color:           input.Color,
+			Drive:           input.Drive,
+			Doors:           input.Doors,
+			RegisteredUntil: input.RegisteredUntil,
+			DamageStatus:    input.DamageStatus,
+			SellerType:      input.SellerType,
+			GalleryURLs:     input.GalleryUrls,
+		},
+		PriceEUR:        pgNumericToDecimal(input.PriceEur),
+		NewPriceEUR:     pgNumericToDecimal(input.NewPriceEur),
+		MileageKM:       pgInt4ToNullInt(input.MileageKm),
+		EngineVolumeCM3: pgInt4ToNullInt(input.EngineVolumeCm3),
+		Year:            pgInt4ToNullInt(input.Year),
+		Brand:           input.Brand,
+		Model:           input.Model,
+		FirstSeenAt:     input.FirstSeenAt.Time,
+		RemovedAt:       pgTimestampToNullTime(input.RemovedAt),
+		LastSeenAt:      input.LastSeenAt.Time,
+		ImageHash:       null.NewInt(input.ImageHash.Int64, input.ImageHash.Valid),
+		ImageURL:        input.ImageUrl,
+		RelistedFrom:    null.NewString(input.RelistedFrom.String, input.RelistedFrom.Valid),
+		RelistedPrice:   null.NewString(input.RelistedPrice.String, input.RelistedPrice.Valid),
+		CreatedAt:       input.CreatedAt.Time,
+		UpdatedAt:       input.UpdatedAt.Time,
 	}, nil
 }
 
+// listingFromListingDB converts a psql.GetListingRow to ds.ListingResponse.
+func listingFromListingDB(input psql.GetListingRow) (ds.ListingResponse, error) {
+	return listingFromListingsByIsNeedSendDB(psql.GetListingsByIsNeedSendRow(input))
+}
+
 // notificationToDB converts a ds.CreateNotificationRequest to psql.CreateNotificationParams.
 func notificationToDB(input ds.CreateNotificationRequest) (psql.CreateNotificationParams, error) {
 	subscriptionID, err := stringToPgUUID(input.SubscriptionID)
@@ -200,6 +404,105 @@ func notificationFromDB(input psql.Notification) (ds.NotificationResponse, error
 	}, nil
 }
 
+// listingPriceFromDB converts a psql.ListingPriceHistory to ds.ListingPriceResponse.
+func listingPriceFromDB(input psql.ListingPriceHistory) (ds.ListingPriceResponse, error) {
+	id, err := pgUUIDToString(input.ID)
+	if err != nil {
+		return ds.ListingPriceResponse{}, err
+	}
+
+	return ds.ListingPriceResponse{
+		ID:         id,
+		ListingID:  input.ListingID,
+		Price:      input.Price,
+		PriceEUR:   pgNumericToDecimal(input.PriceEur),
+		ObservedAt: input.ObservedAt.Time,
+	}, nil
+}
+
+// marketStatsFromDB converts a psql.MarketStat to ds.MarketStatsResponse.
+func marketStatsFromDB(input psql.MarketStat) ds.MarketStatsResponse {
+	return ds.MarketStatsResponse{
+		Brand:              input.Brand,
+		Model:              input.Model,
+		Year:               int(input.Year),
+		MileageBand:        int(input.MileageBand),
+		SampleSize:         int(input.SampleSize),
+		P25PriceEUR:        pgNumericToDecimal(input.P25PriceEur).Decimal,
+		MedianPriceEUR:     pgNumericToDecimal(input.MedianPriceEur).Decimal,
+		P75PriceEUR:        pgNumericToDecimal(input.P75PriceEur).Decimal,
+		RemovedCount:       int(input.RemovedCount),
+		MedianDaysOnMarket: pgNumericToDecimal(input.MedianDaysOnMarket),
+		UpdatedAt:          input.UpdatedAt.Time,
+	}
+}
+
+// outboxNotificationFromDB converts a psql.NotificationOutbox to ds.OutboxNotificationResponse.
+func outboxNotificationFromDB(input psql.NotificationOutbox) (ds.OutboxNotificationResponse, error) {
+	id, err := pgUUIDToString(input.ID)
+	if err != nil {
+		return ds.OutboxNotificationResponse{}, err
+	}
+
+	subscriptionID, err := pgUUIDToString(input.SubscriptionID)
+	if err != nil {
+		return ds.OutboxNotificationResponse{}, err
+	}
+
+	return ds.OutboxNotificationResponse{
+		ID:             id,
+		ListingID:      input.ListingID,
+		SubscriptionID: subscriptionID,
+		Kind:           kindFromDB(input.Kind),
+		Attempts:       int(input.Attempts),
+		NextAttemptAt:  input.NextAttemptAt.Time,
+		LastError:      input.LastError,
+		PhotosSent:     input.PhotosSent,
+		CreatedAt:      input.CreatedAt.Time,
+		UpdatedAt:      input.UpdatedAt.Time,
+	}, nil
+}
+
+// conversationStateFromDB converts a psql.ConversationState to ds.ConversationStateResponse.
+func conversationStateFromDB(input psql.ConversationState) ds.ConversationStateResponse {
+	return ds.ConversationStateResponse{
+		UserID:    input.UserID,
+		State:     input.State,
+		ExpiresAt: input.ExpiresAt.Time,
+		CreatedAt: input.CreatedAt.Time,
+		UpdatedAt: input.UpdatedAt.Time,
+	}
+}
+
+// watchlistItemFromDB converts a psql.GetWatchlistByUserIDRow to ds.WatchlistItemResponse.
+func watchlistItemFromDB(input psql.GetWatchlistByUserIDRow) ds.WatchlistItemResponse {
+	return ds.WatchlistItemResponse{
+		ListingID: input.ListingID,
+		Title:     input.Title,
+		Price:     input.Price,
+		PriceEUR:  pgNumericToDecimal(input.PriceEur),
+		Link:      input.Link,
+		RemovedAt: pgTimestampToNullTime(input.RemovedAt),
+		CreatedAt: input.CreatedAt.Time,
+	}
+}
+
+// watchlistChangeFromDB converts a psql.GetWatchlistChangesRow to ds.WatchlistChangeResponse.
+func watchlistChangeFromDB(input psql.GetWatchlistChangesRow) ds.WatchlistChangeResponse {
+	return ds.WatchlistChangeResponse{
+		UserID:           input.UserID,
+		ListingID:        input.ListingID,
+		Title:            input.Title,
+		Link:             input.Link,
+		Price:            input.Price,
+		PriceEUR:         pgNumericToDecimal(input.PriceEur),
+		NotifiedPrice:    input.NotifiedPrice,
+		NotifiedPriceEUR: pgNumericToDecimal(input.NotifiedPriceEur),
+		FirstSeenAt:      input.FirstSeenAt.Time,
+		RemovedAt:        pgTimestampToNullTime(input.RemovedAt),
+	}
+}
+
 // pgUUIDToString converts pgtype.UUID to string.
 func pgUUIDToString(id pgtype.UUID) (string, error) {
 	if !id.Valid {
@@ -230,6 +533,81 @@ func timeToPgTimestamp(t time.Time) pgtype.Timestamp {
 	return pgtype.Timestamp{Valid: true, Time: t} //nolint:exhaustruct,nolintlint
 }
 
+// pgTimestampToNullTime converts pgtype.Timestamp to null.Time.
+func pgTimestampToNullTime(t pgtype.Timestamp) null.Time {
+	return null.NewTime(t.Time, t.Valid)
+}
+
+// decimalToPgNumeric converts decimal.NullDecimal to pgtype.Numeric.
+func decimalToPgNumeric(d decimal.NullDecimal) pgtype.Numeric {
+	if !d.Valid {
+		return pgtype.Numeric{} //nolint:exhaustruct,nolintlint
+	}
+
+	return pgtype.Numeric{Int: d.Decimal.Coefficient(), Exp: d.Decimal.Exponent(), Valid: true} //nolint:exhaustruct,nolintlint
+}
+
+// pgNumericToDecimal converts pgtype.Numeric to decimal.NullDecimal.
+func pgNumericToDecimal(n pgtype.Numeric) decimal.NullDecimal {
+	if !n.Valid || n.NaN || n.Int == nil {
+		return decimal.NullDecimal{}
+	}
+
+	return decimal.NewNullDecimal(decimal.NewFromBigInt(n.Int, n.Exp))
+}
+
+// nullIntToPgInt4 converts null.Int to pgtype.Int4.
+func nullIntToPgInt4(i null.Int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(i.Int64), Valid: i.Valid} //nolint:gosec,nolintlint
+}
+
+// pgInt4ToNullInt converts pgtype.Int4 to null.Int.
+func pgInt4ToNullInt(i pgtype.Int4) null.Int {
+	return null.NewInt(int64(i.Int32), i.Valid)
+}
+
+// nullIntToPgInt2 converts null.Int to pgtype.Int2.
+func nullIntToPgInt2(i null.Int) pgtype.Int2 {
+	return pgtype.Int2{Int16: int16(i.Int64), Valid: i.Valid} //nolint:gosec,nolintlint
+}
+
+// pgInt2ToNullInt converts pgtype.Int2 to null.Int.
+func pgInt2ToNullInt(i pgtype.Int2) null.Int {
+	return null.NewInt(int64(i.Int16), i.Valid)
+}
+
+// nonNilStrings returns an empty slice instead of nil, the nil slice is stored as NULL.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
+
+// extraParamsToDB encodes the extra query parameters of a subscription, nil is stored as an empty object.
+func extraParamsToDB(params map[string]string) ([]byte, error) {
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	return json.Marshal(params)
+}
+
+// extraParamsFromDB decodes the extra query parameters of a subscription.
+func extraParamsFromDB(data []byte) (map[string]string, error) {
+	params := map[string]string{}
+	if len(data) == 0 {
+		return params, nil
+	}
+
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
 // statusToDB converts ds.NotificationStatus to psql.NullStatus.
 func statusToDB(status ds.NotificationStatus) psql.Status {
 	switch status {
@@ -237,6 +615,8 @@ func statusToDB(status ds.NotificationStatus) psql.Status {
 		return psql.StatusSENT
 	case ds.StatusFailed:
 		return psql.StatusFAILED
+	case ds.StatusDead:
+		return psql.StatusDEAD
 	}
 
 	return ""
@@ -249,6 +629,20 @@ func statusFromDB(status psql.Status) ds.NotificationStatus {
 		return ds.StatusSent
 	case psql.StatusFAILED:
 		return ds.StatusFailed
+	case psql.StatusDEAD:
+		return ds.StatusDead
+	}
+
+	return ""
+}
+
+// kindFromDB converts psql.NotificationKind to ds.NotificationKind.
+func kindFromDB(kind psql.NotificationKind) ds.NotificationKind {
+	switch kind {
+	case psql.NotificationKindLISTING:
+		return ds.NotificationKindListing
+	case psql.NotificationKindREMOVED:
+		return ds.NotificationKindRemoved
 	}
 
 	return ""
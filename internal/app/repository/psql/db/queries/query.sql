@@ -24,7 +24,26 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions;
 
 -- name: CreateSubscription :one
@@ -37,68 +56,210 @@ INSERT INTO subscriptions (user_id,
                            year_from,
                            year_to,
                            region,
+                           min_deal_percent,
+                           include_keywords,
+                           exclude_keywords,
+                           fuel,
+                           gearbox,
+                           mileage_to,
+                           power_from,
+                           power_to,
+                           doors,
+                           seats,
+                           color,
+                           air_condition,
+                           damage,
+                           extra_params,
                            created_at,
                            updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now())
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+        now(), now())
 RETURNING *;
 
--- name: UpsertListing :exec
-INSERT INTO listings (listing_id, subscription_id, title, price, new_price, engine_volume, transmission, body_type, mileage, location,
-                      link, date, is_need_send, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, now(), now())
-ON CONFLICT (listing_id, subscription_id) DO UPDATE SET title         = EXCLUDED.title,
-                                                price         = EXCLUDED.price,
-                                                new_price     = EXCLUDED.new_price,
-                                                engine_volume = EXCLUDED.engine_volume,
-                                                transmission  = EXCLUDED.transmission,
-                                                body_type     = EXCLUDED.body_type,
-                                                mileage       = EXCLUDED.mileage,
-                                                location      = EXCLUDED.location,
-                                                link          = EXCLUDED.link,
-                                                date          = EXCLUDED.date,
-                                                is_need_send  = EXCLUDED.is_need_send,
-                                                updated_at    = now()
+-- name: UpdateSubscription :one
+UPDATE subscriptions
+SET model            = $3,
+    chassis          = $4,
+    price_from       = $5,
+    price_to         = $6,
+    year_from        = $7,
+    year_to          = $8,
+    region           = $9,
+    min_deal_percent = $10,
+    include_keywords = $11,
+    exclude_keywords = $12,
+    fuel             = $13,
+    gearbox          = $14,
+    mileage_to       = $15,
+    power_from       = $16,
+    power_to         = $17,
+    doors            = $18,
+    seats            = $19,
+    color            = $20,
+    air_condition    = $21,
+    damage           = $22,
+    extra_params     = $23,
+    -- the next digest is scheduled anew if its schedule is changed
+    digest_sent_at   = CASE
+                           WHEN delivery_mode <> $24 OR digest_hour <> $25 THEN NULL
+                           ELSE digest_sent_at
+        END,
+    delivery_mode    = $24,
+    digest_hour      = $25,
+    updated_at       = now()
+WHERE id = $1
+  AND user_id = $2
 RETURNING *;
 
+-- name: UpsertCar :exec
+INSERT INTO cars (listing_id, title, price, engine_volume, transmission, body_type, mileage, location, link, date,
+                  fuel_type, power_kw, power_hp, color, drive, doors, registered_until, damage_status, seller_type,
+                  price_eur, mileage_km, engine_volume_cm3, year, brand, model, image_hash, image_url, gallery_urls,
+                  created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+        $25, $26, $27, $28, now(), now())
+ON CONFLICT (listing_id) DO UPDATE SET title             = EXCLUDED.title,
+                                       price             = EXCLUDED.price,
+                                       engine_volume     = EXCLUDED.engine_volume,
+                                       transmission      = EXCLUDED.transmission,
+                                       body_type         = EXCLUDED.body_type,
+                                       mileage           = EXCLUDED.mileage,
+                                       location          = EXCLUDED.location,
+                                       link              = EXCLUDED.link,
+                                       date              = EXCLUDED.date,
+                                       -- keep already fetched details if the new ones are empty
+                                       fuel_type         = COALESCE(NULLIF(EXCLUDED.fuel_type, ''), cars.fuel_type),
+                                       power_kw          = COALESCE(NULLIF(EXCLUDED.power_kw, 0), cars.power_kw),
+                                       power_hp          = COALESCE(NULLIF(EXCLUDED.power_hp, 0), cars.power_hp),
+                                       color             = COALESCE(NULLIF(EXCLUDED.color, ''), cars.color),
+                                       drive             = COALESCE(NULLIF(EXCLUDED.drive, ''), cars.drive),
+                                       doors             = COALESCE(NULLIF(EXCLUDED.doors, ''), cars.doors),
+                                       registered_until  = COALESCE(NULLIF(EXCLUDED.registered_until, ''), cars.registered_until),
+                                       damage_status     = COALESCE(NULLIF(EXCLUDED.damage_status, ''), cars.damage_status),
+                                       seller_type       = COALESCE(NULLIF(EXCLUDED.seller_type, ''), cars.seller_type),
+                                       price_eur         = EXCLUDED.price_eur,
+                                       mileage_km        = EXCLUDED.mileage_km,
+                                       engine_volume_cm3 = EXCLUDED.engine_volume_cm3,
+                                       year              = COALESCE(EXCLUDED.year, cars.year),
+                                       brand             = COALESCE(NULLIF(EXCLUDED.brand, ''), cars.brand),
+                                       model             = COALESCE(NULLIF(EXCLUDED.model, ''), cars.model),
+                                       image_hash        = COALESCE(EXCLUDED.image_hash, cars.image_hash),
+                                       image_url         = COALESCE(NULLIF(EXCLUDED.image_url, ''), cars.image_url),
+                                       gallery_urls      = CASE
+                                                               WHEN cardinality(EXCLUDED.gallery_urls) > 0
+                                                                   THEN EXCLUDED.gallery_urls
+                                                               ELSE cars.gallery_urls END,
+                                       -- the car is on the market again if it was marked as removed
+                                       last_seen_at      = now(),
+                                       missing_count     = 0,
+                                       removed_at        = NULL,
+                                       updated_at        = now();
+
+-- name: UpsertSubscriptionMatch :exec
+INSERT INTO subscription_matches (listing_id, subscription_id, price, new_price, price_eur, new_price_eur, is_need_send,
+                                  relisted_from, relisted_price, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now())
+ON CONFLICT (listing_id, subscription_id) DO UPDATE SET price          = EXCLUDED.price,
+                                                        new_price      = EXCLUDED.new_price,
+                                                        price_eur      = EXCLUDED.price_eur,
+                                                        new_price_eur  = EXCLUDED.new_price_eur,
+                                                        is_need_send   = EXCLUDED.is_need_send,
+                                                        -- the relist is only detected for a new match, keep it
+                                                        relisted_from  = COALESCE(EXCLUDED.relisted_from,
+                                                                                  subscription_matches.relisted_from),
+                                                        relisted_price = COALESCE(EXCLUDED.relisted_price,
+                                                                                  subscription_matches.relisted_price),
+                                                        updated_at     = now();
+
 -- name: GetListingsBySubscriptionID :many
-SELECT id,
-       listing_id,
-       subscription_id,
-       title,
-       price,
-       new_price,
-       engine_volume,
-       transmission,
-       body_type,
-       mileage,
-       location,
-       link,
-       date,
-       is_need_send,
-       created_at,
-       updated_at
-FROM listings
-WHERE subscription_id = $1;
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.subscription_id = $1;
 
 -- name: GetListingsByIsNeedSend :many
-SELECT id,
-       listing_id,
-       subscription_id,
-       title,
-       price,
-       new_price,
-       engine_volume,
-       transmission,
-       body_type,
-       mileage,
-       location,
-       link,
-       date,
-       is_need_send,
-       created_at,
-       updated_at
-FROM listings
-WHERE is_need_send = $1;
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.is_need_send = $1;
 
 -- name: CreateNotification :one
 INSERT INTO notifications (listing_id,
@@ -122,7 +283,26 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions
 WHERE user_id = $1;
 
@@ -138,13 +318,32 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions
 WHERE id = $1;
 
 -- name: DeleteListingsBySubscriptionIDs :exec
 DELETE
-FROM listings
+FROM subscription_matches
 WHERE subscription_id = ANY ($1::uuid[]);
 
 -- name: DeleteSubscriptionsByUserID :exec
@@ -160,4 +359,426 @@ WHERE id = $1;
 -- name: DeleteUserByID :exec
 DELETE
 FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserSettings :one
+SELECT id,
+       time_zone,
+       quiet_hours_from,
+       quiet_hours_to,
+       mute_weekends
+FROM users
+WHERE id = $1;
+
+-- name: UpdateUserSettings :one
+UPDATE users
+SET time_zone        = $2,
+    quiet_hours_from = $3,
+    quiet_hours_to   = $4,
+    mute_weekends    = $5,
+    updated_at       = now()
+WHERE id = $1
+RETURNING id, time_zone, quiet_hours_from, quiet_hours_to, mute_weekends;
+
+-- name: GetConversationState :one
+SELECT user_id,
+       state,
+       expires_at,
+       created_at,
+       updated_at
+FROM conversation_state
+WHERE user_id = $1
+  AND expires_at > $2;
+
+-- name: UpsertConversationState :exec
+INSERT INTO conversation_state (user_id,
+                                state,
+                                expires_at,
+                                created_at,
+                                updated_at)
+VALUES ($1, $2, $3, now(), now())
+ON CONFLICT (user_id) DO UPDATE SET state      = EXCLUDED.state,
+                                    expires_at = EXCLUDED.expires_at,
+                                    updated_at = now();
+
+-- name: DeleteConversationState :exec
+DELETE
+FROM conversation_state
+WHERE user_id = $1;
+
+-- name: DeleteExpiredConversationStates :execrows
+DELETE
+FROM conversation_state
+WHERE expires_at <= $1;
+
+-- name: GetListing :one
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.listing_id = $1
+  AND m.subscription_id = $2;
+
+-- name: EnqueueNotifications :execrows
+INSERT INTO notification_outbox (listing_id,
+                                 subscription_id,
+                                 attempts,
+                                 next_attempt_at,
+                                 last_error,
+                                 created_at,
+                                 updated_at)
+SELECT listing_id, subscription_id, 0, $1::timestamp, '', now(), now()
+FROM subscription_matches
+WHERE is_need_send = TRUE
+ON CONFLICT (listing_id, subscription_id, kind) DO NOTHING;
+
+-- name: GetDueOutboxNotifications :many
+SELECT id,
+       listing_id,
+       subscription_id,
+       attempts,
+       next_attempt_at,
+       last_error,
+       created_at,
+       updated_at,
+       kind,
+       photos_sent
+FROM notification_outbox
+WHERE next_attempt_at <= $1
+ORDER BY next_attempt_at;
+
+-- name: UpdateOutboxNotification :exec
+UPDATE notification_outbox
+SET attempts        = $2,
+    next_attempt_at = $3,
+    last_error      = $4,
+    photos_sent     = $5,
+    updated_at      = now()
+WHERE id = $1;
+
+-- name: DeleteOutboxNotification :exec
+DELETE
+FROM notification_outbox
+WHERE id = $1;
+
+-- name: AddListingPrice :exec
+-- adds the price observation only if the price differs from the last observed one or the last observation is stale,
+-- typed prices are compared when both are known, otherwise the raw strings are compared
+INSERT INTO listing_price_history (listing_id, price, price_eur, observed_at, created_at)
+SELECT sqlc.arg(listing_id)::varchar,
+       sqlc.arg(price)::varchar,
+       sqlc.narg(price_eur)::numeric,
+       sqlc.arg(observed_at)::timestamp,
+       now()
+WHERE NOT EXISTS (SELECT 1
+                  FROM (SELECT price, price_eur, observed_at
+                        FROM listing_price_history
+                        WHERE listing_id = sqlc.arg(listing_id)::varchar
+                        ORDER BY observed_at DESC
+                        LIMIT 1) AS last
+                  WHERE last.observed_at > sqlc.arg(stale_before)::timestamp
+                    AND CASE
+                            WHEN last.price_eur IS NOT NULL AND sqlc.narg(price_eur)::numeric IS NOT NULL
+                                THEN last.price_eur = sqlc.narg(price_eur)::numeric
+                            ELSE last.price = sqlc.arg(price)::varchar
+                        END);
+
+-- name: GetListingPriceHistory :many
+SELECT id,
+       listing_id,
+       price,
+       price_eur,
+       observed_at,
+       created_at
+FROM listing_price_history
+WHERE listing_id = $1
+ORDER BY observed_at;
+
+-- name: GetMedianPricesBySubscriptionID :many
+-- the daily median price of the cars matched by the subscription, which is the market price of its model and years
+SELECT date_trunc('day', h.observed_at)::timestamp                          AS day,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY h.price_eur)::numeric AS median_price_eur
+FROM listing_price_history h
+         JOIN subscription_matches m ON m.listing_id = h.listing_id
+WHERE m.subscription_id = sqlc.arg(subscription_id)::uuid
+  AND h.price_eur IS NOT NULL
+  AND h.observed_at >= sqlc.arg(observed_after)::timestamp
+GROUP BY day
+ORDER BY day;
+
+-- name: RefreshMarketStats :execrows
+-- recomputes the price percentiles and the median time on market of the removed cars of the cars seen after
+-- the given time grouped by brand, model, year and mileage band, the groups without such cars are removed
+WITH stats AS (SELECT brand,
+                      model,
+                      year,
+                      mileage_km / sqlc.arg(mileage_band_km)::integer                         AS mileage_band,
+                      count(*)::integer                                                       AS sample_size,
+                      percentile_cont(0.25) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2) AS p25_price_eur,
+                      percentile_cont(0.5) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2)  AS median_price_eur,
+                      percentile_cont(0.75) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2) AS p75_price_eur,
+                      count(removed_at)::integer                                              AS removed_count,
+                      percentile_cont(0.5) WITHIN GROUP (
+                          ORDER BY extract(EPOCH FROM removed_at - created_at) / 86400)::numeric(8, 1)
+                                                                                              AS median_days_on_market
+               FROM cars
+               WHERE brand <> ''
+                 AND model <> ''
+                 AND year IS NOT NULL
+                 AND mileage_km IS NOT NULL
+                 AND price_eur IS NOT NULL
+                 AND last_seen_at >= sqlc.arg(seen_after)::timestamp
+               GROUP BY brand, model, year, mileage_km / sqlc.arg(mileage_band_km)::integer),
+     deleted AS (DELETE FROM market_stats ms
+         WHERE NOT EXISTS (SELECT 1
+                           FROM stats
+                           WHERE stats.brand = ms.brand
+                             AND stats.model = ms.model
+                             AND stats.year = ms.year
+                             AND stats.mileage_band = ms.mileage_band))
+INSERT
+INTO market_stats (brand, model, year, mileage_band, sample_size, p25_price_eur, median_price_eur, p75_price_eur,
+                   removed_count, median_days_on_market, updated_at)
+SELECT brand,
+       model,
+       year,
+       mileage_band,
+       sample_size,
+       p25_price_eur,
+       median_price_eur,
+       p75_price_eur,
+       removed_count,
+       median_days_on_market,
+       now()
+FROM stats
+ON CONFLICT (brand, model, year, mileage_band) DO UPDATE SET sample_size           = EXCLUDED.sample_size,
+                                                             p25_price_eur         = EXCLUDED.p25_price_eur,
+                                                             median_price_eur      = EXCLUDED.median_price_eur,
+                                                             p75_price_eur         = EXCLUDED.p75_price_eur,
+                                                             removed_count         = EXCLUDED.removed_count,
+                                                             median_days_on_market = EXCLUDED.median_days_on_market,
+                                                             updated_at            = now();
+
+-- name: GetMarketStats :one
+SELECT brand,
+       model,
+       year,
+       mileage_band,
+       sample_size,
+       p25_price_eur,
+       median_price_eur,
+       p75_price_eur,
+       updated_at,
+       removed_count,
+       median_days_on_market
+FROM market_stats
+WHERE brand = $1
+  AND model = $2
+  AND year = $3
+  AND mileage_band = $4;
+
+-- name: MarkCarsSeen :exec
+-- marks the cars found in a full scrape as seen, a car which was marked as removed is on the market again
+UPDATE cars
+SET last_seen_at  = sqlc.arg(seen_at)::timestamp,
+    missing_count = 0,
+    removed_at    = NULL
+WHERE listing_id = ANY (sqlc.arg(listing_ids)::varchar[]);
+
+-- name: MarkCarsMissing :many
+-- increments the missing count of the cars matched by the scraped subscriptions which were not found in a full scrape
+UPDATE cars
+SET missing_count = missing_count + 1
+WHERE removed_at IS NULL
+  AND NOT listing_id = ANY (sqlc.arg(seen_listing_ids)::varchar[])
+  AND listing_id IN (SELECT listing_id
+                     FROM subscription_matches
+                     WHERE subscription_id = ANY (sqlc.arg(subscription_ids)::uuid[]))
+RETURNING listing_id, link, missing_count;
+
+-- name: MarkCarRemoved :execrows
+-- marks the car as removed and, if notify is set, enqueues the removal notifications for the subscriptions
+-- the car was sent to, the number of the enqueued notifications is returned
+WITH removed AS (
+    UPDATE cars
+        SET removed_at = sqlc.arg(removed_at)::timestamp,
+            updated_at = now()
+        WHERE listing_id = sqlc.arg(listing_id)::varchar
+            AND removed_at IS NULL
+        RETURNING listing_id)
+INSERT
+INTO notification_outbox (listing_id,
+                          subscription_id,
+                          kind,
+                          attempts,
+                          next_attempt_at,
+                          last_error,
+                          created_at,
+                          updated_at)
+SELECT m.listing_id, m.subscription_id, 'REMOVED', 0, sqlc.arg(removed_at)::timestamp, '', now(), now()
+FROM removed
+         JOIN subscription_matches m ON m.listing_id = removed.listing_id
+WHERE sqlc.arg(notify)::boolean
+  AND EXISTS (SELECT 1
+              FROM notifications n
+              WHERE n.listing_id = m.listing_id
+                AND n.subscription_id = m.subscription_id
+                AND n.status = 'SENT')
+ON CONFLICT (listing_id, subscription_id, kind) DO NOTHING;
+
+-- name: DeleteOrphanCars :execrows
+-- deletes the cars no subscription matches anymore which were last seen before the given time, so they are out of
+-- the window of the market stats, the cars the users saved or hid and the ones with pending notifications are kept,
+-- the price history of the deleted cars is deleted with them
+DELETE
+FROM cars c
+WHERE c.last_seen_at < sqlc.arg(seen_before)::timestamp
+  AND NOT EXISTS (SELECT 1 FROM subscription_matches m WHERE m.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM watchlist w WHERE w.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM hidden_listings h WHERE h.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM notification_outbox o WHERE o.listing_id = c.listing_id);
+
+-- name: AddToWatchlist :exec
+-- the user knows the current price of the saved car, so only its later changes are notified
+INSERT INTO watchlist (user_id, listing_id, notified_price, notified_price_eur, notified_removed_at, created_at)
+SELECT sqlc.arg(user_id)::bigint, c.listing_id, c.price, c.price_eur, c.removed_at, now()
+FROM cars c
+WHERE c.listing_id = sqlc.arg(listing_id)
+ON CONFLICT (user_id, listing_id) DO NOTHING;
+
+-- name: RemoveFromWatchlist :execrows
+DELETE
+FROM watchlist
+WHERE user_id = $1
+  AND listing_id = $2;
+
+-- name: GetWatchlistByUserID :many
+SELECT c.listing_id,
+       c.title,
+       c.price,
+       c.price_eur,
+       c.link,
+       c.removed_at,
+       w.created_at
+FROM watchlist w
+         JOIN cars c ON c.listing_id = w.listing_id
+WHERE w.user_id = $1
+ORDER BY w.created_at, c.listing_id;
+
+-- name: GetWatchedCars :many
+-- the cars of all the watchlists which are on the market, they are checked on their detail pages
+SELECT c.listing_id,
+       c.link
+FROM cars c
+WHERE c.removed_at IS NULL
+  AND EXISTS (SELECT 1
+              FROM watchlist w
+              WHERE w.listing_id = c.listing_id)
+ORDER BY c.listing_id;
+
+-- name: UpdateCarPrice :exec
+-- sets the price of the car found on its detail page, the car is on the market, so it's seen too
+UPDATE cars
+SET price         = $2,
+    price_eur     = $3,
+    last_seen_at  = $4,
+    missing_count = 0,
+    updated_at    = now()
+WHERE listing_id = $1;
+
+-- name: GetWatchlistChanges :many
+-- the watched cars with a known price which differs from the price the user knows
+-- and the watched cars which were removed since the user was notified
+SELECT w.user_id,
+       c.listing_id,
+       c.title,
+       c.link,
+       c.price,
+       c.price_eur,
+       w.notified_price,
+       w.notified_price_eur,
+       c.created_at AS first_seen_at,
+       c.removed_at
+FROM watchlist w
+         JOIN cars c ON c.listing_id = w.listing_id
+WHERE (c.removed_at IS NULL AND c.price_eur IS NOT NULL AND c.price_eur IS DISTINCT FROM w.notified_price_eur)
+   OR (c.removed_at IS NOT NULL AND c.removed_at IS DISTINCT FROM w.notified_removed_at)
+ORDER BY w.created_at, w.user_id;
+
+-- name: UpdateWatchlistNotified :exec
+UPDATE watchlist
+SET notified_price      = $3,
+    notified_price_eur  = $4,
+    notified_removed_at = $5
+WHERE user_id = $1
+  AND listing_id = $2;
+
+-- name: HideListing :exec
+INSERT INTO hidden_listings (user_id, listing_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT (user_id, listing_id) DO NOTHING;
+
+-- name: IsListingHidden :one
+SELECT EXISTS (SELECT 1
+               FROM hidden_listings
+               WHERE user_id = $1
+                 AND listing_id = $2);
+
+-- name: MuteSubscription :one
+UPDATE subscriptions
+SET muted_until = $3,
+    updated_at  = now()
+WHERE id = $1
+  AND user_id = $2
+RETURNING *;
+
+-- name: SetSubscriptionPriceOnly :one
+UPDATE subscriptions
+SET price_only = $3,
+    updated_at = now()
+WHERE id = $1
+  AND user_id = $2
+RETURNING *;
+
+-- name: UpdateSubscriptionDigestSentAt :exec
+UPDATE subscriptions
+SET digest_sent_at = $2
+WHERE id = $1;
@@ -11,11 +11,54 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type NotificationKind string
+
+const (
+	NotificationKindLISTING NotificationKind = "LISTING"
+	NotificationKindREMOVED NotificationKind = "REMOVED"
+)
+
+func (e *NotificationKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationKind(s)
+	case string:
+		*e = NotificationKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationKind: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationKind struct {
+	NotificationKind NotificationKind `json:"notification_kind"`
+	Valid            bool             `json:"valid"` // Valid is true if NotificationKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationKind), nil
+}
+
 type Status string
 
 const (
 	StatusSENT   Status = "SENT"
 	StatusFAILED Status = "FAILED"
+	StatusDEAD   Status = "DEAD"
 )
 
 func (e *Status) Scan(src interface{}) error {
@@ -53,23 +96,77 @@ func (ns NullStatus) Value() (driver.Value, error) {
 	return string(ns.Status), nil
 }
 
-type Listing struct {
-	ID             pgtype.UUID      `json:"id"`
-	ListingID      string           `json:"listing_id"`
-	SubscriptionID pgtype.UUID      `json:"subscription_id"`
-	Title          string           `json:"title"`
-	Price          string           `json:"price"`
-	EngineVolume   string           `json:"engine_volume"`
-	Transmission   string           `json:"transmission"`
-	BodyType       string           `json:"body_type"`
-	Mileage        string           `json:"mileage"`
-	Location       string           `json:"location"`
-	Link           string           `json:"link"`
-	Date           pgtype.Timestamp `json:"date"`
-	IsNeedSend     bool             `json:"is_need_send"`
-	CreatedAt      pgtype.Timestamp `json:"created_at"`
-	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
-	NewPrice       pgtype.Text      `json:"new_price"`
+type Car struct {
+	ListingID       string           `json:"listing_id"`
+	Title           string           `json:"title"`
+	Price           string           `json:"price"`
+	EngineVolume    string           `json:"engine_volume"`
+	Transmission    string           `json:"transmission"`
+	BodyType        string           `json:"body_type"`
+	Mileage         string           `json:"mileage"`
+	Location        string           `json:"location"`
+	Link            string           `json:"link"`
+	Date            pgtype.Timestamp `json:"date"`
+	FuelType        string           `json:"fuel_type"`
+	PowerKw         int32            `json:"power_kw"`
+	PowerHp         int32            `json:"power_hp"`
+	Color           string           `json:"color"`
+	Drive           string           `json:"drive"`
+	Doors           string           `json:"doors"`
+	RegisteredUntil string           `json:"registered_until"`
+	DamageStatus    string           `json:"damage_status"`
+	SellerType      string           `json:"seller_type"`
+	PriceEur        pgtype.Numeric   `json:"price_eur"`
+	MileageKm       pgtype.Int4      `json:"mileage_km"`
+	EngineVolumeCm3 pgtype.Int4      `json:"engine_volume_cm3"`
+	Year            pgtype.Int4      `json:"year"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+	Brand           string           `json:"brand"`
+	Model           string           `json:"model"`
+	LastSeenAt      pgtype.Timestamp `json:"last_seen_at"`
+	MissingCount    int32            `json:"missing_count"`
+	RemovedAt       pgtype.Timestamp `json:"removed_at"`
+	ImageHash       pgtype.Int8      `json:"image_hash"`
+	ImageUrl        string           `json:"image_url"`
+	GalleryUrls     []string         `json:"gallery_urls"`
+}
+
+type ConversationState struct {
+	UserID    int64            `json:"user_id"`
+	State     []byte           `json:"state"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type HiddenListing struct {
+	UserID    int64            `json:"user_id"`
+	ListingID string           `json:"listing_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type ListingPriceHistory struct {
+	ID         pgtype.UUID      `json:"id"`
+	ListingID  string           `json:"listing_id"`
+	Price      string           `json:"price"`
+	PriceEur   pgtype.Numeric   `json:"price_eur"`
+	ObservedAt pgtype.Timestamp `json:"observed_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type MarketStat struct {
+	Brand              string           `json:"brand"`
+	Model              string           `json:"model"`
+	Year               int32            `json:"year"`
+	MileageBand        int32            `json:"mileage_band"`
+	SampleSize         int32            `json:"sample_size"`
+	P25PriceEur        pgtype.Numeric   `json:"p25_price_eur"`
+	MedianPriceEur     pgtype.Numeric   `json:"median_price_eur"`
+	P75PriceEur        pgtype.Numeric   `json:"p75_price_eur"`
+	UpdatedAt          pgtype.Timestamp `json:"updated_at"`
+	RemovedCount       int32            `json:"removed_count"`
+	MedianDaysOnMarket pgtype.Numeric   `json:"median_days_on_market"`
 }
 
 type Notification struct {
@@ -82,26 +179,86 @@ type Notification struct {
 	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
 }
 
+type NotificationOutbox struct {
+	ID             pgtype.UUID      `json:"id"`
+	ListingID      string           `json:"listing_id"`
+	SubscriptionID pgtype.UUID      `json:"subscription_id"`
+	Attempts       int32            `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	LastError      string           `json:"last_error"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	Kind           NotificationKind `json:"kind"`
+	PhotosSent     bool             `json:"photos_sent"`
+}
+
 type Subscription struct {
-	ID        pgtype.UUID      `json:"id"`
-	UserID    int64            `json:"user_id"`
-	Brand     string           `json:"brand"`
-	Model     []string         `json:"model"`
-	Chassis   []string         `json:"chassis"`
-	PriceFrom string           `json:"price_from"`
-	PriceTo   string           `json:"price_to"`
-	YearFrom  string           `json:"year_from"`
-	YearTo    string           `json:"year_to"`
-	Region    []string         `json:"region"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	ID              pgtype.UUID      `json:"id"`
+	UserID          int64            `json:"user_id"`
+	Brand           string           `json:"brand"`
+	Model           []string         `json:"model"`
+	Chassis         []string         `json:"chassis"`
+	PriceFrom       string           `json:"price_from"`
+	PriceTo         string           `json:"price_to"`
+	YearFrom        string           `json:"year_from"`
+	YearTo          string           `json:"year_to"`
+	Region          []string         `json:"region"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+	MinDealPercent  pgtype.Int4      `json:"min_deal_percent"`
+	IncludeKeywords []string         `json:"include_keywords"`
+	ExcludeKeywords []string         `json:"exclude_keywords"`
+	Fuel            []string         `json:"fuel"`
+	Gearbox         []string         `json:"gearbox"`
+	MileageTo       string           `json:"mileage_to"`
+	PowerFrom       string           `json:"power_from"`
+	PowerTo         string           `json:"power_to"`
+	Doors           []string         `json:"doors"`
+	Seats           []string         `json:"seats"`
+	Color           []string         `json:"color"`
+	AirCondition    []string         `json:"air_condition"`
+	Damage          []string         `json:"damage"`
+	ExtraParams     []byte           `json:"extra_params"`
+	MutedUntil      pgtype.Timestamp `json:"muted_until"`
+	PriceOnly       bool             `json:"price_only"`
+	DeliveryMode    string           `json:"delivery_mode"`
+	DigestHour      int16            `json:"digest_hour"`
+	DigestSentAt    pgtype.Timestamp `json:"digest_sent_at"`
+}
+
+type SubscriptionMatch struct {
+	ID             pgtype.UUID      `json:"id"`
+	ListingID      string           `json:"listing_id"`
+	SubscriptionID pgtype.UUID      `json:"subscription_id"`
+	Price          string           `json:"price"`
+	NewPrice       pgtype.Text      `json:"new_price"`
+	PriceEur       pgtype.Numeric   `json:"price_eur"`
+	NewPriceEur    pgtype.Numeric   `json:"new_price_eur"`
+	IsNeedSend     bool             `json:"is_need_send"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	RelistedFrom   pgtype.Text      `json:"relisted_from"`
+	RelistedPrice  pgtype.Text      `json:"relisted_price"`
 }
 
 type User struct {
-	ID        int64            `json:"id"`
-	Username  string           `json:"username"`
-	FirstName string           `json:"first_name"`
-	LastName  string           `json:"last_name"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	ID             int64            `json:"id"`
+	Username       string           `json:"username"`
+	FirstName      string           `json:"first_name"`
+	LastName       string           `json:"last_name"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	TimeZone       string           `json:"time_zone"`
+	QuietHoursFrom pgtype.Int2      `json:"quiet_hours_from"`
+	QuietHoursTo   pgtype.Int2      `json:"quiet_hours_to"`
+	MuteWeekends   bool             `json:"mute_weekends"`
+}
+
+type Watchlist struct {
+	UserID            int64            `json:"user_id"`
+	ListingID         string           `json:"listing_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	NotifiedPrice     string           `json:"notified_price"`
+	NotifiedPriceEur  pgtype.Numeric   `json:"notified_price_eur"`
+	NotifiedRemovedAt pgtype.Timestamp `json:"notified_removed_at"`
 }
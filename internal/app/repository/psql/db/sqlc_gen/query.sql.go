@@ -11,6 +11,70 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const AddListingPrice = `-- name: AddListingPrice :exec
+INSERT INTO listing_price_history (listing_id, price, price_eur, observed_at, created_at)
+SELECT $1::varchar,
+       $2::varchar,
+       $3::numeric,
+       $4::timestamp,
+       now()
+WHERE NOT EXISTS (SELECT 1
+                  FROM (SELECT price, price_eur, observed_at
+                        FROM listing_price_history
+                        WHERE listing_id = $1::varchar
+                        ORDER BY observed_at DESC
+                        LIMIT 1) AS last
+                  WHERE last.observed_at > $5::timestamp
+                    AND CASE
+                            WHEN last.price_eur IS NOT NULL AND $3::numeric IS NOT NULL
+                                THEN last.price_eur = $3::numeric
+                            ELSE last.price = $2::varchar
+                        END)
+`
+
+type AddListingPriceParams struct {
+	ListingID   string           `json:"listing_id"`
+	Price       string           `json:"price"`
+	PriceEur    pgtype.Numeric   `json:"price_eur"`
+	ObservedAt  pgtype.Timestamp `json:"observed_at"`
+	StaleBefore pgtype.Timestamp `json:"stale_before"`
+}
+
+// adds the price observation only if the price differs from the last observed one or the last observation is stale,
+// typed prices are compared when both are known, otherwise the raw strings are compared
+func (q *Queries) AddListingPrice(ctx context.Context, arg AddListingPriceParams) error {
+	_, err := q.db.Exec(ctx, AddListingPrice,
+		arg.ListingID,
+		arg.Price,
+		arg.PriceEur,
+		arg.ObservedAt,
+		arg.StaleBefore,
+	)
+	return err
+}
+
+const AddToWatchlist = `-- name: AddToWatchlist :exec
+INSERT INTO watchlist (user_id, listing_id, notified_price, notified_price_eur, notified_removed_at, created_at)
+SELECT $1::bigint, c.listing_id, c.price, c.price_eur, c.removed_at, now()
+FROM cars c
+WHERE c.listing_id = $2
+ON CONFLICT (user_id, listing_id) DO NOTHING
+`
+
+type AddToWatchlistParams struct {
+	UserID    int64  `json:"user_id"`
+	ListingID string `json:"listing_id"`
+}
+
+// the user knows the current price of the saved car, so only its later changes are notified
+func (q *Queries) AddToWatchlist(ctx context.Context, arg AddToWatchlistParams) error {
+	_, err := q.db.Exec(ctx, AddToWatchlist,
+		arg.UserID,
+		arg.ListingID,
+	)
+	return err
+}
+
 const CreateNotification = `-- name: CreateNotification :one
 INSERT INTO notifications (listing_id,
                            subscription_id,
@@ -59,22 +123,51 @@ INSERT INTO subscriptions (user_id,
                            year_from,
                            year_to,
                            region,
+                           min_deal_percent,
+                           include_keywords,
+                           exclude_keywords,
+                           fuel,
+                           gearbox,
+                           mileage_to,
+                           power_from,
+                           power_to,
+                           doors,
+                           seats,
+                           color,
+                           air_condition,
+                           damage,
+                           extra_params,
                            created_at,
                            updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now())
-RETURNING id, user_id, brand, model, chassis, price_from, price_to, year_from, year_to, region, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+        now(), now())
+RETURNING id, user_id, brand, model, chassis, price_from, price_to, year_from, year_to, region, created_at, updated_at, min_deal_percent, include_keywords, exclude_keywords, fuel, gearbox, mileage_to, power_from, power_to, doors, seats, color, air_condition, damage, extra_params, muted_until, price_only, delivery_mode, digest_hour, digest_sent_at
 `
 
 type CreateSubscriptionParams struct {
-	UserID    int64    `json:"user_id"`
-	Brand     string   `json:"brand"`
-	Model     []string `json:"model"`
-	Chassis   []string `json:"chassis"`
-	PriceFrom string   `json:"price_from"`
-	PriceTo   string   `json:"price_to"`
-	YearFrom  string   `json:"year_from"`
-	YearTo    string   `json:"year_to"`
-	Region    []string `json:"region"`
+	UserID          int64       `json:"user_id"`
+	Brand           string      `json:"brand"`
+	Model           []string    `json:"model"`
+	Chassis         []string    `json:"chassis"`
+	PriceFrom       string      `json:"price_from"`
+	PriceTo         string      `json:"price_to"`
+	YearFrom        string      `json:"year_from"`
+	YearTo          string      `json:"year_to"`
+	Region          []string    `json:"region"`
+	MinDealPercent  pgtype.Int4 `json:"min_deal_percent"`
+	IncludeKeywords []string    `json:"include_keywords"`
+	ExcludeKeywords []string    `json:"exclude_keywords"`
+	Fuel            []string    `json:"fuel"`
+	Gearbox         []string    `json:"gearbox"`
+	MileageTo       string      `json:"mileage_to"`
+	PowerFrom       string      `json:"power_from"`
+	PowerTo         string      `json:"power_to"`
+	Doors           []string    `json:"doors"`
+	Seats           []string    `json:"seats"`
+	Color           []string    `json:"color"`
+	AirCondition    []string    `json:"air_condition"`
+	Damage          []string    `json:"damage"`
+	ExtraParams     []byte      `json:"extra_params"`
 }
 
 func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (Subscription, error) {
@@ -88,6 +181,20 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		arg.YearFrom,
 		arg.YearTo,
 		arg.Region,
+		arg.MinDealPercent,
+		arg.IncludeKeywords,
+		arg.ExcludeKeywords,
+		arg.Fuel,
+		arg.Gearbox,
+		arg.MileageTo,
+		arg.PowerFrom,
+		arg.PowerTo,
+		arg.Doors,
+		arg.Seats,
+		arg.Color,
+		arg.AirCondition,
+		arg.Damage,
+		arg.ExtraParams,
 	)
 	var i Subscription
 	err := row.Scan(
@@ -103,13 +210,57 @@ func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscription
 		&i.Region,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MinDealPercent,
+		&i.IncludeKeywords,
+		&i.ExcludeKeywords,
+		&i.Fuel,
+		&i.Gearbox,
+		&i.MileageTo,
+		&i.PowerFrom,
+		&i.PowerTo,
+		&i.Doors,
+		&i.Seats,
+		&i.Color,
+		&i.AirCondition,
+		&i.Damage,
+		&i.ExtraParams,
+		&i.MutedUntil,
+		&i.PriceOnly,
+		&i.DeliveryMode,
+		&i.DigestHour,
+		&i.DigestSentAt,
 	)
 	return i, err
 }
 
+const DeleteConversationState = `-- name: DeleteConversationState :exec
+DELETE
+FROM conversation_state
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteConversationState(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, DeleteConversationState, userID)
+	return err
+}
+
+const DeleteExpiredConversationStates = `-- name: DeleteExpiredConversationStates :execrows
+DELETE
+FROM conversation_state
+WHERE expires_at <= $1
+`
+
+func (q *Queries) DeleteExpiredConversationStates(ctx context.Context, expiresAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteExpiredConversationStates, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const DeleteListingsBySubscriptionIDs = `-- name: DeleteListingsBySubscriptionIDs :exec
 DELETE
-FROM listings
+FROM subscription_matches
 WHERE subscription_id = ANY ($1::uuid[])
 `
 
@@ -118,6 +269,38 @@ func (q *Queries) DeleteListingsBySubscriptionIDs(ctx context.Context, dollar_1
 	return err
 }
 
+const DeleteOrphanCars = `-- name: DeleteOrphanCars :execrows
+DELETE
+FROM cars c
+WHERE c.last_seen_at < $1::timestamp
+  AND NOT EXISTS (SELECT 1 FROM subscription_matches m WHERE m.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM watchlist w WHERE w.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM hidden_listings h WHERE h.listing_id = c.listing_id)
+  AND NOT EXISTS (SELECT 1 FROM notification_outbox o WHERE o.listing_id = c.listing_id)
+`
+
+// deletes the cars no subscription matches anymore which were last seen before the given time, so they are out of
+// the window of the market stats, the cars the users saved or hid and the ones with pending notifications are kept,
+// the price history of the deleted cars is deleted with them
+func (q *Queries) DeleteOrphanCars(ctx context.Context, seen_before pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, DeleteOrphanCars, seen_before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const DeleteOutboxNotification = `-- name: DeleteOutboxNotification :exec
+DELETE
+FROM notification_outbox
+WHERE id = $1
+`
+
+func (q *Queries) DeleteOutboxNotification(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, DeleteOutboxNotification, id)
+	return err
+}
+
 const DeleteSubscriptionByID = `-- name: DeleteSubscriptionByID :exec
 DELETE
 FROM subscriptions
@@ -151,6 +334,28 @@ func (q *Queries) DeleteUserByID(ctx context.Context, id int64) error {
 	return err
 }
 
+const EnqueueNotifications = `-- name: EnqueueNotifications :execrows
+INSERT INTO notification_outbox (listing_id,
+                                 subscription_id,
+                                 attempts,
+                                 next_attempt_at,
+                                 last_error,
+                                 created_at,
+                                 updated_at)
+SELECT listing_id, subscription_id, 0, $1::timestamp, '', now(), now()
+FROM subscription_matches
+WHERE is_need_send = TRUE
+ON CONFLICT (listing_id, subscription_id, kind) DO NOTHING
+`
+
+func (q *Queries) EnqueueNotifications(ctx context.Context, dollar_1 pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, EnqueueNotifications, dollar_1)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const GetAllSubscriptions = `-- name: GetAllSubscriptions :many
 SELECT id,
        user_id,
@@ -163,7 +368,26 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions
 `
 
@@ -189,6 +413,25 @@ func (q *Queries) GetAllSubscriptions(ctx context.Context) ([]Subscription, erro
 			&i.Region,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MinDealPercent,
+			&i.IncludeKeywords,
+			&i.ExcludeKeywords,
+			&i.Fuel,
+			&i.Gearbox,
+			&i.MileageTo,
+			&i.PowerFrom,
+			&i.PowerTo,
+			&i.Doors,
+			&i.Seats,
+			&i.Color,
+			&i.AirCondition,
+			&i.Damage,
+			&i.ExtraParams,
+			&i.MutedUntil,
+			&i.PriceOnly,
+			&i.DeliveryMode,
+			&i.DigestHour,
+			&i.DigestSentAt,
 		); err != nil {
 			return nil, err
 		}
@@ -200,44 +443,357 @@ func (q *Queries) GetAllSubscriptions(ctx context.Context) ([]Subscription, erro
 	return items, nil
 }
 
-const GetListingsByIsNeedSend = `-- name: GetListingsByIsNeedSend :many
+const GetConversationState = `-- name: GetConversationState :one
+SELECT user_id,
+       state,
+       expires_at,
+       created_at,
+       updated_at
+FROM conversation_state
+WHERE user_id = $1
+  AND expires_at > $2
+`
+
+type GetConversationStateParams struct {
+	UserID    int64            `json:"user_id"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+}
+
+func (q *Queries) GetConversationState(ctx context.Context, arg GetConversationStateParams) (ConversationState, error) {
+	row := q.db.QueryRow(ctx, GetConversationState,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
+	var i ConversationState
+	err := row.Scan(
+		&i.UserID,
+		&i.State,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetDueOutboxNotifications = `-- name: GetDueOutboxNotifications :many
 SELECT id,
        listing_id,
        subscription_id,
-       title,
-       price,
-       new_price,
-       engine_volume,
-       transmission,
-       body_type,
-       mileage,
-       location,
-       link,
-       date,
-       is_need_send,
+       attempts,
+       next_attempt_at,
+       last_error,
        created_at,
-       updated_at
-FROM listings
-WHERE is_need_send = $1
+       updated_at,
+       kind,
+       photos_sent
+FROM notification_outbox
+WHERE next_attempt_at <= $1
+ORDER BY next_attempt_at
+`
+
+func (q *Queries) GetDueOutboxNotifications(ctx context.Context, nextAttemptAt pgtype.Timestamp) ([]NotificationOutbox, error) {
+	rows, err := q.db.Query(ctx, GetDueOutboxNotifications, nextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []NotificationOutbox{}
+	for rows.Next() {
+		var i NotificationOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.ListingID,
+			&i.SubscriptionID,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Kind,
+			&i.PhotosSent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetListing = `-- name: GetListing :one
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.listing_id = $1
+  AND m.subscription_id = $2
+`
+
+type GetListingRow struct {
+	ID              pgtype.UUID      `json:"id"`
+	ListingID       string           `json:"listing_id"`
+	SubscriptionID  pgtype.UUID      `json:"subscription_id"`
+	Title           string           `json:"title"`
+	Price           string           `json:"price"`
+	NewPrice        pgtype.Text      `json:"new_price"`
+	EngineVolume    string           `json:"engine_volume"`
+	Transmission    string           `json:"transmission"`
+	BodyType        string           `json:"body_type"`
+	Mileage         string           `json:"mileage"`
+	Location        string           `json:"location"`
+	Link            string           `json:"link"`
+	Date            pgtype.Timestamp `json:"date"`
+	IsNeedSend      bool             `json:"is_need_send"`
+	FuelType        string           `json:"fuel_type"`
+	PowerKw         int32            `json:"power_kw"`
+	PowerHp         int32            `json:"power_hp"`
+	Color           string           `json:"color"`
+	Drive           string           `json:"drive"`
+	Doors           string           `json:"doors"`
+	RegisteredUntil string           `json:"registered_until"`
+	DamageStatus    string           `json:"damage_status"`
+	SellerType      string           `json:"seller_type"`
+	PriceEur        pgtype.Numeric   `json:"price_eur"`
+	NewPriceEur     pgtype.Numeric   `json:"new_price_eur"`
+	MileageKm       pgtype.Int4      `json:"mileage_km"`
+	EngineVolumeCm3 pgtype.Int4      `json:"engine_volume_cm3"`
+	Year            pgtype.Int4      `json:"year"`
+	Brand           string           `json:"brand"`
+	Model           string           `json:"model"`
+	FirstSeenAt     pgtype.Timestamp `json:"first_seen_at"`
+	RemovedAt       pgtype.Timestamp `json:"removed_at"`
+	LastSeenAt      pgtype.Timestamp `json:"last_seen_at"`
+	ImageHash       pgtype.Int8      `json:"image_hash"`
+	ImageUrl        string           `json:"image_url"`
+	GalleryUrls     []string         `json:"gallery_urls"`
+	RelistedFrom    pgtype.Text      `json:"relisted_from"`
+	RelistedPrice   pgtype.Text      `json:"relisted_price"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+}
+
+type GetListingParams struct {
+	ListingID      string      `json:"listing_id"`
+	SubscriptionID pgtype.UUID `json:"subscription_id"`
+}
+
+func (q *Queries) GetListing(ctx context.Context, arg GetListingParams) (GetListingRow, error) {
+	row := q.db.QueryRow(ctx, GetListing,
+		arg.ListingID,
+		arg.SubscriptionID,
+	)
+	var i GetListingRow
+	err := row.Scan(
+		&i.ID,
+		&i.ListingID,
+		&i.SubscriptionID,
+		&i.Title,
+		&i.Price,
+		&i.NewPrice,
+		&i.EngineVolume,
+		&i.Transmission,
+		&i.BodyType,
+		&i.Mileage,
+		&i.Location,
+		&i.Link,
+		&i.Date,
+		&i.IsNeedSend,
+		&i.FuelType,
+		&i.PowerKw,
+		&i.PowerHp,
+		&i.Color,
+		&i.Drive,
+		&i.Doors,
+		&i.RegisteredUntil,
+		&i.DamageStatus,
+		&i.SellerType,
+		&i.PriceEur,
+		&i.NewPriceEur,
+		&i.MileageKm,
+		&i.EngineVolumeCm3,
+		&i.Year,
+		&i.Brand,
+		&i.Model,
+		&i.FirstSeenAt,
+		&i.RemovedAt,
+		&i.LastSeenAt,
+		&i.ImageHash,
+		&i.ImageUrl,
+		&i.GalleryUrls,
+		&i.RelistedFrom,
+		&i.RelistedPrice,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetListingPriceHistory = `-- name: GetListingPriceHistory :many
+SELECT id,
+       listing_id,
+       price,
+       price_eur,
+       observed_at,
+       created_at
+FROM listing_price_history
+WHERE listing_id = $1
+ORDER BY observed_at
+`
+
+func (q *Queries) GetListingPriceHistory(ctx context.Context, listingID string) ([]ListingPriceHistory, error) {
+	rows, err := q.db.Query(ctx, GetListingPriceHistory, listingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListingPriceHistory{}
+	for rows.Next() {
+		var i ListingPriceHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.ListingID,
+			&i.Price,
+			&i.PriceEur,
+			&i.ObservedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetListingsByIsNeedSend = `-- name: GetListingsByIsNeedSend :many
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.is_need_send = $1
 `
 
 type GetListingsByIsNeedSendRow struct {
-	ID             pgtype.UUID      `json:"id"`
-	ListingID      string           `json:"listing_id"`
-	SubscriptionID pgtype.UUID      `json:"subscription_id"`
-	Title          string           `json:"title"`
-	Price          string           `json:"price"`
-	NewPrice       pgtype.Text      `json:"new_price"`
-	EngineVolume   string           `json:"engine_volume"`
-	Transmission   string           `json:"transmission"`
-	BodyType       string           `json:"body_type"`
-	Mileage        string           `json:"mileage"`
-	Location       string           `json:"location"`
-	Link           string           `json:"link"`
-	Date           pgtype.Timestamp `json:"date"`
-	IsNeedSend     bool             `json:"is_need_send"`
-	CreatedAt      pgtype.Timestamp `json:"created_at"`
-	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	ID              pgtype.UUID      `json:"id"`
+	ListingID       string           `json:"listing_id"`
+	SubscriptionID  pgtype.UUID      `json:"subscription_id"`
+	Title           string           `json:"title"`
+	Price           string           `json:"price"`
+	NewPrice        pgtype.Text      `json:"new_price"`
+	EngineVolume    string           `json:"engine_volume"`
+	Transmission    string           `json:"transmission"`
+	BodyType        string           `json:"body_type"`
+	Mileage         string           `json:"mileage"`
+	Location        string           `json:"location"`
+	Link            string           `json:"link"`
+	Date            pgtype.Timestamp `json:"date"`
+	IsNeedSend      bool             `json:"is_need_send"`
+	FuelType        string           `json:"fuel_type"`
+	PowerKw         int32            `json:"power_kw"`
+	PowerHp         int32            `json:"power_hp"`
+	Color           string           `json:"color"`
+	Drive           string           `json:"drive"`
+	Doors           string           `json:"doors"`
+	RegisteredUntil string           `json:"registered_until"`
+	DamageStatus    string           `json:"damage_status"`
+	SellerType      string           `json:"seller_type"`
+	PriceEur        pgtype.Numeric   `json:"price_eur"`
+	NewPriceEur     pgtype.Numeric   `json:"new_price_eur"`
+	MileageKm       pgtype.Int4      `json:"mileage_km"`
+	EngineVolumeCm3 pgtype.Int4      `json:"engine_volume_cm3"`
+	Year            pgtype.Int4      `json:"year"`
+	Brand           string           `json:"brand"`
+	Model           string           `json:"model"`
+	FirstSeenAt     pgtype.Timestamp `json:"first_seen_at"`
+	RemovedAt       pgtype.Timestamp `json:"removed_at"`
+	LastSeenAt      pgtype.Timestamp `json:"last_seen_at"`
+	ImageHash       pgtype.Int8      `json:"image_hash"`
+	ImageUrl        string           `json:"image_url"`
+	GalleryUrls     []string         `json:"gallery_urls"`
+	RelistedFrom    pgtype.Text      `json:"relisted_from"`
+	RelistedPrice   pgtype.Text      `json:"relisted_price"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
 }
 
 func (q *Queries) GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool) ([]GetListingsByIsNeedSendRow, error) {
@@ -264,6 +820,30 @@ func (q *Queries) GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool)
 			&i.Link,
 			&i.Date,
 			&i.IsNeedSend,
+			&i.FuelType,
+			&i.PowerKw,
+			&i.PowerHp,
+			&i.Color,
+			&i.Drive,
+			&i.Doors,
+			&i.RegisteredUntil,
+			&i.DamageStatus,
+			&i.SellerType,
+			&i.PriceEur,
+			&i.NewPriceEur,
+			&i.MileageKm,
+			&i.EngineVolumeCm3,
+			&i.Year,
+			&i.Brand,
+			&i.Model,
+			&i.FirstSeenAt,
+			&i.RemovedAt,
+			&i.LastSeenAt,
+			&i.ImageHash,
+			&i.ImageUrl,
+			&i.GalleryUrls,
+			&i.RelistedFrom,
+			&i.RelistedPrice,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -278,43 +858,92 @@ func (q *Queries) GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool)
 }
 
 const GetListingsBySubscriptionID = `-- name: GetListingsBySubscriptionID :many
-SELECT id,
-       listing_id,
-       subscription_id,
-       title,
-       price,
-       new_price,
-       engine_volume,
-       transmission,
-       body_type,
-       mileage,
-       location,
-       link,
-       date,
-       is_need_send,
-       created_at,
-       updated_at
-FROM listings
-WHERE subscription_id = $1
+SELECT m.id,
+       m.listing_id,
+       m.subscription_id,
+       c.title,
+       m.price,
+       m.new_price,
+       c.engine_volume,
+       c.transmission,
+       c.body_type,
+       c.mileage,
+       c.location,
+       c.link,
+       c.date,
+       m.is_need_send,
+       c.fuel_type,
+       c.power_kw,
+       c.power_hp,
+       c.color,
+       c.drive,
+       c.doors,
+       c.registered_until,
+       c.damage_status,
+       c.seller_type,
+       m.price_eur,
+       m.new_price_eur,
+       c.mileage_km,
+       c.engine_volume_cm3,
+       c.year,
+       c.brand,
+       c.model,
+       c.created_at AS first_seen_at,
+       c.removed_at,
+       c.last_seen_at,
+       c.image_hash,
+       c.image_url,
+       c.gallery_urls,
+       m.relisted_from,
+       m.relisted_price,
+       m.created_at,
+       m.updated_at
+FROM subscription_matches m
+         JOIN cars c ON c.listing_id = m.listing_id
+WHERE m.subscription_id = $1
 `
 
 type GetListingsBySubscriptionIDRow struct {
-	ID             pgtype.UUID      `json:"id"`
-	ListingID      string           `json:"listing_id"`
-	SubscriptionID pgtype.UUID      `json:"subscription_id"`
-	Title          string           `json:"title"`
-	Price          string           `json:"price"`
-	NewPrice       pgtype.Text      `json:"new_price"`
-	EngineVolume   string           `json:"engine_volume"`
-	Transmission   string           `json:"transmission"`
-	BodyType       string           `json:"body_type"`
-	Mileage        string           `json:"mileage"`
-	Location       string           `json:"location"`
-	Link           string           `json:"link"`
-	Date           pgtype.Timestamp `json:"date"`
-	IsNeedSend     bool             `json:"is_need_send"`
-	CreatedAt      pgtype.Timestamp `json:"created_at"`
-	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	ID              pgtype.UUID      `json:"id"`
+	ListingID       string           `json:"listing_id"`
+	SubscriptionID  pgtype.UUID      `json:"subscription_id"`
+	Title           string           `json:"title"`
+	Price           string           `json:"price"`
+	NewPrice        pgtype.Text      `json:"new_price"`
+	EngineVolume    string           `json:"engine_volume"`
+	Transmission    string           `json:"transmission"`
+	BodyType        string           `json:"body_type"`
+	Mileage         string           `json:"mileage"`
+	Location        string           `json:"location"`
+	Link            string           `json:"link"`
+	Date            pgtype.Timestamp `json:"date"`
+	IsNeedSend      bool             `json:"is_need_send"`
+	FuelType        string           `json:"fuel_type"`
+	PowerKw         int32            `json:"power_kw"`
+	PowerHp         int32            `json:"power_hp"`
+	Color           string           `json:"color"`
+	Drive           string           `json:"drive"`
+	Doors           string           `json:"doors"`
+	RegisteredUntil string           `json:"registered_until"`
+	DamageStatus    string           `json:"damage_status"`
+	SellerType      string           `json:"seller_type"`
+	PriceEur        pgtype.Numeric   `json:"price_eur"`
+	NewPriceEur     pgtype.Numeric   `json:"new_price_eur"`
+	MileageKm       pgtype.Int4      `json:"mileage_km"`
+	EngineVolumeCm3 pgtype.Int4      `json:"engine_volume_cm3"`
+	Year            pgtype.Int4      `json:"year"`
+	Brand           string           `json:"brand"`
+	Model           string           `json:"model"`
+	FirstSeenAt     pgtype.Timestamp `json:"first_seen_at"`
+	RemovedAt       pgtype.Timestamp `json:"removed_at"`
+	LastSeenAt      pgtype.Timestamp `json:"last_seen_at"`
+	ImageHash       pgtype.Int8      `json:"image_hash"`
+	ImageUrl        string           `json:"image_url"`
+	GalleryUrls     []string         `json:"gallery_urls"`
+	RelistedFrom    pgtype.Text      `json:"relisted_from"`
+	RelistedPrice   pgtype.Text      `json:"relisted_price"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
 }
 
 func (q *Queries) GetListingsBySubscriptionID(ctx context.Context, subscriptionID pgtype.UUID) ([]GetListingsBySubscriptionIDRow, error) {
@@ -341,6 +970,30 @@ func (q *Queries) GetListingsBySubscriptionID(ctx context.Context, subscriptionI
 			&i.Link,
 			&i.Date,
 			&i.IsNeedSend,
+			&i.FuelType,
+			&i.PowerKw,
+			&i.PowerHp,
+			&i.Color,
+			&i.Drive,
+			&i.Doors,
+			&i.RegisteredUntil,
+			&i.DamageStatus,
+			&i.SellerType,
+			&i.PriceEur,
+			&i.NewPriceEur,
+			&i.MileageKm,
+			&i.EngineVolumeCm3,
+			&i.Year,
+			&i.Brand,
+			&i.Model,
+			&i.FirstSeenAt,
+			&i.RemovedAt,
+			&i.LastSeenAt,
+			&i.ImageHash,
+			&i.ImageUrl,
+			&i.GalleryUrls,
+			&i.RelistedFrom,
+			&i.RelistedPrice,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -354,6 +1007,105 @@ func (q *Queries) GetListingsBySubscriptionID(ctx context.Context, subscriptionI
 	return items, nil
 }
 
+const GetMarketStats = `-- name: GetMarketStats :one
+SELECT brand,
+       model,
+       year,
+       mileage_band,
+       sample_size,
+       p25_price_eur,
+       median_price_eur,
+       p75_price_eur,
+       updated_at,
+       removed_count,
+       median_days_on_market
+FROM market_stats
+WHERE brand = $1
+  AND model = $2
+  AND year = $3
+  AND mileage_band = $4
+`
+
+type GetMarketStatsParams struct {
+	Brand       string `json:"brand"`
+	Model       string `json:"model"`
+	Year        int32  `json:"year"`
+	MileageBand int32  `json:"mileage_band"`
+}
+
+func (q *Queries) GetMarketStats(ctx context.Context, arg GetMarketStatsParams) (MarketStat, error) {
+	row := q.db.QueryRow(ctx, GetMarketStats,
+		arg.Brand,
+		arg.Model,
+		arg.Year,
+		arg.MileageBand,
+	)
+	var i MarketStat
+	err := row.Scan(
+		&i.Brand,
+		&i.Model,
+		&i.Year,
+		&i.MileageBand,
+		&i.SampleSize,
+		&i.P25PriceEur,
+		&i.MedianPriceEur,
+		&i.P75PriceEur,
+		&i.UpdatedAt,
+		&i.RemovedCount,
+		&i.MedianDaysOnMarket,
+	)
+	return i, err
+}
+
+const GetMedianPricesBySubscriptionID = `-- name: GetMedianPricesBySubscriptionID :many
+SELECT date_trunc('day', h.observed_at)::timestamp                          AS day,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY h.price_eur)::numeric AS median_price_eur
+FROM listing_price_history h
+         JOIN subscription_matches m ON m.listing_id = h.listing_id
+WHERE m.subscription_id = $1::uuid
+  AND h.price_eur IS NOT NULL
+  AND h.observed_at >= $2::timestamp
+GROUP BY day
+ORDER BY day
+`
+
+type GetMedianPricesBySubscriptionIDRow struct {
+	Day            pgtype.Timestamp `json:"day"`
+	MedianPriceEur pgtype.Numeric   `json:"median_price_eur"`
+}
+
+type GetMedianPricesBySubscriptionIDParams struct {
+	SubscriptionID pgtype.UUID      `json:"subscription_id"`
+	ObservedAfter  pgtype.Timestamp `json:"observed_after"`
+}
+
+// the daily median price of the cars matched by the subscription, which is the market price of its model and years
+func (q *Queries) GetMedianPricesBySubscriptionID(ctx context.Context, arg GetMedianPricesBySubscriptionIDParams) ([]GetMedianPricesBySubscriptionIDRow, error) {
+	rows, err := q.db.Query(ctx, GetMedianPricesBySubscriptionID,
+		arg.SubscriptionID,
+		arg.ObservedAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetMedianPricesBySubscriptionIDRow{}
+	for rows.Next() {
+		var i GetMedianPricesBySubscriptionIDRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.MedianPriceEur,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetSubscriptionByID = `-- name: GetSubscriptionByID :one
 SELECT id,
        user_id,
@@ -366,7 +1118,26 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions
 WHERE id = $1
 `
@@ -387,6 +1158,25 @@ func (q *Queries) GetSubscriptionByID(ctx context.Context, id pgtype.UUID) (Subs
 		&i.Region,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MinDealPercent,
+		&i.IncludeKeywords,
+		&i.ExcludeKeywords,
+		&i.Fuel,
+		&i.Gearbox,
+		&i.MileageTo,
+		&i.PowerFrom,
+		&i.PowerTo,
+		&i.Doors,
+		&i.Seats,
+		&i.Color,
+		&i.AirCondition,
+		&i.Damage,
+		&i.ExtraParams,
+		&i.MutedUntil,
+		&i.PriceOnly,
+		&i.DeliveryMode,
+		&i.DigestHour,
+		&i.DigestSentAt,
 	)
 	return i, err
 }
@@ -403,7 +1193,26 @@ SELECT id,
        year_to,
        region,
        created_at,
-       updated_at
+       updated_at,
+       min_deal_percent,
+       include_keywords,
+       exclude_keywords,
+       fuel,
+       gearbox,
+       mileage_to,
+       power_from,
+       power_to,
+       doors,
+       seats,
+       color,
+       air_condition,
+       damage,
+       extra_params,
+       muted_until,
+       price_only,
+       delivery_mode,
+       digest_hour,
+       digest_sent_at
 FROM subscriptions
 WHERE user_id = $1
 `
@@ -430,6 +1239,25 @@ func (q *Queries) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([
 			&i.Region,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MinDealPercent,
+			&i.IncludeKeywords,
+			&i.ExcludeKeywords,
+			&i.Fuel,
+			&i.Gearbox,
+			&i.MileageTo,
+			&i.PowerFrom,
+			&i.PowerTo,
+			&i.Doors,
+			&i.Seats,
+			&i.Color,
+			&i.AirCondition,
+			&i.Damage,
+			&i.ExtraParams,
+			&i.MutedUntil,
+			&i.PriceOnly,
+			&i.DeliveryMode,
+			&i.DigestHour,
+			&i.DigestSentAt,
 		); err != nil {
 			return nil, err
 		}
@@ -441,56 +1269,1020 @@ func (q *Queries) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([
 	return items, nil
 }
 
-const UpsertListing = `-- name: UpsertListing :exec
-INSERT INTO listings (listing_id, subscription_id, title, price, new_price, engine_volume, transmission, body_type, mileage, location,
-                      link, date, is_need_send, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, now(), now())
-ON CONFLICT (listing_id, subscription_id) DO UPDATE SET title         = EXCLUDED.title,
-                                                price         = EXCLUDED.price,
-                                                new_price     = EXCLUDED.new_price,
-                                                engine_volume = EXCLUDED.engine_volume,
-                                                transmission  = EXCLUDED.transmission,
-                                                body_type     = EXCLUDED.body_type,
-                                                mileage       = EXCLUDED.mileage,
-                                                location      = EXCLUDED.location,
-                                                link          = EXCLUDED.link,
-                                                date          = EXCLUDED.date,
-                                                is_need_send  = EXCLUDED.is_need_send,
-                                                updated_at    = now()
-RETURNING id, listing_id, subscription_id, title, price, engine_volume, transmission, body_type, mileage, location, link, date, is_need_send, created_at, updated_at, new_price
-`
-
-type UpsertListingParams struct {
-	ListingID      string           `json:"listing_id"`
-	SubscriptionID pgtype.UUID      `json:"subscription_id"`
-	Title          string           `json:"title"`
-	Price          string           `json:"price"`
-	NewPrice       pgtype.Text      `json:"new_price"`
-	EngineVolume   string           `json:"engine_volume"`
-	Transmission   string           `json:"transmission"`
-	BodyType       string           `json:"body_type"`
-	Mileage        string           `json:"mileage"`
-	Location       string           `json:"location"`
-	Link           string           `json:"link"`
-	Date           pgtype.Timestamp `json:"date"`
-	IsNeedSend     bool             `json:"is_need_send"`
-}
-
-func (q *Queries) UpsertListing(ctx context.Context, arg UpsertListingParams) error {
-	_, err := q.db.Exec(ctx, UpsertListing,
-		arg.ListingID,
-		arg.SubscriptionID,
-		arg.Title,
-		arg.Price,
-		arg.NewPrice,
-		arg.EngineVolume,
-		arg.Transmission,
-		arg.BodyType,
-		arg.Mileage,
-		arg.Location,
-		arg.Link,
-		arg.Date,
+const GetUserSettings = `-- name: GetUserSettings :one
+SELECT id,
+       time_zone,
+       quiet_hours_from,
+       quiet_hours_to,
+       mute_weekends
+FROM users
+WHERE id = $1
+`
+
+type GetUserSettingsRow struct {
+	ID             int64       `json:"id"`
+	TimeZone       string      `json:"time_zone"`
+	QuietHoursFrom pgtype.Int2 `json:"quiet_hours_from"`
+	QuietHoursTo   pgtype.Int2 `json:"quiet_hours_to"`
+	MuteWeekends   bool        `json:"mute_weekends"`
+}
+
+func (q *Queries) GetUserSettings(ctx context.Context, id int64) (GetUserSettingsRow, error) {
+	row := q.db.QueryRow(ctx, GetUserSettings, id)
+	var i GetUserSettingsRow
+	err := row.Scan(
+		&i.ID,
+		&i.TimeZone,
+		&i.QuietHoursFrom,
+		&i.QuietHoursTo,
+		&i.MuteWeekends,
+	)
+	return i, err
+}
+
+const GetWatchedCars = `-- name: GetWatchedCars :many
+SELECT c.listing_id,
+       c.link
+FROM cars c
+WHERE c.removed_at IS NULL
+  AND EXISTS (SELECT 1
+              FROM watchlist w
+              WHERE w.listing_id = c.listing_id)
+ORDER BY c.listing_id
+`
+
+type GetWatchedCarsRow struct {
+	ListingID string `json:"listing_id"`
+	Link      string `json:"link"`
+}
+
+// the cars of all the watchlists which are on the market, they are checked on their detail pages
+func (q *Queries) GetWatchedCars(ctx context.Context) ([]GetWatchedCarsRow, error) {
+	rows, err := q.db.Query(ctx, GetWatchedCars)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetWatchedCarsRow{}
+	for rows.Next() {
+		var i GetWatchedCarsRow
+		if err := rows.Scan(
+			&i.ListingID,
+			&i.Link,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWatchlistByUserID = `-- name: GetWatchlistByUserID :many
+SELECT c.listing_id,
+       c.title,
+       c.price,
+       c.price_eur,
+       c.link,
+       c.removed_at,
+       w.created_at
+FROM watchlist w
+         JOIN cars c ON c.listing_id = w.listing_id
+WHERE w.user_id = $1
+ORDER BY w.created_at, c.listing_id
+`
+
+type GetWatchlistByUserIDRow struct {
+	ListingID string           `json:"listing_id"`
+	Title     string           `json:"title"`
+	Price     string           `json:"price"`
+	PriceEur  pgtype.Numeric   `json:"price_eur"`
+	Link      string           `json:"link"`
+	RemovedAt pgtype.Timestamp `json:"removed_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+func (q *Queries) GetWatchlistByUserID(ctx context.Context, userID int64) ([]GetWatchlistByUserIDRow, error) {
+	rows, err := q.db.Query(ctx, GetWatchlistByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetWatchlistByUserIDRow{}
+	for rows.Next() {
+		var i GetWatchlistByUserIDRow
+		if err := rows.Scan(
+			&i.ListingID,
+			&i.Title,
+			&i.Price,
+			&i.PriceEur,
+			&i.Link,
+			&i.RemovedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetWatchlistChanges = `-- name: GetWatchlistChanges :many
+SELECT w.user_id,
+       c.listing_id,
+       c.title,
+       c.link,
+       c.price,
+       c.price_eur,
+       w.notified_price,
+       w.notified_price_eur,
+       c.created_at AS first_seen_at,
+       c.removed_at
+FROM watchlist w
+         JOIN cars c ON c.listing_id = w.listing_id
+WHERE (c.removed_at IS NULL AND c.price_eur IS NOT NULL AND c.price_eur IS DISTINCT FROM w.notified_price_eur)
+   OR (c.removed_at IS NOT NULL AND c.removed_at IS DISTINCT FROM w.notified_removed_at)
+ORDER BY w.created_at, w.user_id
+`
+
+type GetWatchlistChangesRow struct {
+	UserID           int64            `json:"user_id"`
+	ListingID        string           `json:"listing_id"`
+	Title            string           `json:"title"`
+	Link             string           `json:"link"`
+	Price            string           `json:"price"`
+	PriceEur         pgtype.Numeric   `json:"price_eur"`
+	NotifiedPrice    string           `json:"notified_price"`
+	NotifiedPriceEur pgtype.Numeric   `json:"notified_price_eur"`
+	FirstSeenAt      pgtype.Timestamp `json:"first_seen_at"`
+	RemovedAt        pgtype.Timestamp `json:"removed_at"`
+}
+
+// the watched cars with a known price which differs from the price the user knows
+// and the watched cars which were removed since the user was notified
+func (q *Queries) GetWatchlistChanges(ctx context.Context) ([]GetWatchlistChangesRow, error) {
+	rows, err := q.db.Query(ctx, GetWatchlistChanges)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetWatchlistChangesRow{}
+	for rows.Next() {
+		var i GetWatchlistChangesRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ListingID,
+			&i.Title,
+			&i.Link,
+			&i.Price,
+			&i.PriceEur,
+			&i.NotifiedPrice,
+			&i.NotifiedPriceEur,
+			&i.FirstSeenAt,
+			&i.RemovedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const HideListing = `-- name: HideListing :exec
+INSERT INTO hidden_listings (user_id, listing_id, created_at)
+VALUES ($1, $2, now())
+ON CONFLICT (user_id, listing_id) DO NOTHING
+`
+
+type HideListingParams struct {
+	UserID    int64  `json:"user_id"`
+	ListingID string `json:"listing_id"`
+}
+
+func (q *Queries) HideListing(ctx context.Context, arg HideListingParams) error {
+	_, err := q.db.Exec(ctx, HideListing,
+		arg.UserID,
+		arg.ListingID,
+	)
+	return err
+}
+
+const IsListingHidden = `-- name: IsListingHidden :one
+SELECT EXISTS (SELECT 1
+               FROM hidden_listings
+               WHERE user_id = $1
+                 AND listing_id = $2)
+`
+
+type IsListingHiddenParams struct {
+	UserID    int64  `json:"user_id"`
+	ListingID string `json:"listing_id"`
+}
+
+func (q *Queries) IsListingHidden(ctx context.Context, arg IsListingHiddenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, IsListingHidden,
+		arg.UserID,
+		arg.ListingID,
+	)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const MarkCarRemoved = `-- name: MarkCarRemoved :execrows
+WITH removed AS (
+    UPDATE cars
+        SET removed_at = $1::timestamp,
+            updated_at = now()
+        WHERE listing_id = $2::varchar
+            AND removed_at IS NULL
+        RETURNING listing_id)
+INSERT
+INTO notification_outbox (listing_id,
+                          subscription_id,
+                          kind,
+                          attempts,
+                          next_attempt_at,
+                          last_error,
+                          created_at,
+                          updated_at)
+SELECT m.listing_id, m.subscription_id, 'REMOVED', 0, $1::timestamp, '', now(), now()
+FROM removed
+         JOIN subscription_matches m ON m.listing_id = removed.listing_id
+WHERE $3::boolean
+  AND EXISTS (SELECT 1
+              FROM notifications n
+              WHERE n.listing_id = m.listing_id
+                AND n.subscription_id = m.subscription_id
+                AND n.status = 'SENT')
+ON CONFLICT (listing_id, subscription_id, kind) DO NOTHING
+`
+
+type MarkCarRemovedParams struct {
+	RemovedAt pgtype.Timestamp `json:"removed_at"`
+	ListingID string           `json:"listing_id"`
+	Notify    bool             `json:"notify"`
+}
+
+// marks the car as removed and, if notify is set, enqueues the removal notifications for the subscriptions
+// the car was sent to, the number of the enqueued notifications is returned
+func (q *Queries) MarkCarRemoved(ctx context.Context, arg MarkCarRemovedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, MarkCarRemoved,
+		arg.RemovedAt,
+		arg.ListingID,
+		arg.Notify,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const MarkCarsMissing = `-- name: MarkCarsMissing :many
+UPDATE cars
+SET missing_count = missing_count + 1
+WHERE removed_at IS NULL
+  AND NOT listing_id = ANY ($1::varchar[])
+  AND listing_id IN (SELECT listing_id
+                     FROM subscription_matches
+                     WHERE subscription_id = ANY ($2::uuid[]))
+RETURNING listing_id, link, missing_count
+`
+
+type MarkCarsMissingRow struct {
+	ListingID    string `json:"listing_id"`
+	Link         string `json:"link"`
+	MissingCount int32  `json:"missing_count"`
+}
+
+type MarkCarsMissingParams struct {
+	SeenListingIds  []string      `json:"seen_listing_ids"`
+	SubscriptionIds []pgtype.UUID `json:"subscription_ids"`
+}
+
+// increments the missing count of the cars matched by the scraped subscriptions which were not found in a full scrape
+func (q *Queries) MarkCarsMissing(ctx context.Context, arg MarkCarsMissingParams) ([]MarkCarsMissingRow, error) {
+	rows, err := q.db.Query(ctx, MarkCarsMissing,
+		arg.SeenListingIds,
+		arg.SubscriptionIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MarkCarsMissingRow{}
+	for rows.Next() {
+		var i MarkCarsMissingRow
+		if err := rows.Scan(
+			&i.ListingID,
+			&i.Link,
+			&i.MissingCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const MarkCarsSeen = `-- name: MarkCarsSeen :exec
+UPDATE cars
+SET last_seen_at  = $1::timestamp,
+    missing_count = 0,
+    removed_at    = NULL
+WHERE listing_id = ANY ($2::varchar[])
+`
+
+type MarkCarsSeenParams struct {
+	SeenAt     pgtype.Timestamp `json:"seen_at"`
+	ListingIds []string         `json:"listing_ids"`
+}
+
+// marks the cars found in a full scrape as seen, a car which was marked as removed is on the market again
+func (q *Queries) MarkCarsSeen(ctx context.Context, arg MarkCarsSeenParams) error {
+	_, err := q.db.Exec(ctx, MarkCarsSeen,
+		arg.SeenAt,
+		arg.ListingIds,
+	)
+	return err
+}
+
+const MuteSubscription = `-- name: MuteSubscription :one
+UPDATE subscriptions
+SET muted_until = $3,
+    updated_at  = now()
+WHERE id = $1
+  AND user_id = $2
+RETURNING id, user_id, brand, model, chassis, price_from, price_to, year_from, year_to, region, created_at, updated_at, min_deal_percent, include_keywords, exclude_keywords, fuel, gearbox, mileage_to, power_from, power_to, doors, seats, color, air_condition, damage, extra_params, muted_until, price_only, delivery_mode, digest_hour, digest_sent_at
+`
+
+type MuteSubscriptionParams struct {
+	ID         pgtype.UUID      `json:"id"`
+	UserID     int64            `json:"user_id"`
+	MutedUntil pgtype.Timestamp `json:"muted_until"`
+}
+
+func (q *Queries) MuteSubscription(ctx context.Context, arg MuteSubscriptionParams) (Subscription, error) {
+	row := q.db.QueryRow(ctx, MuteSubscription,
+		arg.ID,
+		arg.UserID,
+		arg.MutedUntil,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Brand,
+		&i.Model,
+		&i.Chassis,
+		&i.PriceFrom,
+		&i.PriceTo,
+		&i.YearFrom,
+		&i.YearTo,
+		&i.Region,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MinDealPercent,
+		&i.IncludeKeywords,
+		&i.ExcludeKeywords,
+		&i.Fuel,
+		&i.Gearbox,
+		&i.MileageTo,
+		&i.PowerFrom,
+		&i.PowerTo,
+		&i.Doors,
+		&i.Seats,
+		&i.Color,
+		&i.AirCondition,
+		&i.Damage,
+		&i.ExtraParams,
+		&i.MutedUntil,
+		&i.PriceOnly,
+		&i.DeliveryMode,
+		&i.DigestHour,
+		&i.DigestSentAt,
+	)
+	return i, err
+}
+
+const RefreshMarketStats = `-- name: RefreshMarketStats :execrows
+WITH stats AS (SELECT brand,
+                      model,
+                      year,
+                      mileage_km / $1::integer                         AS mileage_band,
+                      count(*)::integer                                                       AS sample_size,
+                      percentile_cont(0.25) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2) AS p25_price_eur,
+                      percentile_cont(0.5) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2)  AS median_price_eur,
+                      percentile_cont(0.75) WITHIN GROUP (ORDER BY price_eur)::numeric(12, 2) AS p75_price_eur,
+                      count(removed_at)::integer                                              AS removed_count,
+                      percentile_cont(0.5) WITHIN GROUP (
+                          ORDER BY extract(EPOCH FROM removed_at - created_at) / 86400)::numeric(8, 1)
+                                                                                              AS median_days_on_market
+               FROM cars
+               WHERE brand <> ''
+                 AND model <> ''
+                 AND year IS NOT NULL
+                 AND mileage_km IS NOT NULL
+                 AND price_eur IS NOT NULL
+                 AND last_seen_at >= $2::timestamp
+               GROUP BY brand, model, year, mileage_km / $1::integer),
+     deleted AS (DELETE FROM market_stats ms
+         WHERE NOT EXISTS (SELECT 1
+                           FROM stats
+                           WHERE stats.brand = ms.brand
+                             AND stats.model = ms.model
+                             AND stats.year = ms.year
+                             AND stats.mileage_band = ms.mileage_band))
+INSERT
+INTO market_stats (brand, model, year, mileage_band, sample_size, p25_price_eur, median_price_eur, p75_price_eur,
+                   removed_count, median_days_on_market, updated_at)
+SELECT brand,
+       model,
+       year,
+       mileage_band,
+       sample_size,
+       p25_price_eur,
+       median_price_eur,
+       p75_price_eur,
+       removed_count,
+       median_days_on_market,
+       now()
+FROM stats
+ON CONFLICT (brand, model, year, mileage_band) DO UPDATE SET sample_size           = EXCLUDED.sample_size,
+                                                             p25_price_eur         = EXCLUDED.p25_price_eur,
+                                                             median_price_eur      = EXCLUDED.median_price_eur,
+                                                             p75_price_eur         = EXCLUDED.p75_price_eur,
+                                                             removed_count         = EXCLUDED.removed_count,
+                                                             median_days_on_market = EXCLUDED.median_days_on_market,
+                                                             updated_at            = now()
+`
+
+type RefreshMarketStatsParams struct {
+	MileageBandKm int32            `json:"mileage_band_km"`
+	SeenAfter     pgtype.Timestamp `json:"seen_after"`
+}
+
+// recomputes the price percentiles and the median time on market of the removed cars of the cars seen after
+// the given time grouped by brand, model, year and mileage band, the groups without such cars are removed
+func (q *Queries) RefreshMarketStats(ctx context.Context, arg RefreshMarketStatsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, RefreshMarketStats,
+		arg.MileageBandKm,
+		arg.SeenAfter,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const RemoveFromWatchlist = `-- name: RemoveFromWatchlist :execrows
+DELETE
+FROM watchlist
+WHERE user_id = $1
+  AND listing_id = $2
+`
+
+type RemoveFromWatchlistParams struct {
+	UserID    int64  `json:"user_id"`
+	ListingID string `json:"listing_id"`
+}
+
+func (q *Queries) RemoveFromWatchlist(ctx context.Context, arg RemoveFromWatchlistParams) (int64, error) {
+	result, err := q.db.Exec(ctx, RemoveFromWatchlist,
+		arg.UserID,
+		arg.ListingID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const SetSubscriptionPriceOnly = `-- name: SetSubscriptionPriceOnly :one
+UPDATE subscriptions
+SET price_only = $3,
+    updated_at = now()
+WHERE id = $1
+  AND user_id = $2
+RETURNING id, user_id, brand, model, chassis, price_from, price_to, year_from, year_to, region, created_at, updated_at, min_deal_percent, include_keywords, exclude_keywords, fuel, gearbox, mileage_to, power_from, power_to, doors, seats, color, air_condition, damage, extra_params, muted_until, price_only, delivery_mode, digest_hour, digest_sent_at
+`
+
+type SetSubscriptionPriceOnlyParams struct {
+	ID        pgtype.UUID `json:"id"`
+	UserID    int64       `json:"user_id"`
+	PriceOnly bool        `json:"price_only"`
+}
+
+func (q *Queries) SetSubscriptionPriceOnly(ctx context.Context, arg SetSubscriptionPriceOnlyParams) (Subscription, error) {
+	row := q.db.QueryRow(ctx, SetSubscriptionPriceOnly,
+		arg.ID,
+		arg.UserID,
+		arg.PriceOnly,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Brand,
+		&i.Model,
+		&i.Chassis,
+		&i.PriceFrom,
+		&i.PriceTo,
+		&i.YearFrom,
+		&i.YearTo,
+		&i.Region,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MinDealPercent,
+		&i.IncludeKeywords,
+		&i.ExcludeKeywords,
+		&i.Fuel,
+		&i.Gearbox,
+		&i.MileageTo,
+		&i.PowerFrom,
+		&i.PowerTo,
+		&i.Doors,
+		&i.Seats,
+		&i.Color,
+		&i.AirCondition,
+		&i.Damage,
+		&i.ExtraParams,
+		&i.MutedUntil,
+		&i.PriceOnly,
+		&i.DeliveryMode,
+		&i.DigestHour,
+		&i.DigestSentAt,
+	)
+	return i, err
+}
+
+const UpdateCarPrice = `-- name: UpdateCarPrice :exec
+UPDATE cars
+SET price         = $2,
+    price_eur     = $3,
+    last_seen_at  = $4,
+    missing_count = 0,
+    updated_at    = now()
+WHERE listing_id = $1
+`
+
+type UpdateCarPriceParams struct {
+	ListingID  string           `json:"listing_id"`
+	Price      string           `json:"price"`
+	PriceEur   pgtype.Numeric   `json:"price_eur"`
+	LastSeenAt pgtype.Timestamp `json:"last_seen_at"`
+}
+
+// sets the price of the car found on its detail page, the car is on the market, so it's seen too
+func (q *Queries) UpdateCarPrice(ctx context.Context, arg UpdateCarPriceParams) error {
+	_, err := q.db.Exec(ctx, UpdateCarPrice,
+		arg.ListingID,
+		arg.Price,
+		arg.PriceEur,
+		arg.LastSeenAt,
+	)
+	return err
+}
+
+const UpdateOutboxNotification = `-- name: UpdateOutboxNotification :exec
+UPDATE notification_outbox
+SET attempts        = $2,
+    next_attempt_at = $3,
+    last_error      = $4,
+    photos_sent     = $5,
+    updated_at      = now()
+WHERE id = $1
+`
+
+type UpdateOutboxNotificationParams struct {
+	ID            pgtype.UUID      `json:"id"`
+	Attempts      int32            `json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	LastError     string           `json:"last_error"`
+	PhotosSent    bool             `json:"photos_sent"`
+}
+
+func (q *Queries) UpdateOutboxNotification(ctx context.Context, arg UpdateOutboxNotificationParams) error {
+	_, err := q.db.Exec(ctx, UpdateOutboxNotification,
+		arg.ID,
+		arg.Attempts,
+		arg.NextAttemptAt,
+		arg.LastError,
+		arg.PhotosSent,
+	)
+	return err
+}
+
+const UpdateSubscription = `-- name: UpdateSubscription :one
+UPDATE subscriptions
+SET model            = $3,
+    chassis          = $4,
+    price_from       = $5,
+    price_to         = $6,
+    year_from        = $7,
+    year_to          = $8,
+    region           = $9,
+    min_deal_percent = $10,
+    include_keywords = $11,
+    exclude_keywords = $12,
+    fuel             = $13,
+    gearbox          = $14,
+    mileage_to       = $15,
+    power_from       = $16,
+    power_to         = $17,
+    doors            = $18,
+    seats            = $19,
+    color            = $20,
+    air_condition    = $21,
+    damage           = $22,
+    extra_params     = $23,
+    -- the next digest is scheduled anew if its schedule is changed
+    digest_sent_at   = CASE
+                           WHEN delivery_mode <> $24 OR digest_hour <> $25 THEN NULL
+                           ELSE digest_sent_at
+        END,
+    delivery_mode    = $24,
+    digest_hour      = $25,
+    updated_at       = now()
+WHERE id = $1
+  AND user_id = $2
+RETURNING id, user_id, brand, model, chassis, price_from, price_to, year_from, year_to, region, created_at, updated_at, min_deal_percent, include_keywords, exclude_keywords, fuel, gearbox, mileage_to, power_from, power_to, doors, seats, color, air_condition, damage, extra_params, muted_until, price_only, delivery_mode, digest_hour, digest_sent_at
+`
+
+type UpdateSubscriptionParams struct {
+	ID              pgtype.UUID `json:"id"`
+	UserID          int64       `json:"user_id"`
+	Model           []string    `json:"model"`
+	Chassis         []string    `json:"chassis"`
+	PriceFrom       string      `json:"price_from"`
+	PriceTo         string      `json:"price_to"`
+	YearFrom        string      `json:"year_from"`
+	YearTo          string      `json:"year_to"`
+	Region          []string    `json:"region"`
+	MinDealPercent  pgtype.Int4 `json:"min_deal_percent"`
+	IncludeKeywords []string    `json:"include_keywords"`
+	ExcludeKeywords []string    `json:"exclude_keywords"`
+	Fuel            []string    `json:"fuel"`
+	Gearbox         []string    `json:"gearbox"`
+	MileageTo       string      `json:"mileage_to"`
+	PowerFrom       string      `json:"power_from"`
+	PowerTo         string      `json:"power_to"`
+	Doors           []string    `json:"doors"`
+	Seats           []string    `json:"seats"`
+	Color           []string    `json:"color"`
+	AirCondition    []string    `json:"air_condition"`
+	Damage          []string    `json:"damage"`
+	ExtraParams     []byte      `json:"extra_params"`
+	DeliveryMode    string      `json:"delivery_mode"`
+	DigestHour      int16       `json:"digest_hour"`
+}
+
+func (q *Queries) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (Subscription, error) {
+	row := q.db.QueryRow(ctx, UpdateSubscription,
+		arg.ID,
+		arg.UserID,
+		arg.Model,
+		arg.Chassis,
+		arg.PriceFrom,
+		arg.PriceTo,
+		arg.YearFrom,
+		arg.YearTo,
+		arg.Region,
+		arg.MinDealPercent,
+		arg.IncludeKeywords,
+		arg.ExcludeKeywords,
+		arg.Fuel,
+		arg.Gearbox,
+		arg.MileageTo,
+		arg.PowerFrom,
+		arg.PowerTo,
+		arg.Doors,
+		arg.Seats,
+		arg.Color,
+		arg.AirCondition,
+		arg.Damage,
+		arg.ExtraParams,
+		arg.DeliveryMode,
+		arg.DigestHour,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Brand,
+		&i.Model,
+		&i.Chassis,
+		&i.PriceFrom,
+		&i.PriceTo,
+		&i.YearFrom,
+		&i.YearTo,
+		&i.Region,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MinDealPercent,
+		&i.IncludeKeywords,
+		&i.ExcludeKeywords,
+		&i.Fuel,
+		&i.Gearbox,
+		&i.MileageTo,
+		&i.PowerFrom,
+		&i.PowerTo,
+		&i.Doors,
+		&i.Seats,
+		&i.Color,
+		&i.AirCondition,
+		&i.Damage,
+		&i.ExtraParams,
+		&i.MutedUntil,
+		&i.PriceOnly,
+		&i.DeliveryMode,
+		&i.DigestHour,
+		&i.DigestSentAt,
+	)
+	return i, err
+}
+
+const UpdateSubscriptionDigestSentAt = `-- name: UpdateSubscriptionDigestSentAt :exec
+UPDATE subscriptions
+SET digest_sent_at = $2
+WHERE id = $1
+`
+
+type UpdateSubscriptionDigestSentAtParams struct {
+	ID           pgtype.UUID      `json:"id"`
+	DigestSentAt pgtype.Timestamp `json:"digest_sent_at"`
+}
+
+func (q *Queries) UpdateSubscriptionDigestSentAt(ctx context.Context, arg UpdateSubscriptionDigestSentAtParams) error {
+	_, err := q.db.Exec(ctx, UpdateSubscriptionDigestSentAt,
+		arg.ID,
+		arg.DigestSentAt,
+	)
+	return err
+}
+
+const UpdateUserSettings = `-- name: UpdateUserSettings :one
+UPDATE users
+SET time_zone        = $2,
+    quiet_hours_from = $3,
+    quiet_hours_to   = $4,
+    mute_weekends    = $5,
+    updated_at       = now()
+WHERE id = $1
+RETURNING id, time_zone, quiet_hours_from, quiet_hours_to, mute_weekends
+`
+
+type UpdateUserSettingsRow struct {
+	ID             int64       `json:"id"`
+	TimeZone       string      `json:"time_zone"`
+	QuietHoursFrom pgtype.Int2 `json:"quiet_hours_from"`
+	QuietHoursTo   pgtype.Int2 `json:"quiet_hours_to"`
+	MuteWeekends   bool        `json:"mute_weekends"`
+}
+
+type UpdateUserSettingsParams struct {
+	ID             int64       `json:"id"`
+	TimeZone       string      `json:"time_zone"`
+	QuietHoursFrom pgtype.Int2 `json:"quiet_hours_from"`
+	QuietHoursTo   pgtype.Int2 `json:"quiet_hours_to"`
+	MuteWeekends   bool        `json:"mute_weekends"`
+}
+
+func (q *Queries) UpdateUserSettings(ctx context.Context, arg UpdateUserSettingsParams) (UpdateUserSettingsRow, error) {
+	row := q.db.QueryRow(ctx, UpdateUserSettings,
+		arg.ID,
+		arg.TimeZone,
+		arg.QuietHoursFrom,
+		arg.QuietHoursTo,
+		arg.MuteWeekends,
+	)
+	var i UpdateUserSettingsRow
+	err := row.Scan(
+		&i.ID,
+		&i.TimeZone,
+		&i.QuietHoursFrom,
+		&i.QuietHoursTo,
+		&i.MuteWeekends,
+	)
+	return i, err
+}
+
+const UpdateWatchlistNotified = `-- name: UpdateWatchlistNotified :exec
+UPDATE watchlist
+SET notified_price      = $3,
+    notified_price_eur  = $4,
+    notified_removed_at = $5
+WHERE user_id = $1
+  AND listing_id = $2
+`
+
+type UpdateWatchlistNotifiedParams struct {
+	UserID            int64            `json:"user_id"`
+	ListingID         string           `json:"listing_id"`
+	NotifiedPrice     string           `json:"notified_price"`
+	NotifiedPriceEur  pgtype.Numeric   `json:"notified_price_eur"`
+	NotifiedRemovedAt pgtype.Timestamp `json:"notified_removed_at"`
+}
+
+func (q *Queries) UpdateWatchlistNotified(ctx context.Context, arg UpdateWatchlistNotifiedParams) error {
+	_, err := q.db.Exec(ctx, UpdateWatchlistNotified,
+		arg.UserID,
+		arg.ListingID,
+		arg.NotifiedPrice,
+		arg.NotifiedPriceEur,
+		arg.NotifiedRemovedAt,
+	)
+	return err
+}
+
+const UpsertCar = `-- name: UpsertCar :exec
+INSERT INTO cars (listing_id, title, price, engine_volume, transmission, body_type, mileage, location, link, date,
+                  fuel_type, power_kw, power_hp, color, drive, doors, registered_until, damage_status, seller_type,
+                  price_eur, mileage_km, engine_volume_cm3, year, brand, model, image_hash, image_url, gallery_urls,
+                  created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+        $25, $26, $27, $28, now(), now())
+ON CONFLICT (listing_id) DO UPDATE SET title             = EXCLUDED.title,
+                                       price             = EXCLUDED.price,
+                                       engine_volume     = EXCLUDED.engine_volume,
+                                       transmission      = EXCLUDED.transmission,
+                                       body_type         = EXCLUDED.body_type,
+                                       mileage           = EXCLUDED.mileage,
+                                       location          = EXCLUDED.location,
+                                       link              = EXCLUDED.link,
+                                       date              = EXCLUDED.date,
+                                       -- keep already fetched details if the new ones are empty
+                                       fuel_type         = COALESCE(NULLIF(EXCLUDED.fuel_type, ''), cars.fuel_type),
+                                       power_kw          = COALESCE(NULLIF(EXCLUDED.power_kw, 0), cars.power_kw),
+                                       power_hp          = COALESCE(NULLIF(EXCLUDED.power_hp, 0), cars.power_hp),
+                                       color             = COALESCE(NULLIF(EXCLUDED.color, ''), cars.color),
+                                       drive             = COALESCE(NULLIF(EXCLUDED.drive, ''), cars.drive),
+                                       doors             = COALESCE(NULLIF(EXCLUDED.doors, ''), cars.doors),
+                                       registered_until  = COALESCE(NULLIF(EXCLUDED.registered_until, ''), cars.registered_until),
+                                       damage_status     = COALESCE(NULLIF(EXCLUDED.damage_status, ''), cars.damage_status),
+                                       seller_type       = COALESCE(NULLIF(EXCLUDED.seller_type, ''), cars.seller_type),
+                                       price_eur         = EXCLUDED.price_eur,
+                                       mileage_km        = EXCLUDED.mileage_km,
+                                       engine_volume_cm3 = EXCLUDED.engine_volume_cm3,
+                                       year              = COALESCE(EXCLUDED.year, cars.year),
+                                       brand             = COALESCE(NULLIF(EXCLUDED.brand, ''), cars.brand),
+                                       model             = COALESCE(NULLIF(EXCLUDED.model, ''), cars.model),
+                                       image_hash        = COALESCE(EXCLUDED.image_hash, cars.image_hash),
+                                       image_url         = COALESCE(NULLIF(EXCLUDED.image_url, ''), cars.image_url),
+                                       gallery_urls      = CASE
+                                                               WHEN cardinality(EXCLUDED.gallery_urls) > 0
+                                                                   THEN EXCLUDED.gallery_urls
+                                                               ELSE cars.gallery_urls END,
+                                       -- the car is on the market again if it was marked as removed
+                                       last_seen_at      = now(),
+                                       missing_count     = 0,
+                                       removed_at        = NULL,
+                                       updated_at        = now()
+`
+
+type UpsertCarParams struct {
+	ListingID       string           `json:"listing_id"`
+	Title           string           `json:"title"`
+	Price           string           `json:"price"`
+	EngineVolume    string           `json:"engine_volume"`
+	Transmission    string           `json:"transmission"`
+	BodyType        string           `json:"body_type"`
+	Mileage         string           `json:"mileage"`
+	Location        string           `json:"location"`
+	Link            string           `json:"link"`
+	Date            pgtype.Timestamp `json:"date"`
+	FuelType        string           `json:"fuel_type"`
+	PowerKw         int32            `json:"power_kw"`
+	PowerHp         int32            `json:"power_hp"`
+	Color           string           `json:"color"`
+	Drive           string           `json:"drive"`
+	Doors           string           `json:"doors"`
+	RegisteredUntil string           `json:"registered_until"`
+	DamageStatus    string           `json:"damage_status"`
+	SellerType      string           `json:"seller_type"`
+	PriceEur        pgtype.Numeric   `json:"price_eur"`
+	MileageKm       pgtype.Int4      `json:"mileage_km"`
+	EngineVolumeCm3 pgtype.Int4      `json:"engine_volume_cm3"`
+	Year            pgtype.Int4      `json:"year"`
+	Brand           string           `json:"brand"`
+	Model           string           `json:"model"`
+	ImageHash       pgtype.Int8      `json:"image_hash"`
+	ImageUrl        string           `json:"image_url"`
+	GalleryUrls     []string         `json:"gallery_urls"`
+}
+
+func (q *Queries) UpsertCar(ctx context.Context, arg UpsertCarParams) error {
+	_, err := q.db.Exec(ctx, UpsertCar,
+		arg.ListingID,
+		arg.Title,
+		arg.Price,
+		arg.EngineVolume,
+		arg.Transmission,
+		arg.BodyType,
+		arg.Mileage,
+		arg.Location,
+		arg.Link,
+		arg.Date,
+		arg.FuelType,
+		arg.PowerKw,
+		arg.PowerHp,
+		arg.Color,
+		arg.Drive,
+		arg.Doors,
+		arg.RegisteredUntil,
+		arg.DamageStatus,
+		arg.SellerType,
+		arg.PriceEur,
+		arg.MileageKm,
+		arg.EngineVolumeCm3,
+		arg.Year,
+		arg.Brand,
+		arg.Model,
+		arg.ImageHash,
+		arg.ImageUrl,
+		arg.GalleryUrls,
+	)
+	return err
+}
+
+const UpsertConversationState = `-- name: UpsertConversationState :exec
+INSERT INTO conversation_state (user_id,
+                                state,
+                                expires_at,
+                                created_at,
+                                updated_at)
+VALUES ($1, $2, $3, now(), now())
+ON CONFLICT (user_id) DO UPDATE SET state      = EXCLUDED.state,
+                                    expires_at = EXCLUDED.expires_at,
+                                    updated_at = now()
+`
+
+type UpsertConversationStateParams struct {
+	UserID    int64            `json:"user_id"`
+	State     []byte           `json:"state"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+}
+
+func (q *Queries) UpsertConversationState(ctx context.Context, arg UpsertConversationStateParams) error {
+	_, err := q.db.Exec(ctx, UpsertConversationState,
+		arg.UserID,
+		arg.State,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const UpsertSubscriptionMatch = `-- name: UpsertSubscriptionMatch :exec
+INSERT INTO subscription_matches (listing_id, subscription_id, price, new_price, price_eur, new_price_eur, is_need_send,
+                                  relisted_from, relisted_price, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now())
+ON CONFLICT (listing_id, subscription_id) DO UPDATE SET price          = EXCLUDED.price,
+                                                        new_price      = EXCLUDED.new_price,
+                                                        price_eur      = EXCLUDED.price_eur,
+                                                        new_price_eur  = EXCLUDED.new_price_eur,
+                                                        is_need_send   = EXCLUDED.is_need_send,
+                                                        -- the relist is only detected for a new match, keep it
+                                                        relisted_from  = COALESCE(EXCLUDED.relisted_from,
+                                                                                  subscription_matches.relisted_from),
+                                                        relisted_price = COALESCE(EXCLUDED.relisted_price,
+                                                                                  subscription_matches.relisted_price),
+                                                        updated_at     = now()
+`
+
+type UpsertSubscriptionMatchParams struct {
+	ListingID      string         `json:"listing_id"`
+	SubscriptionID pgtype.UUID    `json:"subscription_id"`
+	Price          string         `json:"price"`
+	NewPrice       pgtype.Text    `json:"new_price"`
+	PriceEur       pgtype.Numeric `json:"price_eur"`
+	NewPriceEur    pgtype.Numeric `json:"new_price_eur"`
+	IsNeedSend     bool           `json:"is_need_send"`
+	RelistedFrom   pgtype.Text    `json:"relisted_from"`
+	RelistedPrice  pgtype.Text    `json:"relisted_price"`
+}
+
+func (q *Queries) UpsertSubscriptionMatch(ctx context.Context, arg UpsertSubscriptionMatchParams) error {
+	_, err := q.db.Exec(ctx, UpsertSubscriptionMatch,
+		arg.ListingID,
+		arg.SubscriptionID,
+		arg.Price,
+		arg.NewPrice,
+		arg.PriceEur,
+		arg.NewPriceEur,
 		arg.IsNeedSend,
+		arg.RelistedFrom,
+		arg.RelistedPrice,
 	)
 	return err
 }
@@ -507,7 +2299,7 @@ ON CONFLICT (id) DO UPDATE SET username   = EXCLUDED.username,
                                first_name = EXCLUDED.first_name,
                                last_name  = EXCLUDED.last_name,
                                updated_at = now()
-RETURNING id, username, first_name, last_name, created_at, updated_at
+RETURNING id, username, first_name, last_name, created_at, updated_at, time_zone, quiet_hours_from, quiet_hours_to, mute_weekends
 `
 
 type UpsertUserParams struct {
@@ -532,6 +2324,10 @@ func (q *Queries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, e
 		&i.LastName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TimeZone,
+		&i.QuietHoursFrom,
+		&i.QuietHoursTo,
+		&i.MuteWeekends,
 	)
 	return i, err
 }
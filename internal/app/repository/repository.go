@@ -2,20 +2,67 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 )
 
+// DB is the data access of the repository, it is implemented by both the repository and its transactions.
+//
+//go:generate mockgen -source=repository.go -destination=repository_mock.go -package=repository
 type DB interface {
+	// WithTx runs fn in a transaction, the transaction is committed if fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(repo DB) error) error
+
+	UpsertUser(ctx context.Context, request ds.UserRequest) (ds.UserResponse, error)
+	DeleteUserByID(ctx context.Context, id int64) error
+	GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error)
+	UpdateUserSettings(ctx context.Context, request ds.UpdateUserSettingsRequest) (ds.UserSettingsResponse, error)
 	GetAllSubscriptions(ctx context.Context) ([]ds.SubscriptionResponse, error)
 	CreateSubscription(ctx context.Context, sub ds.SubscriptionRequest) (ds.SubscriptionResponse, error)
-	UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error
+	UpdateSubscription(ctx context.Context, sub ds.UpdateSubscriptionRequest) (ds.SubscriptionResponse, error)
+	GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error)
+	GetSubscriptionByID(ctx context.Context, id string) (ds.SubscriptionResponse, error)
+	DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error
+	DeleteSubscriptionByID(ctx context.Context, id string) error
+	UpsertCar(ctx context.Context, car ds.UpsertCarRequest) error
+	UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error
 	GetListingsBySubscriptionID(ctx context.Context, subscriptionID string) ([]ds.ListingResponse, error)
 	GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool) ([]ds.ListingResponse, error)
-	CreateNotification(ctx context.Context, notification ds.CreateNotificationRequest) (ds.NotificationResponse, error)
-	GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error)
+	GetListing(ctx context.Context, listingID, subscriptionID string) (ds.ListingResponse, error)
 	DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error
-	DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error
-	DeleteUserByID(ctx context.Context, id int64)
-	UpsertUser(ctx context.Context, request ds.UserRequest) (ds.UserResponse, error)
+	AddListingPrice(ctx context.Context, price ds.ListingPriceRequest) error
+	GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error)
+	GetMedianPricesBySubscriptionID(
+		ctx context.Context, subscriptionID string, observedAfter time.Time,
+	) ([]ds.MedianPriceResponse, error)
+	RefreshMarketStats(ctx context.Context, request ds.RefreshMarketStatsRequest) (int64, error)
+	GetMarketStats(ctx context.Context, request ds.MarketStatsRequest) (ds.MarketStatsResponse, error)
+	MarkCarsSeen(ctx context.Context, request ds.MarkCarsSeenRequest) error
+	MarkCarsMissing(ctx context.Context, request ds.MarkCarsMissingRequest) ([]ds.MissingCarResponse, error)
+	MarkCarRemoved(ctx context.Context, request ds.MarkCarRemovedRequest) (int64, error)
+	DeleteOrphanCars(ctx context.Context, seenBefore time.Time) (int64, error)
+	CreateNotification(ctx context.Context, notification ds.CreateNotificationRequest) (ds.NotificationResponse, error)
+	GetConversationState(ctx context.Context, userID int64, now time.Time) (ds.ConversationStateResponse, error)
+	UpsertConversationState(ctx context.Context, state ds.ConversationStateRequest) error
+	DeleteConversationState(ctx context.Context, userID int64) error
+	DeleteExpiredConversationStates(ctx context.Context, now time.Time) (int64, error)
+	EnqueueNotifications(ctx context.Context, now time.Time) (int64, error)
+	GetDueOutboxNotifications(ctx context.Context, now time.Time) ([]ds.OutboxNotificationResponse, error)
+	UpdateOutboxNotification(ctx context.Context, request ds.UpdateOutboxNotificationRequest) error
+	DeleteOutboxNotification(ctx context.Context, id string) error
+	AddToWatchlist(ctx context.Context, userID int64, listingID string) error
+	RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error
+	GetWatchlistByUserID(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error)
+	GetWatchedCars(ctx context.Context) ([]ds.WatchedCarResponse, error)
+	UpdateCarPrice(ctx context.Context, request ds.UpdateCarPriceRequest) error
+	GetWatchlistChanges(ctx context.Context) ([]ds.WatchlistChangeResponse, error)
+	UpdateWatchlistNotified(ctx context.Context, request ds.UpdateWatchlistNotifiedRequest) error
+	HideListing(ctx context.Context, userID int64, listingID string) error
+	IsListingHidden(ctx context.Context, userID int64, listingID string) (bool, error)
+	MuteSubscription(ctx context.Context, userID int64, id string, until time.Time) (ds.SubscriptionResponse, error)
+	SetSubscriptionPriceOnly(
+		ctx context.Context, userID int64, id string, priceOnly bool,
+	) (ds.SubscriptionResponse, error)
+	UpdateSubscriptionDigestSentAt(ctx context.Context, id string, sentAt time.Time) error
 }
@@ -0,0 +1,743 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository.go
+//
+// Generated by this command:
+//
+//	mockgen -source=repository.go -destination=repository_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	ds "github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDB is a mock of DB interface.
+type MockDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockDBMockRecorder
+}
+
+// MockDBMockRecorder is the mock recorder for MockDB.
+type MockDBMockRecorder struct {
+	mock *MockDB
+}
+
+// NewMockDB creates a new mock instance.
+func NewMockDB(ctrl *gomock.Controller) *MockDB {
+	mock := &MockDB{ctrl: ctrl}
+	mock.recorder = &MockDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDB) EXPECT() *MockDBMockRecorder {
+	return m.recorder
+}
+
+// AddListingPrice mocks base method.
+func (m *MockDB) AddListingPrice(ctx context.Context, price ds.ListingPriceRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddListingPrice", ctx, price)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddListingPrice indicates an expected call of AddListingPrice.
+func (mr *MockDBMockRecorder) AddListingPrice(ctx, price any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddListingPrice", reflect.TypeOf((*MockDB)(nil).AddListingPrice), ctx, price)
+}
+
+// AddToWatchlist mocks base method.
+func (m *MockDB) AddToWatchlist(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToWatchlist", ctx, userID, listingID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddToWatchlist indicates an expected call of AddToWatchlist.
+func (mr *MockDBMockRecorder) AddToWatchlist(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToWatchlist", reflect.TypeOf((*MockDB)(nil).AddToWatchlist), ctx, userID, listingID)
+}
+
+// CreateNotification mocks base method.
+func (m *MockDB) CreateNotification(ctx context.Context, notification ds.CreateNotificationRequest) (ds.NotificationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNotification", ctx, notification)
+	ret0, _ := ret[0].(ds.NotificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNotification indicates an expected call of CreateNotification.
+func (mr *MockDBMockRecorder) CreateNotification(ctx, notification any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNotification", reflect.TypeOf((*MockDB)(nil).CreateNotification), ctx, notification)
+}
+
+// CreateSubscription mocks base method.
+func (m *MockDB) CreateSubscription(ctx context.Context, sub ds.SubscriptionRequest) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscription", ctx, sub)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSubscription indicates an expected call of CreateSubscription.
+func (mr *MockDBMockRecorder) CreateSubscription(ctx, sub any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockDB)(nil).CreateSubscription), ctx, sub)
+}
+
+// DeleteConversationState mocks base method.
+func (m *MockDB) DeleteConversationState(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConversationState", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConversationState indicates an expected call of DeleteConversationState.
+func (mr *MockDBMockRecorder) DeleteConversationState(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConversationState", reflect.TypeOf((*MockDB)(nil).DeleteConversationState), ctx, userID)
+}
+
+// DeleteExpiredConversationStates mocks base method.
+func (m *MockDB) DeleteExpiredConversationStates(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredConversationStates", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredConversationStates indicates an expected call of DeleteExpiredConversationStates.
+func (mr *MockDBMockRecorder) DeleteExpiredConversationStates(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredConversationStates", reflect.TypeOf((*MockDB)(nil).DeleteExpiredConversationStates), ctx, now)
+}
+
+// DeleteListingsBySubscriptionIDs mocks base method.
+func (m *MockDB) DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteListingsBySubscriptionIDs", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteListingsBySubscriptionIDs indicates an expected call of DeleteListingsBySubscriptionIDs.
+func (mr *MockDBMockRecorder) DeleteListingsBySubscriptionIDs(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteListingsBySubscriptionIDs", reflect.TypeOf((*MockDB)(nil).DeleteListingsBySubscriptionIDs), ctx, ids)
+}
+
+// DeleteOrphanCars mocks base method.
+func (m *MockDB) DeleteOrphanCars(ctx context.Context, seenBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphanCars", ctx, seenBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphanCars indicates an expected call of DeleteOrphanCars.
+func (mr *MockDBMockRecorder) DeleteOrphanCars(ctx, seenBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphanCars", reflect.TypeOf((*MockDB)(nil).DeleteOrphanCars), ctx, seenBefore)
+}
+
+// DeleteOutboxNotification mocks base method.
+func (m *MockDB) DeleteOutboxNotification(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOutboxNotification", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOutboxNotification indicates an expected call of DeleteOutboxNotification.
+func (mr *MockDBMockRecorder) DeleteOutboxNotification(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutboxNotification", reflect.TypeOf((*MockDB)(nil).DeleteOutboxNotification), ctx, id)
+}
+
+// DeleteSubscriptionByID mocks base method.
+func (m *MockDB) DeleteSubscriptionByID(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscriptionByID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscriptionByID indicates an expected call of DeleteSubscriptionByID.
+func (mr *MockDBMockRecorder) DeleteSubscriptionByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionByID", reflect.TypeOf((*MockDB)(nil).DeleteSubscriptionByID), ctx, id)
+}
+
+// DeleteSubscriptionsByUserID mocks base method.
+func (m *MockDB) DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscriptionsByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscriptionsByUserID indicates an expected call of DeleteSubscriptionsByUserID.
+func (mr *MockDBMockRecorder) DeleteSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionsByUserID", reflect.TypeOf((*MockDB)(nil).DeleteSubscriptionsByUserID), ctx, userID)
+}
+
+// DeleteUserByID mocks base method.
+func (m *MockDB) DeleteUserByID(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserByID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserByID indicates an expected call of DeleteUserByID.
+func (mr *MockDBMockRecorder) DeleteUserByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserByID", reflect.TypeOf((*MockDB)(nil).DeleteUserByID), ctx, id)
+}
+
+// EnqueueNotifications mocks base method.
+func (m *MockDB) EnqueueNotifications(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueNotifications", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueNotifications indicates an expected call of EnqueueNotifications.
+func (mr *MockDBMockRecorder) EnqueueNotifications(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueNotifications", reflect.TypeOf((*MockDB)(nil).EnqueueNotifications), ctx, now)
+}
+
+// GetAllSubscriptions mocks base method.
+func (m *MockDB) GetAllSubscriptions(ctx context.Context) ([]ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllSubscriptions", ctx)
+	ret0, _ := ret[0].([]ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllSubscriptions indicates an expected call of GetAllSubscriptions.
+func (mr *MockDBMockRecorder) GetAllSubscriptions(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllSubscriptions", reflect.TypeOf((*MockDB)(nil).GetAllSubscriptions), ctx)
+}
+
+// GetConversationState mocks base method.
+func (m *MockDB) GetConversationState(ctx context.Context, userID int64, now time.Time) (ds.ConversationStateResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConversationState", ctx, userID, now)
+	ret0, _ := ret[0].(ds.ConversationStateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConversationState indicates an expected call of GetConversationState.
+func (mr *MockDBMockRecorder) GetConversationState(ctx, userID, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConversationState", reflect.TypeOf((*MockDB)(nil).GetConversationState), ctx, userID, now)
+}
+
+// GetDueOutboxNotifications mocks base method.
+func (m *MockDB) GetDueOutboxNotifications(ctx context.Context, now time.Time) ([]ds.OutboxNotificationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueOutboxNotifications", ctx, now)
+	ret0, _ := ret[0].([]ds.OutboxNotificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueOutboxNotifications indicates an expected call of GetDueOutboxNotifications.
+func (mr *MockDBMockRecorder) GetDueOutboxNotifications(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueOutboxNotifications", reflect.TypeOf((*MockDB)(nil).GetDueOutboxNotifications), ctx, now)
+}
+
+// GetListing mocks base method.
+func (m *MockDB) GetListing(ctx context.Context, listingID, subscriptionID string) (ds.ListingResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListing", ctx, listingID, subscriptionID)
+	ret0, _ := ret[0].(ds.ListingResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListing indicates an expected call of GetListing.
+func (mr *MockDBMockRecorder) GetListing(ctx, listingID, subscriptionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListing", reflect.TypeOf((*MockDB)(nil).GetListing), ctx, listingID, subscriptionID)
+}
+
+// GetListingPriceHistory mocks base method.
+func (m *MockDB) GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListingPriceHistory", ctx, listingID)
+	ret0, _ := ret[0].([]ds.ListingPriceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListingPriceHistory indicates an expected call of GetListingPriceHistory.
+func (mr *MockDBMockRecorder) GetListingPriceHistory(ctx, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingPriceHistory", reflect.TypeOf((*MockDB)(nil).GetListingPriceHistory), ctx, listingID)
+}
+
+// GetListingsByIsNeedSend mocks base method.
+func (m *MockDB) GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool) ([]ds.ListingResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListingsByIsNeedSend", ctx, isNeedSend)
+	ret0, _ := ret[0].([]ds.ListingResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListingsByIsNeedSend indicates an expected call of GetListingsByIsNeedSend.
+func (mr *MockDBMockRecorder) GetListingsByIsNeedSend(ctx, isNeedSend any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingsByIsNeedSend", reflect.TypeOf((*MockDB)(nil).GetListingsByIsNeedSend), ctx, isNeedSend)
+}
+
+// GetListingsBySubscriptionID mocks base method.
+func (m *MockDB) GetListingsBySubscriptionID(ctx context.Context, subscriptionID string) ([]ds.ListingResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListingsBySubscriptionID", ctx, subscriptionID)
+	ret0, _ := ret[0].([]ds.ListingResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListingsBySubscriptionID indicates an expected call of GetListingsBySubscriptionID.
+func (mr *MockDBMockRecorder) GetListingsBySubscriptionID(ctx, subscriptionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingsBySubscriptionID", reflect.TypeOf((*MockDB)(nil).GetListingsBySubscriptionID), ctx, subscriptionID)
+}
+
+// GetMarketStats mocks base method.
+func (m *MockDB) GetMarketStats(ctx context.Context, request ds.MarketStatsRequest) (ds.MarketStatsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMarketStats", ctx, request)
+	ret0, _ := ret[0].(ds.MarketStatsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMarketStats indicates an expected call of GetMarketStats.
+func (mr *MockDBMockRecorder) GetMarketStats(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMarketStats", reflect.TypeOf((*MockDB)(nil).GetMarketStats), ctx, request)
+}
+
+// GetMedianPricesBySubscriptionID mocks base method.
+func (m *MockDB) GetMedianPricesBySubscriptionID(ctx context.Context, subscriptionID string, observedAfter time.Time) ([]ds.MedianPriceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMedianPricesBySubscriptionID", ctx, subscriptionID, observedAfter)
+	ret0, _ := ret[0].([]ds.MedianPriceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMedianPricesBySubscriptionID indicates an expected call of GetMedianPricesBySubscriptionID.
+func (mr *MockDBMockRecorder) GetMedianPricesBySubscriptionID(ctx, subscriptionID, observedAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMedianPricesBySubscriptionID", reflect.TypeOf((*MockDB)(nil).GetMedianPricesBySubscriptionID), ctx, subscriptionID, observedAfter)
+}
+
+// GetSubscriptionByID mocks base method.
+func (m *MockDB) GetSubscriptionByID(ctx context.Context, id string) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionByID", ctx, id)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionByID indicates an expected call of GetSubscriptionByID.
+func (mr *MockDBMockRecorder) GetSubscriptionByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionByID", reflect.TypeOf((*MockDB)(nil).GetSubscriptionByID), ctx, id)
+}
+
+// GetSubscriptionsByUserID mocks base method.
+func (m *MockDB) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionsByUserID", ctx, userID)
+	ret0, _ := ret[0].([]ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionsByUserID indicates an expected call of GetSubscriptionsByUserID.
+func (mr *MockDBMockRecorder) GetSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionsByUserID", reflect.TypeOf((*MockDB)(nil).GetSubscriptionsByUserID), ctx, userID)
+}
+
+// GetUserSettings mocks base method.
+func (m *MockDB) GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", ctx, userID)
+	ret0, _ := ret[0].(ds.UserSettingsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockDBMockRecorder) GetUserSettings(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockDB)(nil).GetUserSettings), ctx, userID)
+}
+
+// GetWatchedCars mocks base method.
+func (m *MockDB) GetWatchedCars(ctx context.Context) ([]ds.WatchedCarResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchedCars", ctx)
+	ret0, _ := ret[0].([]ds.WatchedCarResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWatchedCars indicates an expected call of GetWatchedCars.
+func (mr *MockDBMockRecorder) GetWatchedCars(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchedCars", reflect.TypeOf((*MockDB)(nil).GetWatchedCars), ctx)
+}
+
+// GetWatchlistByUserID mocks base method.
+func (m *MockDB) GetWatchlistByUserID(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchlistByUserID", ctx, userID)
+	ret0, _ := ret[0].([]ds.WatchlistItemResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWatchlistByUserID indicates an expected call of GetWatchlistByUserID.
+func (mr *MockDBMockRecorder) GetWatchlistByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchlistByUserID", reflect.TypeOf((*MockDB)(nil).GetWatchlistByUserID), ctx, userID)
+}
+
+// GetWatchlistChanges mocks base method.
+func (m *MockDB) GetWatchlistChanges(ctx context.Context) ([]ds.WatchlistChangeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchlistChanges", ctx)
+	ret0, _ := ret[0].([]ds.WatchlistChangeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWatchlistChanges indicates an expected call of GetWatchlistChanges.
+func (mr *MockDBMockRecorder) GetWatchlistChanges(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchlistChanges", reflect.TypeOf((*MockDB)(nil).GetWatchlistChanges), ctx)
+}
+
+// HideListing mocks base method.
+func (m *MockDB) HideListing(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideListing", ctx, userID, listingID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HideListing indicates an expected call of HideListing.
+func (mr *MockDBMockRecorder) HideListing(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideListing", reflect.TypeOf((*MockDB)(nil).HideListing), ctx, userID, listingID)
+}
+
+// IsListingHidden mocks base method.
+func (m *MockDB) IsListingHidden(ctx context.Context, userID int64, listingID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsListingHidden", ctx, userID, listingID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsListingHidden indicates an expected call of IsListingHidden.
+func (mr *MockDBMockRecorder) IsListingHidden(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsListingHidden", reflect.TypeOf((*MockDB)(nil).IsListingHidden), ctx, userID, listingID)
+}
+
+// MarkCarRemoved mocks base method.
+func (m *MockDB) MarkCarRemoved(ctx context.Context, request ds.MarkCarRemovedRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarRemoved", ctx, request)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkCarRemoved indicates an expected call of MarkCarRemoved.
+func (mr *MockDBMockRecorder) MarkCarRemoved(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarRemoved", reflect.TypeOf((*MockDB)(nil).MarkCarRemoved), ctx, request)
+}
+
+// MarkCarsMissing mocks base method.
+func (m *MockDB) MarkCarsMissing(ctx context.Context, request ds.MarkCarsMissingRequest) ([]ds.MissingCarResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarsMissing", ctx, request)
+	ret0, _ := ret[0].([]ds.MissingCarResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkCarsMissing indicates an expected call of MarkCarsMissing.
+func (mr *MockDBMockRecorder) MarkCarsMissing(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarsMissing", reflect.TypeOf((*MockDB)(nil).MarkCarsMissing), ctx, request)
+}
+
+// MarkCarsSeen mocks base method.
+func (m *MockDB) MarkCarsSeen(ctx context.Context, request ds.MarkCarsSeenRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarsSeen", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkCarsSeen indicates an expected call of MarkCarsSeen.
+func (mr *MockDBMockRecorder) MarkCarsSeen(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarsSeen", reflect.TypeOf((*MockDB)(nil).MarkCarsSeen), ctx, request)
+}
+
+// MuteSubscription mocks base method.
+func (m *MockDB) MuteSubscription(ctx context.Context, userID int64, id string, until time.Time) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteSubscription", ctx, userID, id, until)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MuteSubscription indicates an expected call of MuteSubscription.
+func (mr *MockDBMockRecorder) MuteSubscription(ctx, userID, id, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteSubscription", reflect.TypeOf((*MockDB)(nil).MuteSubscription), ctx, userID, id, until)
+}
+
+// RefreshMarketStats mocks base method.
+func (m *MockDB) RefreshMarketStats(ctx context.Context, request ds.RefreshMarketStatsRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshMarketStats", ctx, request)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshMarketStats indicates an expected call of RefreshMarketStats.
+func (mr *MockDBMockRecorder) RefreshMarketStats(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshMarketStats", reflect.TypeOf((*MockDB)(nil).RefreshMarketStats), ctx, request)
+}
+
+// RemoveFromWatchlist mocks base method.
+func (m *MockDB) RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFromWatchlist", ctx, userID, listingID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFromWatchlist indicates an expected call of RemoveFromWatchlist.
+func (mr *MockDBMockRecorder) RemoveFromWatchlist(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromWatchlist", reflect.TypeOf((*MockDB)(nil).RemoveFromWatchlist), ctx, userID, listingID)
+}
+
+// SetSubscriptionPriceOnly mocks base method.
+func (m *MockDB) SetSubscriptionPriceOnly(ctx context.Context, userID int64, id string, priceOnly bool) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSubscriptionPriceOnly", ctx, userID, id, priceOnly)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSubscriptionPriceOnly indicates an expected call of SetSubscriptionPriceOnly.
+func (mr *MockDBMockRecorder) SetSubscriptionPriceOnly(ctx, userID, id, priceOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubscriptionPriceOnly", reflect.TypeOf((*MockDB)(nil).SetSubscriptionPriceOnly), ctx, userID, id, priceOnly)
+}
+
+// UpdateCarPrice mocks base method.
+func (m *MockDB) UpdateCarPrice(ctx context.Context, request ds.UpdateCarPriceRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCarPrice", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCarPrice indicates an expected call of UpdateCarPrice.
+func (mr *MockDBMockRecorder) UpdateCarPrice(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCarPrice", reflect.TypeOf((*MockDB)(nil).UpdateCarPrice), ctx, request)
+}
+
+// UpdateOutboxNotification mocks base method.
+func (m *MockDB) UpdateOutboxNotification(ctx context.Context, request ds.UpdateOutboxNotificationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutboxNotification", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutboxNotification indicates an expected call of UpdateOutboxNotification.
+func (mr *MockDBMockRecorder) UpdateOutboxNotification(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutboxNotification", reflect.TypeOf((*MockDB)(nil).UpdateOutboxNotification), ctx, request)
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockDB) UpdateSubscription(ctx context.Context, sub ds.UpdateSubscriptionRequest) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", ctx, sub)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockDBMockRecorder) UpdateSubscription(ctx, sub any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockDB)(nil).UpdateSubscription), ctx, sub)
+}
+
+// UpdateSubscriptionDigestSentAt mocks base method.
+func (m *MockDB) UpdateSubscriptionDigestSentAt(ctx context.Context, id string, sentAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscriptionDigestSentAt", ctx, id, sentAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSubscriptionDigestSentAt indicates an expected call of UpdateSubscriptionDigestSentAt.
+func (mr *MockDBMockRecorder) UpdateSubscriptionDigestSentAt(ctx, id, sentAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscriptionDigestSentAt", reflect.TypeOf((*MockDB)(nil).UpdateSubscriptionDigestSentAt), ctx, id, sentAt)
+}
+
+// UpdateUserSettings mocks base method.
+func (m *MockDB) UpdateUserSettings(ctx context.Context, request ds.UpdateUserSettingsRequest) (ds.UserSettingsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserSettings", ctx, request)
+	ret0, _ := ret[0].(ds.UserSettingsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserSettings indicates an expected call of UpdateUserSettings.
+func (mr *MockDBMockRecorder) UpdateUserSettings(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserSettings", reflect.TypeOf((*MockDB)(nil).UpdateUserSettings), ctx, request)
+}
+
+// UpdateWatchlistNotified mocks base method.
+func (m *MockDB) UpdateWatchlistNotified(ctx context.Context, request ds.UpdateWatchlistNotifiedRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWatchlistNotified", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWatchlistNotified indicates an expected call of UpdateWatchlistNotified.
+func (mr *MockDBMockRecorder) UpdateWatchlistNotified(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWatchlistNotified", reflect.TypeOf((*MockDB)(nil).UpdateWatchlistNotified), ctx, request)
+}
+
+// UpsertCar mocks base method.
+func (m *MockDB) UpsertCar(ctx context.Context, car ds.UpsertCarRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertCar", ctx, car)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertCar indicates an expected call of UpsertCar.
+func (mr *MockDBMockRecorder) UpsertCar(ctx, car any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertCar", reflect.TypeOf((*MockDB)(nil).UpsertCar), ctx, car)
+}
+
+// UpsertConversationState mocks base method.
+func (m *MockDB) UpsertConversationState(ctx context.Context, state ds.ConversationStateRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertConversationState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertConversationState indicates an expected call of UpsertConversationState.
+func (mr *MockDBMockRecorder) UpsertConversationState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertConversationState", reflect.TypeOf((*MockDB)(nil).UpsertConversationState), ctx, state)
+}
+
+// UpsertSubscriptionMatch mocks base method.
+func (m *MockDB) UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertSubscriptionMatch", ctx, match)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertSubscriptionMatch indicates an expected call of UpsertSubscriptionMatch.
+func (mr *MockDBMockRecorder) UpsertSubscriptionMatch(ctx, match any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertSubscriptionMatch", reflect.TypeOf((*MockDB)(nil).UpsertSubscriptionMatch), ctx, match)
+}
+
+// UpsertUser mocks base method.
+func (m *MockDB) UpsertUser(ctx context.Context, request ds.UserRequest) (ds.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertUser", ctx, request)
+	ret0, _ := ret[0].(ds.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertUser indicates an expected call of UpsertUser.
+func (mr *MockDBMockRecorder) UpsertUser(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertUser", reflect.TypeOf((*MockDB)(nil).UpsertUser), ctx, request)
+}
+
+// WithTx mocks base method.
+func (m *MockDB) WithTx(ctx context.Context, fn func(DB) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockDBMockRecorder) WithTx(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockDB)(nil).WithTx), ctx, fn)
+}
@@ -9,5 +9,6 @@ type (
 		GetCarsList(context.Context) (map[string][]string, error)
 		GetCarChassisList(context.Context) (map[string]string, error)
 		GetRegionsList(context.Context) (map[string]string, error)
+		GetSearchFilters(ctx context.Context, filters []string) (map[string]map[string]string, error)
 	}
 )
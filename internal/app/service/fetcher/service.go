@@ -8,6 +8,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
@@ -16,7 +17,7 @@ type Service struct {
 	paAdapter PolovniAutoAdapter
 }
 
-const countGoroutines = 3
+const countGoroutines = 4
 
 //go:embed data/regions.json
 var regionsJSON []byte
@@ -27,10 +28,14 @@ var chassisJSON []byte
 //go:embed data/cars.json
 var carsJSON []byte
 
+//go:embed data/filters.json
+var filtersJSON []byte
+
 const (
 	regionsPath = "data/regions.json"
 	chassisPath = "data/chassis.json"
 	carsPath    = "data/cars.json"
+	filtersPath = "data/filters.json"
 )
 
 // NewService creates a new Fetcher Service instance.
@@ -46,7 +51,7 @@ func (s *Service) Start(ctx context.Context) error {
 	return s.Fetch(ctx)
 }
 
-// Fetch fetches regions, chassis and cars.
+// Fetch fetches regions, chassis, cars and search filters.
 func (s *Service) Fetch(ctx context.Context) error {
 	var wg sync.WaitGroup
 
@@ -81,6 +86,15 @@ func (s *Service) Fetch(ctx context.Context) error {
 		}
 	}()
 
+	go func() {
+		defer wg.Done()
+
+		if err := s.fetchSearchFilters(ctx); err != nil {
+			s.l.Error("failed to fetch search filters", logger.ErrAttr(err))
+			errChan <- err
+		}
+	}()
+
 	wg.Wait()
 
 	s.l.Info("fetching completed")
@@ -124,6 +138,16 @@ func (s *Service) GetCarsFromJSON() (map[string][]string, error) {
 	return cars, nil
 }
 
+// GetSearchFiltersFromJSON returns the options of the search filters from the filters.json.
+func (s *Service) GetSearchFiltersFromJSON() (map[ds.SearchFilter]map[string]string, error) {
+	filters := make(map[ds.SearchFilter]map[string]string)
+	if err := json.Unmarshal(filtersJSON, &filters); err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
+
 // fetchRegions fetches regions from the https://www.polovniautomobili.com.
 func (s *Service) fetchRegions(ctx context.Context) error {
 	regionList, err := s.paAdapter.GetRegionsList(ctx)
@@ -172,6 +196,27 @@ func (s *Service) fetchCars(ctx context.Context) error {
 	return nil
 }
 
+// fetchSearchFilters fetches the options of the search filters from the https://www.polovniautomobili.com.
+func (s *Service) fetchSearchFilters(ctx context.Context) error {
+	filters := make([]string, len(ds.SearchFilters))
+	for i, filter := range ds.SearchFilters {
+		filters[i] = string(filter)
+	}
+
+	searchFilters, err := s.paAdapter.GetSearchFilters(ctx, filters)
+	if err != nil {
+		s.l.Error("failed to get search filters", logger.ErrAttr(err))
+		return err
+	}
+
+	if err = s.saveToFile(filtersPath, searchFilters); err != nil {
+		s.l.Error("failed to save search filters to file", logger.ErrAttr(err))
+		return err
+	}
+
+	return nil
+}
+
 // saveToFile saves data to a file.
 func (s *Service) saveToFile(filename string, data any) error {
 	file, err := os.Create(filename)
@@ -2,7 +2,9 @@ package notifier
 
 import (
 	"context"
+	"time"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 )
 
@@ -10,17 +12,27 @@ import (
 type (
 	Repository interface {
 		UpsertUser(ctx context.Context, request ds.UserRequest) (ds.UserResponse, error)
+		GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error)
+		UpdateUserSettings(ctx context.Context, request ds.UpdateUserSettingsRequest) (ds.UserSettingsResponse, error)
 		CreateSubscription(ctx context.Context, sub ds.SubscriptionRequest) (ds.SubscriptionResponse, error)
+		UpdateSubscription(ctx context.Context, sub ds.UpdateSubscriptionRequest) (ds.SubscriptionResponse, error)
 		GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error)
-		DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error
-		DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error
-		DeleteUserByID(ctx context.Context, id int64) error
-		DeleteSubscriptionByID(ctx context.Context, id string) error
+		GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error)
+		AddToWatchlist(ctx context.Context, userID int64, listingID string) error
+		RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error
+		GetWatchlistByUserID(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error)
+		HideListing(ctx context.Context, userID int64, listingID string) error
+		MuteSubscription(ctx context.Context, userID int64, id string, until time.Time) (ds.SubscriptionResponse, error)
+		SetSubscriptionPriceOnly(
+			ctx context.Context, userID int64, id string, priceOnly bool,
+		) (ds.SubscriptionResponse, error)
+		WithTx(ctx context.Context, fn func(repo repository.DB) error) error
 	}
 
 	Fetcher interface {
 		GetChassisFromJSON() (map[string]string, error)
 		GetRegionsFromJSON() (map[string]string, error)
 		GetCarsFromJSON() (map[string][]string, error)
+		GetSearchFiltersFromJSON() (map[ds.SearchFilter]map[string]string, error)
 	}
 )
@@ -12,7 +12,9 @@ package notifier
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	repository "github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	ds "github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +42,20 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddToWatchlist mocks base method.
+func (m *MockRepository) AddToWatchlist(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToWatchlist", ctx, userID, listingID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddToWatchlist indicates an expected call of AddToWatchlist.
+func (mr *MockRepositoryMockRecorder) AddToWatchlist(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToWatchlist", reflect.TypeOf((*MockRepository)(nil).AddToWatchlist), ctx, userID, listingID)
+}
+
 // CreateSubscription mocks base method.
 func (m *MockRepository) CreateSubscription(ctx context.Context, sub ds.SubscriptionRequest) (ds.SubscriptionResponse, error) {
 	m.ctrl.T.Helper()
@@ -55,75 +71,152 @@ func (mr *MockRepositoryMockRecorder) CreateSubscription(ctx, sub any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockRepository)(nil).CreateSubscription), ctx, sub)
 }
 
-// DeleteListingsBySubscriptionIDs mocks base method.
-func (m *MockRepository) DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error {
+// GetListingPriceHistory mocks base method.
+func (m *MockRepository) GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteListingsBySubscriptionIDs", ctx, ids)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetListingPriceHistory", ctx, listingID)
+	ret0, _ := ret[0].([]ds.ListingPriceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteListingsBySubscriptionIDs indicates an expected call of DeleteListingsBySubscriptionIDs.
-func (mr *MockRepositoryMockRecorder) DeleteListingsBySubscriptionIDs(ctx, ids any) *gomock.Call {
+// GetListingPriceHistory indicates an expected call of GetListingPriceHistory.
+func (mr *MockRepositoryMockRecorder) GetListingPriceHistory(ctx, listingID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteListingsBySubscriptionIDs", reflect.TypeOf((*MockRepository)(nil).DeleteListingsBySubscriptionIDs), ctx, ids)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingPriceHistory", reflect.TypeOf((*MockRepository)(nil).GetListingPriceHistory), ctx, listingID)
 }
 
-// DeleteSubscriptionByID mocks base method.
-func (m *MockRepository) DeleteSubscriptionByID(ctx context.Context, id string) error {
+// GetSubscriptionsByUserID mocks base method.
+func (m *MockRepository) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSubscriptionByID", ctx, id)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetSubscriptionsByUserID", ctx, userID)
+	ret0, _ := ret[0].([]ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteSubscriptionByID indicates an expected call of DeleteSubscriptionByID.
-func (mr *MockRepositoryMockRecorder) DeleteSubscriptionByID(ctx, id any) *gomock.Call {
+// GetSubscriptionsByUserID indicates an expected call of GetSubscriptionsByUserID.
+func (mr *MockRepositoryMockRecorder) GetSubscriptionsByUserID(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionByID", reflect.TypeOf((*MockRepository)(nil).DeleteSubscriptionByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionsByUserID", reflect.TypeOf((*MockRepository)(nil).GetSubscriptionsByUserID), ctx, userID)
 }
 
-// DeleteSubscriptionsByUserID mocks base method.
-func (m *MockRepository) DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error {
+// GetUserSettings mocks base method.
+func (m *MockRepository) GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSubscriptionsByUserID", ctx, userID)
+	ret := m.ctrl.Call(m, "GetUserSettings", ctx, userID)
+	ret0, _ := ret[0].(ds.UserSettingsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockRepositoryMockRecorder) GetUserSettings(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockRepository)(nil).GetUserSettings), ctx, userID)
+}
+
+// GetWatchlistByUserID mocks base method.
+func (m *MockRepository) GetWatchlistByUserID(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchlistByUserID", ctx, userID)
+	ret0, _ := ret[0].([]ds.WatchlistItemResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWatchlistByUserID indicates an expected call of GetWatchlistByUserID.
+func (mr *MockRepositoryMockRecorder) GetWatchlistByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchlistByUserID", reflect.TypeOf((*MockRepository)(nil).GetWatchlistByUserID), ctx, userID)
+}
+
+// HideListing mocks base method.
+func (m *MockRepository) HideListing(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HideListing", ctx, userID, listingID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteSubscriptionsByUserID indicates an expected call of DeleteSubscriptionsByUserID.
-func (mr *MockRepositoryMockRecorder) DeleteSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+// HideListing indicates an expected call of HideListing.
+func (mr *MockRepositoryMockRecorder) HideListing(ctx, userID, listingID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionsByUserID", reflect.TypeOf((*MockRepository)(nil).DeleteSubscriptionsByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HideListing", reflect.TypeOf((*MockRepository)(nil).HideListing), ctx, userID, listingID)
 }
 
-// DeleteUserByID mocks base method.
-func (m *MockRepository) DeleteUserByID(ctx context.Context, id int64) error {
+// MuteSubscription mocks base method.
+func (m *MockRepository) MuteSubscription(ctx context.Context, userID int64, id string, until time.Time) (ds.SubscriptionResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUserByID", ctx, id)
+	ret := m.ctrl.Call(m, "MuteSubscription", ctx, userID, id, until)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MuteSubscription indicates an expected call of MuteSubscription.
+func (mr *MockRepositoryMockRecorder) MuteSubscription(ctx, userID, id, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteSubscription", reflect.TypeOf((*MockRepository)(nil).MuteSubscription), ctx, userID, id, until)
+}
+
+// RemoveFromWatchlist mocks base method.
+func (m *MockRepository) RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFromWatchlist", ctx, userID, listingID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteUserByID indicates an expected call of DeleteUserByID.
-func (mr *MockRepositoryMockRecorder) DeleteUserByID(ctx, id any) *gomock.Call {
+// RemoveFromWatchlist indicates an expected call of RemoveFromWatchlist.
+func (mr *MockRepositoryMockRecorder) RemoveFromWatchlist(ctx, userID, listingID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserByID", reflect.TypeOf((*MockRepository)(nil).DeleteUserByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromWatchlist", reflect.TypeOf((*MockRepository)(nil).RemoveFromWatchlist), ctx, userID, listingID)
 }
 
-// GetSubscriptionsByUserID mocks base method.
-func (m *MockRepository) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
+// SetSubscriptionPriceOnly mocks base method.
+func (m *MockRepository) SetSubscriptionPriceOnly(ctx context.Context, userID int64, id string, priceOnly bool) (ds.SubscriptionResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSubscriptionsByUserID", ctx, userID)
-	ret0, _ := ret[0].([]ds.SubscriptionResponse)
+	ret := m.ctrl.Call(m, "SetSubscriptionPriceOnly", ctx, userID, id, priceOnly)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSubscriptionsByUserID indicates an expected call of GetSubscriptionsByUserID.
-func (mr *MockRepositoryMockRecorder) GetSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+// SetSubscriptionPriceOnly indicates an expected call of SetSubscriptionPriceOnly.
+func (mr *MockRepositoryMockRecorder) SetSubscriptionPriceOnly(ctx, userID, id, priceOnly any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionsByUserID", reflect.TypeOf((*MockRepository)(nil).GetSubscriptionsByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubscriptionPriceOnly", reflect.TypeOf((*MockRepository)(nil).SetSubscriptionPriceOnly), ctx, userID, id, priceOnly)
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockRepository) UpdateSubscription(ctx context.Context, sub ds.UpdateSubscriptionRequest) (ds.SubscriptionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", ctx, sub)
+	ret0, _ := ret[0].(ds.SubscriptionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockRepositoryMockRecorder) UpdateSubscription(ctx, sub any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockRepository)(nil).UpdateSubscription), ctx, sub)
+}
+
+// UpdateUserSettings mocks base method.
+func (m *MockRepository) UpdateUserSettings(ctx context.Context, request ds.UpdateUserSettingsRequest) (ds.UserSettingsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserSettings", ctx, request)
+	ret0, _ := ret[0].(ds.UserSettingsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserSettings indicates an expected call of UpdateUserSettings.
+func (mr *MockRepositoryMockRecorder) UpdateUserSettings(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserSettings", reflect.TypeOf((*MockRepository)(nil).UpdateUserSettings), ctx, request)
 }
 
 // UpsertUser mocks base method.
@@ -141,6 +234,20 @@ func (mr *MockRepositoryMockRecorder) UpsertUser(ctx, request any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertUser", reflect.TypeOf((*MockRepository)(nil).UpsertUser), ctx, request)
 }
 
+// WithTx mocks base method.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repository.DB) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryMockRecorder) WithTx(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepository)(nil).WithTx), ctx, fn)
+}
+
 // MockFetcher is a mock of Fetcher interface.
 type MockFetcher struct {
 	ctrl     *gomock.Controller
@@ -208,3 +315,18 @@ func (mr *MockFetcherMockRecorder) GetRegionsFromJSON() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRegionsFromJSON", reflect.TypeOf((*MockFetcher)(nil).GetRegionsFromJSON))
 }
+
+// GetSearchFiltersFromJSON mocks base method.
+func (m *MockFetcher) GetSearchFiltersFromJSON() (map[ds.SearchFilter]map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSearchFiltersFromJSON")
+	ret0, _ := ret[0].(map[ds.SearchFilter]map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSearchFiltersFromJSON indicates an expected call of GetSearchFiltersFromJSON.
+func (mr *MockFetcherMockRecorder) GetSearchFiltersFromJSON() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSearchFiltersFromJSON", reflect.TypeOf((*MockFetcher)(nil).GetSearchFiltersFromJSON))
+}
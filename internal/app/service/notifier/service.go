@@ -2,9 +2,12 @@ package notifier
 
 import (
 	"context"
+	"maps"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	cache "github.com/gudimz/polovni-auto-alert/pkg/in_memory_storage"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
@@ -19,17 +22,20 @@ type Service struct {
 	carsList    *cache.Storage[string, []string]
 	chassisList *cache.Storage[string, string]
 	regionsList *cache.Storage[string, string]
+	// searchFilters maps the names of the options of the search filters to their IDs
+	searchFilters *cache.Storage[ds.SearchFilter, map[string]string]
 }
 
 // NewService creates a new instance of the notification service.
 func NewService(l *logger.Logger, repo Repository, fetcher Fetcher) *Service {
 	return &Service{
-		l:           l,
-		repo:        repo,
-		fetcher:     fetcher,
-		carsList:    cache.New[string, []string](),
-		chassisList: cache.New[string, string](),
-		regionsList: cache.New[string, string](),
+		l:             l,
+		repo:          repo,
+		fetcher:       fetcher,
+		carsList:      cache.New[string, []string](),
+		chassisList:   cache.New[string, string](),
+		regionsList:   cache.New[string, string](),
+		searchFilters: cache.New[ds.SearchFilter, map[string]string](),
 	}
 }
 
@@ -59,6 +65,14 @@ func (s *Service) Start() error {
 
 	s.regionsList.SetBatch(regions)
 
+	// set search filters in cache
+	searchFilters, err := s.fetcher.GetSearchFiltersFromJSON()
+	if err != nil {
+		return errors.Wrap(err, "failed to get search filters from json")
+	}
+
+	s.searchFilters.SetBatch(searchFilters)
+
 	s.l.Info("notifier service started")
 
 	return nil
@@ -97,70 +111,209 @@ func (s *Service) CreateSubscription(
 	return sub, nil
 }
 
+// UpdateSubscription updates an existing subscription in place, keeping its listings.
+func (s *Service) UpdateSubscription(
+	ctx context.Context, subscription ds.UpdateSubscriptionRequest,
+) (ds.SubscriptionResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", subscription.UserID),
+		logger.StringAttr("subscription_id", subscription.ID),
+	)
+
+	sub, err := s.repo.UpdateSubscription(ctx, subscription)
+	if err != nil {
+		lg.Error("failed to update subscription", logger.ErrAttr(err))
+		return ds.SubscriptionResponse{}, errors.Wrap(err, "failed to update subscription")
+	}
+
+	return sub, nil
+}
+
 // RemoveAllSubscriptionsByUserID removes all subscriptions and associated listings for a given user.
 func (s *Service) RemoveAllSubscriptionsByUserID(ctx context.Context, userID int64) error {
 	lg := s.l.With(logger.Int64Attr("user_id", userID))
 
+	return s.repo.WithTx(ctx, func(repo repository.DB) error {
+		subscriptions, err := repo.GetSubscriptionsByUserID(ctx, userID)
+		if err != nil {
+			lg.Error("failed to get subscriptions by user id", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to get subscriptions by user id")
+		}
+
+		ids := make([]string, len(subscriptions))
+		for i, sub := range subscriptions {
+			ids[i] = sub.ID
+		}
+
+		if err = repo.DeleteListingsBySubscriptionIDs(ctx, ids); err != nil {
+			lg.Error("failed to delete listings by subscription ids", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to delete listings by subscription ids")
+		}
+
+		if err = repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
+			lg.Error("failed to delete subscriptions by user id", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to delete subscriptions by user")
+		}
+
+		if err = repo.DeleteUserByID(ctx, userID); err != nil {
+			lg.Error("failed to delete user by id", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to delete user by id")
+		}
+
+		return nil
+	})
+}
+
+// GetAllSubscriptionsByUserID retrieves all subscriptions for a given user.
+func (s *Service) GetAllSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", userID))
+
 	subscriptions, err := s.repo.GetSubscriptionsByUserID(ctx, userID)
 	if err != nil {
 		lg.Error("failed to get subscriptions by user id", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to get subscriptions by user id")
+		return []ds.SubscriptionResponse{}, errors.Wrap(err, "failed to get subscriptions by user id")
 	}
 
-	ids := make([]string, len(subscriptions))
-	for i, sub := range subscriptions {
-		ids[i] = sub.ID
+	return subscriptions, nil
+}
+
+// RemoveSubscriptionByID removes subscription and associated listings for a given subscription id.
+func (s *Service) RemoveSubscriptionByID(ctx context.Context, id string) error {
+	lg := s.l.With(logger.StringAttr("subscription_id", id))
+
+	return s.repo.WithTx(ctx, func(repo repository.DB) error {
+		if err := repo.DeleteListingsBySubscriptionIDs(ctx, []string{id}); err != nil {
+			lg.Error("failed to delete listings by subscription ids", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to delete listings by subscription ids")
+		}
+
+		if err := repo.DeleteSubscriptionByID(ctx, id); err != nil {
+			lg.Error("failed to delete subscription by id", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to delete subscription by id")
+		}
+
+		return nil
+	})
+}
+
+// GetListingPriceHistory retrieves the observed prices of a listing ordered by the observation time.
+func (s *Service) GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error) {
+	lg := s.l.With(logger.StringAttr("listing_id", listingID))
+
+	history, err := s.repo.GetListingPriceHistory(ctx, listingID)
+	if err != nil {
+		lg.Error("failed to get listing price history", logger.ErrAttr(err))
+		return []ds.ListingPriceResponse{}, errors.Wrap(err, "failed to get listing price history")
 	}
 
-	if err = s.repo.DeleteListingsBySubscriptionIDs(ctx, ids); err != nil {
-		lg.Error("failed to delete listings by subscription ids", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to delete listings by subscription ids")
+	return history, nil
+}
+
+// AddToWatchlist saves the car of the listing to the watchlist of the user.
+func (s *Service) AddToWatchlist(ctx context.Context, userID int64, listingID string) error {
+	lg := s.l.With(logger.Int64Attr("user_id", userID), logger.StringAttr("listing_id", listingID))
+
+	if err := s.repo.AddToWatchlist(ctx, userID, listingID); err != nil {
+		lg.Error("failed to add listing to watchlist", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to add listing to watchlist")
 	}
 
-	if err = s.repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
-		lg.Error("failed to delete subscriptions by user id", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to delete subscriptions by user")
+	return nil
+}
+
+// RemoveFromWatchlist removes the car of the listing from the watchlist of the user.
+func (s *Service) RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error {
+	lg := s.l.With(logger.Int64Attr("user_id", userID), logger.StringAttr("listing_id", listingID))
+
+	if err := s.repo.RemoveFromWatchlist(ctx, userID, listingID); err != nil {
+		lg.Error("failed to remove listing from watchlist", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to remove listing from watchlist")
 	}
 
-	if err = s.repo.DeleteUserByID(ctx, userID); err != nil {
-		lg.Error("failed to delete user by id", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to delete user by id")
+	return nil
+}
+
+// GetWatchlist retrieves the cars of the watchlist of the user with their current prices.
+func (s *Service) GetWatchlist(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", userID))
+
+	items, err := s.repo.GetWatchlistByUserID(ctx, userID)
+	if err != nil {
+		lg.Error("failed to get watchlist by user id", logger.ErrAttr(err))
+		return []ds.WatchlistItemResponse{}, errors.Wrap(err, "failed to get watchlist by user id")
 	}
 
-	// TODO add transactions in future
+	return items, nil
+}
+
+// HideListing stops the notifications about the car of the listing for the user.
+func (s *Service) HideListing(ctx context.Context, userID int64, listingID string) error {
+	lg := s.l.With(logger.Int64Attr("user_id", userID), logger.StringAttr("listing_id", listingID))
+
+	if err := s.repo.HideListing(ctx, userID, listingID); err != nil {
+		lg.Error("failed to hide listing", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to hide listing")
+	}
 
 	return nil
 }
 
-// GetAllSubscriptionsByUserID retrieves all subscriptions for a given user.
-func (s *Service) GetAllSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
-	lg := s.l.With(logger.Int64Attr("user_id", userID))
+// MuteSubscription stops the notifications of the subscription of the user until the given time.
+func (s *Service) MuteSubscription(
+	ctx context.Context, userID int64, id string, until time.Time,
+) (ds.SubscriptionResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", userID), logger.StringAttr("subscription_id", id))
 
-	subscriptions, err := s.repo.GetSubscriptionsByUserID(ctx, userID)
+	sub, err := s.repo.MuteSubscription(ctx, userID, id, until)
 	if err != nil {
-		lg.Error("failed to get subscriptions by user id", logger.ErrAttr(err))
-		return []ds.SubscriptionResponse{}, errors.Wrap(err, "failed to get subscriptions by user id")
+		lg.Error("failed to mute subscription", logger.ErrAttr(err))
+		return ds.SubscriptionResponse{}, errors.Wrap(err, "failed to mute subscription")
 	}
 
-	return subscriptions, nil
+	return sub, nil
 }
 
-// RemoveSubscriptionByID removes subscription and associated listings for a given subscription id.
-func (s *Service) RemoveSubscriptionByID(ctx context.Context, id string) error {
-	lg := s.l.With(logger.StringAttr("subscription_id", id))
-	if err := s.repo.DeleteListingsBySubscriptionIDs(ctx, []string{id}); err != nil {
-		lg.Error("failed to delete listings by subscription ids", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to delete listings by subscription ids")
+// SetSubscriptionPriceOnly switches the subscription of the user to the notifications about the price changes
+// only or about all the listings.
+func (s *Service) SetSubscriptionPriceOnly(
+	ctx context.Context, userID int64, id string, priceOnly bool,
+) (ds.SubscriptionResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", userID), logger.StringAttr("subscription_id", id))
+
+	sub, err := s.repo.SetSubscriptionPriceOnly(ctx, userID, id, priceOnly)
+	if err != nil {
+		lg.Error("failed to set price only of subscription", logger.ErrAttr(err))
+		return ds.SubscriptionResponse{}, errors.Wrap(err, "failed to set price only of subscription")
 	}
 
-	if err := s.repo.DeleteSubscriptionByID(ctx, id); err != nil {
-		lg.Error("failed to delete subscription by id", logger.ErrAttr(err))
-		return errors.Wrap(err, "failed to delete subscription by id")
+	return sub, nil
+}
+
+// GetUserSettings retrieves the time zone and the quiet hours of the user.
+func (s *Service) GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", userID))
+
+	settings, err := s.repo.GetUserSettings(ctx, userID)
+	if err != nil {
+		lg.Error("failed to get user settings", logger.ErrAttr(err))
+		return ds.UserSettingsResponse{}, errors.Wrap(err, "failed to get user settings")
 	}
 
-	// TODO add transactions in future
+	return settings, nil
+}
 
-	return nil
+// UpdateUserSettings updates the time zone and the quiet hours of the user.
+func (s *Service) UpdateUserSettings(
+	ctx context.Context, request ds.UpdateUserSettingsRequest,
+) (ds.UserSettingsResponse, error) {
+	lg := s.l.With(logger.Int64Attr("user_id", request.UserID))
+
+	settings, err := s.repo.UpdateUserSettings(ctx, request)
+	if err != nil {
+		lg.Error("failed to update user settings", logger.ErrAttr(err))
+		return ds.UserSettingsResponse{}, errors.Wrap(err, "failed to update user settings")
+	}
+
+	return settings, nil
 }
 
 // GetCarBrandsList retrieves the list of car brands.
@@ -182,3 +335,10 @@ func (s *Service) GetCarChassisList() map[string]string {
 func (s *Service) GetRegionsList() map[string]string {
 	return s.regionsList.CopyMap()
 }
+
+// GetSearchFilterOptions retrieves the options of the search filter, it's empty if the filter has no options.
+func (s *Service) GetSearchFilterOptions(filter ds.SearchFilter) map[string]string {
+	options, _ := s.searchFilters.Get(filter)
+
+	return maps.Clone(options)
+}
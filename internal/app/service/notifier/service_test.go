@@ -7,9 +7,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
@@ -20,6 +22,7 @@ type ServiceTestSuite struct {
 	suite.Suite
 	ctrl        *gomock.Controller
 	mockRepo    *MockRepository
+	mockTxRepo  *repository.MockDB
 	mockFetcher *MockFetcher
 	svc         *Service
 }
@@ -29,6 +32,7 @@ func (s *ServiceTestSuite) SetupTest() {
 
 	s.ctrl = gomock.NewController(s.T())
 	s.mockRepo = NewMockRepository(s.ctrl)
+	s.mockTxRepo = repository.NewMockDB(s.ctrl)
 	s.mockFetcher = NewMockFetcher(s.ctrl)
 
 	lg := logger.NewLogger()
@@ -57,12 +61,25 @@ func (s *ServiceTestSuite) SetupTest() {
 	s.svc.regionsList.SetBatch(map[string]string{
 		"Beograd": "Beograd",
 	})
+
+	s.svc.searchFilters.SetBatch(map[ds.SearchFilter]map[string]string{
+		ds.SearchFilterFuel: {"Benzin": "45", "Dizel": "2309"},
+	})
 }
 
 func (s *ServiceTestSuite) TearDownTest() {
 	s.ctrl.Finish()
 }
 
+// expectTx expects a transaction which runs its function with the transaction repository mock.
+func (s *ServiceTestSuite) expectTx() {
+	s.mockRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repo repository.DB) error) error {
+			return fn(s.mockTxRepo)
+		}).
+		Times(1)
+}
+
 func (s *ServiceTestSuite) TestService_UpsertUser() {
 	now := time.Now()
 
@@ -198,6 +215,76 @@ func (s *ServiceTestSuite) TestService_CreateSubscription() {
 	}
 }
 
+func (s *ServiceTestSuite) TestService_UpdateSubscription() {
+	now := time.Now()
+	subID := uuid.NewString()
+
+	type testCase struct {
+		mock         func(*testCase)
+		name         string
+		subscription ds.UpdateSubscriptionRequest
+		want         ds.SubscriptionResponse
+		expectErr    error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().UpdateSubscription(gomock.Any(), tc.subscription).
+					Return(tc.want, nil).
+					Times(1)
+			},
+			subscription: ds.UpdateSubscriptionRequest{
+				ID:        subID,
+				UserID:    1,
+				Model:     []string{"m3"},
+				PriceFrom: "1000",
+			},
+			want: ds.SubscriptionResponse{
+				ID:        subID,
+				UserID:    1,
+				Brand:     "bmw",
+				Model:     []string{"m3"},
+				PriceFrom: "1000",
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+		{
+			name: "update subscription in DB failed: common error",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().UpdateSubscription(gomock.Any(), tc.subscription).
+					Return(ds.SubscriptionResponse{}, errCommon).
+					Times(1)
+			},
+			subscription: ds.UpdateSubscriptionRequest{
+				ID:     subID,
+				UserID: 1,
+				Model:  []string{"m3"},
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			want, err := s.svc.UpdateSubscription(context.Background(), tc.subscription)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, want)
+			}
+		})
+	}
+}
+
 func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 	now := time.Now()
 
@@ -212,7 +299,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "success",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -232,13 +320,13 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
 			},
@@ -247,7 +335,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "get subscription from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{}, errCommon).
 					Times(1)
 			},
@@ -257,7 +346,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete listings from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -277,7 +367,7 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(errCommon).
 					Times(1)
 			},
@@ -287,7 +377,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete subscriptions from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -307,10 +398,10 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(errCommon).
 					Times(1)
 			},
@@ -320,7 +411,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete user from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -340,13 +432,13 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
 					Return(errCommon).
 					Times(1)
 			},
@@ -458,10 +550,11 @@ func (s *ServiceTestSuite) TestService_RemoveSubscriptionByID() {
 		{
 			name: "success",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionByID(gomock.Any(), tc.id).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionByID(gomock.Any(), tc.id).
 					Return(nil).
 					Times(1)
 			},
@@ -470,7 +563,8 @@ func (s *ServiceTestSuite) TestService_RemoveSubscriptionByID() {
 		{
 			name: "delete listings from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
 					Return(errCommon).
 					Times(1)
 			},
@@ -480,10 +574,11 @@ func (s *ServiceTestSuite) TestService_RemoveSubscriptionByID() {
 		{
 			name: "delete subscription from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), []string{tc.id}).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionByID(gomock.Any(), tc.id).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionByID(gomock.Any(), tc.id).
 					Return(errCommon).
 					Times(1)
 			},
@@ -508,6 +603,432 @@ func (s *ServiceTestSuite) TestService_RemoveSubscriptionByID() {
 	}
 }
 
+func (s *ServiceTestSuite) TestService_GetListingPriceHistory() {
+	now := time.Now()
+	listingID := "26135927"
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		listingID string
+		want      []ds.ListingPriceResponse
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), tc.listingID).
+					Return([]ds.ListingPriceResponse{
+						{ListingID: listingID, Price: "2400€", ObservedAt: now.AddDate(0, 0, -1)},
+						{ListingID: listingID, Price: "2000€", ObservedAt: now},
+					}, nil).
+					Times(1)
+			},
+			listingID: listingID,
+			want: []ds.ListingPriceResponse{
+				{ListingID: listingID, Price: "2400€", ObservedAt: now.AddDate(0, 0, -1)},
+				{ListingID: listingID, Price: "2000€", ObservedAt: now},
+			},
+		},
+		{
+			name: "get listing price history from DB failed: common error",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), tc.listingID).
+					Return([]ds.ListingPriceResponse{}, errCommon).
+					Times(1)
+			},
+			listingID: listingID,
+			want:      []ds.ListingPriceResponse{},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			want, err := s.svc.GetListingPriceHistory(context.Background(), tc.listingID)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, want)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_AddToWatchlist() {
+	const (
+		userID    = int64(1)
+		listingID = "26135927"
+	)
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().AddToWatchlist(gomock.Any(), userID, listingID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "add to watchlist in DB failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().AddToWatchlist(gomock.Any(), userID, listingID).
+					Return(errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			err := s.svc.AddToWatchlist(context.Background(), userID, listingID)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_RemoveFromWatchlist() {
+	const (
+		userID    = int64(1)
+		listingID = "26135927"
+	)
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().RemoveFromWatchlist(gomock.Any(), userID, listingID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "car is not on watchlist",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().RemoveFromWatchlist(gomock.Any(), userID, listingID).
+					Return(ds.ErrNotFound).
+					Times(1)
+			},
+			expectErr: ds.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			err := s.svc.RemoveFromWatchlist(context.Background(), userID, listingID)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_GetWatchlist() {
+	const userID = int64(1)
+
+	now := time.Now()
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		want      []ds.WatchlistItemResponse
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().GetWatchlistByUserID(gomock.Any(), userID).
+					Return(tc.want, nil).
+					Times(1)
+			},
+			want: []ds.WatchlistItemResponse{
+				{ListingID: "26135927", Title: "Best bmw", Price: "2400€", CreatedAt: now},
+			},
+		},
+		{
+			name: "get watchlist from DB failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().GetWatchlistByUserID(gomock.Any(), userID).
+					Return([]ds.WatchlistItemResponse{}, errCommon).
+					Times(1)
+			},
+			want:      []ds.WatchlistItemResponse{},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			got, err := s.svc.GetWatchlist(context.Background(), userID)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_HideListing() {
+	const (
+		userID    = int64(1)
+		listingID = "26135927"
+	)
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().HideListing(gomock.Any(), userID, listingID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "hide listing in DB failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().HideListing(gomock.Any(), userID, listingID).
+					Return(errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			err := s.svc.HideListing(context.Background(), userID, listingID)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_MuteSubscription() {
+	const userID = int64(1)
+
+	id := uuid.NewString()
+	until := time.Now().Add(24 * time.Hour)
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		want      ds.SubscriptionResponse
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().MuteSubscription(gomock.Any(), userID, id, until).
+					Return(tc.want, nil).
+					Times(1)
+			},
+			want: ds.SubscriptionResponse{ID: id, UserID: userID, MutedUntil: null.TimeFrom(until)},
+		},
+		{
+			name: "subscription not found",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().MuteSubscription(gomock.Any(), userID, id, until).
+					Return(ds.SubscriptionResponse{}, ds.ErrNotFound).
+					Times(1)
+			},
+			expectErr: ds.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			got, err := s.svc.MuteSubscription(context.Background(), userID, id, until)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_SetSubscriptionPriceOnly() {
+	const userID = int64(1)
+
+	id := uuid.NewString()
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		want      ds.SubscriptionResponse
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().SetSubscriptionPriceOnly(gomock.Any(), userID, id, true).
+					Return(tc.want, nil).
+					Times(1)
+			},
+			want: ds.SubscriptionResponse{ID: id, UserID: userID, PriceOnly: true},
+		},
+		{
+			name: "set price only in DB failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().SetSubscriptionPriceOnly(gomock.Any(), userID, id, true).
+					Return(ds.SubscriptionResponse{}, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			got, err := s.svc.SetSubscriptionPriceOnly(context.Background(), userID, id, true)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_UpdateUserSettings() {
+	request := ds.UpdateUserSettingsRequest{
+		UserID:         1,
+		TimeZone:       "Europe/Belgrade",
+		QuietHoursFrom: null.IntFrom(22),
+		QuietHoursTo:   null.IntFrom(8),
+		MuteWeekends:   true,
+	}
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		want      ds.UserSettingsResponse
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(tc *testCase) {
+				s.mockRepo.EXPECT().UpdateUserSettings(gomock.Any(), request).
+					Return(tc.want, nil).
+					Times(1)
+			},
+			want: ds.UserSettingsResponse{
+				UserID:         request.UserID,
+				TimeZone:       request.TimeZone,
+				QuietHoursFrom: request.QuietHoursFrom,
+				QuietHoursTo:   request.QuietHoursTo,
+				MuteWeekends:   request.MuteWeekends,
+			},
+		},
+		{
+			name: "user not found",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().UpdateUserSettings(gomock.Any(), request).
+					Return(ds.UserSettingsResponse{}, ds.ErrNotFound).
+					Times(1)
+			},
+			want:      ds.UserSettingsResponse{},
+			expectErr: ds.ErrNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			got, err := s.svc.UpdateUserSettings(context.Background(), request)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIsf(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
 func (s *ServiceTestSuite) TestService_GetCarBrandsList() {
 	testCases := []struct {
 		name string
@@ -600,6 +1121,32 @@ func (s *ServiceTestSuite) TestService_GetRegionsList() {
 	}
 }
 
+func (s *ServiceTestSuite) TestService_GetSearchFilterOptions() {
+	testCases := []struct {
+		name   string
+		filter ds.SearchFilter
+		want   map[string]string
+	}{
+		{
+			name:   "success",
+			filter: ds.SearchFilterFuel,
+			want:   map[string]string{"Benzin": "45", "Dizel": "2309"},
+		},
+		{
+			name:   "filter without options",
+			filter: ds.SearchFilterColor,
+			want:   nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			got := s.svc.GetSearchFilterOptions(tc.filter)
+			s.Equal(tc.want, got)
+		})
+	}
+}
+
 func TestServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(ServiceTestSuite))
 }
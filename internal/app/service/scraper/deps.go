@@ -2,7 +2,9 @@ package scraper
 
 import (
 	"context"
+	"time"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
 )
@@ -11,15 +13,30 @@ import (
 type (
 	PolovniAutoAdapter interface {
 		GetNewListings(ctx context.Context, params map[string]string) ([]polovniauto.Listing, error)
+		GetListingDetails(ctx context.Context, link string) (polovniauto.ListingDetails, error)
+		IsListingRemoved(ctx context.Context, link string) (bool, error)
+		GetImageHash(ctx context.Context, imageURL string) (uint64, error)
 	}
 
 	Fetcher interface {
 		GetChassisFromJSON() (map[string]string, error)
+		GetCarsFromJSON() (map[string][]string, error)
+		GetSearchFiltersFromJSON() (map[ds.SearchFilter]map[string]string, error)
 	}
 
 	Repository interface {
+		WithTx(ctx context.Context, fn func(repo repository.DB) error) error
 		GetAllSubscriptions(ctx context.Context) ([]ds.SubscriptionResponse, error)
-		UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error
+		UpsertCar(ctx context.Context, car ds.UpsertCarRequest) error
+		UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error
+		AddListingPrice(ctx context.Context, price ds.ListingPriceRequest) error
 		GetListingsBySubscriptionID(ctx context.Context, subscriptionID string) ([]ds.ListingResponse, error)
+		RefreshMarketStats(ctx context.Context, request ds.RefreshMarketStatsRequest) (int64, error)
+		MarkCarsSeen(ctx context.Context, request ds.MarkCarsSeenRequest) error
+		MarkCarsMissing(ctx context.Context, request ds.MarkCarsMissingRequest) ([]ds.MissingCarResponse, error)
+		MarkCarRemoved(ctx context.Context, request ds.MarkCarRemovedRequest) (int64, error)
+		DeleteOrphanCars(ctx context.Context, seenBefore time.Time) (int64, error)
+		GetWatchedCars(ctx context.Context) ([]ds.WatchedCarResponse, error)
+		UpdateCarPrice(ctx context.Context, request ds.UpdateCarPriceRequest) error
 	}
 )
@@ -12,7 +12,9 @@ package scraper
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	repository "github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	ds "github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	polovniauto "github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +43,36 @@ func (m *MockPolovniAutoAdapter) EXPECT() *MockPolovniAutoAdapterMockRecorder {
 	return m.recorder
 }
 
+// GetImageHash mocks base method.
+func (m *MockPolovniAutoAdapter) GetImageHash(ctx context.Context, imageURL string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageHash", ctx, imageURL)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageHash indicates an expected call of GetImageHash.
+func (mr *MockPolovniAutoAdapterMockRecorder) GetImageHash(ctx, imageURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageHash", reflect.TypeOf((*MockPolovniAutoAdapter)(nil).GetImageHash), ctx, imageURL)
+}
+
+// GetListingDetails mocks base method.
+func (m *MockPolovniAutoAdapter) GetListingDetails(ctx context.Context, link string) (polovniauto.ListingDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListingDetails", ctx, link)
+	ret0, _ := ret[0].(polovniauto.ListingDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListingDetails indicates an expected call of GetListingDetails.
+func (mr *MockPolovniAutoAdapterMockRecorder) GetListingDetails(ctx, link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingDetails", reflect.TypeOf((*MockPolovniAutoAdapter)(nil).GetListingDetails), ctx, link)
+}
+
 // GetNewListings mocks base method.
 func (m *MockPolovniAutoAdapter) GetNewListings(ctx context.Context, params map[string]string) ([]polovniauto.Listing, error) {
 	m.ctrl.T.Helper()
@@ -56,6 +88,21 @@ func (mr *MockPolovniAutoAdapterMockRecorder) GetNewListings(ctx, params any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNewListings", reflect.TypeOf((*MockPolovniAutoAdapter)(nil).GetNewListings), ctx, params)
 }
 
+// IsListingRemoved mocks base method.
+func (m *MockPolovniAutoAdapter) IsListingRemoved(ctx context.Context, link string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsListingRemoved", ctx, link)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsListingRemoved indicates an expected call of IsListingRemoved.
+func (mr *MockPolovniAutoAdapterMockRecorder) IsListingRemoved(ctx, link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsListingRemoved", reflect.TypeOf((*MockPolovniAutoAdapter)(nil).IsListingRemoved), ctx, link)
+}
+
 // MockFetcher is a mock of Fetcher interface.
 type MockFetcher struct {
 	ctrl     *gomock.Controller
@@ -79,6 +126,21 @@ func (m *MockFetcher) EXPECT() *MockFetcherMockRecorder {
 	return m.recorder
 }
 
+// GetCarsFromJSON mocks base method.
+func (m *MockFetcher) GetCarsFromJSON() (map[string][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCarsFromJSON")
+	ret0, _ := ret[0].(map[string][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCarsFromJSON indicates an expected call of GetCarsFromJSON.
+func (mr *MockFetcherMockRecorder) GetCarsFromJSON() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCarsFromJSON", reflect.TypeOf((*MockFetcher)(nil).GetCarsFromJSON))
+}
+
 // GetChassisFromJSON mocks base method.
 func (m *MockFetcher) GetChassisFromJSON() (map[string]string, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +156,21 @@ func (mr *MockFetcherMockRecorder) GetChassisFromJSON() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChassisFromJSON", reflect.TypeOf((*MockFetcher)(nil).GetChassisFromJSON))
 }
 
+// GetSearchFiltersFromJSON mocks base method.
+func (m *MockFetcher) GetSearchFiltersFromJSON() (map[ds.SearchFilter]map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSearchFiltersFromJSON")
+	ret0, _ := ret[0].(map[ds.SearchFilter]map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSearchFiltersFromJSON indicates an expected call of GetSearchFiltersFromJSON.
+func (mr *MockFetcherMockRecorder) GetSearchFiltersFromJSON() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSearchFiltersFromJSON", reflect.TypeOf((*MockFetcher)(nil).GetSearchFiltersFromJSON))
+}
+
 // MockRepository is a mock of Repository interface.
 type MockRepository struct {
 	ctrl     *gomock.Controller
@@ -117,6 +194,35 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddListingPrice mocks base method.
+func (m *MockRepository) AddListingPrice(ctx context.Context, price ds.ListingPriceRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddListingPrice", ctx, price)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddListingPrice indicates an expected call of AddListingPrice.
+func (mr *MockRepositoryMockRecorder) AddListingPrice(ctx, price any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddListingPrice", reflect.TypeOf((*MockRepository)(nil).AddListingPrice), ctx, price)
+}
+
+// DeleteOrphanCars mocks base method.
+func (m *MockRepository) DeleteOrphanCars(ctx context.Context, seenBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphanCars", ctx, seenBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphanCars indicates an expected call of DeleteOrphanCars.
+func (mr *MockRepositoryMockRecorder) DeleteOrphanCars(ctx, seenBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphanCars", reflect.TypeOf((*MockRepository)(nil).DeleteOrphanCars), ctx, seenBefore)
+}
+
 // GetAllSubscriptions mocks base method.
 func (m *MockRepository) GetAllSubscriptions(ctx context.Context) ([]ds.SubscriptionResponse, error) {
 	m.ctrl.T.Helper()
@@ -147,16 +253,132 @@ func (mr *MockRepositoryMockRecorder) GetListingsBySubscriptionID(ctx, subscript
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingsBySubscriptionID", reflect.TypeOf((*MockRepository)(nil).GetListingsBySubscriptionID), ctx, subscriptionID)
 }
 
-// UpsertListing mocks base method.
-func (m *MockRepository) UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error {
+// GetWatchedCars mocks base method.
+func (m *MockRepository) GetWatchedCars(ctx context.Context) ([]ds.WatchedCarResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchedCars", ctx)
+	ret0, _ := ret[0].([]ds.WatchedCarResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWatchedCars indicates an expected call of GetWatchedCars.
+func (mr *MockRepositoryMockRecorder) GetWatchedCars(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchedCars", reflect.TypeOf((*MockRepository)(nil).GetWatchedCars), ctx)
+}
+
+// MarkCarRemoved mocks base method.
+func (m *MockRepository) MarkCarRemoved(ctx context.Context, request ds.MarkCarRemovedRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarRemoved", ctx, request)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkCarRemoved indicates an expected call of MarkCarRemoved.
+func (mr *MockRepositoryMockRecorder) MarkCarRemoved(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarRemoved", reflect.TypeOf((*MockRepository)(nil).MarkCarRemoved), ctx, request)
+}
+
+// MarkCarsMissing mocks base method.
+func (m *MockRepository) MarkCarsMissing(ctx context.Context, request ds.MarkCarsMissingRequest) ([]ds.MissingCarResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarsMissing", ctx, request)
+	ret0, _ := ret[0].([]ds.MissingCarResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkCarsMissing indicates an expected call of MarkCarsMissing.
+func (mr *MockRepositoryMockRecorder) MarkCarsMissing(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarsMissing", reflect.TypeOf((*MockRepository)(nil).MarkCarsMissing), ctx, request)
+}
+
+// MarkCarsSeen mocks base method.
+func (m *MockRepository) MarkCarsSeen(ctx context.Context, request ds.MarkCarsSeenRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCarsSeen", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkCarsSeen indicates an expected call of MarkCarsSeen.
+func (mr *MockRepositoryMockRecorder) MarkCarsSeen(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCarsSeen", reflect.TypeOf((*MockRepository)(nil).MarkCarsSeen), ctx, request)
+}
+
+// RefreshMarketStats mocks base method.
+func (m *MockRepository) RefreshMarketStats(ctx context.Context, request ds.RefreshMarketStatsRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshMarketStats", ctx, request)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshMarketStats indicates an expected call of RefreshMarketStats.
+func (mr *MockRepositoryMockRecorder) RefreshMarketStats(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshMarketStats", reflect.TypeOf((*MockRepository)(nil).RefreshMarketStats), ctx, request)
+}
+
+// UpdateCarPrice mocks base method.
+func (m *MockRepository) UpdateCarPrice(ctx context.Context, request ds.UpdateCarPriceRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCarPrice", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCarPrice indicates an expected call of UpdateCarPrice.
+func (mr *MockRepositoryMockRecorder) UpdateCarPrice(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCarPrice", reflect.TypeOf((*MockRepository)(nil).UpdateCarPrice), ctx, request)
+}
+
+// UpsertCar mocks base method.
+func (m *MockRepository) UpsertCar(ctx context.Context, car ds.UpsertCarRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertCar", ctx, car)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertCar indicates an expected call of UpsertCar.
+func (mr *MockRepositoryMockRecorder) UpsertCar(ctx, car any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertCar", reflect.TypeOf((*MockRepository)(nil).UpsertCar), ctx, car)
+}
+
+// UpsertSubscriptionMatch mocks base method.
+func (m *MockRepository) UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertSubscriptionMatch", ctx, match)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertSubscriptionMatch indicates an expected call of UpsertSubscriptionMatch.
+func (mr *MockRepositoryMockRecorder) UpsertSubscriptionMatch(ctx, match any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertSubscriptionMatch", reflect.TypeOf((*MockRepository)(nil).UpsertSubscriptionMatch), ctx, match)
+}
+
+// WithTx mocks base method.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repository.DB) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpsertListing", ctx, listing)
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpsertListing indicates an expected call of UpsertListing.
-func (mr *MockRepositoryMockRecorder) UpsertListing(ctx, listing any) *gomock.Call {
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryMockRecorder) WithTx(ctx, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertListing", reflect.TypeOf((*MockRepository)(nil).UpsertListing), ctx, listing)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepository)(nil).WithTx), ctx, fn)
 }
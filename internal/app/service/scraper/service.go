@@ -2,20 +2,35 @@ package scraper
 
 import (
 	"context"
+	stderrors "errors"
+	"maps"
+	"net/url"
+	"path"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/guregu/null"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/fingerprint"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/keywords"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/marketstats"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/searchparams"
 	cache "github.com/gudimz/polovni-auto-alert/pkg/in_memory_storage"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
 )
 
+// priceSampleInterval is the interval after which an unchanged price is recorded again,
+// so the price history has data points for the charts and the market median.
+const priceSampleInterval = 24 * time.Hour
+
 type Service struct {
 	l         *logger.Logger
 	repo      Repository
@@ -23,8 +38,34 @@ type Service struct {
 	fetcher   Fetcher
 	interval  time.Duration
 	workers   int
+	removal   RemovalConfig
+	now       func() time.Time
 	// TODO: add job for updating the cache
 	chassisList *cache.Storage[string, string]
+	carsList    *cache.Storage[string, []string]
+	// searchFilters maps the names of the options of the search filters to their IDs
+	searchFilters *cache.Storage[ds.SearchFilter, map[string]string]
+}
+
+// RemovalConfig holds the policy of detecting the listings which were removed from the site.
+// A listing is removed if it's missing from MissingScrapes full scrapes in a row and its detail page confirms it,
+// the full scrapes are disabled if FullScrapeInterval is not positive.
+type RemovalConfig struct {
+	FullScrapeInterval time.Duration
+	MissingScrapes     int
+	Notify             bool
+}
+
+// subscriptionGroup is a group of subscriptions with the same query, which are scraped together.
+type subscriptionGroup struct {
+	params        map[string]string
+	subscriptions []ds.SubscriptionResponse
+}
+
+// idCatalog looks up the IDs of the site in the catalog cached by the scraper, see searchparams.IDCatalog.
+type idCatalog struct {
+	chassisList   *cache.Storage[string, string]
+	searchFilters *cache.Storage[ds.SearchFilter, map[string]string]
 }
 
 // NewService creates a new Scraper Service instance.
@@ -35,15 +76,20 @@ func NewService(
 	fetcher Fetcher,
 	interval time.Duration,
 	workers int,
+	removal RemovalConfig,
 ) *Service {
 	return &Service{
-		l:           l,
-		repo:        repo,
-		paAdapter:   paAdapter,
-		fetcher:     fetcher,
-		interval:    interval,
-		workers:     workers,
-		chassisList: cache.New[string, string](),
+		l:             l,
+		repo:          repo,
+		paAdapter:     paAdapter,
+		fetcher:       fetcher,
+		interval:      interval,
+		workers:       workers,
+		removal:       removal,
+		now:           time.Now,
+		chassisList:   cache.New[string, string](),
+		carsList:      cache.New[string, []string](),
+		searchFilters: cache.New[ds.SearchFilter, map[string]string](),
 	}
 }
 
@@ -57,12 +103,32 @@ func (s *Service) Start(ctx context.Context) error {
 
 	s.chassisList.SetBatch(chassis)
 
+	// set cars list in cache, the models are used to detect the model of the listings
+	cars, err := s.fetcher.GetCarsFromJSON()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cars from json")
+	}
+
+	s.carsList.SetBatch(cars)
+
+	// set search filters in cache
+	searchFilters, err := s.fetcher.GetSearchFiltersFromJSON()
+	if err != nil {
+		return errors.Wrap(err, "failed to get search filters from json")
+	}
+
+	s.searchFilters.SetBatch(searchFilters)
+
 	s.l.Info("scraper interval set to", logger.DurationAttr("interval", s.interval))
 
 	if err = s.ScrapeNewListings(ctx); err != nil {
 		return err
 	}
 
+	s.checkWatchedListings(ctx)
+	s.refreshMarketStats(ctx)
+	s.deleteOrphanCars(ctx)
+
 	ticker := time.NewTicker(s.interval)
 
 	go func() {
@@ -76,6 +142,10 @@ func (s *Service) Start(ctx context.Context) error {
 				if err = s.ScrapeNewListings(ctx); err != nil {
 					s.l.Error("failed to scrape new listings", logger.ErrAttr(err))
 				}
+
+				s.checkWatchedListings(ctx)
+				s.refreshMarketStats(ctx)
+				s.deleteOrphanCars(ctx)
 			case <-ctx.Done():
 				ticker.Stop()
 				s.l.Info("scraper stopped", logger.ErrAttr(ctx.Err()))
@@ -85,11 +155,43 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}()
 
+	if s.removal.FullScrapeInterval > 0 {
+		s.startRemovalDetection(ctx)
+	}
+
 	s.l.Info("scraper service started")
 
 	return nil
 }
 
+// startRemovalDetection periodically runs the full scrapes to detect the removed listings.
+// A full scrape takes much longer than a scrape of the new listings, so it has its own ticker.
+func (s *Service) startRemovalDetection(ctx context.Context) {
+	s.l.Info("full scrape interval set to", logger.DurationAttr("interval", s.removal.FullScrapeInterval))
+
+	ticker := time.NewTicker(s.removal.FullScrapeInterval)
+
+	go func() {
+		defer s.recoverPanic()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.l.Info("full scrape ticker ticked")
+
+				if err := s.DetectRemovedListings(ctx); err != nil {
+					s.l.Error("failed to detect removed listings", logger.ErrAttr(err))
+				}
+			case <-ctx.Done():
+				ticker.Stop()
+				s.l.Info("full scrape stopped", logger.ErrAttr(ctx.Err()))
+
+				return
+			}
+		}
+	}()
+}
+
 // ScrapeAllListings scrapes all listings by parameter.
 func (s *Service) ScrapeAllListings(ctx context.Context) error {
 	subscriptions, err := s.repo.GetAllSubscriptions(ctx)
@@ -142,16 +244,270 @@ func (s *Service) ScrapeNewListings(ctx context.Context) error {
 	return nil
 }
 
-// scrapeSubscriptions scrapes listings for each subscription using the provided scrape function.
+// DetectRemovedListings scrapes all listings of the subscriptions and counts the cars of the subscriptions
+// which were not found, a car missing from MissingScrapes full scrapes in a row is checked on its detail page
+// and marked as removed if the removal is confirmed.
+// The cars of the queries which failed to be scraped are not counted as missing.
+func (s *Service) DetectRemovedListings(ctx context.Context) error {
+	subscriptions, err := s.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get all subscriptions")
+	}
+
+	if len(subscriptions) == 0 {
+		s.l.Info("no subscriptions found")
+		return nil
+	}
+
+	var mu sync.Mutex
+
+	seen := make(map[string]struct{})
+	scrapedSubscriptionIDs := make([]string, 0, len(subscriptions))
+
+	errCh := s.scrapeSubscriptions(ctx, subscriptions, func(ctx context.Context, group subscriptionGroup) error {
+		listings, err := s.scrape(ctx, maps.Clone(group.params))
+		if err != nil {
+			return errors.Wrap(err, "failed to scrape listings by subscription IDs "+group.subscriptionIDs())
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, listing := range listings {
+			seen[listing.ID] = struct{}{}
+		}
+
+		for _, sub := range group.subscriptions {
+			scrapedSubscriptionIDs = append(scrapedSubscriptionIDs, sub.ID)
+		}
+
+		return nil
+	})
+
+	var errs []error
+	for err = range errCh {
+		errs = append(errs, err)
+	}
+
+	if len(scrapedSubscriptionIDs) == 0 {
+		return errors.Wrap(stderrors.Join(errs...), "failed to scrape all listings")
+	}
+
+	for _, err = range errs {
+		s.l.Warn("failed to scrape all listings, the cars of the query are not counted as missing",
+			logger.ErrAttr(err))
+	}
+
+	now := s.now().UTC()
+	seenListingIDs := slices.Collect(maps.Keys(seen))
+
+	if err = s.repo.MarkCarsSeen(ctx, ds.MarkCarsSeenRequest{
+		ListingIDs: seenListingIDs,
+		SeenAt:     now,
+	}); err != nil {
+		return errors.Wrap(err, "failed to mark cars as seen")
+	}
+
+	missing, err := s.repo.MarkCarsMissing(ctx, ds.MarkCarsMissingRequest{
+		SubscriptionIDs: scrapedSubscriptionIDs,
+		SeenListingIDs:  seenListingIDs,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to mark cars as missing")
+	}
+
+	removed := 0
+
+	for _, car := range missing {
+		if car.MissingCount >= s.removal.MissingScrapes && s.confirmRemoval(ctx, car, now) {
+			removed++
+		}
+	}
+
+	s.l.Info("detected removed listings",
+		logger.IntAttr("seen", len(seenListingIDs)),
+		logger.IntAttr("missing", len(missing)),
+		logger.IntAttr("removed", removed),
+	)
+
+	return nil
+}
+
+// confirmRemoval checks the detail page of the missing car and marks the car as removed if the removal is confirmed.
+// A car which is still on the site, e.g. it's out of the page limit of the search results, is marked as seen,
+// so its detail page is checked again only after MissingScrapes full scrapes.
+// The check is repeated on the next full scrape, so an error is only logged.
+func (s *Service) confirmRemoval(ctx context.Context, car ds.MissingCarResponse, now time.Time) bool {
+	l := s.l.With(logger.StringAttr("listing_id", car.ListingID))
+
+	removed, err := s.paAdapter.IsListingRemoved(ctx, car.Link)
+	if err != nil {
+		l.Warn("failed to check if listing is removed", logger.ErrAttr(err))
+		return false
+	}
+
+	if !removed {
+		if err = s.repo.MarkCarsSeen(ctx, ds.MarkCarsSeenRequest{
+			ListingIDs: []string{car.ListingID},
+			SeenAt:     now,
+		}); err != nil {
+			l.Warn("failed to mark car as seen", logger.ErrAttr(err))
+		}
+
+		return false
+	}
+
+	notifications, err := s.repo.MarkCarRemoved(ctx, ds.MarkCarRemovedRequest{
+		ListingID: car.ListingID,
+		RemovedAt: now,
+		Notify:    s.removal.Notify,
+	})
+	if err != nil {
+		l.Warn("failed to mark car as removed", logger.ErrAttr(err))
+		return false
+	}
+
+	l.Info("listing is removed",
+		logger.IntAttr("missing_scrapes", car.MissingCount),
+		logger.Int64Attr("notifications", notifications),
+	)
+
+	return true
+}
+
+// CheckWatchedListings checks the detail pages of the cars of the watchlists, so a watched car is tracked
+// even if it's not in the search results of any subscription.
+// The price of a car which is still on the site is updated, a car which is not is marked as removed.
+// A car which failed to be checked is checked again on the next scrape, so an error is only logged.
+func (s *Service) CheckWatchedListings(ctx context.Context) error {
+	cars, err := s.repo.GetWatchedCars(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get watched cars")
+	}
+
+	now := s.now().UTC()
+	removed := 0
+
+	for _, car := range cars {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "failed to check watched listings")
+		}
+
+		if s.checkWatchedCar(ctx, car, now) {
+			removed++
+		}
+	}
+
+	s.l.Info("checked watched listings",
+		logger.IntAttr("watched", len(cars)),
+		logger.IntAttr("removed", removed),
+	)
+
+	return nil
+}
+
+// checkWatchedCar checks the detail page of the watched car, true is returned if the car is removed.
+// The page is fetched once for the details, which report the removal too, the removal is checked separately
+// only if the details failed for another reason, e.g. the page was not parsed.
+func (s *Service) checkWatchedCar(ctx context.Context, car ds.WatchedCarResponse, now time.Time) bool {
+	l := s.l.With(logger.StringAttr("listing_id", car.ListingID))
+
+	details, detailsErr := s.paAdapter.GetListingDetails(ctx, car.Link)
+
+	removed := errors.Is(detailsErr, polovniauto.ErrListingRemoved)
+	if detailsErr != nil && !removed {
+		var err error
+
+		removed, err = s.paAdapter.IsListingRemoved(ctx, car.Link)
+		if err != nil {
+			l.Warn("failed to check if watched listing is removed", logger.ErrAttr(err))
+			return false
+		}
+
+		if !removed {
+			l.Warn("failed to get watched listing details", logger.ErrAttr(detailsErr))
+			return false
+		}
+	}
+
+	if removed {
+		notifications, err := s.repo.MarkCarRemoved(ctx, ds.MarkCarRemovedRequest{
+			ListingID: car.ListingID,
+			RemovedAt: now,
+			Notify:    s.removal.Notify,
+		})
+		if err != nil {
+			l.Warn("failed to mark watched car as removed", logger.ErrAttr(err))
+			return false
+		}
+
+		l.Info("watched listing is removed", logger.Int64Attr("notifications", notifications))
+
+		return true
+	}
+
+	// the price is not shown, e.g. "Na upit", so the known price is kept
+	if !details.PriceEUR.Valid {
+		if err := s.repo.MarkCarsSeen(ctx, ds.MarkCarsSeenRequest{
+			ListingIDs: []string{car.ListingID},
+			SeenAt:     now,
+		}); err != nil {
+			l.Warn("failed to mark watched car as seen", logger.ErrAttr(err))
+		}
+
+		return false
+	}
+
+	if err := s.repo.UpdateCarPrice(ctx, ds.UpdateCarPriceRequest{
+		ListingID: car.ListingID,
+		Price:     details.Price,
+		PriceEUR:  details.PriceEUR,
+		SeenAt:    now,
+	}); err != nil {
+		l.Warn("failed to update watched car price", logger.ErrAttr(err))
+		return false
+	}
+
+	if err := s.repo.AddListingPrice(ctx, ds.ListingPriceRequest{
+		ListingID:   car.ListingID,
+		Price:       details.Price,
+		PriceEUR:    details.PriceEUR,
+		ObservedAt:  now,
+		StaleBefore: now.Add(-priceSampleInterval),
+	}); err != nil {
+		l.Warn("failed to add watched car price", logger.ErrAttr(err))
+	}
+
+	return false
+}
+
+// checkWatchedListings checks the cars of the watchlists after the scrape.
+// The watchlists are checked again on the next scrape, so an error is only logged.
+func (s *Service) checkWatchedListings(ctx context.Context) {
+	if err := s.CheckWatchedListings(ctx); err != nil {
+		s.l.Error("failed to check watched listings", logger.ErrAttr(err))
+	}
+}
+
+// scrapeSubscriptions groups the subscriptions with the same query and scrapes each group
+// using the provided scrape function, so every distinct query is scraped only once.
 func (s *Service) scrapeSubscriptions(
 	ctx context.Context,
 	subscriptions []ds.SubscriptionResponse,
-	scrapeFunc func(context.Context, ds.SubscriptionResponse) error,
+	scrapeFunc func(context.Context, subscriptionGroup) error,
 ) chan error {
 	var wg sync.WaitGroup
 
-	errCh := make(chan error, len(subscriptions))
-	tasks := make(chan ds.SubscriptionResponse, len(subscriptions))
+	groups := s.groupSubscriptions(subscriptions)
+
+	s.l.Info("subscriptions grouped by query",
+		logger.IntAttr("subscriptions", len(subscriptions)),
+		logger.IntAttr("queries", len(groups)),
+		logger.IntAttr("saved_queries", len(subscriptions)-len(groups)),
+	)
+
+	errCh := make(chan error, len(groups))
+	tasks := make(chan subscriptionGroup, len(groups))
 
 	for i := 0; i < s.workers; i++ {
 		wg.Add(1)
@@ -159,12 +515,12 @@ func (s *Service) scrapeSubscriptions(
 		go func() {
 			defer wg.Done()
 
-			for sub := range tasks {
+			for group := range tasks {
 				select {
 				case <-ctx.Done():
 					return
 				default:
-					if err := scrapeFunc(ctx, sub); err != nil {
+					if err := scrapeFunc(ctx, group); err != nil {
 						errCh <- err
 					}
 				}
@@ -172,8 +528,8 @@ func (s *Service) scrapeSubscriptions(
 		}()
 	}
 
-	for _, sub := range subscriptions {
-		tasks <- sub
+	for _, group := range groups {
+		tasks <- group
 	}
 
 	close(tasks)
@@ -184,34 +540,67 @@ func (s *Service) scrapeSubscriptions(
 	return errCh
 }
 
-// scrapeAllListings scrapes all listings for a subscription.
-func (s *Service) scrapeAllListings(ctx context.Context, sub ds.SubscriptionResponse) error {
-	params := s.subscriptionToParams(sub)
+// groupSubscriptions groups the subscriptions by the query key of their parameters, keeping the order of the groups.
+func (s *Service) groupSubscriptions(subscriptions []ds.SubscriptionResponse) []subscriptionGroup {
+	groups := make([]subscriptionGroup, 0, len(subscriptions))
+	groupIdx := make(map[string]int, len(subscriptions))
 
-	listings, err := s.scrape(ctx, params)
+	for _, sub := range subscriptions {
+		params := s.subscriptionToParams(sub)
+		key := queryKey(params)
+
+		if idx, exists := groupIdx[key]; exists {
+			groups[idx].subscriptions = append(groups[idx].subscriptions, sub)
+			continue
+		}
+
+		groupIdx[key] = len(groups)
+		groups = append(groups, subscriptionGroup{
+			params:        params,
+			subscriptions: []ds.SubscriptionResponse{sub},
+		})
+	}
+
+	return groups
+}
+
+// scrapeAllListings scrapes all listings for a group of subscriptions.
+func (s *Service) scrapeAllListings(ctx context.Context, group subscriptionGroup) error {
+	listings, err := s.scrape(ctx, group.params)
 	if err != nil {
-		return errors.Wrap(err, "failed to scrape listings by subscription ID "+sub.ID)
+		return errors.Wrap(err, "failed to scrape listings by subscription IDs "+group.subscriptionIDs())
+	}
+
+	var errs []error
+
+	for _, sub := range group.subscriptions {
+		if err = s.saveAllListings(ctx, sub, listings); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
+	return stderrors.Join(errs...)
+}
+
+// saveAllListings saves all scraped listings for a subscription.
+func (s *Service) saveAllListings(ctx context.Context, sub ds.SubscriptionResponse, listings []polovniauto.Listing) error {
+	listings = filterByKeywords(sub, listings)
+
 	if len(listings) == 0 {
 		s.l.Info("no listings found for subscription", logger.StringAttr("subscriptionID", sub.ID))
 		return nil
 	}
 
 	for _, listing := range listings {
-		if err = s.repo.UpsertListing(ctx, ds.UpsertListingRequest{
+		car := carFromListing(listing, sub.Brand, s.detectModel(sub, listing.Link), ds.ListingDetails{})
+
+		if err := s.saveListing(ctx, car, ds.UpsertSubscriptionMatchRequest{
 			ListingID:      listing.ID,
 			SubscriptionID: sub.ID,
-			Title:          listing.Title,
 			Price:          listing.Price,
 			NewPrice:       null.NewString("", false),
-			EngineVolume:   listing.EngineVolume,
-			Transmission:   listing.Transmission,
-			BodyType:       listing.BodyType,
-			Mileage:        listing.Mileage,
-			Location:       listing.Location,
-			Link:           listing.Link,
-			Date:           listing.Date,
+			PriceEUR:       listing.PriceEUR,
+			NewPriceEUR:    decimal.NullDecimal{},
 			IsNeedSend:     false,
 		}); err != nil {
 			return errors.Wrap(err, "failed to scrape listings for subscription ID "+sub.ID)
@@ -223,17 +612,44 @@ func (s *Service) scrapeAllListings(ctx context.Context, sub ds.SubscriptionResp
 	return nil
 }
 
-// scrapeNewListings scrapes new listings for the past 24 hours for a subscription.
-func (s *Service) scrapeNewListings(ctx context.Context, sub ds.SubscriptionResponse) error {
-	params := s.subscriptionToParams(sub)
+// scrapeNewListings scrapes new listings for the past 24 hours for a group of subscriptions.
+func (s *Service) scrapeNewListings(ctx context.Context, group subscriptionGroup) error {
+	params := maps.Clone(group.params)
 	params["sort"] = "renewDate_desc"
 	params["date_limit"] = "1" // last 24h
 
 	listings, err := s.scrape(ctx, params)
 	if err != nil {
-		return errors.Wrap(err, "failed to scrape listings by subscription ID "+sub.ID)
+		return errors.Wrap(err, "failed to scrape listings by subscription IDs "+group.subscriptionIDs())
 	}
 
+	// the details and the photo hash of a listing are fetched once for all subscriptions of the group
+	details := make(map[string]ds.ListingDetails)
+	imageHashes := make(map[string]null.Int)
+
+	var errs []error
+
+	for _, sub := range group.subscriptions {
+		if err = s.saveNewListings(ctx, sub, listings, details, imageHashes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+// saveNewListings saves the new listings and the listings with changed price for a subscription.
+// A new listing which is a relist of a listing already seen by the subscription is only sent
+// if its price differs from the price the subscriber knows.
+func (s *Service) saveNewListings(
+	ctx context.Context,
+	sub ds.SubscriptionResponse,
+	listings []polovniauto.Listing,
+	details map[string]ds.ListingDetails,
+	imageHashes map[string]null.Int,
+) error {
+	listings = filterByKeywords(sub, listings)
+
 	if len(listings) == 0 {
 		s.l.Info("no listings found for subscription", logger.StringAttr("subscriptionID", sub.ID))
 		return nil
@@ -251,41 +667,64 @@ func (s *Service) scrapeNewListings(ctx context.Context, sub ds.SubscriptionResp
 		isNeedSend = false
 	}
 
-	existingListingIDSet := make(map[string]string, len(existListings))
+	existingListingIDSet := make(map[string]ds.ListingResponse, len(existListings))
 	for _, existListing := range existListings {
-		existingListingIDSet[existListing.ListingID] = existListing.Price
+		existingListingIDSet[existListing.ListingID] = existListing
 	}
 
 	for _, listing := range listings {
-		oldPrice, exists := existingListingIDSet[listing.ID]
+		existListing, exists := existingListingIDSet[listing.ID]
 
 		// check if the listing exists
-		if exists && listing.Price == oldPrice {
+		if exists && !isPriceChanged(existListing, listing) {
+			s.sampleListingPrice(ctx, listing)
 			continue
 		}
 
-		req := ds.UpsertListingRequest{ //nolint:exhaustruct,nolintlint
+		match := ds.UpsertSubscriptionMatchRequest{ //nolint:exhaustruct,nolintlint
 			ListingID:      listing.ID,
 			SubscriptionID: sub.ID,
-			Title:          listing.Title,
-			EngineVolume:   listing.EngineVolume,
-			Transmission:   listing.Transmission,
-			BodyType:       listing.BodyType,
-			Mileage:        listing.Mileage,
-			Location:       listing.Location,
-			Link:           listing.Link,
-			Date:           listing.Date,
 			IsNeedSend:     isNeedSend, // it's important for send
 		}
 
 		if !exists {
-			req.Price = listing.Price
+			match.Price = listing.Price
+			match.PriceEUR = listing.PriceEUR
 		} else {
-			req.Price = oldPrice
-			req.NewPrice = null.NewString(listing.Price, listing.Price != "")
+			match.Price = existListing.Price
+			match.PriceEUR = existListing.PriceEUR
+			match.NewPrice = null.NewString(listing.Price, listing.Price != "")
+			match.NewPriceEUR = listing.PriceEUR
+		}
+
+		// details are only needed for listings which will be sent to the user
+		var listingDetails ds.ListingDetails
+
+		if isNeedSend {
+			var fetched bool
+
+			listingDetails, fetched = details[listing.Link]
+			if !fetched {
+				listingDetails = s.getListingDetails(ctx, listing.Link)
+				details[listing.Link] = listingDetails
+			}
 		}
 
-		if err = s.repo.UpsertListing(ctx, req); err != nil {
+		car := carFromListing(listing, sub.Brand, s.detectModel(sub, listing.Link), listingDetails)
+
+		if !exists && isNeedSend {
+			imageHash, fetched := imageHashes[listing.ImageURL]
+			if !fetched {
+				imageHash = s.getImageHash(ctx, listing.ImageURL)
+				imageHashes[listing.ImageURL] = imageHash
+			}
+
+			car.ImageHash = imageHash
+
+			s.markRelist(sub, listing, car, existListings, &match)
+		}
+
+		if err = s.saveListing(ctx, car, match); err != nil {
 			return errors.Wrap(err, "failed to upsert listings for subscription ID "+sub.ID)
 		}
 	}
@@ -295,6 +734,81 @@ func (s *Service) scrapeNewListings(ctx context.Context, sub ds.SubscriptionResp
 	return nil
 }
 
+// sampleListingPrice records the unchanged price of the known listing once the last observation is stale,
+// so the price chart has data points while the price doesn't change.
+// The price history is optional, so an error is only logged.
+func (s *Service) sampleListingPrice(ctx context.Context, listing polovniauto.Listing) {
+	now := s.now().UTC()
+	if err := s.repo.AddListingPrice(ctx, ds.ListingPriceRequest{
+		ListingID:   listing.ID,
+		Price:       listing.Price,
+		PriceEUR:    listing.PriceEUR,
+		ObservedAt:  now,
+		StaleBefore: now.Add(-priceSampleInterval),
+	}); err != nil {
+		s.l.Warn("failed to sample listing price",
+			logger.ErrAttr(err),
+			logger.StringAttr("listing_id", listing.ID),
+		)
+	}
+}
+
+// markRelist links the match of the new car to the listing of the same car the subscription has already seen,
+// the alert is suppressed if the price is the same, otherwise it's labelled with the previous price.
+func (s *Service) markRelist(
+	sub ds.SubscriptionResponse,
+	listing polovniauto.Listing,
+	car ds.UpsertCarRequest,
+	existListings []ds.ListingResponse,
+	match *ds.UpsertSubscriptionMatchRequest,
+) {
+	prev, ok := fingerprint.FindRelist(carFingerprint(car), car.ListingID, existListings, s.now())
+	if !ok {
+		return
+	}
+
+	match.RelistedFrom = null.StringFrom(prev.ListingID)
+	match.RelistedPrice = null.StringFrom(prev.Price)
+	match.IsNeedSend = isPriceChanged(prev, listing)
+
+	s.l.Info("relisted listing detected",
+		logger.StringAttr("subscriptionID", sub.ID),
+		logger.StringAttr("listing_id", car.ListingID),
+		logger.StringAttr("relisted_from", prev.ListingID),
+		logger.BoolAttr("suppressed", !match.IsNeedSend),
+	)
+}
+
+// saveListing saves the car, which is shared by all subscriptions, its price and its match with the subscription
+// in a transaction, so the match is never saved without the car and the price it was compared with.
+func (s *Service) saveListing(
+	ctx context.Context, car ds.UpsertCarRequest, match ds.UpsertSubscriptionMatchRequest,
+) error {
+	return s.repo.WithTx(ctx, func(repo repository.DB) error {
+		if err := repo.UpsertCar(ctx, car); err != nil {
+			return errors.Wrap(err, "failed to upsert car")
+		}
+
+		// the price is only recorded when it differs from the last observed one or the last observation is stale
+		now := s.now().UTC()
+		if err := repo.AddListingPrice(ctx, ds.ListingPriceRequest{
+			ListingID:   car.ListingID,
+			Price:       car.Price,
+			PriceEUR:    car.PriceEUR,
+			ObservedAt:  now,
+			StaleBefore: now.Add(-priceSampleInterval),
+		}); err != nil {
+			return errors.Wrap(err, "failed to add listing price")
+		}
+
+		if err := repo.UpsertSubscriptionMatch(ctx, match); err != nil {
+			return errors.Wrap(err, "failed to upsert subscription match")
+		}
+
+		return nil
+	})
+}
+
 // scrape retrieves and processes car listings by parameters.
 func (s *Service) scrape(ctx context.Context, params map[string]string) ([]polovniauto.Listing, error) {
 	s.l.Info("scraping started")
@@ -309,6 +823,185 @@ func (s *Service) scrape(ctx context.Context, params map[string]string) ([]polov
 	return listings, nil
 }
 
+// getListingDetails retrieves the listing details from the detail page.
+// Details are optional, so an error is only logged and empty details are returned.
+func (s *Service) getListingDetails(ctx context.Context, link string) ds.ListingDetails {
+	if link == "" {
+		return ds.ListingDetails{}
+	}
+
+	details, err := s.paAdapter.GetListingDetails(ctx, link)
+	if err != nil {
+		s.l.Warn("failed to get listing details", logger.ErrAttr(err), logger.StringAttr("link", link))
+		return ds.ListingDetails{}
+	}
+
+	return ds.ListingDetails{
+		FuelType:        details.FuelType,
+		PowerKW:         details.PowerKW,
+		PowerHP:         details.PowerHP,
+		Color:           details.Color,
+		Drive:           details.Drive,
+		Doors:           details.Doors,
+		RegisteredUntil: details.RegisteredUntil,
+		DamageStatus:    details.DamageStatus,
+		SellerType:      details.SellerType,
+		GalleryURLs:     details.GalleryURLs,
+	}
+}
+
+// getImageHash retrieves the hash of the listing photo, which is used to detect the relisted cars.
+// The photo is optional, so an error is only logged and an unknown hash is returned.
+func (s *Service) getImageHash(ctx context.Context, imageURL string) null.Int {
+	if imageURL == "" {
+		return null.NewInt(0, false)
+	}
+
+	hash, err := s.paAdapter.GetImageHash(ctx, imageURL)
+	if err != nil {
+		s.l.Warn("failed to get image hash", logger.ErrAttr(err), logger.StringAttr("image_url", imageURL))
+		return null.NewInt(0, false)
+	}
+
+	// the hash is stored in a signed column, only its bits matter
+	return null.IntFrom(int64(hash)) //nolint:gosec,nolintlint
+}
+
+// refreshMarketStats recomputes the market prices and the time on market from the scraped cars.
+// The stats are optional, so an error is only logged.
+func (s *Service) refreshMarketStats(ctx context.Context) {
+	groups, err := s.repo.RefreshMarketStats(ctx, ds.RefreshMarketStatsRequest{
+		MileageBandKM: marketstats.MileageBandKM,
+		SeenAfter:     s.now().UTC().AddDate(0, 0, -marketstats.WindowDays),
+	})
+	if err != nil {
+		s.l.Warn("failed to refresh market stats", logger.ErrAttr(err))
+		return
+	}
+
+	s.l.Info("market stats refreshed", logger.Int64Attr("groups", groups))
+}
+
+// deleteOrphanCars deletes the cars which are not matched by any subscription anymore, e.g. after the subscription
+// was removed, once they are out of the window of the market stats.
+// The cleanup is optional, so an error is only logged.
+func (s *Service) deleteOrphanCars(ctx context.Context) {
+	deleted, err := s.repo.DeleteOrphanCars(ctx, s.now().UTC().AddDate(0, 0, -marketstats.WindowDays))
+	if err != nil {
+		s.l.Warn("failed to delete orphan cars", logger.ErrAttr(err))
+		return
+	}
+
+	s.l.Info("orphan cars deleted", logger.Int64Attr("cars", deleted))
+}
+
+// detectModel returns the model of the listing detected from its link,
+// if the link has no known model, the only model of the subscription is used.
+func (s *Service) detectModel(sub ds.SubscriptionResponse, link string) string {
+	models, _ := s.carsList.Get(sub.Brand)
+
+	if model := modelFromLink(link, sub.Brand, models); model != "" {
+		return model
+	}
+
+	if len(sub.Model) == 1 {
+		return sub.Model[0]
+	}
+
+	return ""
+}
+
+// modelFromLink returns the longest model which the slug of the link starts with after the brand,
+// e.g. "320" for "/auto-oglasi/25000001/bmw-320-d", an empty string is returned if there is no such model.
+func modelFromLink(link, brand string, models []string) string {
+	if brand == "" {
+		return ""
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	slug, ok := strings.CutPrefix(path.Base(u.Path), brand+"-")
+	if !ok {
+		return ""
+	}
+
+	var model string
+
+	for _, m := range models {
+		if len(m) > len(model) && (slug == m || strings.HasPrefix(slug, m+"-")) {
+			model = m
+		}
+	}
+
+	return model
+}
+
+// filterByKeywords returns the listings whose titles match the include and exclude keywords of the subscription.
+func filterByKeywords(sub ds.SubscriptionResponse, listings []polovniauto.Listing) []polovniauto.Listing {
+	if len(sub.IncludeKeywords) == 0 && len(sub.ExcludeKeywords) == 0 {
+		return listings
+	}
+
+	filtered := make([]polovniauto.Listing, 0, len(listings))
+
+	for _, listing := range listings {
+		if keywords.Match(listing.Title, sub.IncludeKeywords, sub.ExcludeKeywords) {
+			filtered = append(filtered, listing)
+		}
+	}
+
+	return filtered
+}
+
+// carFromListing converts a scraped listing to the car with its current price.
+func carFromListing(listing polovniauto.Listing, brand, model string, details ds.ListingDetails) ds.UpsertCarRequest {
+	return ds.UpsertCarRequest{
+		ListingID:       listing.ID,
+		Title:           listing.Title,
+		Price:           listing.Price,
+		EngineVolume:    listing.EngineVolume,
+		Transmission:    listing.Transmission,
+		BodyType:        listing.BodyType,
+		Mileage:         listing.Mileage,
+		Location:        listing.Location,
+		Link:            listing.Link,
+		Date:            listing.Date,
+		Details:         details,
+		PriceEUR:        listing.PriceEUR,
+		MileageKM:       listing.MileageKM,
+		EngineVolumeCM3: listing.EngineVolumeCM3,
+		Year:            listing.ProductionYear,
+		Brand:           brand,
+		Model:           model,
+		ImageURL:        listing.ImageURL,
+	}
+}
+
+// carFingerprint returns the fingerprint of the scraped car.
+func carFingerprint(car ds.UpsertCarRequest) fingerprint.Fingerprint {
+	return fingerprint.Fingerprint{
+		Title:           car.Title,
+		Year:            car.Year,
+		MileageKM:       car.MileageKM,
+		EngineVolumeCM3: car.EngineVolumeCM3,
+		Location:        car.Location,
+		ImageHash:       car.ImageHash,
+	}
+}
+
+// isPriceChanged checks if the price of the scraped listing differs from the stored one.
+// Typed prices are compared when both are known, otherwise it falls back to the raw strings.
+func isPriceChanged(existListing ds.ListingResponse, listing polovniauto.Listing) bool {
+	if existListing.PriceEUR.Valid && listing.PriceEUR.Valid {
+		return !existListing.PriceEUR.Decimal.Equal(listing.PriceEUR.Decimal)
+	}
+
+	return existListing.Price != listing.Price
+}
+
 // recoverPanic recovers from a panic and logs the error.
 func (s *Service) recoverPanic() {
 	if r := recover(); r != nil {
@@ -322,35 +1015,47 @@ func (s *Service) recoverPanic() {
 
 // subscriptionToParams converts a subscription to a map of query parameters.
 func (s *Service) subscriptionToParams(subscription ds.SubscriptionResponse) map[string]string {
-	params := map[string]string{
-		"brand":      subscription.Brand,
-		"price_from": subscription.PriceFrom,
-		"price_to":   subscription.PriceTo,
-		"year_from":  subscription.YearFrom,
-		"year_to":    subscription.YearTo,
-		"showOldNew": "all",
-	}
+	catalog := idCatalog{chassisList: s.chassisList, searchFilters: s.searchFilters}
 
-	if len(subscription.Model) > 0 {
-		params["model[]"] = strings.Join(subscription.Model, ",")
-	}
+	return searchparams.FromSubscription(subscription, catalog)
+}
 
-	if len(subscription.Region) > 0 {
-		params["region[]"] = strings.Join(subscription.Region, ",")
+// GetCarChassisList returns the IDs of the chassis by their names.
+func (c idCatalog) GetCarChassisList() map[string]string {
+	return c.chassisList.CopyMap()
+}
+
+// GetSearchFilterOptions returns the IDs of the options of the search filter by their names.
+func (c idCatalog) GetSearchFilterOptions(filter ds.SearchFilter) map[string]string {
+	options, _ := c.searchFilters.Get(filter)
+
+	return options
+}
+
+// subscriptionIDs returns the comma separated IDs of the subscriptions in the group.
+func (g subscriptionGroup) subscriptionIDs() string {
+	ids := make([]string, len(g.subscriptions))
+	for i, sub := range g.subscriptions {
+		ids[i] = sub.ID
 	}
 
-	if len(subscription.Chassis) > 0 {
-		var mappedChassis []string
+	return strings.Join(ids, ",")
+}
+
+// queryKey builds a canonical key of the query parameters, the order of the values
+// of the multi-value parameters does not change the search results, so they are sorted.
+func queryKey(params map[string]string) string {
+	values := make(url.Values, len(params))
 
-		// map chassis names to IDs
-		for _, chassisName := range subscription.Chassis {
-			if chassisID, exists := s.chassisList.Get(chassisName); exists {
-				mappedChassis = append(mappedChassis, chassisID)
-			}
+	for key, value := range params {
+		if strings.HasSuffix(key, "[]") {
+			items := strings.Split(value, ",")
+			slices.Sort(items)
+			value = strings.Join(items, ",")
 		}
 
-		params["chassis[]"] = strings.Join(mappedChassis, ",")
+		values.Set(key, value)
 	}
 
-	return params
+	return values.Encode()
 }
@@ -6,10 +6,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
@@ -21,6 +25,7 @@ type ServiceTestSuite struct {
 	suite.Suite
 	ctrl             *gomock.Controller
 	mockRepo         *MockRepository
+	mockTxRepo       *repository.MockDB
 	mockPpolovniAuto *MockPolovniAutoAdapter
 	mockFetcher      *MockFetcher
 	svc              *Service
@@ -30,6 +35,7 @@ func (s *ServiceTestSuite) SetupTest() {
 	s.ctrl = gomock.NewController(s.T())
 	lg := logger.NewLogger()
 	s.mockRepo = NewMockRepository(s.ctrl)
+	s.mockTxRepo = repository.NewMockDB(s.ctrl)
 	s.mockPpolovniAuto = NewMockPolovniAutoAdapter(s.ctrl)
 	s.svc = NewService(
 		lg,
@@ -38,20 +44,38 @@ func (s *ServiceTestSuite) SetupTest() {
 		s.mockFetcher,
 		10*time.Second,
 		5,
+		RemovalConfig{FullScrapeInterval: time.Hour, MissingScrapes: 3, Notify: true},
 	)
 
 	s.svc.chassisList.SetBatch(map[string]string{
 		"Limuzina": "277",
 		"Pickup":   "2635",
 	})
+	s.svc.carsList.SetBatch(map[string][]string{
+		"bmw": {"m3", "m5", "m5-competition"},
+	})
+	s.svc.searchFilters.SetBatch(map[ds.SearchFilter]map[string]string{
+		ds.SearchFilterFuel:    {"Benzin": "45", "Dizel": "2309"},
+		ds.SearchFilterGearbox: {"Automatski / poluautomatski": "3212"},
+	})
 }
 
 func (s *ServiceTestSuite) TearDownTest() {
 	s.ctrl.Finish()
 }
 
+// expectTx expects a transaction which runs its function with the transaction repository mock.
+func (s *ServiceTestSuite) expectTx() {
+	s.mockRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repo repository.DB) error) error {
+			return fn(s.mockTxRepo)
+		}).
+		Times(1)
+}
+
 func (s *ServiceTestSuite) TestService_ScrapeAllListings() {
-	now := time.Now()
+	now := time.Now().UTC()
+	s.svc.now = func() time.Time { return now }
 	listingID := uuid.NewString()
 	subID := uuid.NewString()
 
@@ -96,12 +120,28 @@ func (s *ServiceTestSuite) TestService_ScrapeAllListings() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingID,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingID,
+					Price:       "2000€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
 					ListingID:      listingID,
 					SubscriptionID: subID,
-					Title:          "Best bmw",
 					Price:          "2000€",
-					Date:           now,
 					IsNeedSend:     false,
 				}).
 					Return(nil).
@@ -222,13 +262,13 @@ func (s *ServiceTestSuite) TestService_ScrapeAllListings() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
-					ListingID:      listingID,
-					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2000€",
-					Date:           now,
-					IsNeedSend:     false,
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingID,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Date:      now,
+					Brand:     "bmw",
 				}).
 					Return(errCommon).
 					Times(1)
@@ -259,10 +299,26 @@ func (s *ServiceTestSuite) TestService_ScrapeAllListings() {
 }
 
 func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
-	now := time.Now()
+	now := time.Now().UTC()
+	s.svc.now = func() time.Time { return now }
 	listingIDExist := "1"
 	listingIDNotExist := "2"
+	listingIDOther := "3"
 	subID := uuid.NewString()
+	otherSubID := uuid.NewString()
+
+	// expectPriceSample expects the unchanged price of the known listing to be recorded if it's stale
+	expectPriceSample := func(priceEUR decimal.NullDecimal, err error) {
+		s.mockRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+			ListingID:   listingIDExist,
+			Price:       "2400€",
+			PriceEUR:    priceEUR,
+			ObservedAt:  now,
+			StaleBefore: now.Add(-priceSampleInterval),
+		}).
+			Return(err).
+			Times(1)
+	}
 
 	testCases := []struct {
 		name      string
@@ -326,12 +382,29 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 							IsNeedSend:     false,
 						},
 					}, nil)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
+				expectPriceSample(decimal.NullDecimal{}, nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDNotExist,
+					Price:       "2000€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
 					ListingID:      listingIDNotExist,
 					SubscriptionID: subID,
-					Title:          "Best bmw",
 					Price:          "2000€",
-					Date:           now,
 					IsNeedSend:     true,
 				}).
 					Return(nil).
@@ -339,15 +412,7 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 			},
 		},
 		{
-			name: "success no subscriptions find",
-			mock: func() {
-				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
-					Return([]ds.SubscriptionResponse{}, nil).
-					Times(1)
-			},
-		},
-		{
-			name: "success no listings find",
+			name: "success with typed price change",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
 					Return([]ds.SubscriptionResponse{
@@ -374,12 +439,82 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 					"date_limit": "1",
 					"showOldNew": "all",
 				}).
-					Return([]polovniauto.Listing{}, nil).
+					Return([]polovniauto.Listing{
+						{
+							ID:       listingIDExist,
+							Title:    "Best audi",
+							Price:    "2400€",
+							PriceEUR: decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+							Date:     now,
+						},
+						{
+							ID:        listingIDOther,
+							Title:     "Best bmw",
+							Price:     "1900€",
+							PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(1900)),
+							MileageKM: null.IntFrom(150000),
+							Date:      now,
+						},
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+					Return([]ds.ListingResponse{
+						{
+							ID:             uuid.NewString(),
+							ListingID:      listingIDExist,
+							SubscriptionID: subID,
+							Title:          "Best audi",
+							Price:          "2.400 €", // same price in a different format
+							PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+							Date:           now,
+						},
+						{
+							ID:             uuid.NewString(),
+							ListingID:      listingIDOther,
+							SubscriptionID: subID,
+							Title:          "Best bmw",
+							Price:          "2000€",
+							PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+							Date:           now,
+						},
+					}, nil)
+				expectPriceSample(decimal.NewNullDecimal(decimal.NewFromInt(2400)), nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDOther,
+					Title:     "Best bmw",
+					Price:     "1900€",
+					Date:      now,
+					PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(1900)),
+					MileageKM: null.IntFrom(150000),
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDOther,
+					Price:       "1900€",
+					PriceEUR:    decimal.NewNullDecimal(decimal.NewFromInt(1900)),
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingIDOther,
+					SubscriptionID: subID,
+					Price:          "2000€",
+					NewPrice:       null.StringFrom("1900€"),
+					IsNeedSend:     true,
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+					NewPriceEUR:    decimal.NewNullDecimal(decimal.NewFromInt(1900)),
+				}).
+					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "success: first time, no need send listings",
+			name: "success with listing details",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
 					Return([]ds.SubscriptionResponse{
@@ -409,38 +544,234 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 					Return([]polovniauto.Listing{
 						{
 							ID:    listingIDNotExist,
-							Title: "Best bmw in the world",
-							Price: "2300€",
-							Year:  "2004",
+							Title: "Best bmw",
+							Price: "2000€",
+							Year:  "2001",
+							Link:  "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw",
+							Date:  now,
+						},
+						{
+							ID:    listingIDOther,
+							Title: "Best bmw m5",
+							Price: "2900€",
+							Year:  "2003",
+							Link:  "https://www.polovniautomobili.com/auto-oglasi/3/bmw-m5",
 							Date:  now,
 						},
 					}, nil).
 					Times(1)
 				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
-					Return([]ds.ListingResponse{}, nil)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
+					Return([]ds.ListingResponse{
+						{
+							ID:             uuid.NewString(),
+							ListingID:      listingIDExist,
+							SubscriptionID: subID,
+							Title:          "Best audi",
+							Price:          "2400€",
+							Date:           now,
+							IsNeedSend:     false,
+						},
+					}, nil)
+				s.mockPpolovniAuto.EXPECT().
+					GetListingDetails(gomock.Any(), "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw").
+					Return(polovniauto.ListingDetails{
+						FuelType:   "Dizel",
+						PowerKW:    135,
+						PowerHP:    184,
+						Color:      "Crna",
+						SellerType: polovniauto.SellerTypePrivate,
+					}, nil).
+					Times(1)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Link:      "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw",
+					Date:      now,
+					Details: ds.ListingDetails{
+						FuelType:   "Dizel",
+						PowerKW:    135,
+						PowerHP:    184,
+						Color:      "Crna",
+						SellerType: polovniauto.SellerTypePrivate,
+					},
+					Brand: "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDNotExist,
+					Price:       "2000€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
 					ListingID:      listingIDNotExist,
 					SubscriptionID: subID,
-					Title:          "Best bmw in the world",
-					Price:          "2300€",
-					Date:           now,
+					Price:          "2000€",
+					IsNeedSend:     true,
+				}).
+					Return(nil).
+					Times(1)
+				// details are optional, so the listing is saved without them
+				s.mockPpolovniAuto.EXPECT().
+					GetListingDetails(gomock.Any(), "https://www.polovniautomobili.com/auto-oglasi/3/bmw-m5").
+					Return(polovniauto.ListingDetails{}, errCommon).
+					Times(1)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDOther,
+					Title:     "Best bmw m5",
+					Price:     "2900€",
+					Link:      "https://www.polovniautomobili.com/auto-oglasi/3/bmw-m5",
+					Date:      now,
+					Brand:     "bmw",
+					Model:     "m5",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDOther,
+					Price:       "2900€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingIDOther,
+					SubscriptionID: subID,
+					Price:          "2900€",
+					IsNeedSend:     true,
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with relisted listings",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "volkswagen",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), gomock.Any()).
+					Return([]polovniauto.Listing{
+						{
+							ID:              "12",
+							Title:           "Volkswagen Golf 7 1.6 TDI",
+							Price:           "9.500 €",
+							Location:        "Novi Sad",
+							Link:            "https://www.polovniautomobili.com/auto-oglasi/12/volkswagen-golf-7",
+							ImageURL:        "https://images.polovniautomobili.com/user-images/thumbs/12/12_golf.jpg",
+							Date:            now,
+							PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(9500)),
+							ProductionYear:  null.IntFrom(2016),
+							EngineVolumeCM3: null.IntFrom(1598),
+							MileageKM:       null.IntFrom(182000),
+						},
+						{
+							ID:              "13",
+							Title:           "Volkswagen Passat B8 2.0 TDI",
+							Price:           "14.900 €",
+							Location:        "Beograd",
+							Link:            "https://www.polovniautomobili.com/auto-oglasi/13/volkswagen-passat-b8",
+							Date:            now,
+							PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(14900)),
+							ProductionYear:  null.IntFrom(2017),
+							EngineVolumeCM3: null.IntFrom(1968),
+							MileageKM:       null.IntFrom(210000),
+						},
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+					Return([]ds.ListingResponse{
+						{
+							ID:              uuid.NewString(),
+							ListingID:       "10",
+							SubscriptionID:  subID,
+							Title:           "Volkswagen Golf 7 1.6 TDI",
+							Price:           "9.500 €",
+							Location:        "Novi Sad",
+							PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(9500)),
+							Year:            null.IntFrom(2016),
+							EngineVolumeCM3: null.IntFrom(1598),
+							MileageKM:       null.IntFrom(182000),
+							LastSeenAt:      now.AddDate(0, 0, -2),
+						},
+						{
+							ID:              uuid.NewString(),
+							ListingID:       "11",
+							SubscriptionID:  subID,
+							Title:           "Volkswagen Passat B8 2.0 TDI",
+							Price:           "15.500 €",
+							Location:        "Beograd",
+							PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(15500)),
+							Year:            null.IntFrom(2017),
+							EngineVolumeCM3: null.IntFrom(1968),
+							MileageKM:       null.IntFrom(209500),
+							LastSeenAt:      now.AddDate(0, 0, -5),
+						},
+					}, nil)
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), gomock.Any()).
+					Return(polovniauto.ListingDetails{}, nil).
+					Times(2)
+				// the photo is optional, the previous listing has no photo hash, so the titles are compared
+				s.mockPpolovniAuto.EXPECT().
+					GetImageHash(gomock.Any(), "https://images.polovniautomobili.com/user-images/thumbs/12/12_golf.jpg").
+					Return(uint64(0), errCommon).
+					Times(1)
+				// a transaction per listing
+				s.expectTx()
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				// the same price, the subscriber has already seen the car
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      "12",
+					SubscriptionID: subID,
+					Price:          "9.500 €",
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(9500)),
 					IsNeedSend:     false,
+					RelistedFrom:   null.StringFrom("10"),
+					RelistedPrice:  null.StringFrom("9.500 €"),
+				}).
+					Return(nil).
+					Times(1)
+				// the price is lower, the alert is labelled with the previous price
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      "13",
+					SubscriptionID: subID,
+					Price:          "14.900 €",
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(14900)),
+					IsNeedSend:     true,
+					RelistedFrom:   null.StringFrom("11"),
+					RelistedPrice:  null.StringFrom("15.500 €"),
 				}).
 					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "get all subscriptions failed: common error",
+			name: "success no subscriptions find",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
-					Return([]ds.SubscriptionResponse{}, errCommon).
+					Return([]ds.SubscriptionResponse{}, nil).
 					Times(1)
 			},
-			expectErr: errCommon,
 		},
 		{
-			name: "get listings failed: common error",
+			name: "success no listings find",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
 					Return([]ds.SubscriptionResponse{
@@ -467,13 +798,12 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 					"date_limit": "1",
 					"showOldNew": "all",
 				}).
-					Return([]polovniauto.Listing{}, errCommon).
+					Return([]polovniauto.Listing{}, nil).
 					Times(1)
 			},
-			expectErr: errCommon,
 		},
 		{
-			name: "upsert listings failed: get listings common error",
+			name: "success: first time, no need send listings",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
 					Return([]ds.SubscriptionResponse{
@@ -501,41 +831,61 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 					"showOldNew": "all",
 				}).
 					Return([]polovniauto.Listing{
-						{
-							ID:    listingIDExist,
-							Title: "Best audi",
-							Price: "2400€",
-							Year:  "2002",
-							Date:  now,
-						},
 						{
 							ID:    listingIDNotExist,
-							Title: "Best bmw",
-							Price: "2000€",
-							Year:  "2001",
+							Title: "Best bmw in the world",
+							Price: "2300€",
+							Year:  "2004",
 							Date:  now,
 						},
 					}, nil).
 					Times(1)
 				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
-					Return([]ds.ListingResponse{}, errCommon)
+					Return([]ds.ListingResponse{}, nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw in the world",
+					Price:     "2300€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDNotExist,
+					Price:       "2300€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingIDNotExist,
+					SubscriptionID: subID,
+					Price:          "2300€",
+					IsNeedSend:     false,
+				}).
+					Return(nil).
+					Times(1)
 			},
-			expectErr: errCommon,
 		},
 		{
-			name: "upsert listings failed: common error",
+			name: "success: listings filtered by keywords",
 			mock: func() {
 				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
 					Return([]ds.SubscriptionResponse{
 						{
-							ID:        subID,
-							UserID:    1,
-							Brand:     "bmw",
-							Model:     []string{"m3", "m5"},
-							PriceFrom: "1000",
-							PriceTo:   "3000",
-							CreatedAt: now,
-							UpdatedAt: now,
+							ID:              subID,
+							UserID:          1,
+							Brand:           "bmw",
+							Model:           []string{"m3", "m5"},
+							PriceFrom:       "1000",
+							PriceTo:         "3000",
+							IncludeKeywords: []string{"bmw"},
+							ExcludeKeywords: []string{"oštećen"},
+							CreatedAt:       now,
+							UpdatedAt:       now,
 						},
 					}, nil).
 					Times(1)
@@ -552,65 +902,1045 @@ func (s *ServiceTestSuite) TestService_ScrapeNewListings() {
 				}).
 					Return([]polovniauto.Listing{
 						{
-							ID:    listingIDExist,
-							Title: "Best audi",
-							Price: "2400€",
-							Year:  "2002",
+							ID:    uuid.NewString(),
+							Title: "BMW M3 OSTECEN",
+							Price: "1300€",
+							Year:  "2004",
+							Date:  now,
+						},
+						{
+							ID:    uuid.NewString(),
+							Title: "M5 Competition",
+							Price: "2900€",
+							Year:  "2004",
 							Date:  now,
 						},
 						{
 							ID:    listingIDNotExist,
-							Title: "Best bmw",
-							Price: "2000€",
-							Year:  "2001",
+							Title: "Best bmw in the world",
+							Price: "2300€",
+							Year:  "2004",
 							Date:  now,
 						},
 					}, nil).
 					Times(1)
 				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingIDExist,
-							SubscriptionID: subID,
-							Title:          "Best audi",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     false,
-						},
-					}, nil)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
+					Return([]ds.ListingResponse{}, nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw in the world",
+					Price:     "2300€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDNotExist,
+					Price:       "2300€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
 					ListingID:      listingIDNotExist,
 					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2000€",
-					Date:           now,
-					IsNeedSend:     true,
+					Price:          "2300€",
+					IsNeedSend:     false,
 				}).
-					Return(errCommon).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "get all subscriptions failed: common error",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{}, errCommon).
 					Times(1)
 			},
 			expectErr: errCommon,
 		},
-	}
-
-	for _, tc := range testCases {
-		s.Run(tc.name, func() {
-			tc.mock()
+		{
+			name: "get listings failed: common error",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), map[string]string{
+					"brand":      "bmw",
+					"model[]":    "m3,m5",
+					"price_from": "1000",
+					"price_to":   "3000",
+					"year_from":  "",
+					"year_to":    "",
+					"sort":       "renewDate_desc",
+					"date_limit": "1",
+					"showOldNew": "all",
+				}).
+					Return([]polovniauto.Listing{}, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "upsert listings failed: get listings common error",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), map[string]string{
+					"brand":      "bmw",
+					"model[]":    "m3,m5",
+					"price_from": "1000",
+					"price_to":   "3000",
+					"year_from":  "",
+					"year_to":    "",
+					"sort":       "renewDate_desc",
+					"date_limit": "1",
+					"showOldNew": "all",
+				}).
+					Return([]polovniauto.Listing{
+						{
+							ID:    listingIDExist,
+							Title: "Best audi",
+							Price: "2400€",
+							Year:  "2002",
+							Date:  now,
+						},
+						{
+							ID:    listingIDNotExist,
+							Title: "Best bmw",
+							Price: "2000€",
+							Year:  "2001",
+							Date:  now,
+						},
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+					Return([]ds.ListingResponse{}, errCommon)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "success: subscriptions with the same query are scraped once",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+						{
+							ID:        otherSubID,
+							UserID:    2,
+							Brand:     "bmw",
+							Model:     []string{"m5", "m3"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), map[string]string{
+					"brand":      "bmw",
+					"model[]":    "m3,m5",
+					"price_from": "1000",
+					"price_to":   "3000",
+					"year_from":  "",
+					"year_to":    "",
+					"sort":       "renewDate_desc",
+					"date_limit": "1",
+					"showOldNew": "all",
+				}).
+					Return([]polovniauto.Listing{
+						{
+							ID:    listingIDNotExist,
+							Title: "Best bmw",
+							Price: "2000€",
+							Link:  "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw",
+							Date:  now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().
+					GetListingDetails(gomock.Any(), "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw").
+					Return(polovniauto.ListingDetails{FuelType: "Dizel"}, nil). //nolint:exhaustruct,nolintlint
+					Times(1)
+
+				for _, id := range []string{subID, otherSubID} {
+					s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), id).
+						Return([]ds.ListingResponse{
+							{
+								ID:             uuid.NewString(),
+								ListingID:      listingIDExist,
+								SubscriptionID: id,
+								Title:          "Best audi",
+								Price:          "2400€",
+								Date:           now,
+							},
+						}, nil).
+						Times(1)
+					s.expectTx()
+					s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+						ListingID: listingIDNotExist,
+						Title:     "Best bmw",
+						Price:     "2000€",
+						Link:      "https://www.polovniautomobili.com/auto-oglasi/2/best-bmw",
+						Date:      now,
+						Details:   ds.ListingDetails{FuelType: "Dizel"}, //nolint:exhaustruct,nolintlint
+						Brand:     "bmw",
+					}).
+						Return(nil).
+						Times(1)
+					s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+						ListingID:   listingIDNotExist,
+						Price:       "2000€",
+						ObservedAt:  now,
+						StaleBefore: now.Add(-priceSampleInterval),
+					}).
+						Return(nil).
+						Times(1)
+					s.mockTxRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+						ListingID:      listingIDNotExist,
+						SubscriptionID: id,
+						Price:          "2000€",
+						IsNeedSend:     true,
+					}).
+						Return(nil).
+						Times(1)
+				}
+			},
+		},
+		{
+			name: "upsert listings failed: common error",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), map[string]string{
+					"brand":      "bmw",
+					"model[]":    "m3,m5",
+					"price_from": "1000",
+					"price_to":   "3000",
+					"year_from":  "",
+					"year_to":    "",
+					"sort":       "renewDate_desc",
+					"date_limit": "1",
+					"showOldNew": "all",
+				}).
+					Return([]polovniauto.Listing{
+						{
+							ID:    listingIDExist,
+							Title: "Best audi",
+							Price: "2400€",
+							Year:  "2002",
+							Date:  now,
+						},
+						{
+							ID:    listingIDNotExist,
+							Title: "Best bmw",
+							Price: "2000€",
+							Year:  "2001",
+							Date:  now,
+						},
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+					Return([]ds.ListingResponse{
+						{
+							ID:             uuid.NewString(),
+							ListingID:      listingIDExist,
+							SubscriptionID: subID,
+							Title:          "Best audi",
+							Price:          "2400€",
+							Date:           now,
+							IsNeedSend:     false,
+						},
+					}, nil)
+				expectPriceSample(decimal.NullDecimal{}, nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "add listing price failed: common error",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							PriceFrom: "1000",
+							PriceTo:   "3000",
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), map[string]string{
+					"brand":      "bmw",
+					"model[]":    "m3,m5",
+					"price_from": "1000",
+					"price_to":   "3000",
+					"year_from":  "",
+					"year_to":    "",
+					"sort":       "renewDate_desc",
+					"date_limit": "1",
+					"showOldNew": "all",
+				}).
+					Return([]polovniauto.Listing{
+						{
+							ID:    listingIDExist,
+							Title: "Best audi",
+							Price: "2400€",
+							Year:  "2002",
+							Date:  now,
+						},
+						{
+							ID:    listingIDNotExist,
+							Title: "Best bmw",
+							Price: "2000€",
+							Year:  "2001",
+							Date:  now,
+						},
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+					Return([]ds.ListingResponse{
+						{
+							ID:             uuid.NewString(),
+							ListingID:      listingIDExist,
+							SubscriptionID: subID,
+							Title:          "Best audi",
+							Price:          "2400€",
+							Date:           now,
+							IsNeedSend:     false,
+						},
+					}, nil)
+				expectPriceSample(decimal.NullDecimal{}, nil)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().UpsertCar(gomock.Any(), ds.UpsertCarRequest{
+					ListingID: listingIDNotExist,
+					Title:     "Best bmw",
+					Price:     "2000€",
+					Date:      now,
+					Brand:     "bmw",
+				}).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   listingIDNotExist,
+					Price:       "2000€",
+					ObservedAt:  now,
+					StaleBefore: now.Add(-priceSampleInterval),
+				}).
+					Return(errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+			err := s.svc.ScrapeNewListings(ctx)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+			}
+
+			cancel()
+		})
+	}
+}
+
+// TestService_ScrapeNewListings_unchangedPrice checks that the unchanged price of a known listing is sampled
+// on every scrape, so it's recorded again once the last observation is older than the interval.
+func (s *ServiceTestSuite) TestService_ScrapeNewListings_unchangedPrice() {
+	start := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+	subID := uuid.NewString()
+
+	for _, now := range []time.Time{start, start.Add(priceSampleInterval)} {
+		s.svc.now = func() time.Time { return now }
+
+		s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+			Return([]ds.SubscriptionResponse{{ID: subID, UserID: 1, Brand: "bmw"}}, nil). //nolint:exhaustruct,nolintlint
+			Times(1)
+		s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), gomock.Any()).
+			Return([]polovniauto.Listing{
+				{
+					ID:       "1",
+					Title:    "Best bmw",
+					Price:    "2400€",
+					PriceEUR: decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+					Date:     start,
+				},
+			}, nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetListingsBySubscriptionID(gomock.Any(), subID).
+			Return([]ds.ListingResponse{
+				{
+					ListingID:      "1",
+					SubscriptionID: subID,
+					Title:          "Best bmw",
+					Price:          "2400€",
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+					Date:           start,
+				},
+			}, nil).
+			Times(1)
+		// the price observed by the previous scrape is stale for the next one, so it's recorded again
+		s.mockRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+			ListingID:   "1",
+			Price:       "2400€",
+			PriceEUR:    decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+			ObservedAt:  now,
+			StaleBefore: now.Add(-priceSampleInterval),
+		}).
+			Return(nil).
+			Times(1)
+
+		s.Require().NoError(s.svc.ScrapeNewListings(context.Background()))
+	}
+}
+
+func (s *ServiceTestSuite) TestService_DetectRemovedListings() {
+	now := time.Now()
+	s.svc.now = func() time.Time { return now }
+	subID := uuid.NewString()
+	otherSubID := uuid.NewString()
+
+	sub := ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+		ID:        subID,
+		UserID:    1,
+		Brand:     "bmw",
+		PriceFrom: "1000",
+		PriceTo:   "3000",
+	}
+	otherSub := ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+		ID:        otherSubID,
+		UserID:    2,
+		Brand:     "audi",
+		PriceFrom: "1000",
+		PriceTo:   "3000",
+	}
+	params := map[string]string{
+		"brand":      "bmw",
+		"price_from": "1000",
+		"price_to":   "3000",
+		"year_from":  "",
+		"year_to":    "",
+		"showOldNew": "all",
+	}
+	listings := []polovniauto.Listing{{ID: "1", Link: "/auto-oglasi/1/bmw-m3"}} //nolint:exhaustruct,nolintlint
+
+	testCases := []struct {
+		name      string
+		mock      func()
+		expectErr error
+	}{
+		{
+			name: "success",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{sub}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), params).
+					Return(listings, nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsSeen(gomock.Any(), ds.MarkCarsSeenRequest{
+					ListingIDs: []string{"1"},
+					SeenAt:     now.UTC(),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsMissing(gomock.Any(), ds.MarkCarsMissingRequest{
+					SubscriptionIDs: []string{subID},
+					SeenListingIDs:  []string{"1"},
+				}).
+					Return([]ds.MissingCarResponse{
+						{ListingID: "2", Link: "/auto-oglasi/2/bmw-m5", MissingCount: 3},
+						{ListingID: "3", Link: "/auto-oglasi/3/bmw-m5", MissingCount: 1},
+						{ListingID: "4", Link: "/auto-oglasi/4/bmw-m3", MissingCount: 3},
+						{ListingID: "5", Link: "/auto-oglasi/5/bmw-m3", MissingCount: 4},
+					}, nil).
+					Times(1)
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				// removed
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/2/bmw-m5").
+					Return(true, nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarRemoved(gomock.Any(), ds.MarkCarRemovedRequest{
+					ListingID: "2",
+					RemovedAt: now.UTC(),
+					Notify:    true,
+				}).
+					Return(int64(2), nil).
+					Times(1)
 
-			err := s.svc.ScrapeNewListings(ctx)
+				// still on the site, so it's seen
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/4/bmw-m3").
+					Return(false, nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsSeen(gomock.Any(), ds.MarkCarsSeenRequest{
+					ListingIDs: []string{"4"},
+					SeenAt:     now.UTC(),
+				}).
+					Return(nil).
+					Times(1)
 
-			switch {
-			case tc.expectErr != nil:
-				s.Require().Error(err)
-				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
-			default:
-				s.Require().NoError(err)
+				// the check is repeated on the next full scrape
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/5/bmw-m3").
+					Return(false, errCommon).
+					Times(1)
+			},
+			expectErr: nil,
+		},
+		{
+			name: "the cars of the failed query are not counted as missing",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{sub, otherSub}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, params map[string]string) ([]polovniauto.Listing, error) {
+						if params["brand"] == "audi" {
+							return nil, errCommon
+						}
+
+						return listings, nil
+					}).
+					Times(2)
+				s.mockRepo.EXPECT().MarkCarsSeen(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsMissing(gomock.Any(), ds.MarkCarsMissingRequest{
+					SubscriptionIDs: []string{subID},
+					SeenListingIDs:  []string{"1"},
+				}).
+					Return([]ds.MissingCarResponse{}, nil).
+					Times(1)
+			},
+			expectErr: nil,
+		},
+		{
+			name: "no subscriptions",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{}, nil).
+					Times(1)
+			},
+			expectErr: nil,
+		},
+		{
+			name: "failed to get subscriptions",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return(nil, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "all queries failed",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{sub}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), params).
+					Return(nil, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "failed to mark cars as missing",
+			mock: func() {
+				s.mockRepo.EXPECT().GetAllSubscriptions(gomock.Any()).
+					Return([]ds.SubscriptionResponse{sub}, nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetNewListings(gomock.Any(), params).
+					Return(listings, nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsSeen(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsMissing(gomock.Any(), gomock.Any()).
+					Return(nil, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock()
+
+			err := s.svc.DetectRemovedListings(context.Background())
+			if tc.expectErr != nil {
+				s.Require().ErrorIs(err, tc.expectErr)
+				return
 			}
 
-			cancel()
+			s.Require().NoError(err)
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_CheckWatchedListings() {
+	now := time.Now()
+	s.svc.now = func() time.Time { return now }
+
+	cars := []ds.WatchedCarResponse{
+		{ListingID: "1", Link: "/auto-oglasi/1/bmw-m3"},
+		{ListingID: "2", Link: "/auto-oglasi/2/bmw-m5"},
+		{ListingID: "3", Link: "/auto-oglasi/3/bmw-m5"},
+		{ListingID: "4", Link: "/auto-oglasi/4/bmw-m3"},
+	}
+
+	testCases := []struct {
+		name      string
+		mock      func()
+		expectErr error
+	}{
+		{
+			name: "success",
+			mock: func() {
+				s.mockRepo.EXPECT().GetWatchedCars(gomock.Any()).
+					Return(cars, nil).
+					Times(1)
+
+				// still on the site, so its price is updated
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/1/bmw-m3").
+					Return(polovniauto.ListingDetails{ //nolint:exhaustruct,nolintlint
+						Price:    "2200€",
+						PriceEUR: decimal.NewNullDecimal(decimal.NewFromInt(2200)),
+					}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().UpdateCarPrice(gomock.Any(), ds.UpdateCarPriceRequest{
+					ListingID: "1",
+					Price:     "2200€",
+					PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(2200)),
+					SeenAt:    now.UTC(),
+				}).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().AddListingPrice(gomock.Any(), ds.ListingPriceRequest{
+					ListingID:   "1",
+					Price:       "2200€",
+					PriceEUR:    decimal.NewNullDecimal(decimal.NewFromInt(2200)),
+					ObservedAt:  now.UTC(),
+					StaleBefore: now.UTC().Add(-priceSampleInterval),
+				}).
+					Return(nil).
+					Times(1)
+
+				// removed, the details report it, so the page is not fetched again
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/2/bmw-m5").
+					Return(polovniauto.ListingDetails{}, polovniauto.ErrListingRemoved). //nolint:exhaustruct,nolintlint
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarRemoved(gomock.Any(), ds.MarkCarRemovedRequest{
+					ListingID: "2",
+					RemovedAt: now.UTC(),
+					Notify:    true,
+				}).
+					Return(int64(1), nil).
+					Times(1)
+
+				// the price is not shown, so the car is only seen
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/3/bmw-m5").
+					Return(polovniauto.ListingDetails{}, nil). //nolint:exhaustruct,nolintlint
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarsSeen(gomock.Any(), ds.MarkCarsSeenRequest{
+					ListingIDs: []string{"3"},
+					SeenAt:     now.UTC(),
+				}).
+					Return(nil).
+					Times(1)
+
+				// failed to check, so it's checked on the next scrape
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/4/bmw-m3").
+					Return(polovniauto.ListingDetails{}, errCommon). //nolint:exhaustruct,nolintlint
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/4/bmw-m3").
+					Return(false, errCommon).
+					Times(1)
+			},
+		},
+		{
+			name: "failed to get listing details, the error is only logged",
+			mock: func() {
+				s.mockRepo.EXPECT().GetWatchedCars(gomock.Any()).
+					Return(cars[:1], nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/1/bmw-m3").
+					Return(polovniauto.ListingDetails{}, errCommon). //nolint:exhaustruct,nolintlint
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/1/bmw-m3").
+					Return(false, nil).
+					Times(1)
+			},
+		},
+		{
+			name: "failed to get listing details, the removal is checked",
+			mock: func() {
+				s.mockRepo.EXPECT().GetWatchedCars(gomock.Any()).
+					Return(cars[:1], nil).
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().GetListingDetails(gomock.Any(), "/auto-oglasi/1/bmw-m3").
+					Return(polovniauto.ListingDetails{}, errCommon). //nolint:exhaustruct,nolintlint
+					Times(1)
+				s.mockPpolovniAuto.EXPECT().IsListingRemoved(gomock.Any(), "/auto-oglasi/1/bmw-m3").
+					Return(true, nil).
+					Times(1)
+				s.mockRepo.EXPECT().MarkCarRemoved(gomock.Any(), ds.MarkCarRemovedRequest{
+					ListingID: "1",
+					RemovedAt: now.UTC(),
+					Notify:    true,
+				}).
+					Return(int64(0), nil).
+					Times(1)
+			},
+		},
+		{
+			name: "no watched cars",
+			mock: func() {
+				s.mockRepo.EXPECT().GetWatchedCars(gomock.Any()).
+					Return([]ds.WatchedCarResponse{}, nil).
+					Times(1)
+			},
+		},
+		{
+			name: "failed to get watched cars",
+			mock: func() {
+				s.mockRepo.EXPECT().GetWatchedCars(gomock.Any()).
+					Return(nil, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock()
+
+			err := s.svc.CheckWatchedListings(context.Background())
+			if tc.expectErr != nil {
+				s.Require().ErrorIs(err, tc.expectErr)
+				return
+			}
+
+			s.Require().NoError(err)
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_refreshMarketStats() {
+	now := time.Now()
+	s.svc.now = func() time.Time { return now }
+
+	testCases := []struct {
+		name string
+		mock func()
+	}{
+		{
+			name: "success",
+			mock: func() {
+				s.mockRepo.EXPECT().RefreshMarketStats(gomock.Any(), ds.RefreshMarketStatsRequest{
+					MileageBandKM: 50000,
+					SeenAfter:     now.UTC().AddDate(0, 0, -90),
+				}).
+					Return(int64(3), nil).
+					Times(1)
+			},
+		},
+		{
+			name: "failed to refresh, the error is only logged",
+			mock: func() {
+				s.mockRepo.EXPECT().RefreshMarketStats(gomock.Any(), gomock.Any()).
+					Return(int64(0), errCommon).
+					Times(1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock()
+
+			s.svc.refreshMarketStats(context.Background())
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_deleteOrphanCars() {
+	now := time.Now()
+	s.svc.now = func() time.Time { return now }
+
+	testCases := []struct {
+		name string
+		mock func()
+	}{
+		{
+			name: "success",
+			mock: func() {
+				s.mockRepo.EXPECT().DeleteOrphanCars(gomock.Any(), now.UTC().AddDate(0, 0, -90)).
+					Return(int64(2), nil).
+					Times(1)
+			},
+		},
+		{
+			name: "failed to delete, the error is only logged",
+			mock: func() {
+				s.mockRepo.EXPECT().DeleteOrphanCars(gomock.Any(), gomock.Any()).
+					Return(int64(0), errCommon).
+					Times(1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock()
+
+			s.svc.deleteOrphanCars(context.Background())
+		})
+	}
+}
+
+func Test_modelFromLink(t *testing.T) {
+	models := []string{"320", "320d", "320-gt", "serija-3", "x5"}
+
+	testCases := []struct {
+		name   string
+		link   string
+		brand  string
+		expect string
+	}{
+		{
+			name:   "model followed by the rest of the title",
+			link:   "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-320-d",
+			brand:  "bmw",
+			expect: "320",
+		},
+		{
+			name:   "the longest model",
+			link:   "https://www.polovniautomobili.com/auto-oglasi/25000002/bmw-320-gt-xdrive",
+			brand:  "bmw",
+			expect: "320-gt",
+		},
+		{
+			name:   "model is the whole slug",
+			link:   "/auto-oglasi/25000003/bmw-x5",
+			brand:  "bmw",
+			expect: "x5",
+		},
+		{
+			name:   "model is only a prefix of a word",
+			link:   "/auto-oglasi/25000004/bmw-x50",
+			brand:  "bmw",
+			expect: "",
+		},
+		{
+			name:   "other brand",
+			link:   "/auto-oglasi/25000005/audi-a4",
+			brand:  "bmw",
+			expect: "",
+		},
+		{
+			name:   "no slug",
+			link:   "/auto-oglasi/25000006",
+			brand:  "bmw",
+			expect: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, modelFromLink(tc.link, tc.brand, models))
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_subscriptionToParams() {
+	testCases := []struct {
+		name   string
+		sub    ds.SubscriptionResponse
+		expect map[string]string
+	}{
+		{
+			name: "base filters",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand:     "bmw",
+				Model:     []string{"m3", "m5"},
+				Chassis:   []string{"Limuzina", "Unknown"},
+				Region:    []string{"Beograd"},
+				PriceFrom: "1000",
+				YearTo:    "2010",
+			},
+			expect: map[string]string{
+				"brand":      "bmw",
+				"model[]":    "m3,m5",
+				"chassis[]":  "277",
+				"region[]":   "Beograd",
+				"price_from": "1000",
+				"price_to":   "",
+				"year_from":  "",
+				"year_to":    "2010",
+				"showOldNew": "all",
+			},
+		},
+		{
+			name: "additional filters",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand:     "bmw",
+				Fuel:      []string{"Dizel", "Benzin"},
+				Gearbox:   []string{"Automatski / poluautomatski"},
+				Color:     []string{"Crna"}, // not in the catalog
+				MileageTo: "150000",
+				PowerFrom: "100",
+				PowerTo:   "200",
+			},
+			expect: map[string]string{
+				"brand":      "bmw",
+				"price_from": "",
+				"price_to":   "",
+				"year_from":  "",
+				"year_to":    "",
+				"showOldNew": "all",
+				"fuel[]":     "2309,45",
+				"gearbox[]":  "3212",
+				"mileage_to": "150000",
+				"power_from": "100",
+				"power_to":   "200",
+			},
+		},
+		{
+			name: "extra params",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand:       "bmw",
+				PriceTo:     "3000",
+				ExtraParams: map[string]string{"showOldNew": "old", "without_price": "1", "price_to": "1000"},
+			},
+			expect: map[string]string{
+				"brand":         "bmw",
+				"price_from":    "",
+				"price_to":      "3000",
+				"year_from":     "",
+				"year_to":       "",
+				"showOldNew":    "old",
+				"without_price": "1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.expect, s.svc.subscriptionToParams(tc.sub))
+		})
+	}
+}
+
+func Test_queryKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		params  map[string]string
+		other   map[string]string
+		isEqual bool
+	}{
+		{
+			name:    "same params",
+			params:  map[string]string{"brand": "bmw", "price_to": "3000"},
+			other:   map[string]string{"price_to": "3000", "brand": "bmw"},
+			isEqual: true,
+		},
+		{
+			name:    "multi-value params in a different order",
+			params:  map[string]string{"brand": "bmw", "model[]": "m3,m5", "region[]": "Beograd,Novi Sad"},
+			other:   map[string]string{"brand": "bmw", "model[]": "m5,m3", "region[]": "Novi Sad,Beograd"},
+			isEqual: true,
+		},
+		{
+			name:    "different values",
+			params:  map[string]string{"brand": "bmw", "price_to": "3000"},
+			other:   map[string]string{"brand": "bmw", "price_to": "4000"},
+			isEqual: false,
+		},
+		{
+			name:    "different params",
+			params:  map[string]string{"brand": "bmw", "year_from": ""},
+			other:   map[string]string{"brand": "bmw"},
+			isEqual: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.isEqual, queryKey(tc.params) == queryKey(tc.other))
 		})
 	}
 }
@@ -2,9 +2,11 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/telegram"
 )
@@ -12,19 +14,31 @@ import (
 //go:generate mockgen -source=deps.go -destination=deps_mock.go -package=worker
 type (
 	Repository interface {
-		UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error
-		GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool) ([]ds.ListingResponse, error)
+		UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error
+		GetListing(ctx context.Context, listingID, subscriptionID string) (ds.ListingResponse, error)
+		GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error)
+		GetMedianPricesBySubscriptionID(
+			ctx context.Context, subscriptionID string, observedAfter time.Time,
+		) ([]ds.MedianPriceResponse, error)
+		GetMarketStats(ctx context.Context, request ds.MarketStatsRequest) (ds.MarketStatsResponse, error)
 		CreateNotification(ctx context.Context, notification ds.CreateNotificationRequest) (ds.NotificationResponse, error)
 		GetSubscriptionByID(ctx context.Context, id string) (ds.SubscriptionResponse, error)
-		GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error)
-		DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error
-		DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error
-		DeleteUserByID(ctx context.Context, id int64) error
+		IsListingHidden(ctx context.Context, userID int64, listingID string) (bool, error)
+		GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error)
+		UpdateSubscriptionDigestSentAt(ctx context.Context, id string, sentAt time.Time) error
+		EnqueueNotifications(ctx context.Context, now time.Time) (int64, error)
+		GetDueOutboxNotifications(ctx context.Context, now time.Time) ([]ds.OutboxNotificationResponse, error)
+		UpdateOutboxNotification(ctx context.Context, notification ds.UpdateOutboxNotificationRequest) error
+		DeleteOutboxNotification(ctx context.Context, id string) error
+		GetWatchlistChanges(ctx context.Context) ([]ds.WatchlistChangeResponse, error)
+		UpdateWatchlistNotified(ctx context.Context, request ds.UpdateWatchlistNotifiedRequest) error
+		WithTx(ctx context.Context, fn func(repo repository.DB) error) error
 	}
 	TgBot interface {
 		GetAPI() *tgbotapi.BotAPI
 		GetCfg() *telegram.Config
 		SendMessage(c tgbotapi.Chattable) (tgbotapi.Message, error)
+		SendMessageWithContext(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error)
 		SetCommands(commands []tgbotapi.BotCommand) error
 	}
 )
@@ -12,8 +12,10 @@ package worker
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	repository "github.com/gudimz/polovni-auto-alert/internal/app/repository"
 	ds "github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	telegram "github.com/gudimz/polovni-auto-alert/pkg/telegram"
 	gomock "go.uber.org/mock/gomock"
@@ -57,61 +59,108 @@ func (mr *MockRepositoryMockRecorder) CreateNotification(ctx, notification any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNotification", reflect.TypeOf((*MockRepository)(nil).CreateNotification), ctx, notification)
 }
 
-// DeleteListingsBySubscriptionIDs mocks base method.
-func (m *MockRepository) DeleteListingsBySubscriptionIDs(ctx context.Context, ids []string) error {
+// DeleteOutboxNotification mocks base method.
+func (m *MockRepository) DeleteOutboxNotification(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteListingsBySubscriptionIDs", ctx, ids)
+	ret := m.ctrl.Call(m, "DeleteOutboxNotification", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteListingsBySubscriptionIDs indicates an expected call of DeleteListingsBySubscriptionIDs.
-func (mr *MockRepositoryMockRecorder) DeleteListingsBySubscriptionIDs(ctx, ids any) *gomock.Call {
+// DeleteOutboxNotification indicates an expected call of DeleteOutboxNotification.
+func (mr *MockRepositoryMockRecorder) DeleteOutboxNotification(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteListingsBySubscriptionIDs", reflect.TypeOf((*MockRepository)(nil).DeleteListingsBySubscriptionIDs), ctx, ids)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutboxNotification", reflect.TypeOf((*MockRepository)(nil).DeleteOutboxNotification), ctx, id)
 }
 
-// DeleteSubscriptionsByUserID mocks base method.
-func (m *MockRepository) DeleteSubscriptionsByUserID(ctx context.Context, userID int64) error {
+// EnqueueNotifications mocks base method.
+func (m *MockRepository) EnqueueNotifications(ctx context.Context, now time.Time) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSubscriptionsByUserID", ctx, userID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "EnqueueNotifications", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteSubscriptionsByUserID indicates an expected call of DeleteSubscriptionsByUserID.
-func (mr *MockRepositoryMockRecorder) DeleteSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+// EnqueueNotifications indicates an expected call of EnqueueNotifications.
+func (mr *MockRepositoryMockRecorder) EnqueueNotifications(ctx, now any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionsByUserID", reflect.TypeOf((*MockRepository)(nil).DeleteSubscriptionsByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueNotifications", reflect.TypeOf((*MockRepository)(nil).EnqueueNotifications), ctx, now)
 }
 
-// DeleteUserByID mocks base method.
-func (m *MockRepository) DeleteUserByID(ctx context.Context, id int64) error {
+// GetDueOutboxNotifications mocks base method.
+func (m *MockRepository) GetDueOutboxNotifications(ctx context.Context, now time.Time) ([]ds.OutboxNotificationResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUserByID", ctx, id)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetDueOutboxNotifications", ctx, now)
+	ret0, _ := ret[0].([]ds.OutboxNotificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteUserByID indicates an expected call of DeleteUserByID.
-func (mr *MockRepositoryMockRecorder) DeleteUserByID(ctx, id any) *gomock.Call {
+// GetDueOutboxNotifications indicates an expected call of GetDueOutboxNotifications.
+func (mr *MockRepositoryMockRecorder) GetDueOutboxNotifications(ctx, now any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserByID", reflect.TypeOf((*MockRepository)(nil).DeleteUserByID), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueOutboxNotifications", reflect.TypeOf((*MockRepository)(nil).GetDueOutboxNotifications), ctx, now)
 }
 
-// GetListingsByIsNeedSend mocks base method.
-func (m *MockRepository) GetListingsByIsNeedSend(ctx context.Context, isNeedSend bool) ([]ds.ListingResponse, error) {
+// GetListing mocks base method.
+func (m *MockRepository) GetListing(ctx context.Context, listingID, subscriptionID string) (ds.ListingResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetListingsByIsNeedSend", ctx, isNeedSend)
-	ret0, _ := ret[0].([]ds.ListingResponse)
+	ret := m.ctrl.Call(m, "GetListing", ctx, listingID, subscriptionID)
+	ret0, _ := ret[0].(ds.ListingResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetListingsByIsNeedSend indicates an expected call of GetListingsByIsNeedSend.
-func (mr *MockRepositoryMockRecorder) GetListingsByIsNeedSend(ctx, isNeedSend any) *gomock.Call {
+// GetListing indicates an expected call of GetListing.
+func (mr *MockRepositoryMockRecorder) GetListing(ctx, listingID, subscriptionID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingsByIsNeedSend", reflect.TypeOf((*MockRepository)(nil).GetListingsByIsNeedSend), ctx, isNeedSend)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListing", reflect.TypeOf((*MockRepository)(nil).GetListing), ctx, listingID, subscriptionID)
+}
+
+// GetListingPriceHistory mocks base method.
+func (m *MockRepository) GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetListingPriceHistory", ctx, listingID)
+	ret0, _ := ret[0].([]ds.ListingPriceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetListingPriceHistory indicates an expected call of GetListingPriceHistory.
+func (mr *MockRepositoryMockRecorder) GetListingPriceHistory(ctx, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetListingPriceHistory", reflect.TypeOf((*MockRepository)(nil).GetListingPriceHistory), ctx, listingID)
+}
+
+// GetMarketStats mocks base method.
+func (m *MockRepository) GetMarketStats(ctx context.Context, request ds.MarketStatsRequest) (ds.MarketStatsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMarketStats", ctx, request)
+	ret0, _ := ret[0].(ds.MarketStatsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMarketStats indicates an expected call of GetMarketStats.
+func (mr *MockRepositoryMockRecorder) GetMarketStats(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMarketStats", reflect.TypeOf((*MockRepository)(nil).GetMarketStats), ctx, request)
+}
+
+// GetMedianPricesBySubscriptionID mocks base method.
+func (m *MockRepository) GetMedianPricesBySubscriptionID(ctx context.Context, subscriptionID string, observedAfter time.Time) ([]ds.MedianPriceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMedianPricesBySubscriptionID", ctx, subscriptionID, observedAfter)
+	ret0, _ := ret[0].([]ds.MedianPriceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMedianPricesBySubscriptionID indicates an expected call of GetMedianPricesBySubscriptionID.
+func (mr *MockRepositoryMockRecorder) GetMedianPricesBySubscriptionID(ctx, subscriptionID, observedAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMedianPricesBySubscriptionID", reflect.TypeOf((*MockRepository)(nil).GetMedianPricesBySubscriptionID), ctx, subscriptionID, observedAfter)
 }
 
 // GetSubscriptionByID mocks base method.
@@ -129,33 +178,119 @@ func (mr *MockRepositoryMockRecorder) GetSubscriptionByID(ctx, id any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionByID", reflect.TypeOf((*MockRepository)(nil).GetSubscriptionByID), ctx, id)
 }
 
-// GetSubscriptionsByUserID mocks base method.
-func (m *MockRepository) GetSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error) {
+// GetUserSettings mocks base method.
+func (m *MockRepository) GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSettings", ctx, userID)
+	ret0, _ := ret[0].(ds.UserSettingsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSettings indicates an expected call of GetUserSettings.
+func (mr *MockRepositoryMockRecorder) GetUserSettings(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSettings", reflect.TypeOf((*MockRepository)(nil).GetUserSettings), ctx, userID)
+}
+
+// GetWatchlistChanges mocks base method.
+func (m *MockRepository) GetWatchlistChanges(ctx context.Context) ([]ds.WatchlistChangeResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSubscriptionsByUserID", ctx, userID)
-	ret0, _ := ret[0].([]ds.SubscriptionResponse)
+	ret := m.ctrl.Call(m, "GetWatchlistChanges", ctx)
+	ret0, _ := ret[0].([]ds.WatchlistChangeResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetSubscriptionsByUserID indicates an expected call of GetSubscriptionsByUserID.
-func (mr *MockRepositoryMockRecorder) GetSubscriptionsByUserID(ctx, userID any) *gomock.Call {
+// GetWatchlistChanges indicates an expected call of GetWatchlistChanges.
+func (mr *MockRepositoryMockRecorder) GetWatchlistChanges(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionsByUserID", reflect.TypeOf((*MockRepository)(nil).GetSubscriptionsByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchlistChanges", reflect.TypeOf((*MockRepository)(nil).GetWatchlistChanges), ctx)
 }
 
-// UpsertListing mocks base method.
-func (m *MockRepository) UpsertListing(ctx context.Context, listing ds.UpsertListingRequest) error {
+// IsListingHidden mocks base method.
+func (m *MockRepository) IsListingHidden(ctx context.Context, userID int64, listingID string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpsertListing", ctx, listing)
+	ret := m.ctrl.Call(m, "IsListingHidden", ctx, userID, listingID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsListingHidden indicates an expected call of IsListingHidden.
+func (mr *MockRepositoryMockRecorder) IsListingHidden(ctx, userID, listingID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsListingHidden", reflect.TypeOf((*MockRepository)(nil).IsListingHidden), ctx, userID, listingID)
+}
+
+// UpdateOutboxNotification mocks base method.
+func (m *MockRepository) UpdateOutboxNotification(ctx context.Context, notification ds.UpdateOutboxNotificationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutboxNotification", ctx, notification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutboxNotification indicates an expected call of UpdateOutboxNotification.
+func (mr *MockRepositoryMockRecorder) UpdateOutboxNotification(ctx, notification any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutboxNotification", reflect.TypeOf((*MockRepository)(nil).UpdateOutboxNotification), ctx, notification)
+}
+
+// UpdateSubscriptionDigestSentAt mocks base method.
+func (m *MockRepository) UpdateSubscriptionDigestSentAt(ctx context.Context, id string, sentAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscriptionDigestSentAt", ctx, id, sentAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSubscriptionDigestSentAt indicates an expected call of UpdateSubscriptionDigestSentAt.
+func (mr *MockRepositoryMockRecorder) UpdateSubscriptionDigestSentAt(ctx, id, sentAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscriptionDigestSentAt", reflect.TypeOf((*MockRepository)(nil).UpdateSubscriptionDigestSentAt), ctx, id, sentAt)
+}
+
+// UpdateWatchlistNotified mocks base method.
+func (m *MockRepository) UpdateWatchlistNotified(ctx context.Context, request ds.UpdateWatchlistNotifiedRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWatchlistNotified", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWatchlistNotified indicates an expected call of UpdateWatchlistNotified.
+func (mr *MockRepositoryMockRecorder) UpdateWatchlistNotified(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWatchlistNotified", reflect.TypeOf((*MockRepository)(nil).UpdateWatchlistNotified), ctx, request)
+}
+
+// UpsertSubscriptionMatch mocks base method.
+func (m *MockRepository) UpsertSubscriptionMatch(ctx context.Context, match ds.UpsertSubscriptionMatchRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertSubscriptionMatch", ctx, match)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertSubscriptionMatch indicates an expected call of UpsertSubscriptionMatch.
+func (mr *MockRepositoryMockRecorder) UpsertSubscriptionMatch(ctx, match any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertSubscriptionMatch", reflect.TypeOf((*MockRepository)(nil).UpsertSubscriptionMatch), ctx, match)
+}
+
+// WithTx mocks base method.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(repository.DB) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpsertListing indicates an expected call of UpsertListing.
-func (mr *MockRepositoryMockRecorder) UpsertListing(ctx, listing any) *gomock.Call {
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryMockRecorder) WithTx(ctx, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertListing", reflect.TypeOf((*MockRepository)(nil).UpsertListing), ctx, listing)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepository)(nil).WithTx), ctx, fn)
 }
 
 // MockTgBot is a mock of TgBot interface.
@@ -224,6 +359,21 @@ func (mr *MockTgBotMockRecorder) SendMessage(c any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockTgBot)(nil).SendMessage), c)
 }
 
+// SendMessageWithContext mocks base method.
+func (m *MockTgBot) SendMessageWithContext(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageWithContext", ctx, c)
+	ret0, _ := ret[0].(tgbotapi.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessageWithContext indicates an expected call of SendMessageWithContext.
+func (mr *MockTgBotMockRecorder) SendMessageWithContext(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageWithContext", reflect.TypeOf((*MockTgBot)(nil).SendMessageWithContext), ctx, c)
+}
+
 // SetCommands mocks base method.
 func (m *MockTgBot) SetCommands(commands []tgbotapi.BotCommand) error {
 	m.ctrl.T.Helper()
@@ -4,18 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
 	"github.com/guregu/null"
 	pkgerrors "github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/digest"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/listingaction"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/marketstats"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/pricetrend"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/quiethours"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+	tgCli "github.com/gudimz/polovni-auto-alert/pkg/telegram"
 )
 
 // Service represents the worker service which processes listings periodically.
@@ -24,17 +34,76 @@ type Service struct {
 	repo     Repository
 	tgBot    TgBot
 	interval time.Duration
+	retry    RetryConfig
+	now      func() time.Time
+	randInt  func(n int64) int64
 }
 
+// RetryConfig holds the retry policy of the failed notifications.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// marketDeal is the price of the listing compared to the market, the values are null if they are unknown.
+type marketDeal struct {
+	percent      null.Int
+	daysOnMarket null.Int
+}
+
+// digestItem is a listing of a digest subscription which is sent with the digest.
+type digestItem struct {
+	outbox       ds.OutboxNotificationResponse
+	subscription ds.SubscriptionResponse
+	listing      ds.ListingResponse
+	deal         marketDeal
+}
+
+// photosSentError is returned if the photos of the listing were sent, but the text following them failed,
+// so the retry sends only the text.
+type photosSentError struct {
+	err error
+}
+
+// pendingDigest is the listings of a digest subscription which are due to be sent together.
+type pendingDigest struct {
+	subscription ds.SubscriptionResponse
+	items        []digestItem
+}
+
+const (
+	// maxCaptionLength is the maximum length of a photo caption, Telegram counts it in UTF-16 code units.
+	maxCaptionLength = 1024
+	// maxAlbumPhotos is the maximum number of the photos in a Telegram media group.
+	maxAlbumPhotos = 10
+
+	// maxDigestHeaderLength is the length reserved for the header on each page of the digest.
+	maxDigestHeaderLength = 256
+	// maxDigestModels is the max number of the models of the subscription named in the header of the digest.
+	maxDigestModels = 3
+)
+
 var errBotBlockedByUser = pkgerrors.New("bot is blocked by user")
 
+func (e *photosSentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *photosSentError) Unwrap() error {
+	return e.err
+}
+
 // NewService creates a new Worker Service instance.
-func NewService(l *logger.Logger, repo Repository, tgBot TgBot, interval time.Duration) *Service {
+func NewService(l *logger.Logger, repo Repository, tgBot TgBot, interval time.Duration, retry RetryConfig) *Service {
 	return &Service{
 		l:        l,
 		repo:     repo,
 		tgBot:    tgBot,
 		interval: interval,
+		retry:    retry,
+		now:      time.Now,
+		randInt:  rand.Int63n,
 	}
 }
 
@@ -55,6 +124,10 @@ func (s *Service) Start(ctx context.Context) error {
 				if err := s.ProcessListings(ctx); err != nil {
 					s.l.Error("failed to process listings", logger.ErrAttr(err))
 				}
+
+				if err := s.ProcessWatchlist(ctx); err != nil {
+					s.l.Error("failed to process watchlist", logger.ErrAttr(err))
+				}
 			case <-ctx.Done():
 				ticker.Stop()
 				s.l.Info("worker stopped", logger.ErrAttr(ctx.Err()))
@@ -69,143 +142,595 @@ func (s *Service) Start(ctx context.Context) error {
 	return nil
 }
 
-// ProcessListings processes listings that need to be sent, notifies via Telegram,
-// and updates their status in the repository.
+// ProcessListings puts the listings that need to be sent to the notification outbox, sends the due notifications
+// via Telegram, and updates their status in the repository.
+// The listings of a digest subscription wait in the outbox until its digest is due and are sent together.
+// The notifications wait in the outbox during the quiet hours of the user and on the weekends if the user muted them.
+// Failed notifications are retried with exponential backoff until the maximum number of attempts,
+// after that or on a permanent error the notification is dead-lettered.
 func (s *Service) ProcessListings(ctx context.Context) error {
-	listings, err := s.repo.GetListingsByIsNeedSend(ctx, true)
+	now := s.now().UTC()
+
+	count, err := s.repo.EnqueueNotifications(ctx, now)
 	if err != nil {
-		return pkgerrors.Wrap(err, "failed to get listings")
+		return pkgerrors.Wrap(err, "failed to enqueue notifications")
 	}
 
-	for _, listing := range listings {
-		var subscription ds.SubscriptionResponse
+	s.l.Debug("enqueued notifications", logger.Int64Attr("count", count))
 
-		subscription, err = s.repo.GetSubscriptionByID(ctx, listing.SubscriptionID)
-		if err != nil {
-			s.l.Error("failed to get subscription",
-				logger.ErrAttr(err),
-				logger.StringAttr("subscription_id", listing.SubscriptionID),
-			)
+	notifications, err := s.repo.GetDueOutboxNotifications(ctx, now)
+	if err != nil {
+		return pkgerrors.Wrap(err, "failed to get due notifications")
+	}
+
+	var digests []*pendingDigest
 
+	for _, notification := range notifications {
+		item, ok := s.processNotification(ctx, notification)
+		if !ok {
 			continue
 		}
 
-		notification := ds.CreateNotificationRequest{
-			SubscriptionID: listing.SubscriptionID,
-			ListingID:      listing.ListingID,
-			Status:         ds.StatusSent,
-			Reason:         "",
+		idx := slices.IndexFunc(digests, func(d *pendingDigest) bool {
+			return d.subscription.ID == item.subscription.ID
+		})
+		if idx == -1 {
+			digests = append(digests, &pendingDigest{subscription: item.subscription, items: nil})
+			idx = len(digests) - 1
 		}
 
-		if err = s.sendListing(ctx, subscription.UserID, listing); err != nil {
-			s.l.Error("failed to send listing",
-				logger.ErrAttr(err),
-				logger.Int64Attr("user_id", subscription.UserID),
-				logger.StringAttr("listing_id", listing.ListingID),
-			)
+		digests[idx].items = append(digests[idx].items, item)
+	}
 
-			notification.Status = ds.StatusFailed
-			notification.Reason = err.Error()
+	for _, d := range digests {
+		s.processDigest(ctx, d)
+	}
+
+	s.l.Info("processed all listings", logger.IntAttr("count", len(notifications)))
+
+	return nil
+}
+
+// ProcessWatchlist notifies the users about the changed prices and the removals of the cars of their watchlists,
+// whatever their subscriptions say. A failed notification is retried on the next run, unless the error is permanent.
+// The changes aren't sent during the quiet hours of the user, they are sent on the first run after them.
+func (s *Service) ProcessWatchlist(ctx context.Context) error {
+	changes, err := s.repo.GetWatchlistChanges(ctx)
+	if err != nil {
+		return pkgerrors.Wrap(err, "failed to get watchlist changes")
+	}
+
+	for _, change := range changes {
+		s.processWatchlistChange(ctx, change)
+	}
+
+	s.l.Info("processed watchlist changes", logger.IntAttr("count", len(changes)))
+
+	return nil
+}
+
+// processWatchlistChange sends the change of the watched car and records it as notified.
+func (s *Service) processWatchlistChange(ctx context.Context, change ds.WatchlistChangeResponse) {
+	l := s.l.With(
+		logger.StringAttr("listing_id", change.ListingID),
+		logger.Int64Attr("user_id", change.UserID),
+	)
+
+	settings, err := s.repo.GetUserSettings(ctx, change.UserID)
+	if err != nil {
+		l.Error("failed to get user settings", logger.ErrAttr(err))
+		return
+	}
+
+	if _, isQuiet := quiethours.EndAt(settings, s.now()); isQuiet {
+		l.Debug("watchlist change waits for the end of quiet hours")
+		return
+	}
+
+	err = s.sendWatchlistChange(ctx, change)
+
+	switch {
+	case errors.Is(err, errBotBlockedByUser):
+		// the watchlist is removed together with the user
+		l.Warn("bot is blocked by user", logger.ErrAttr(err))
+		return
+	case err != nil && !tgCli.IsPermanentError(err):
+		l.Error("failed to send watchlist change, it's retried on the next run", logger.ErrAttr(err))
+		return
+	case err != nil:
+		l.Error("failed to send watchlist change, it's dropped", logger.ErrAttr(err))
+	}
+
+	if err = s.repo.UpdateWatchlistNotified(ctx, ds.UpdateWatchlistNotifiedRequest{
+		UserID:    change.UserID,
+		ListingID: change.ListingID,
+		Price:     change.Price,
+		PriceEUR:  change.PriceEUR,
+		RemovedAt: change.RemovedAt,
+	}); err != nil {
+		l.Error("failed to update watchlist notified", logger.ErrAttr(err))
+	}
+}
+
+// processNotification sends the listing of the outbox notification and records the result.
+// The listing of a digest subscription isn't sent, it's returned if the digest is due.
+func (s *Service) processNotification(ctx context.Context, outbox ds.OutboxNotificationResponse) (digestItem, bool) {
+	l := s.l.With(
+		logger.StringAttr("listing_id", outbox.ListingID),
+		logger.StringAttr("subscription_id", outbox.SubscriptionID),
+	)
+
+	if outbox.Kind == ds.NotificationKindRemoved {
+		s.processRemovedNotification(ctx, l, outbox)
+		return digestItem{}, false
+	}
+
+	listing, err := s.repo.GetListing(ctx, outbox.ListingID, outbox.SubscriptionID)
+	if err != nil && !errors.Is(err, ds.ErrNotFound) {
+		l.Error("failed to get listing", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	// the listing was removed or already sent, so there is nothing to send
+	if errors.Is(err, ds.ErrNotFound) || !listing.IsNeedSend {
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+		return digestItem{}, false
+	}
+
+	// the car was removed from the site before it was sent, so there is nothing to look at
+	if listing.RemovedAt.Valid {
+		l.Info("listing is skipped, it was removed from the site")
+
+		s.completeListing(ctx, l, listing)
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+
+		return digestItem{}, false
+	}
+
+	subscription, err := s.repo.GetSubscriptionByID(ctx, listing.SubscriptionID)
+	if err != nil {
+		l.Error("failed to get subscription", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	l = l.With(logger.Int64Attr("user_id", subscription.UserID))
+
+	settings, err := s.repo.GetUserSettings(ctx, subscription.UserID)
+	if err != nil {
+		l.Error("failed to get user settings", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	reason, err := s.subscriberSkipReason(ctx, subscription, listing, isPriceChanged(listing))
+	if err != nil {
+		l.Error("failed to check listing actions of user", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	if reason != "" {
+		l.Info("listing is skipped, " + reason)
+
+		s.completeListing(ctx, l, listing)
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+
+		return digestItem{}, false
+	}
+
+	deal := s.getMarketDeal(ctx, l, listing)
+
+	// the user wants only the listings which are cheaper than the market by the threshold
+	if subscription.MinDealPercent.Valid &&
+		(!deal.percent.Valid || deal.percent.Int64 < subscription.MinDealPercent.Int64) {
+		l.Info("listing is skipped, it's not a good enough deal",
+			logger.Int64Attr("min_deal_percent", subscription.MinDealPercent.Int64),
+			logger.AnyAttr("deal_percent", deal.percent),
+		)
+
+		s.completeListing(ctx, l, listing)
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+
+		return digestItem{}, false
+	}
+
+	if s.holdDuringQuietHours(ctx, l, outbox, settings) {
+		return digestItem{}, false
+	}
+
+	loc := quiethours.Location(settings.TimeZone)
+
+	if digest.IsDigest(subscription.DeliveryMode) {
+		return s.scheduleDigestListing(ctx, l, digestItem{
+			outbox:       outbox,
+			subscription: subscription,
+			listing:      listing,
+			deal:         deal,
+		}, loc)
+	}
+
+	err = s.sendListing(ctx, subscription.UserID, listing, deal, loc, outbox.PhotosSent)
+	if errors.Is(err, errBotBlockedByUser) {
+		// the subscriptions are removed together with their outbox notifications
+		l.Warn("bot is blocked by user", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	if s.recordNotification(ctx, l, outbox, err) {
+		return digestItem{}, false
+	}
+
+	s.completeListing(ctx, l, listing)
+	s.deleteOutboxNotification(ctx, l, outbox.ID)
+
+	return digestItem{}, false
+}
+
+// scheduleDigestListing returns the listing of the digest subscription if the digest is due,
+// otherwise the listing waits in the outbox until the digest is due.
+// The first digest after the digest is switched on is scheduled from the current time.
+func (s *Service) scheduleDigestListing(
+	ctx context.Context, l *logger.Logger, item digestItem, loc *time.Location,
+) (digestItem, bool) {
+	now := s.now().UTC()
+	sub := item.subscription
+
+	sentAt := sub.DigestSentAt.Time
+	if !sub.DigestSentAt.Valid {
+		if err := s.repo.UpdateSubscriptionDigestSentAt(ctx, sub.ID, now); err != nil {
+			l.Error("failed to start digest of subscription", logger.ErrAttr(err))
+			return digestItem{}, false
 		}
 
-		if errors.Is(err, errBotBlockedByUser) {
-			continue
+		sentAt = now
+	}
+
+	dueAt := digest.NextAt(sub.DeliveryMode, sub.DigestHour, sentAt, loc)
+	if !dueAt.After(now) {
+		return item, true
+	}
+
+	if err := s.postponeOutboxNotification(ctx, item.outbox, dueAt); err != nil {
+		l.Error("failed to postpone outbox notification until digest", logger.ErrAttr(err))
+		return digestItem{}, false
+	}
+
+	l.Debug("listing waits for digest", logger.StringAttr("due_at", dueAt.Format(time.DateTime)))
+
+	return digestItem{}, false
+}
+
+// holdDuringQuietHours postpones the outbox notification until the end of the quiet hours of the user,
+// true is returned if the notification isn't sent now.
+func (s *Service) holdDuringQuietHours(
+	ctx context.Context, l *logger.Logger, outbox ds.OutboxNotificationResponse, settings ds.UserSettingsResponse,
+) bool {
+	endAt, isQuiet := quiethours.EndAt(settings, s.now())
+	if !isQuiet {
+		return false
+	}
+
+	// the notification is still due, so it's held on the next run
+	if err := s.postponeOutboxNotification(ctx, outbox, endAt); err != nil {
+		l.Error("failed to postpone outbox notification until end of quiet hours", logger.ErrAttr(err))
+		return true
+	}
+
+	l.Debug("notification waits for end of quiet hours", logger.StringAttr("due_at", endAt.Format(time.DateTime)))
+
+	return true
+}
+
+// postponeOutboxNotification makes the outbox notification due at the time, the attempts aren't counted.
+func (s *Service) postponeOutboxNotification(
+	ctx context.Context, outbox ds.OutboxNotificationResponse, dueAt time.Time,
+) error {
+	return s.repo.UpdateOutboxNotification(ctx, ds.UpdateOutboxNotificationRequest{
+		ID:            outbox.ID,
+		Attempts:      outbox.Attempts,
+		NextAttemptAt: dueAt,
+		LastError:     outbox.LastError,
+		PhotosSent:    outbox.PhotosSent,
+	})
+}
+
+// processDigest sends the listings of the digest subscription as one summary and records the result
+// of each of them the same as if it was sent alone. If a page of the digest fails, the listings of the pages
+// sent before it are recorded as sent, so only the rest of them are retried.
+func (s *Service) processDigest(ctx context.Context, d *pendingDigest) {
+	l := s.l.With(
+		logger.StringAttr("subscription_id", d.subscription.ID),
+		logger.Int64Attr("user_id", d.subscription.UserID),
+	)
+
+	sent, err := s.sendDigest(ctx, d)
+	if errors.Is(err, errBotBlockedByUser) {
+		// the subscriptions are removed together with their outbox notifications
+		l.Warn("bot is blocked by user", logger.ErrAttr(err))
+		return
+	}
+
+	for i, item := range d.items {
+		il := l.With(logger.StringAttr("listing_id", item.listing.ListingID))
+
+		var sendErr error
+		if i >= sent {
+			sendErr = err
 		}
 
-		_, err = s.repo.CreateNotification(ctx, notification)
-		if err != nil {
-			s.l.Error("failed to create notification",
-				logger.ErrAttr(err),
-				logger.Int64Attr("user_id", subscription.UserID),
-				logger.StringAttr("listing_id", listing.ListingID),
-			)
+		if s.recordNotification(ctx, il, item.outbox, sendErr) {
+			continue
 		}
 
-		price := listing.Price
-		newPrice := listing.NewPrice
+		s.completeListing(ctx, il, item.listing)
+		s.deleteOutboxNotification(ctx, il, item.outbox.ID)
+	}
 
-		// if the price has changed, we need to update price and reset new price
-		if listing.NewPrice.Valid && listing.NewPrice.String != "" {
-			price = listing.NewPrice.String
-			newPrice = null.NewString("", false)
-		}
+	if err != nil {
+		return
+	}
+
+	if err = s.repo.UpdateSubscriptionDigestSentAt(ctx, d.subscription.ID, s.now().UTC()); err != nil {
+		l.Error("failed to update digest sent at", logger.ErrAttr(err))
+	}
+
+	l.Info("digest is sent", logger.IntAttr("count", len(d.items)))
+}
+
+// processRemovedNotification sends the notification about the removed listing, which was sent to the user before,
+// and records the result.
+func (s *Service) processRemovedNotification(
+	ctx context.Context, l *logger.Logger, outbox ds.OutboxNotificationResponse,
+) {
+	listing, err := s.repo.GetListing(ctx, outbox.ListingID, outbox.SubscriptionID)
+	if err != nil && !errors.Is(err, ds.ErrNotFound) {
+		l.Error("failed to get listing", logger.ErrAttr(err))
+		return
+	}
+
+	// the subscription was removed or the car is on the market again, so there is nothing to send
+	if errors.Is(err, ds.ErrNotFound) || !listing.RemovedAt.Valid {
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+		return
+	}
+
+	subscription, err := s.repo.GetSubscriptionByID(ctx, listing.SubscriptionID)
+	if err != nil {
+		l.Error("failed to get subscription", logger.ErrAttr(err))
+		return
+	}
+
+	l = l.With(logger.Int64Attr("user_id", subscription.UserID))
+
+	settings, err := s.repo.GetUserSettings(ctx, subscription.UserID)
+	if err != nil {
+		l.Error("failed to get user settings", logger.ErrAttr(err))
+		return
+	}
+
+	reason, err := s.subscriberSkipReason(ctx, subscription, listing, false)
+	if err != nil {
+		l.Error("failed to check listing actions of user", logger.ErrAttr(err))
+		return
+	}
+
+	if reason != "" {
+		l.Info("removed listing is skipped, " + reason)
+		s.deleteOutboxNotification(ctx, l, outbox.ID)
+
+		return
+	}
+
+	if s.holdDuringQuietHours(ctx, l, outbox, settings) {
+		return
+	}
 
-		isNeedSend := false
-		// if the notification failed, we need to send it again
-		if notification.Status == ds.StatusFailed {
-			isNeedSend = true
-			price = listing.Price
-			newPrice = listing.NewPrice
+	err = s.sendRemovedListing(ctx, subscription.UserID, listing)
+	if errors.Is(err, errBotBlockedByUser) {
+		l.Warn("bot is blocked by user", logger.ErrAttr(err))
+		return
+	}
+
+	if s.recordNotification(ctx, l, outbox, err) {
+		return
+	}
+
+	s.deleteOutboxNotification(ctx, l, outbox.ID)
+}
+
+// subscriberSkipReason returns why the user doesn't want the notification about the listing with the buttons of
+// the previous notifications, it's empty if the notification is sent.
+// The notifications of a muted subscription are dropped rather than delayed until the end of the mute.
+func (s *Service) subscriberSkipReason(
+	ctx context.Context, subscription ds.SubscriptionResponse, listing ds.ListingResponse, isPriceChange bool,
+) (string, error) {
+	switch {
+	case subscription.MutedUntil.Valid && s.now().Before(subscription.MutedUntil.Time):
+		return "the subscription is muted", nil
+	case subscription.PriceOnly && !isPriceChange:
+		return "the subscription notifies only about the price changes", nil
+	}
+
+	hidden, err := s.repo.IsListingHidden(ctx, subscription.UserID, listing.ListingID)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "failed to check if listing is hidden")
+	}
+
+	if hidden {
+		return "the user hid the car", nil
+	}
+
+	return "", nil
+}
+
+// recordNotification records the result of sending the outbox notification.
+// A failed notification is rescheduled with backoff and true is returned,
+// unless the error is permanent or the maximum number of attempts is reached.
+func (s *Service) recordNotification(
+	ctx context.Context, l *logger.Logger, outbox ds.OutboxNotificationResponse, sendErr error,
+) bool {
+	notification := ds.CreateNotificationRequest{
+		SubscriptionID: outbox.SubscriptionID,
+		ListingID:      outbox.ListingID,
+		Status:         ds.StatusSent,
+		Reason:         "",
+	}
+
+	attempts := outbox.Attempts + 1
+	isRetry := false
+
+	if sendErr != nil {
+		notification.Status = ds.StatusDead
+		notification.Reason = sendErr.Error()
+
+		if !tgCli.IsPermanentError(sendErr) && attempts < s.retry.MaxAttempts {
+			notification.Status = ds.StatusFailed
+			isRetry = true
 		}
 
-		if err = s.repo.UpsertListing(ctx, ds.UpsertListingRequest{
-			ListingID:      listing.ListingID,
-			SubscriptionID: listing.SubscriptionID,
-			Title:          listing.Title,
-			Price:          price,
-			NewPrice:       newPrice,
-			EngineVolume:   listing.EngineVolume,
-			Transmission:   listing.Transmission,
-			BodyType:       listing.BodyType,
-			Mileage:        listing.Mileage,
-			Location:       listing.Location,
-			Link:           listing.Link,
-			Date:           listing.Date,
-			IsNeedSend:     isNeedSend,
+		l.Error("failed to send notification",
+			logger.ErrAttr(sendErr),
+			logger.IntAttr("attempts", attempts),
+			logger.StringAttr("status", string(notification.Status)),
+		)
+	}
+
+	if _, err := s.repo.CreateNotification(ctx, notification); err != nil {
+		l.Error("failed to create notification", logger.ErrAttr(err))
+	}
+
+	if isRetry {
+		if err := s.repo.UpdateOutboxNotification(ctx, ds.UpdateOutboxNotificationRequest{
+			ID:            outbox.ID,
+			Attempts:      attempts,
+			NextAttemptAt: s.now().UTC().Add(s.backoff(attempts)),
+			LastError:     notification.Reason,
+			PhotosSent:    outbox.PhotosSent || errors.As(sendErr, new(*photosSentError)),
 		}); err != nil {
-			s.l.Error("failed to update listing",
-				logger.ErrAttr(err),
-				logger.Int64Attr("user_id", subscription.UserID),
-				logger.StringAttr("listing_id", listing.ListingID),
-				logger.StringAttr("subscription_id", listing.SubscriptionID),
-			)
+			l.Error("failed to update outbox notification", logger.ErrAttr(err))
 		}
 	}
 
-	s.l.Info("processed all listings")
+	return isRetry
+}
 
-	return nil
+// getMarketDeal returns how many percent the current price of the listing is below the market price
+// and the median time on market of the similar cars.
+// The percent is null if the price or the market price of the listing is unknown or the market price is based
+// on too few cars, the time on market is null if too few similar cars were removed.
+// The market price is optional, so an error is only logged.
+func (s *Service) getMarketDeal(ctx context.Context, l *logger.Logger, listing ds.ListingResponse) marketDeal {
+	price := listing.PriceEUR
+	if listing.NewPriceEUR.Valid {
+		price = listing.NewPriceEUR
+	}
+
+	key, ok := marketstats.Key(listing)
+	if !ok || !price.Valid {
+		return marketDeal{}
+	}
+
+	stats, err := s.repo.GetMarketStats(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ds.ErrNotFound) {
+			l.Warn("failed to get market stats", logger.ErrAttr(err))
+		}
+
+		return marketDeal{}
+	}
+
+	if stats.SampleSize < marketstats.MinSampleSize {
+		return marketDeal{}
+	}
+
+	var deal marketDeal
+
+	percent, ok := marketstats.DealPercent(price.Decimal, stats.MedianPriceEUR)
+	deal.percent = null.NewInt(int64(percent), ok)
+
+	if stats.RemovedCount >= marketstats.MinSampleSize && stats.MedianDaysOnMarket.Valid {
+		deal.daysOnMarket = null.IntFrom(stats.MedianDaysOnMarket.Decimal.Round(0).IntPart())
+	}
+
+	return deal
 }
 
-// sendListing sends a listing message to the user's tg with all the details.
-func (s *Service) sendListing(ctx context.Context, chatID int64, listing ds.ListingResponse) error {
+// completeListing marks the listing as processed and accepts its new price, so it is not sent again.
+func (s *Service) completeListing(ctx context.Context, l *logger.Logger, listing ds.ListingResponse) {
 	price := listing.Price
+	newPrice := listing.NewPrice
+	priceEUR := listing.PriceEUR
+	newPriceEUR := listing.NewPriceEUR
 
-	if !listing.NewPrice.IsZero() && listing.NewPrice.ValueOrZero() != listing.Price {
-		attention := "🔴"
-		direction := "🔺"
+	// if the price has changed, we need to update price and reset new price
+	if listing.NewPrice.Valid && listing.NewPrice.String != "" {
+		price = listing.NewPrice.String
+		newPrice = null.NewString("", false)
+		priceEUR = listing.NewPriceEUR
+		newPriceEUR = decimal.NullDecimal{}
+	}
 
-		// check if the new price is less than the old price
-		useAttention := true
-		cleanedNewPrice := strings.ReplaceAll(listing.NewPrice.ValueOrZero(), "€", "")
-		cleanedNewPrice = strings.TrimSpace(cleanedNewPrice)
+	if err := s.repo.UpsertSubscriptionMatch(ctx, ds.UpsertSubscriptionMatchRequest{
+		ListingID:      listing.ListingID,
+		SubscriptionID: listing.SubscriptionID,
+		Price:          price,
+		NewPrice:       newPrice,
+		PriceEUR:       priceEUR,
+		NewPriceEUR:    newPriceEUR,
+		IsNeedSend:     false,
+	}); err != nil {
+		l.Error("failed to update listing", logger.ErrAttr(err))
+	}
+}
 
-		newPrice, err := decimal.NewFromString(cleanedNewPrice)
-		if err != nil {
-			s.l.Warn("failed to parse new price", logger.StringAttr("new_price", cleanedNewPrice))
+// deleteOutboxNotification removes the notification from the outbox.
+func (s *Service) deleteOutboxNotification(ctx context.Context, l *logger.Logger, id string) {
+	if err := s.repo.DeleteOutboxNotification(ctx, id); err != nil {
+		l.Error("failed to delete outbox notification", logger.ErrAttr(err))
+	}
+}
 
-			useAttention = false
-		}
+// backoff returns the delay before the next attempt, it doubles with every attempt up to the maximum delay.
+// The delay is randomized between its half and its full value, so the retries of many notifications are spread.
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.retry.BaseDelay
+	for i := 1; i < attempts && delay < s.retry.MaxDelay; i++ {
+		delay *= 2
+	}
 
-		cleanedOldPrice := strings.ReplaceAll(listing.Price, "€", "")
-		cleanedOldPrice = strings.TrimSpace(cleanedOldPrice)
+	delay = min(delay, s.retry.MaxDelay)
 
-		oldPrice, err := decimal.NewFromString(cleanedOldPrice)
-		if err != nil {
-			s.l.Warn("failed to parse old price", logger.StringAttr("old_price", cleanedOldPrice))
+	half := int64(delay) / 2
+	if half <= 0 {
+		return delay
+	}
 
-			useAttention = false
-		}
+	return time.Duration(half + s.randInt(half+1))
+}
+
+// sendListing sends a listing message to the user's tg with all the details, the date is in the time zone of the user.
+func (s *Service) sendListing(
+	ctx context.Context, chatID int64, listing ds.ListingResponse, deal marketDeal, loc *time.Location, photosSent bool,
+) error {
+	price := listing.Price
+	trend := ""
+	isPriceDrop := false
+
+	var history []ds.ListingPriceResponse
+
+	// price change is shown only if both prices are known
+	if isPriceChanged(listing) {
+		attention := "🔴"
+		direction := "🔺"
 
-		if newPrice.LessThan(oldPrice) {
+		// check if the new price is less than the old price
+		if listing.NewPriceEUR.Decimal.LessThan(listing.PriceEUR.Decimal) {
 			attention = "🟢"
 			direction = "🔻"
+			isPriceDrop = true
 		}
 
-		// all price converts to decimal
-		if useAttention {
-			price = attention + listing.Price + direction + listing.NewPrice.ValueOrZero()
-		}
+		price = attention + listing.Price + direction + listing.NewPrice.ValueOrZero()
+		history = s.getPriceHistory(ctx, listing.ListingID)
+		trend = buildTrendText(history)
 	}
 
 	text := fmt.Sprintf(`
@@ -214,30 +739,228 @@ func (s *Service) sendListing(ctx context.Context, chatID int64, listing ds.List
 
 	📝 *Title:* %s
 	💰 *Price:* %s
-	🏎️ *Engine Volume:* %s
+%s%s%s	🏎️ *Engine Volume:* %s
 	⚙️ *Transmission:* %s
 	🚗 *Body Type:* %s
 	🧭 *Mileage:* %s
-	📍 *Location:* %s
+%s	📍 *Location:* %s
 	📅 *Date:* %s
 	🌐 *Link:* [tap to link](%s)
 	`,
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, "👋 Hi, here's a new listing for your subscription."),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Title),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, price),
+		buildRelistText(listing),
+		trend,
+		buildDealText(deal),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.EngineVolume),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Transmission),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.BodyType),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Mileage),
+		buildDetailsText(listing.Details),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Location),
-		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Date.Format(time.DateTime)),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Date.In(loc).Format(time.DateTime)),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Link),
+	)
+
+	if err := s.sendListingWithPhotos(ctx, chatID, listing, text, s.listingButtons(listing), photosSent); err != nil {
+		return err
+	}
+
+	if isPriceDrop {
+		s.sendPriceChart(ctx, chatID, listing, history)
+	}
+
+	return nil
+}
+
+// sendRemovedListing sends a message to the user's tg that the listing was removed from the site.
+func (s *Service) sendRemovedListing(ctx context.Context, chatID int64, listing ds.ListingResponse) error {
+	days := marketstats.DaysOnMarket(listing.FirstSeenAt, listing.RemovedAt.Time)
+
+	text := fmt.Sprintf(`
+
+	%s
+
+	🌐 *Link:* [tap to link](%s)
+	`,
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, fmt.Sprintf("🗑️ The %s you were watching was removed after %s.",
+			listing.Title, marketstats.FormatDays(days))),
 		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Link),
 	)
 
+	return s.sendTextMessage(ctx, chatID, text, nil)
+}
+
+// sendWatchlistChange sends a message to the user's tg that the price of the watched car has changed
+// or that the car was removed from the site.
+func (s *Service) sendWatchlistChange(ctx context.Context, change ds.WatchlistChangeResponse) error {
+	if change.RemovedAt.Valid {
+		days := marketstats.DaysOnMarket(change.FirstSeenAt, change.RemovedAt.Time)
+
+		text := fmt.Sprintf(`
+
+	%s
+
+	🌐 *Link:* [tap to link](%s)
+	`,
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, fmt.Sprintf("🗑️ The %s from your watchlist was removed after %s.",
+				change.Title, marketstats.FormatDays(days))),
+			tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, change.Link),
+		)
+
+		return s.sendTextMessage(ctx, change.UserID, text, nil)
+	}
+
+	// the previous price is unknown if the car had no price when it was saved
+	price := change.Price
+	if change.NotifiedPriceEUR.Valid {
+		attention := "🔴"
+		direction := "🔺"
+
+		if change.PriceEUR.Decimal.LessThan(change.NotifiedPriceEUR.Decimal) {
+			attention = "🟢"
+			direction = "🔻"
+		}
+
+		price = attention + change.NotifiedPrice + direction + change.Price
+	}
+
+	text := fmt.Sprintf(`
+
+	%s
+
+	📝 *Title:* %s
+	💰 *Price:* %s
+%s	🌐 *Link:* [tap to link](%s)
+	`,
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, "⭐ The price of a car from your watchlist has changed."),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, change.Title),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, price),
+		buildTrendText(s.getPriceHistory(ctx, change.ListingID)),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, change.Link),
+	)
+
+	return s.sendTextMessage(ctx, change.UserID, text, nil)
+}
+
+// sendDigest sends the listings of the digest to the user's tg as a compact summary,
+// it's split into pages if it doesn't fit a message. The pages keep the order of the listings,
+// so the number of the listings on the pages sent before an error is returned with it.
+func (s *Service) sendDigest(ctx context.Context, d *pendingDigest) (int, error) {
+	items := make([]string, 0, len(d.items))
+	for i, item := range d.items {
+		items = append(items, buildDigestItemText(i+1, item.listing, item.deal))
+	}
+
+	pages := digest.Paginate(items, digest.MaxMessageLength-maxDigestHeaderLength)
+	sent := 0
+
+	for i, page := range pages {
+		text := buildDigestHeaderText(d.subscription, len(items), i+1, len(pages)) + strings.Join(page, "\n")
+
+		if err := s.sendTextMessage(ctx, d.subscription.UserID, text, nil); err != nil {
+			return sent, err
+		}
+
+		sent += len(page)
+	}
+
+	return sent, nil
+}
+
+// sendListingWithPhotos sends the text of the listing with the buttons as the caption of its photo.
+// Telegram doesn't show the buttons under a media group, so the photos of the gallery are sent without
+// the caption and the text with the buttons follows them, the same as if the text is too long for a caption.
+// If the photos can't be sent, e.g. Telegram failed to download them, the text is sent alone.
+// If the photos were sent, but the text following them failed, *photosSentError is returned,
+// and the photos sent by the previous attempt aren't sent again.
+func (s *Service) sendListingWithPhotos(
+	ctx context.Context, chatID int64, listing ds.ListingResponse, text string, buttons any, photosSent bool,
+) error {
+	photos := listingPhotos(listing)
+	if len(photos) == 0 || photosSent {
+		return s.sendTextMessage(ctx, chatID, text, buttons)
+	}
+
+	caption := text
+	if len(photos) > 1 || markdownV2TextLength(text) > maxCaptionLength {
+		caption = ""
+	}
+
+	err := s.sendMessage(ctx, chatID, buildPhotosMessage(chatID, photos, caption, buttons))
+
+	switch {
+	case err == nil && caption != "":
+		return nil
+	case err == nil:
+		if err = s.sendTextMessage(ctx, chatID, text, buttons); err != nil && !errors.Is(err, errBotBlockedByUser) {
+			return &photosSentError{err: err}
+		}
+
+		return err
+	case errors.Is(err, errBotBlockedByUser):
+		return err
+	}
+
+	s.l.Warn("failed to send listing photos, the listing is sent without them",
+		logger.ErrAttr(err),
+		logger.StringAttr("listing_id", listing.ListingID),
+	)
+
+	return s.sendTextMessage(ctx, chatID, text, buttons)
+}
+
+// sendTextMessage sends the MarkdownV2 text with the optional buttons to the user's tg.
+func (s *Service) sendTextMessage(ctx context.Context, chatID int64, text string, buttons any) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	msg.ReplyMarkup = buttons
+
+	return s.sendMessage(ctx, chatID, msg)
+}
+
+// listingButtons builds the buttons of the actions with the car of the listing notification.
+// The buttons are optional, so if the listing can't be encoded in their data, the error is only logged
+// and nil is returned.
+func (s *Service) listingButtons(listing ds.ListingResponse) any {
+	actions := []struct {
+		action listingaction.Action
+		text   string
+	}{
+		{action: listingaction.Save, text: "⭐ Save"},
+		{action: listingaction.Hide, text: "🙈 Hide this car"},
+		{action: listingaction.Mute, text: "🔕 Mute for 24h"},
+		{action: listingaction.PriceOnly, text: "📉 Track price only"},
+	}
+
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(actions))
+
+	for _, a := range actions {
+		data, err := listingaction.Encode(listingaction.Data{
+			Action:         a.action,
+			ListingID:      listing.ListingID,
+			SubscriptionID: listing.SubscriptionID,
+		})
+		if err != nil {
+			s.l.Warn("failed to encode listing action, the listing is sent without buttons",
+				logger.ErrAttr(err),
+				logger.StringAttr("listing_id", listing.ListingID),
+			)
+
+			return nil
+		}
+
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(a.text, data))
+	}
 
-	_, err := s.tgBot.SendMessage(msg)
+	return tgbotapi.NewInlineKeyboardMarkup(buttons[:2], buttons[2:])
+}
+
+// sendMessage sends the message to the user's tg, if the user blocked the bot, all the user's subscriptions
+// are removed and errBotBlockedByUser is returned.
+func (s *Service) sendMessage(ctx context.Context, chatID int64, msg tgbotapi.Chattable) error {
+	_, err := s.tgBot.SendMessageWithContext(ctx, msg)
 	if err != nil {
 		var apiErr *tgbotapi.Error
 		if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden { // user blocked tg bot
@@ -259,35 +982,292 @@ func (s *Service) sendListing(ctx context.Context, chatID int64, listing ds.List
 	return nil
 }
 
-// RemoveAllSubscriptionsByUserID removes all subscriptions and associated listings for a given user.
-func (s *Service) RemoveAllSubscriptionsByUserID(ctx context.Context, userID int64) error {
-	subscriptions, err := s.repo.GetSubscriptionsByUserID(ctx, userID)
-	if err != nil {
-		return pkgerrors.Wrap(err, "failed to get subscriptions by user id")
+// sendPriceChart sends the chart of the listing prices with the market median of the subscription.
+// The chart is optional, so an error is only logged.
+func (s *Service) sendPriceChart(
+	ctx context.Context, chatID int64, listing ds.ListingResponse, history []ds.ListingPriceResponse,
+) {
+	if len(history) == 0 {
+		return
 	}
 
-	ids := make([]string, len(subscriptions))
-	for i, sub := range subscriptions {
-		ids[i] = sub.ID
+	lg := s.l.With(logger.StringAttr("listing_id", listing.ListingID))
+
+	// the median is calculated for the whole days of the listing history
+	median, err := s.repo.GetMedianPricesBySubscriptionID(
+		ctx, listing.SubscriptionID, history[0].ObservedAt.Truncate(ds.HoursInDay*time.Hour),
+	)
+	if err != nil {
+		lg.Warn("failed to get median prices, the chart is sent without them", logger.ErrAttr(err))
 	}
 
-	if err = s.repo.DeleteListingsBySubscriptionIDs(ctx, ids); err != nil {
-		return pkgerrors.Wrap(err, "failed to delete listings by subscription ids")
+	img, err := pricetrend.RenderChart(history, median, s.now())
+	if err != nil {
+		lg.Warn("failed to render price chart", logger.ErrAttr(err))
+		return
 	}
 
-	if err = s.repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
-		return pkgerrors.Wrap(err, "failed to delete subscriptions by user id")
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "price_chart.png", Bytes: img})
+	photo.Caption = "📉 Price history of the listing and the market median of your subscription"
+
+	if _, err = s.tgBot.SendMessageWithContext(ctx, photo); err != nil {
+		lg.Warn("failed to send price chart", logger.ErrAttr(err))
 	}
+}
 
-	if err = s.repo.DeleteUserByID(ctx, userID); err != nil {
-		return pkgerrors.Wrap(err, "failed to delete user by id")
+// isPriceChanged checks if the listing is sent because of its price change, it's known only if both prices are known.
+func isPriceChanged(listing ds.ListingResponse) bool {
+	return listing.PriceEUR.Valid && listing.NewPriceEUR.Valid &&
+		!listing.NewPriceEUR.Decimal.Equal(listing.PriceEUR.Decimal)
+}
+
+// listingPhotos returns the URLs of the photos sent with the listing, the gallery of the listing page if it was
+// fetched, otherwise the thumbnail of the search results.
+func listingPhotos(listing ds.ListingResponse) []string {
+	if len(listing.Details.GalleryURLs) > 0 {
+		return listing.Details.GalleryURLs[:min(len(listing.Details.GalleryURLs), maxAlbumPhotos)]
 	}
 
-	// TODO add transactions in future
+	if listing.ImageURL != "" {
+		return []string{listing.ImageURL}
+	}
 
 	return nil
 }
 
+// buildPhotosMessage builds a photo message with the caption and the buttons or a media group for several photos,
+// which can have neither of them.
+func buildPhotosMessage(chatID int64, photos []string, caption string, buttons any) tgbotapi.Chattable {
+	if len(photos) == 1 {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(photos[0]))
+		photo.Caption = caption
+		photo.ParseMode = tgbotapi.ModeMarkdownV2
+
+		if caption != "" {
+			photo.ReplyMarkup = buttons
+		}
+
+		return photo
+	}
+
+	media := make([]any, 0, len(photos))
+
+	for _, photoURL := range photos {
+		media = append(media, tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL(photoURL)))
+	}
+
+	return tgbotapi.NewMediaGroup(chatID, media)
+}
+
+// markdownV2TextLength returns the length of the MarkdownV2 text as Telegram counts it, in UTF-16 code units
+// of the text without the markup: the escaping backslashes, the entity markers and the URLs of the links.
+func markdownV2TextLength(text string) int {
+	length := 0
+	escaped, linkEnd, inURL := false, false, false
+
+	for _, r := range text {
+		wasLinkEnd := linkEnd
+		linkEnd = false
+
+		switch {
+		case escaped:
+			escaped = false
+
+			if !inURL {
+				length += utf16.RuneLen(r)
+			}
+		case r == '\\':
+			escaped = true
+		case inURL:
+			inURL = r != ')'
+		case r == ']':
+			linkEnd = true
+		case r == '(' && wasLinkEnd:
+			inURL = true
+		case strings.ContainsRune("*_~|`[", r):
+		default:
+			length += utf16.RuneLen(r)
+		}
+	}
+
+	return length
+}
+
+// getPriceHistory retrieves the price history of the listing.
+// The history is optional, so an error is only logged and an empty history is returned.
+func (s *Service) getPriceHistory(ctx context.Context, listingID string) []ds.ListingPriceResponse {
+	history, err := s.repo.GetListingPriceHistory(ctx, listingID)
+	if err != nil {
+		s.l.Warn("failed to get listing price history",
+			logger.ErrAttr(err),
+			logger.StringAttr("listing_id", listingID),
+		)
+
+		return nil
+	}
+
+	return history
+}
+
+// buildTrendText builds the message line with the price trend of the listing, it's empty if there is no trend.
+func buildTrendText(history []ds.ListingPriceResponse) string {
+	trend := pricetrend.Format(history)
+	if trend == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("\t📊 *Trend:* %s\n", tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, trend))
+}
+
+// buildRelistText builds the message line with the price of the previous listing of the relisted car,
+// it's only shown with the new listing, the line is empty if the listing is not a relist.
+func buildRelistText(listing ds.ListingResponse) string {
+	if !listing.RelistedPrice.Valid || listing.NewPrice.Valid {
+		return ""
+	}
+
+	return fmt.Sprintf("\t♻️ *Relisted*, previously %s\n",
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.RelistedPrice.String))
+}
+
+// buildDealText builds the message line with the price of the listing compared to the market
+// and the time on market of the similar cars if it's known, the line is empty if the market price is unknown.
+func buildDealText(deal marketDeal) string {
+	if !deal.percent.Valid {
+		return ""
+	}
+
+	text := marketstats.FormatDeal(int(deal.percent.Int64))
+	if deal.daysOnMarket.Valid && deal.daysOnMarket.Int64 > 0 {
+		text += ", similar cars sell in ~" + marketstats.FormatDays(int(deal.daysOnMarket.Int64))
+	}
+
+	return fmt.Sprintf("\t🏷️ *Deal:* %s\n", tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, text))
+}
+
+// buildDigestHeaderText builds the header of the page of the digest with the number of the listings
+// and the subscription they are for, the page number is shown only if there are several pages.
+func buildDigestHeaderText(sub ds.SubscriptionResponse, count, page, pages int) string {
+	name := sub.Brand
+	if len(sub.Model) > 0 {
+		models := sub.Model
+		if len(models) > maxDigestModels {
+			models = append(slices.Clone(models[:maxDigestModels]), "…")
+		}
+
+		name += " " + strings.Join(models, ", ")
+	}
+
+	text := fmt.Sprintf("📬 Your %s: %d listings of the %s subscription",
+		digest.Format(sub.DeliveryMode, sub.DigestHour), count, name)
+	if pages > 1 {
+		text += fmt.Sprintf(" (%d/%d)", page, pages)
+	}
+
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, text) + "\n\n"
+}
+
+// buildDigestItemText builds the lines of the listing in the digest: its title with the link,
+// the price or its change, the relist and the deal if they are known.
+func buildDigestItemText(number int, listing ds.ListingResponse, deal marketDeal) string {
+	price := listing.Price
+	if isPriceChanged(listing) {
+		direction := "🔺"
+		if listing.NewPriceEUR.Decimal.LessThan(listing.PriceEUR.Decimal) {
+			direction = "🔻"
+		}
+
+		price = listing.Price + direction + listing.NewPrice.ValueOrZero()
+	}
+
+	details := []string{"💰 " + price}
+
+	if listing.RelistedPrice.Valid && !listing.NewPrice.Valid {
+		details = append(details, "♻️ relisted, previously "+listing.RelistedPrice.String)
+	}
+
+	if deal.percent.Valid {
+		details = append(details, "🏷️ "+marketstats.FormatDeal(int(deal.percent.Int64)))
+	}
+
+	return fmt.Sprintf("*%d\\.* [%s](%s)\n%s\n",
+		number,
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Title),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, listing.Link),
+		tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, strings.Join(details, " · ")),
+	)
+}
+
+// buildDetailsText builds the message lines with the listing details, empty details are skipped.
+func buildDetailsText(details ds.ListingDetails) string {
+	var power string
+	if details.PowerKW != 0 || details.PowerHP != 0 {
+		power = fmt.Sprintf("%d kW / %d HP", details.PowerKW, details.PowerHP)
+	}
+
+	sellerType := details.SellerType
+	if sellerType != "" {
+		sellerType = strings.ToUpper(sellerType[:1]) + sellerType[1:]
+	}
+
+	fields := []struct {
+		emoji string
+		label string
+		value string
+	}{
+		{emoji: "⛽", label: "Fuel", value: details.FuelType},
+		{emoji: "🐎", label: "Power", value: power},
+		{emoji: "🎨", label: "Color", value: details.Color},
+		{emoji: "🛞", label: "Drive", value: details.Drive},
+		{emoji: "🚪", label: "Doors", value: details.Doors},
+		{emoji: "🪪", label: "Registered Until", value: details.RegisteredUntil},
+		{emoji: "🔧", label: "Damage", value: details.DamageStatus},
+		{emoji: "👤", label: "Seller", value: sellerType},
+	}
+
+	var sb strings.Builder
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("\t%s *%s:* %s\n",
+			field.emoji, field.label, tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, field.value)))
+	}
+
+	return sb.String()
+}
+
+// RemoveAllSubscriptionsByUserID removes all subscriptions and associated listings for a given user.
+func (s *Service) RemoveAllSubscriptionsByUserID(ctx context.Context, userID int64) error {
+	return s.repo.WithTx(ctx, func(repo repository.DB) error {
+		subscriptions, err := repo.GetSubscriptionsByUserID(ctx, userID)
+		if err != nil {
+			return pkgerrors.Wrap(err, "failed to get subscriptions by user id")
+		}
+
+		ids := make([]string, len(subscriptions))
+		for i, sub := range subscriptions {
+			ids[i] = sub.ID
+		}
+
+		if err = repo.DeleteListingsBySubscriptionIDs(ctx, ids); err != nil {
+			return pkgerrors.Wrap(err, "failed to delete listings by subscription ids")
+		}
+
+		if err = repo.DeleteSubscriptionsByUserID(ctx, userID); err != nil {
+			return pkgerrors.Wrap(err, "failed to delete subscriptions by user id")
+		}
+
+		if err = repo.DeleteUserByID(ctx, userID); err != nil {
+			return pkgerrors.Wrap(err, "failed to delete user by id")
+		}
+
+		return nil
+	})
+}
+
 // recoverPanic recovers from a panic and logs the error.
 func (s *Service) recoverPanic() {
 	if r := recover(); r != nil {
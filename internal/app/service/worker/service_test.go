@@ -3,16 +3,26 @@ package worker
 import (
 	"context"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 
+	"github.com/gudimz/polovni-auto-alert/internal/app/repository"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/digest"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/listingaction"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/quiethours"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
@@ -20,371 +30,1760 @@ var errCommon = errors.New("common error")
 
 type ServiceTestSuite struct {
 	suite.Suite
-	ctrl      *gomock.Controller
-	mockRepo  *MockRepository
-	mockTgBot *MockTgBot
-	svc       *Service
+	ctrl       *gomock.Controller
+	mockRepo   *MockRepository
+	mockTxRepo *repository.MockDB
+	mockTgBot  *MockTgBot
+	svc        *Service
 }
 
 func (s *ServiceTestSuite) SetupTest() {
 	s.ctrl = gomock.NewController(s.T())
 	lg := logger.NewLogger()
 	s.mockRepo = NewMockRepository(s.ctrl)
+	s.mockTxRepo = repository.NewMockDB(s.ctrl)
 	s.mockTgBot = NewMockTgBot(s.ctrl)
-	s.svc = NewService(lg, s.mockRepo, s.mockTgBot, 10*time.Second)
+	s.svc = NewService(lg, s.mockRepo, s.mockTgBot, 10*time.Second, RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	})
 }
 
 func (s *ServiceTestSuite) TearDownTest() {
 	s.ctrl.Finish()
 }
 
+// expectTx expects a transaction which runs its function with the transaction repository mock.
+func (s *ServiceTestSuite) expectTx() {
+	s.mockRepo.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repo repository.DB) error) error {
+			return fn(s.mockTxRepo)
+		}).
+		Times(1)
+}
+
 func (s *ServiceTestSuite) TestService_ProcessListings() {
-	now := time.Now()
-	listingID := uuid.NewString()
+	now := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+	outboxID := uuid.NewString()
+	listingID := "25000001"
 	subID := uuid.NewString()
 
+	s.svc.now = func() time.Time { return now }
+	s.svc.randInt = func(int64) int64 { return 0 }
+
+	listing := ds.ListingResponse{
+		ID:             uuid.NewString(),
+		ListingID:      listingID,
+		SubscriptionID: subID,
+		Title:          "Best bmw",
+		Price:          "2400€",
+		Date:           now,
+		IsNeedSend:     true,
+	}
+
+	sentListing := ds.UpsertSubscriptionMatchRequest{
+		ListingID:      listingID,
+		SubscriptionID: subID,
+		Price:          "2400€",
+		IsNeedSend:     false,
+	}
+
+	expectDue := func(attempts int) {
+		s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+			Return(int64(1), nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetDueOutboxNotifications(gomock.Any(), now).
+			Return([]ds.OutboxNotificationResponse{
+				{
+					ID:             outboxID,
+					ListingID:      listingID,
+					SubscriptionID: subID,
+					Attempts:       attempts,
+					NextAttemptAt:  now,
+				},
+			}, nil).
+			Times(1)
+	}
+
+	expectRemovedDue := func(attempts int) {
+		s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+			Return(int64(0), nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetDueOutboxNotifications(gomock.Any(), now).
+			Return([]ds.OutboxNotificationResponse{
+				{
+					ID:             outboxID,
+					ListingID:      listingID,
+					SubscriptionID: subID,
+					Kind:           ds.NotificationKindRemoved,
+					Attempts:       attempts,
+					NextAttemptAt:  now,
+				},
+			}, nil).
+			Times(1)
+	}
+
+	// the listing which was sent and then removed from the site
+	removedListing := listing
+	removedListing.IsNeedSend = false
+	removedListing.FirstSeenAt = now.AddDate(0, 0, -12)
+	removedListing.RemovedAt = null.TimeFrom(now)
+
+	subscription := ds.SubscriptionResponse{
+		ID:        subID,
+		UserID:    1,
+		Brand:     "bmw",
+		Model:     []string{"m3", "m5"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	expectSettingsListing := func(
+		listing ds.ListingResponse, subscription ds.SubscriptionResponse, settings ds.UserSettingsResponse,
+	) {
+		s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+			Return(listing, nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
+			Return(subscription, nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetUserSettings(gomock.Any(), subscription.UserID).
+			Return(settings, nil).
+			Times(1)
+	}
+
+	// expectSkippedListing expects the listing which is skipped before it's checked if the user hid the car
+	expectSkippedListing := func(listing ds.ListingResponse, subscription ds.SubscriptionResponse) {
+		expectSettingsListing(listing, subscription, ds.UserSettingsResponse{
+			UserID:   subscription.UserID,
+			TimeZone: quiethours.DefaultTimeZone,
+		})
+	}
+
+	expectHidden := func(hidden bool, err error) {
+		s.mockRepo.EXPECT().IsListingHidden(gomock.Any(), subscription.UserID, listingID).
+			Return(hidden, err).
+			Times(1)
+	}
+
+	expectListingWithSubscription := func(listing ds.ListingResponse, subscription ds.SubscriptionResponse) {
+		expectSkippedListing(listing, subscription)
+		expectHidden(false, nil)
+	}
+
+	expectListing := func(listing ds.ListingResponse) {
+		expectListingWithSubscription(listing, subscription)
+	}
+
+	// the quiet hours of the user are from 11:00 to 17:00 in Belgrade, it's 12:00 there now
+	quietSettings := ds.UserSettingsResponse{
+		UserID:         subscription.UserID,
+		TimeZone:       "Europe/Belgrade",
+		QuietHoursFrom: null.IntFrom(11),
+		QuietHoursTo:   null.IntFrom(17),
+	}
+
+	// the listing with a known market price
+	marketListing := listing
+	marketListing.Brand = "bmw"
+	marketListing.Model = "m3"
+	marketListing.Year = null.IntFrom(2015)
+	marketListing.MileageKM = null.IntFrom(180000)
+	marketListing.PriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2400))
+
+	marketKey := ds.MarketStatsRequest{Brand: "bmw", Model: "m3", Year: 2015, MileageBand: 3}
+	marketStats := ds.MarketStatsResponse{
+		Brand:          "bmw",
+		Model:          "m3",
+		Year:           2015,
+		MileageBand:    3,
+		SampleSize:     12,
+		P25PriceEUR:    decimal.NewFromInt(2500),
+		MedianPriceEUR: decimal.NewFromInt(2800),
+		P75PriceEUR:    decimal.NewFromInt(3100),
+		UpdatedAt:      now,
+	}
+
+	sentMarketListing := sentListing
+	sentMarketListing.PriceEUR = marketListing.PriceEUR
+
+	expectNotification := func(status ds.NotificationStatus, reason string) {
+		s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
+			SubscriptionID: subID,
+			ListingID:      listingID,
+			Status:         status,
+			Reason:         reason,
+		}).Return(ds.NotificationResponse{
+			ID:        uuid.NewString(),
+			ListingID: listingID,
+			Status:    status,
+			Reason:    reason,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, nil).
+			Times(1)
+	}
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		expectErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with price change",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				changed := listing
+				changed.NewPrice = null.StringFrom("2000€")
+				changed.PriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2400))
+				changed.NewPriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2000))
+				changed.MileageKM = null.IntFrom(150000)
+				expectListing(changed)
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), listingID).
+					Return([]ds.ListingPriceResponse{
+						{
+							ListingID:  listingID,
+							Price:      "2400€",
+							PriceEUR:   decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+							ObservedAt: now.AddDate(0, 0, -9),
+						},
+						{
+							ListingID:  listingID,
+							Price:      "2000€",
+							PriceEUR:   decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+							ObservedAt: now,
+						},
+					}, nil).
+					Times(1)
+
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "🟢2400€🔻2000€") &&
+						strings.Contains(msg.Text, "📊 *Trend:* 2 400 → 2 000 € over 9 days")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().
+					GetMedianPricesBySubscriptionID(gomock.Any(), subID, now.AddDate(0, 0, -9).Truncate(24*time.Hour)).
+					Return([]ds.MedianPriceResponse{
+						{Day: now.AddDate(0, 0, -9), PriceEUR: decimal.NewFromInt(2300)},
+						{Day: now, PriceEUR: decimal.NewFromInt(2200)},
+					}, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					photo, ok := c.(tgbotapi.PhotoConfig)
+					return ok && strings.Contains(photo.Caption, "market median")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingID,
+					SubscriptionID: subID,
+					Price:          "2000€",
+					NewPrice:       null.NewString("", false),
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+					NewPriceEUR:    decimal.NullDecimal{},
+					IsNeedSend:     false,
+				}).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with price change: get price history failed",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				changed := listing
+				changed.NewPrice = null.StringFrom("2000€")
+				changed.PriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2400))
+				changed.NewPriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2000))
+				changed.MileageKM = null.IntFrom(150000)
+				expectListing(changed)
+				// the trend is optional, so the listing is sent without it
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), listingID).
+					Return(nil, errCommon).
+					Times(1)
+
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "🟢2400€🔻2000€") && !strings.Contains(msg.Text, "Trend")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingID,
+					SubscriptionID: subID,
+					Price:          "2000€",
+					NewPrice:       null.NewString("", false),
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+					NewPriceEUR:    decimal.NullDecimal{},
+					IsNeedSend:     false,
+				}).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with relisted listing",
+			mock: func(*testCase) {
+				relisted := listing
+				relisted.RelistedFrom = null.StringFrom(uuid.NewString())
+				relisted.RelistedPrice = null.StringFrom("2900€")
+
+				expectDue(0)
+				expectListing(relisted)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "♻️ *Relisted*, previously 2900€")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with action buttons",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					markup, _ := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+
+					var actions []listingaction.Action
+
+					for _, row := range markup.InlineKeyboard {
+						for _, button := range row {
+							data, err := listingaction.Decode(*button.CallbackData)
+							if err != nil || data.ListingID != listingID || data.SubscriptionID != subID {
+								return false
+							}
+
+							actions = append(actions, data.Action)
+						}
+					}
+
+					return ok && slices.Equal(actions, []listingaction.Action{
+						listingaction.Save, listingaction.Hide, listingaction.Mute, listingaction.PriceOnly,
+					})
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "listing is hidden by user: listing is skipped",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectSkippedListing(listing, subscription)
+				expectHidden(true, nil)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "check if listing is hidden failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectSkippedListing(listing, subscription)
+				expectHidden(false, errCommon)
+			},
+		},
+		{
+			name: "subscription is muted: listing is skipped",
+			mock: func(*testCase) {
+				muted := subscription
+				muted.MutedUntil = null.TimeFrom(now.Add(time.Hour))
+
+				expectDue(0)
+				expectSkippedListing(listing, muted)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "mute of subscription is over",
+			mock: func(*testCase) {
+				muted := subscription
+				muted.MutedUntil = null.TimeFrom(now.Add(-time.Minute))
+
+				expectDue(0)
+				expectListingWithSubscription(listing, muted)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "price only subscription: new listing is skipped",
+			mock: func(*testCase) {
+				priceOnly := subscription
+				priceOnly.PriceOnly = true
+
+				expectDue(0)
+				expectSkippedListing(listing, priceOnly)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "price only subscription: price change is sent",
+			mock: func(*testCase) {
+				priceOnly := subscription
+				priceOnly.PriceOnly = true
+
+				changed := listing
+				changed.NewPrice = null.StringFrom("2600€")
+				changed.PriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2400))
+				changed.NewPriceEUR = decimal.NewNullDecimal(decimal.NewFromInt(2600))
+
+				expectDue(0)
+				expectListingWithSubscription(changed, priceOnly)
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), listingID).
+					Return(nil, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "🔴2400€🔺2600€")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+					ListingID:      listingID,
+					SubscriptionID: subID,
+					Price:          "2600€",
+					NewPrice:       null.NewString("", false),
+					PriceEUR:       decimal.NewNullDecimal(decimal.NewFromInt(2600)),
+					NewPriceEUR:    decimal.NullDecimal{},
+					IsNeedSend:     false,
+				}).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with photo",
+			mock: func(*testCase) {
+				withPhoto := listing
+				withPhoto.ImageURL = "https://images.polovniautomobili.com/thumbs/1.jpg"
+
+				expectDue(0)
+				expectListing(withPhoto)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					photo, ok := c.(tgbotapi.PhotoConfig)
+					_, hasButtons := photo.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+
+					return ok && photo.File == tgbotapi.FileURL(withPhoto.ImageURL) &&
+						strings.Contains(photo.Caption, "Best bmw") && photo.ParseMode == tgbotapi.ModeMarkdownV2 &&
+						hasButtons
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with gallery",
+			mock: func(*testCase) {
+				withGallery := listing
+				withGallery.ImageURL = "https://images.polovniautomobili.com/thumbs/1.jpg"
+				withGallery.Details.GalleryURLs = []string{
+					"https://images.polovniautomobili.com/big/1.jpg",
+					"https://images.polovniautomobili.com/big/2.jpg",
+				}
+
+				expectDue(0)
+				expectListing(withGallery)
+				// a media group can't have the buttons, so the text with them follows the photos
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						group, ok := c.(tgbotapi.MediaGroupConfig)
+						if !ok || len(group.Media) != 2 {
+							return false
+						}
+
+						first, _ := group.Media[0].(tgbotapi.InputMediaPhoto)
+
+						return first.Media == tgbotapi.FileURL(withGallery.Details.GalleryURLs[0]) && first.Caption == ""
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						msg, ok := c.(tgbotapi.MessageConfig)
+						_, hasButtons := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+
+						return ok && strings.Contains(msg.Text, "Best bmw") && hasButtons
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+				)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with photo: send photo failed, text is sent",
+			mock: func(*testCase) {
+				withPhoto := listing
+				withPhoto.ImageURL = "https://images.polovniautomobili.com/thumbs/1.jpg"
+
+				expectDue(0)
+				expectListing(withPhoto)
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.AssignableToTypeOf(tgbotapi.PhotoConfig{})).
+						Return(tgbotapi.Message{}, &tgbotapi.Error{
+							Code:    http.StatusBadRequest,
+							Message: "Bad Request: wrong file identifier/HTTP URL specified",
+						}).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						msg, ok := c.(tgbotapi.MessageConfig)
+						return ok && strings.Contains(msg.Text, "Best bmw")
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+				)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with photo: text is too long for caption",
+			mock: func(*testCase) {
+				withPhoto := listing
+				withPhoto.ImageURL = "https://images.polovniautomobili.com/thumbs/1.jpg"
+				// each emoji is two UTF-16 code units, so the text is too long while it has less than 1024 runes
+				withPhoto.Title = strings.Repeat("🚗", maxCaptionLength/2)
+
+				expectDue(0)
+				expectListing(withPhoto)
+				// the photo is sent without the caption and the text follows it
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						photo, ok := c.(tgbotapi.PhotoConfig)
+						return ok && photo.Caption == ""
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						msg, ok := c.(tgbotapi.MessageConfig)
+						return ok && strings.Contains(msg.Text, withPhoto.Title)
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+				)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with photo: escaped text fits caption",
+			mock: func(*testCase) {
+				withPhoto := listing
+				withPhoto.ImageURL = "https://images.polovniautomobili.com/thumbs/1.jpg"
+				// each dot is escaped, so only the MarkdownV2 source of the text is longer than 1024
+				withPhoto.Title = strings.Repeat(".", maxCaptionLength*2/3)
+
+				expectDue(0)
+				expectListing(withPhoto)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					photo, ok := c.(tgbotapi.PhotoConfig)
+					return ok && len(photo.Caption) > maxCaptionLength && photo.ReplyMarkup != nil
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with deal",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(marketListing)
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(marketStats, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "🏷️ *Deal:* 14% below market")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with deal and time on market",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(marketListing)
+
+				sold := marketStats
+				sold.RemovedCount = 6
+				sold.MedianDaysOnMarket = decimal.NewNullDecimal(decimal.RequireFromString("11.5"))
+
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(sold, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text,
+						"🏷️ *Deal:* 14% below market, similar cars sell in \\~12 days")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with deal: too few cars for the market price",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(marketListing)
+
+				few := marketStats
+				few.SampleSize = 2
+
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(few, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && !strings.Contains(msg.Text, "Deal")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success with deal: get market stats failed",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(marketListing)
+				// the deal is optional, so the listing is sent without it
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(ds.MarketStatsResponse{}, errCommon).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && !strings.Contains(msg.Text, "Deal")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "min deal percent is reached",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				sub := subscription
+				sub.MinDealPercent = null.IntFrom(10)
+
+				expectListingWithSubscription(marketListing, sub)
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(marketStats, nil).
+					Times(1)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "min deal percent is not reached: listing is skipped",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				sub := subscription
+				sub.MinDealPercent = null.IntFrom(20)
+
+				expectListingWithSubscription(marketListing, sub)
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(marketStats, nil).
+					Times(1)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "min deal percent with unknown market price: listing is skipped",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				sub := subscription
+				sub.MinDealPercent = null.IntFrom(10)
+
+				expectListingWithSubscription(marketListing, sub)
+				s.mockRepo.EXPECT().GetMarketStats(gomock.Any(), marketKey).
+					Return(ds.MarketStatsResponse{}, ds.ErrNotFound).
+					Times(1)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentMarketListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "enqueue notifications failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+					Return(int64(0), errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "get due notifications failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+					Return(int64(0), nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetDueOutboxNotifications(gomock.Any(), now).
+					Return([]ds.OutboxNotificationResponse{}, errCommon).
+					Times(1)
+			},
+			expectErr: errCommon,
+		},
+		{
+			name: "listing not found",
+			mock: func(*testCase) {
+				expectDue(0)
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(ds.ListingResponse{}, ds.ErrNotFound).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "listing already sent",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				sent := listing
+				sent.IsNeedSend = false
+
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(sent, nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "listing removed before it was sent: listing is skipped",
+			mock: func(*testCase) {
+				expectDue(0)
+
+				removed := listing
+				removed.RemovedAt = null.TimeFrom(now)
+
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(removed, nil).
+					Times(1)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "removed listing",
+			mock: func(*testCase) {
+				expectRemovedDue(0)
+				expectListing(removedListing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "The Best bmw you were watching was removed after 12 days")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "removed listing waits for the end of quiet hours",
+			mock: func(*testCase) {
+				expectRemovedDue(0)
+				expectSettingsListing(removedListing, subscription, quietSettings)
+				expectHidden(false, nil)
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      0,
+					NextAttemptAt: time.Date(2024, 10, 10, 15, 0, 0, 0, time.UTC),
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "removed listing is on the market again",
+			mock: func(*testCase) {
+				expectRemovedDue(0)
+
+				back := removedListing
+				back.RemovedAt = null.Time{}
+
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(back, nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "removed listing: send notification failed: transient error is retried with backoff",
+			mock: func(*testCase) {
+				expectRemovedDue(1)
+				expectListing(removedListing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, errCommon).
+					Times(1)
+				expectNotification(ds.StatusFailed, errCommon.Error())
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      2,
+					NextAttemptAt: now.Add(time.Minute),
+					LastError:     errCommon.Error(),
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "listing waits for the end of quiet hours",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectSettingsListing(listing, subscription, quietSettings)
+				expectHidden(false, nil)
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      0,
+					NextAttemptAt: time.Date(2024, 10, 10, 15, 0, 0, 0, time.UTC),
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "listing date is in the time zone of user",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectSettingsListing(listing, subscription, ds.UserSettingsResponse{
+					UserID:   subscription.UserID,
+					TimeZone: "Europe/Belgrade",
+				})
+				expectHidden(false, nil)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && strings.Contains(msg.Text, "2024\\-10\\-10 12:00:00")
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "get user settings failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(listing, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
+					Return(subscription, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetUserSettings(gomock.Any(), subscription.UserID).
+					Return(ds.UserSettingsResponse{}, errCommon).
+					Times(1)
+			},
+		},
+		{
+			name: "get listing failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(ds.ListingResponse{}, errCommon).
+					Times(1)
+			},
+		},
+		{
+			name: "get subscription failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+					Return(listing, nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
+					Return(ds.SubscriptionResponse{}, errCommon).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: transient error is retried with backoff",
+			mock: func(*testCase) {
+				expectDue(1)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, errCommon).
+					Times(1)
+				expectNotification(ds.StatusFailed, errCommon.Error())
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      2,
+					NextAttemptAt: now.Add(time.Minute),
+					LastError:     errCommon.Error(),
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: too many requests is retried",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+
+				apiErr := &tgbotapi.Error{
+					Code:               http.StatusTooManyRequests,
+					Message:            "Too Many Requests: retry after 5",
+					ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5},
+				}
+
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, apiErr).
+					Times(1)
+				expectNotification(ds.StatusFailed, apiErr.Error())
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      1,
+					NextAttemptAt: now.Add(30 * time.Second),
+					LastError:     apiErr.Error(),
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: text after photos failed, photos are remembered",
+			mock: func(*testCase) {
+				withGallery := listing
+				withGallery.Details.GalleryURLs = []string{
+					"https://images.polovniautomobili.com/big/1.jpg",
+					"https://images.polovniautomobili.com/big/2.jpg",
+				}
+
+				expectDue(0)
+				expectListing(withGallery)
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(),
+						gomock.AssignableToTypeOf(tgbotapi.MediaGroupConfig{})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(),
+						gomock.AssignableToTypeOf(tgbotapi.MessageConfig{})).
+						Return(tgbotapi.Message{}, errCommon).
+						Times(1),
+				)
+				expectNotification(ds.StatusFailed, errCommon.Error())
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            outboxID,
+					Attempts:      1,
+					NextAttemptAt: now.Add(30 * time.Second),
+					LastError:     errCommon.Error(),
+					PhotosSent:    true,
+				}).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "success after retry: photos are not sent again",
+			mock: func(*testCase) {
+				withGallery := listing
+				withGallery.Details.GalleryURLs = []string{
+					"https://images.polovniautomobili.com/big/1.jpg",
+					"https://images.polovniautomobili.com/big/2.jpg",
+				}
+
+				s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+					Return(int64(0), nil).
+					Times(1)
+				s.mockRepo.EXPECT().GetDueOutboxNotifications(gomock.Any(), now).
+					Return([]ds.OutboxNotificationResponse{
+						{
+							ID:             outboxID,
+							ListingID:      listingID,
+							SubscriptionID: subID,
+							Attempts:       1,
+							NextAttemptAt:  now,
+							PhotosSent:     true,
+						},
+					}, nil).
+					Times(1)
+				expectListing(withGallery)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					_, hasButtons := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+
+					return ok && strings.Contains(msg.Text, "Best bmw") && hasButtons
+				})).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: max attempts are reached",
+			mock: func(*testCase) {
+				expectDue(2)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, errCommon).
+					Times(1)
+				expectNotification(ds.StatusDead, errCommon.Error())
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: permanent error is dead-lettered",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+
+				apiErr := &tgbotapi.Error{
+					Code:    http.StatusBadRequest,
+					Message: "Bad Request: chat not found",
+				}
+
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, apiErr).
+					Times(1)
+				expectNotification(ds.StatusDead, apiErr.Error())
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: user blocked bot",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, &tgbotapi.Error{
+						Code: http.StatusForbidden,
+					}).
+					Times(1)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), gomock.Any()).
+					Return([]ds.SubscriptionResponse{
+						{
+							ID:        subID,
+							UserID:    1,
+							Brand:     "bmw",
+							Model:     []string{"m3", "m5"},
+							CreatedAt: now,
+							UpdatedAt: now,
+						},
+					}, nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+				s.mockTxRepo.EXPECT().DeleteUserByID(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "send notification failed: user blocked bot and failed remove all subscriptions",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, &tgbotapi.Error{
+						Code: http.StatusForbidden,
+					}).
+					Times(1)
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), gomock.Any()).
+					Return([]ds.SubscriptionResponse{}, errCommon).
+					Times(1)
+			},
+		},
+		{
+			name: "create notification failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).
+					Return(ds.NotificationResponse{}, errCommon).
+					Times(1)
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(nil).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "upsert listing failed: common error",
+			mock: func(*testCase) {
+				expectDue(0)
+				expectListing(listing)
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+					Return(tgbotapi.Message{}, nil).
+					Times(1)
+				expectNotification(ds.StatusSent, "")
+				s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), sentListing).
+					Return(errCommon).
+					Times(1)
+				s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), outboxID).
+					Return(nil).
+					Times(1)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+			err := s.svc.ProcessListings(ctx)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+			}
+
+			cancel()
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_ProcessListings_Digest() {
+	now := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+	subID := uuid.NewString()
+
+	s.svc.now = func() time.Time { return now }
+	s.svc.randInt = func(int64) int64 { return 0 }
+
+	subscription := ds.SubscriptionResponse{
+		ID:           subID,
+		UserID:       1,
+		Brand:        "bmw",
+		Model:        []string{"m3"},
+		DeliveryMode: ds.DeliveryModeHourly,
+		DigestSentAt: null.TimeFrom(now.Add(-time.Hour)),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	outbox := func(listingID string) ds.OutboxNotificationResponse {
+		return ds.OutboxNotificationResponse{
+			ID:             "outbox-" + listingID,
+			ListingID:      listingID,
+			SubscriptionID: subID,
+			NextAttemptAt:  now,
+		}
+	}
+
+	listing := func(listingID string) ds.ListingResponse {
+		return ds.ListingResponse{
+			ID:             uuid.NewString(),
+			ListingID:      listingID,
+			SubscriptionID: subID,
+			Title:          "Bmw " + listingID,
+			Price:          "2400€",
+			Link:           "https://www.polovniautomobili.com/auto-oglasi/" + listingID,
+			Date:           now,
+			IsNeedSend:     true,
+		}
+	}
+
+	// expectDue expects the due outbox notifications of the listings of the subscription
+	expectDue := func(subscription ds.SubscriptionResponse, listingIDs ...string) {
+		notifications := make([]ds.OutboxNotificationResponse, 0, len(listingIDs))
+		for _, listingID := range listingIDs {
+			notifications = append(notifications, outbox(listingID))
+		}
+
+		s.mockRepo.EXPECT().EnqueueNotifications(gomock.Any(), now).
+			Return(int64(len(listingIDs)), nil).
+			Times(1)
+		s.mockRepo.EXPECT().GetDueOutboxNotifications(gomock.Any(), now).
+			Return(notifications, nil).
+			Times(1)
+
+		for _, listingID := range listingIDs {
+			s.mockRepo.EXPECT().GetListing(gomock.Any(), listingID, subID).
+				Return(listing(listingID), nil).
+				Times(1)
+			s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
+				Return(subscription, nil).
+				Times(1)
+			s.mockRepo.EXPECT().GetUserSettings(gomock.Any(), subscription.UserID).
+				Return(ds.UserSettingsResponse{UserID: subscription.UserID, TimeZone: quiethours.DefaultTimeZone}, nil).
+				Times(1)
+			s.mockRepo.EXPECT().IsListingHidden(gomock.Any(), subscription.UserID, listingID).
+				Return(false, nil).
+				Times(1)
+		}
+	}
+
+	// expectSent expects the listing to be recorded as sent with the digest
+	expectSent := func(listingID string) {
+		s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
+			SubscriptionID: subID,
+			ListingID:      listingID,
+			Status:         ds.StatusSent,
+			Reason:         "",
+		}).
+			Return(ds.NotificationResponse{}, nil).
+			Times(1)
+		s.mockRepo.EXPECT().UpsertSubscriptionMatch(gomock.Any(), ds.UpsertSubscriptionMatchRequest{
+			ListingID:      listingID,
+			SubscriptionID: subID,
+			Price:          "2400€",
+			IsNeedSend:     false,
+		}).
+			Return(nil).
+			Times(1)
+		s.mockRepo.EXPECT().DeleteOutboxNotification(gomock.Any(), "outbox-"+listingID).
+			Return(nil).
+			Times(1)
+	}
+
+	// expectPostponed expects the listing to wait in the outbox until the digest is due
+	expectPostponed := func(listingID string, dueAt time.Time) {
+		s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+			ID:            "outbox-" + listingID,
+			Attempts:      0,
+			NextAttemptAt: dueAt,
+			LastError:     "",
+		}).
+			Return(nil).
+			Times(1)
+	}
+
 	type testCase struct {
-		mock      func(*testCase)
-		name      string
-		expectErr error
+		mock func(*testCase)
+		name string
 	}
 
 	testCases := []testCase{
 		{
-			name: "success",
+			name: "digest is due: listings are sent in one message",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
-					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
+				expectDue(subscription, "25000001", "25000002")
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+					msg, ok := c.(tgbotapi.MessageConfig)
+					return ok && msg.ChatID == subscription.UserID &&
+						strings.HasPrefix(msg.Text, "📬 Your hourly digest: 2 listings of the bmw m3 subscription\n\n") &&
+						strings.Contains(msg.Text, "*1\\.* [Bmw 25000001]") &&
+						strings.Contains(msg.Text, "*2\\.* [Bmw 25000002]")
+				})).
 					Return(tgbotapi.Message{}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
-					SubscriptionID: subID,
-					ListingID:      listingID,
-					Status:         ds.StatusSent,
-				}).Return(ds.NotificationResponse{
-					ID:        uuid.NewString(),
-					ListingID: listingID,
-					Status:    ds.StatusSent,
-					CreatedAt: now,
-					UpdatedAt: now,
-				}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
-					ListingID:      listingID,
-					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2400€",
-					Date:           now,
-					IsNeedSend:     false,
-				}).
+				expectSent("25000001")
+				expectSent("25000002")
+				s.mockRepo.EXPECT().UpdateSubscriptionDigestSentAt(gomock.Any(), subID, now).
 					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "get listings failed: common error",
+			name: "digest is not due: listing waits in outbox",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{}, errCommon).
-					Times(1)
+				sub := subscription
+				sub.DigestSentAt = null.TimeFrom(now)
+
+				expectDue(sub, "25000001")
+				expectPostponed("25000001", now.Add(time.Hour))
 			},
-			expectErr: errCommon,
 		},
 		{
-			name: "get subscription failed: common error",
+			name: "digest is switched on: first digest is scheduled from now",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
+				sub := subscription
+				sub.DeliveryMode = ds.DeliveryModeDaily
+				sub.DigestHour = 18
+				sub.DigestSentAt = null.Time{}
+
+				expectDue(sub, "25000001")
+				s.mockRepo.EXPECT().UpdateSubscriptionDigestSentAt(gomock.Any(), subID, now).
+					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{}, errCommon).
+				expectPostponed("25000001", time.Date(2024, 10, 10, 18, 0, 0, 0, time.UTC))
+			},
+		},
+		{
+			name: "digest is split into pages",
+			mock: func(*testCase) {
+				listingIDs := make([]string, 0, digest.MaxPageItems+1)
+				for i := range digest.MaxPageItems + 1 {
+					listingIDs = append(listingIDs, strconv.Itoa(25000001+i))
+				}
+
+				expectDue(subscription, listingIDs...)
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						msg, ok := c.(tgbotapi.MessageConfig)
+						return ok && strings.Contains(msg.Text, "21 listings of the bmw m3 subscription \\(1/2\\)")
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+						msg, ok := c.(tgbotapi.MessageConfig)
+						return ok && strings.Contains(msg.Text, "\\(2/2\\)") &&
+							strings.Contains(msg.Text, "*21\\.* [Bmw 25000021]")
+					})).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+				)
+
+				for _, listingID := range listingIDs {
+					expectSent(listingID)
+				}
+
+				s.mockRepo.EXPECT().UpdateSubscriptionDigestSentAt(gomock.Any(), subID, now).
+					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "send notification failed: common error",
+			name: "second page of digest failed: only listings of second page are retried",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
+				listingIDs := make([]string, 0, digest.MaxPageItems+1)
+				for i := range digest.MaxPageItems + 1 {
+					listingIDs = append(listingIDs, strconv.Itoa(25000001+i))
+				}
+
+				expectDue(subscription, listingIDs...)
+				gomock.InOrder(
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+						Return(tgbotapi.Message{}, nil).
+						Times(1),
+					s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
+						Return(tgbotapi.Message{}, errCommon).
+						Times(1),
+				)
+
+				for _, listingID := range listingIDs[:digest.MaxPageItems] {
+					expectSent(listingID)
+				}
+
+				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
+					SubscriptionID: subID,
+					ListingID:      "25000021",
+					Status:         ds.StatusFailed,
+					Reason:         errCommon.Error(),
+				}).
+					Return(ds.NotificationResponse{}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            "outbox-25000021",
+					Attempts:      1,
+					NextAttemptAt: now.Add(30 * time.Second),
+					LastError:     errCommon.Error(),
+				}).
+					Return(nil).
 					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
+			},
+		},
+		{
+			name: "send digest failed: listings are retried",
+			mock: func(*testCase) {
+				expectDue(subscription, "25000001")
+				s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Any()).
 					Return(tgbotapi.Message{}, errCommon).
 					Times(1)
 				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
 					SubscriptionID: subID,
-					ListingID:      listingID,
+					ListingID:      "25000001",
 					Status:         ds.StatusFailed,
 					Reason:         errCommon.Error(),
-				}).Return(ds.NotificationResponse{
-					ID:        uuid.NewString(),
-					ListingID: listingID,
-					Status:    ds.StatusFailed,
-					CreatedAt: now,
-					UpdatedAt: now,
-				}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
-					ListingID:      listingID,
-					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2400€",
-					Date:           now,
-					IsNeedSend:     true,
+				}).
+					Return(ds.NotificationResponse{}, nil).
+					Times(1)
+				s.mockRepo.EXPECT().UpdateOutboxNotification(gomock.Any(), ds.UpdateOutboxNotificationRequest{
+					ID:            "outbox-25000001",
+					Attempts:      1,
+					NextAttemptAt: now.Add(30 * time.Second),
+					LastError:     errCommon.Error(),
 				}).
 					Return(nil).
 					Times(1)
 			},
 		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			tc.mock(&tc)
+
+			s.Require().NoError(s.svc.ProcessListings(context.Background()))
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestService_ProcessWatchlist() {
+	now := time.Now().UTC()
+	listingID := "25000001"
+
+	s.svc.now = func() time.Time { return now }
+
+	priceChange := ds.WatchlistChangeResponse{
+		UserID:           1,
+		ListingID:        listingID,
+		Title:            "Best bmw",
+		Link:             "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-m3",
+		Price:            "2200€",
+		PriceEUR:         decimal.NewNullDecimal(decimal.NewFromInt(2200)),
+		NotifiedPrice:    "2400€",
+		NotifiedPriceEUR: decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+		FirstSeenAt:      now.AddDate(0, 0, -10),
+		RemovedAt:        null.NewTime(time.Time{}, false),
+	}
+
+	removal := priceChange
+	removal.RemovedAt = null.TimeFrom(now)
+
+	notified := func(change ds.WatchlistChangeResponse) ds.UpdateWatchlistNotifiedRequest {
+		return ds.UpdateWatchlistNotifiedRequest{
+			UserID:    change.UserID,
+			ListingID: change.ListingID,
+			Price:     change.Price,
+			PriceEUR:  change.PriceEUR,
+			RemovedAt: change.RemovedAt,
+		}
+	}
+
+	expectSettings := func(settings ds.UserSettingsResponse) {
+		s.mockRepo.EXPECT().GetUserSettings(gomock.Any(), int64(1)).
+			Return(settings, nil).
+			Times(1)
+	}
+
+	settings := ds.UserSettingsResponse{UserID: 1, TimeZone: quiethours.DefaultTimeZone}
+
+	// the quiet hours of the user are the current hour
+	quietSettings := settings
+	quietSettings.QuietHoursFrom = null.IntFrom(int64(now.Hour()))
+	quietSettings.QuietHoursTo = null.IntFrom(int64((now.Hour() + 1) % ds.HoursInDay))
+
+	expectText := func(text string, err error) {
+		s.mockTgBot.EXPECT().SendMessageWithContext(gomock.Any(), gomock.Cond(func(c tgbotapi.Chattable) bool {
+			msg, ok := c.(tgbotapi.MessageConfig)
+			return ok && msg.ChatID == 1 && strings.Contains(msg.Text, text)
+		})).
+			Return(tgbotapi.Message{}, err).
+			Times(1)
+	}
+
+	type testCase struct {
+		mock      func(*testCase)
+		name      string
+		expectErr error
+	}
+
+	testCases := []testCase{
 		{
-			name: "send notification failed: user blocked bot",
+			name: "success: price drop",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
-					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
-					Return(tgbotapi.Message{}, &tgbotapi.Error{
-						Code: http.StatusForbidden,
-					}).
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{priceChange}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), gomock.Any()).
-					Return([]ds.SubscriptionResponse{
-						{
-							ID:        subID,
-							UserID:    1,
-							Brand:     "bmw",
-							Model:     []string{"m3", "m5"},
-							CreatedAt: now,
-							UpdatedAt: now,
-						},
-					}, nil).
+				expectSettings(settings)
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), listingID).
+					Return(nil, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				expectText("🟢2400€🔻2200€", nil)
+				s.mockRepo.EXPECT().UpdateWatchlistNotified(gomock.Any(), notified(priceChange)).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), gomock.Any()).
-					Return(nil).
+			},
+		},
+		{
+			name: "success: price is known for the first time",
+			mock: func(*testCase) {
+				change := priceChange
+				change.NotifiedPrice = ""
+				change.NotifiedPriceEUR = decimal.NullDecimal{}
+
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{change}, nil).
+					Times(1)
+				expectSettings(settings)
+				s.mockRepo.EXPECT().GetListingPriceHistory(gomock.Any(), listingID).
+					Return(nil, errCommon).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteUserByID(gomock.Any(), gomock.Any()).
+				expectText("*Price:* 2200€", nil)
+				s.mockRepo.EXPECT().UpdateWatchlistNotified(gomock.Any(), notified(change)).
 					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "send notification failed: user blocked bot and failed remove all subscriptions",
+			name: "success: car is removed",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
-					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
-					Return(tgbotapi.Message{}, &tgbotapi.Error{
-						Code: http.StatusForbidden,
-					}).
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{removal}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), gomock.Any()).
-					Return([]ds.SubscriptionResponse{}, errCommon).
+				expectSettings(settings)
+				expectText("from your watchlist was removed after 10 days", nil)
+				s.mockRepo.EXPECT().UpdateWatchlistNotified(gomock.Any(), notified(removal)).
+					Return(nil).
 					Times(1)
 			},
 		},
 		{
-			name: "create notification failed: common error",
+			name: "send failed: change is retried on the next run",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{removal}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
+				expectSettings(settings)
+				expectText("removed", errCommon)
+			},
+		},
+		{
+			name: "send failed: permanent error, change is dropped",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{removal}, nil).
 					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
-					Return(tgbotapi.Message{}, nil).
+				expectSettings(settings)
+				expectText("removed", &tgbotapi.Error{
+					Code:    http.StatusBadRequest,
+					Message: "Bad Request: chat not found",
+				})
+				s.mockRepo.EXPECT().UpdateWatchlistNotified(gomock.Any(), notified(removal)).
+					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
-					SubscriptionID: subID,
-					ListingID:      listingID,
-					Status:         ds.StatusSent,
-				}).Return(ds.NotificationResponse{}, errCommon).
+			},
+		},
+		{
+			name: "update watchlist notified failed: error is only logged",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{removal}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
-					ListingID:      listingID,
-					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2400€",
-					Date:           now,
-					IsNeedSend:     false,
-				}).
-					Return(nil).
+				expectSettings(settings)
+				expectText("removed", nil)
+				s.mockRepo.EXPECT().UpdateWatchlistNotified(gomock.Any(), notified(removal)).
+					Return(errCommon).
 					Times(1)
 			},
 		},
 		{
-			name: "upsert listing failed: common error",
+			name: "change waits for the end of quiet hours",
 			mock: func(*testCase) {
-				s.mockRepo.EXPECT().GetListingsByIsNeedSend(gomock.Any(), true).
-					Return([]ds.ListingResponse{
-						{
-							ID:             uuid.NewString(),
-							ListingID:      listingID,
-							SubscriptionID: subID,
-							Title:          "Best bmw",
-							Price:          "2400€",
-							Date:           now,
-							IsNeedSend:     true,
-						},
-					}, nil).
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{priceChange}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().GetSubscriptionByID(gomock.Any(), subID).
-					Return(ds.SubscriptionResponse{
-						ID:        subID,
-						UserID:    1,
-						Brand:     "bmw",
-						Model:     []string{"m3", "m5"},
-						CreatedAt: now,
-						UpdatedAt: now,
-					}, nil).
+				expectSettings(quietSettings)
+			},
+		},
+		{
+			name: "get user settings failed: change is retried on the next run",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return([]ds.WatchlistChangeResponse{priceChange}, nil).
 					Times(1)
-				s.mockTgBot.EXPECT().SendMessage(gomock.Any()).
-					Return(tgbotapi.Message{}, nil).
+				s.mockRepo.EXPECT().GetUserSettings(gomock.Any(), int64(1)).
+					Return(ds.UserSettingsResponse{}, errCommon).
 					Times(1)
-				s.mockRepo.EXPECT().CreateNotification(gomock.Any(), ds.CreateNotificationRequest{
-					SubscriptionID: subID,
-					ListingID:      listingID,
-					Status:         ds.StatusSent,
-				}).Return(ds.NotificationResponse{
-					ID:        uuid.NewString(),
-					ListingID: listingID,
-					Status:    ds.StatusSent,
-					CreatedAt: now,
-					UpdatedAt: now,
-				}, nil).
-					Times(1)
-				s.mockRepo.EXPECT().UpsertListing(gomock.Any(), ds.UpsertListingRequest{
-					ListingID:      listingID,
-					SubscriptionID: subID,
-					Title:          "Best bmw",
-					Price:          "2400€",
-					Date:           now,
-					IsNeedSend:     false,
-				}).
-					Return(errCommon).
+			},
+		},
+		{
+			name: "get watchlist changes failed: common error",
+			mock: func(*testCase) {
+				s.mockRepo.EXPECT().GetWatchlistChanges(gomock.Any()).
+					Return(nil, errCommon).
 					Times(1)
 			},
+			expectErr: errCommon,
 		},
 	}
+
 	for _, tc := range testCases {
 		s.Run(tc.name, func() {
 			tc.mock(&tc)
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-
-			err := s.svc.ProcessListings(ctx)
+			err := s.svc.ProcessWatchlist(context.Background())
 
 			switch {
 			case tc.expectErr != nil:
@@ -393,8 +1792,47 @@ func (s *ServiceTestSuite) TestService_ProcessListings() {
 			default:
 				s.Require().NoError(err)
 			}
+		})
+	}
+}
 
-			cancel()
+func (s *ServiceTestSuite) TestService_backoff() {
+	testCases := []struct {
+		name     string
+		attempts int
+		randInt  func(n int64) int64
+		want     time.Duration
+	}{
+		{
+			name:     "first attempt with min jitter",
+			attempts: 1,
+			randInt:  func(int64) int64 { return 0 },
+			want:     30 * time.Second,
+		},
+		{
+			name:     "first attempt with max jitter",
+			attempts: 1,
+			randInt:  func(n int64) int64 { return n - 1 },
+			want:     time.Minute,
+		},
+		{
+			name:     "delay doubles with every attempt",
+			attempts: 4,
+			randInt:  func(n int64) int64 { return n - 1 },
+			want:     8 * time.Minute,
+		},
+		{
+			name:     "delay is limited by max delay",
+			attempts: 100,
+			randInt:  func(n int64) int64 { return n - 1 },
+			want:     time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.svc.randInt = tc.randInt
+			s.Require().Equal(tc.want, s.svc.backoff(tc.attempts))
 		})
 	}
 }
@@ -413,7 +1851,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "success",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -433,13 +1872,13 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
 			},
@@ -448,7 +1887,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "get subscription from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{}, errCommon).
 					Times(1)
 			},
@@ -458,7 +1898,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete listings from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -478,7 +1919,7 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(errCommon).
 					Times(1)
 			},
@@ -488,7 +1929,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete subscriptions from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -508,10 +1950,10 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(errCommon).
 					Times(1)
 			},
@@ -521,7 +1963,8 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 		{
 			name: "delete user from DB failed: common error",
 			mock: func(tc *testCase) {
-				s.mockRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.expectTx()
+				s.mockTxRepo.EXPECT().GetSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return([]ds.SubscriptionResponse{
 						{
 							ID:        uuid.NewString(),
@@ -541,13 +1984,13 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 						},
 					}, nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
+				s.mockTxRepo.EXPECT().DeleteListingsBySubscriptionIDs(gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteSubscriptionsByUserID(gomock.Any(), tc.userID).
 					Return(nil).
 					Times(1)
-				s.mockRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
+				s.mockTxRepo.EXPECT().DeleteUserByID(gomock.Any(), tc.userID).
 					Return(errCommon).
 					Times(1)
 			},
@@ -573,6 +2016,102 @@ func (s *ServiceTestSuite) TestService_RemoveAllSubscriptionsByUserID() {
 	}
 }
 
+func Test_buildDetailsText(t *testing.T) {
+	testCases := []struct {
+		name    string
+		details ds.ListingDetails
+		want    string
+	}{
+		{
+			name:    "empty details",
+			details: ds.ListingDetails{},
+			want:    "",
+		},
+		{
+			name: "all details",
+			details: ds.ListingDetails{
+				FuelType:        "Dizel",
+				PowerKW:         135,
+				PowerHP:         184,
+				Color:           "Crna",
+				Drive:           "Zadnji",
+				Doors:           "4/5 vrata",
+				RegisteredUntil: "05.2025",
+				DamageStatus:    "Nije oštećen",
+				SellerType:      "private",
+			},
+			want: "\t⛽ *Fuel:* Dizel\n" +
+				"\t🐎 *Power:* 135 kW / 184 HP\n" +
+				"\t🎨 *Color:* Crna\n" +
+				"\t🛞 *Drive:* Zadnji\n" +
+				"\t🚪 *Doors:* 4/5 vrata\n" +
+				"\t🪪 *Registered Until:* 05\\.2025\n" +
+				"\t🔧 *Damage:* Nije oštećen\n" +
+				"\t👤 *Seller:* Private\n",
+		},
+		{
+			name: "partial details",
+			details: ds.ListingDetails{
+				FuelType:     "Benzin",
+				DamageStatus: "Oštećen - u voznom stanju",
+			},
+			want: "\t⛽ *Fuel:* Benzin\n" +
+				"\t🔧 *Damage:* Oštećen \\- u voznom stanju\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, buildDetailsText(tc.details))
+		})
+	}
+}
+
+func Test_markdownV2TextLength(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want int
+	}{
+		{
+			name: "plain text",
+			text: "hello",
+			want: 5,
+		},
+		{
+			name: "escaped characters",
+			text: "05\\.2025 \\- \\(new\\)",
+			want: 15,
+		},
+		{
+			name: "bold",
+			text: "*Title:* bmw",
+			want: 10,
+		},
+		{
+			name: "link",
+			text: "[tap to link](https://www.polovniautomobili.com/auto-oglasi/1/bmw\\)x)",
+			want: 11,
+		},
+		{
+			name: "escaped brackets are not a link",
+			text: "\\[a\\](b)",
+			want: 6,
+		},
+		{
+			name: "emoji are counted in UTF-16",
+			text: "🚗 *car*",
+			want: 6,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, markdownV2TextLength(tc.text))
+		})
+	}
+}
+
 func TestServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(ServiceTestSuite))
 }
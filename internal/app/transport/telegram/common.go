@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
 	"github.com/pkg/errors"
 
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/digest"
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
@@ -110,8 +112,11 @@ func createKeyboardWithPagination(
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
-// buildMessageWithSubscription constructs a subscription message with optional labels.
-func (h *BotHandler) buildMessageWithSubscription(subscription ds.SubscriptionResponse, isIncludeLabel bool) string {
+// buildMessageWithSubscription constructs a subscription message with optional labels,
+// the times are in the time zone of the user.
+func (h *BotHandler) buildMessageWithSubscription(
+	subscription ds.SubscriptionResponse, isIncludeLabel bool, loc *time.Location,
+) string {
 	var sb strings.Builder
 
 	if subscription.Brand != "" {
@@ -159,6 +164,74 @@ func (h *BotHandler) buildMessageWithSubscription(subscription ds.SubscriptionRe
 		)
 	}
 
+	state := stateFromSubscription(subscription)
+
+	for _, f := range optionFilters {
+		sb.WriteString(h.formatSubscriptionField(
+			strings.Join(*f.selected(state), ", "),
+			f.emoji,
+			f.label,
+			isIncludeLabel),
+		)
+	}
+
+	sb.WriteString(h.formatSubscriptionField(formatMileage(subscription.MileageTo), "🛣️", "Mileage", isIncludeLabel))
+	sb.WriteString(h.formatSubscriptionField(
+		formatPower(subscription.PowerFrom, subscription.PowerTo),
+		"🐎",
+		"Power",
+		isIncludeLabel),
+	)
+
+	if subscription.MinDealPercent.Valid {
+		sb.WriteString(h.formatSubscriptionField(
+			formatMinDealPercent(minDealPercentToState(subscription.MinDealPercent)),
+			"🏷️",
+			"Deal",
+			isIncludeLabel),
+		)
+	}
+
+	if len(subscription.IncludeKeywords) != 0 || len(subscription.ExcludeKeywords) != 0 {
+		sb.WriteString(h.formatSubscriptionField(
+			formatKeywords(subscription.IncludeKeywords, subscription.ExcludeKeywords),
+			"🔤",
+			"Keywords",
+			isIncludeLabel),
+		)
+	}
+
+	if len(subscription.ExtraParams) != 0 {
+		sb.WriteString(h.formatSubscriptionField(
+			formatExtraParams(subscription.ExtraParams),
+			"🔗",
+			"Other parameters",
+			isIncludeLabel),
+		)
+	}
+
+	if digest.IsDigest(subscription.DeliveryMode) {
+		sb.WriteString(h.formatSubscriptionField(
+			digest.Format(subscription.DeliveryMode, subscription.DigestHour),
+			"📬",
+			"Delivery",
+			isIncludeLabel),
+		)
+	}
+
+	if subscription.PriceOnly {
+		sb.WriteString(h.formatSubscriptionField("price changes only", "📉", "Notifications", isIncludeLabel))
+	}
+
+	if subscription.MutedUntil.Valid && h.now().Before(subscription.MutedUntil.Time) {
+		sb.WriteString(h.formatSubscriptionField(
+			formatMutedUntil(subscription.MutedUntil.Time, loc),
+			"🔕",
+			"Muted until",
+			isIncludeLabel),
+		)
+	}
+
 	sb.WriteString("\n")
 
 	return sb.String()
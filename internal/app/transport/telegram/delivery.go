@@ -0,0 +1,193 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/digest"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+const (
+	deliveryModeButtonsPerRow = 3
+	digestHourButtonsPerRow   = 6
+
+	// deliveryModePrefix is the prefix of the callback data of the buttons of the delivery modes.
+	deliveryModePrefix = "delivery:"
+	// digestHourPrefix is the prefix of the callback data of the buttons of the hours of the daily digest.
+	digestHourPrefix = "digest_hour:"
+)
+
+// sendDeliveryModeMessage sends a message asking the user to choose how the listings of the subscription are sent.
+func (h *BotHandler) sendDeliveryModeMessage(ctx context.Context, chatID int64) error {
+	state, exists := h.getState(ctx, chatID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	text := fmt.Sprintf(`
+📬 Delivery: %s
+
+Please choose how to get the listings of this subscription:
+⚡ Instant - each listing in its own message as soon as it's found
+🕐 Hourly - the listings of the last hour in one message at the start of every hour
+📅 Daily - the listings of the last day in one message at the hour you choose
+
+You can cancel the process at any time by sending '🚫 cancel'.`,
+		formatDeliveryMode(state),
+	)
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", handleNameCancel),
+	}
+
+	buttons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⚡ Instant", deliveryModePrefix+string(ds.DeliveryModeInstant)),
+		tgbotapi.NewInlineKeyboardButtonData("🕐 Hourly", deliveryModePrefix+string(ds.DeliveryModeHourly)),
+		tgbotapi.NewInlineKeyboardButtonData("📅 Daily", deliveryModePrefix+string(ds.DeliveryModeDaily)),
+	}
+
+	if err := h.sendSubscribeMessageWithButtons(ctx, MessageWithButtonsParams{
+		ChatID:         chatID,
+		Text:           text,
+		Buttons:        buttons,
+		ActionsButtons: actionsButtons,
+		ButtonsPerRow:  deliveryModeButtonsPerRow,
+		IsNeedEditMsg:  false,
+	}); err != nil {
+		h.l.Error("failed to send delivery mode message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send delivery mode message")
+	}
+
+	return nil
+}
+
+// handleSelectDeliveryMode handles the chosen delivery mode, the hour is chosen next for the daily digest.
+func (h *BotHandler) handleSelectDeliveryMode(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	mode, ok := parseDeliveryMode(callbackQuery.Data)
+	if !ok {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state.DeliveryMode = string(mode)
+
+	if mode == ds.DeliveryModeDaily {
+		state.Step = digestHourStep
+
+		if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+			return err
+		}
+
+		return h.sendDigestHourMessage(ctx, chatID)
+	}
+
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	return h.sendEditMenuMessage(ctx, chatID)
+}
+
+// sendDigestHourMessage sends a message asking the user to choose the hour of the daily digest.
+func (h *BotHandler) sendDigestHourMessage(ctx context.Context, chatID int64) error {
+	text := `
+🕘 Please choose the hour to get the daily digest at, the hours are in your time zone (see /settings):
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", handleNameCancel),
+	}
+
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, ds.HoursInDay)
+	for hour := range ds.HoursInDay {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%02d:00", hour), digestHourPrefix+strconv.Itoa(hour)),
+		)
+	}
+
+	if err := h.sendSubscribeMessageWithButtons(ctx, MessageWithButtonsParams{
+		ChatID:         chatID,
+		Text:           text,
+		Buttons:        buttons,
+		ActionsButtons: actionsButtons,
+		ButtonsPerRow:  digestHourButtonsPerRow,
+		IsNeedEditMsg:  false,
+	}); err != nil {
+		h.l.Error("failed to send digest hour message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send digest hour message")
+	}
+
+	return nil
+}
+
+// handleSelectDigestHour handles the chosen hour of the daily digest and returns to the edit menu.
+func (h *BotHandler) handleSelectDigestHour(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	hour, ok := parseDigestHour(callbackQuery.Data)
+	if !ok {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state.DigestHour = hour
+
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	return h.sendEditMenuMessage(ctx, chatID)
+}
+
+// parseDeliveryMode parses the delivery mode from the callback data of its button.
+func parseDeliveryMode(data string) (ds.DeliveryMode, bool) {
+	value, ok := strings.CutPrefix(data, deliveryModePrefix)
+	if !ok {
+		return "", false
+	}
+
+	switch mode := ds.DeliveryMode(value); mode {
+	case ds.DeliveryModeInstant, ds.DeliveryModeHourly, ds.DeliveryModeDaily:
+		return mode, true
+	}
+
+	return "", false
+}
+
+// parseDigestHour parses the hour of the daily digest from the callback data of its button.
+func parseDigestHour(data string) (int, bool) {
+	value, ok := strings.CutPrefix(data, digestHourPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	hour, err := strconv.Atoi(value)
+	if err != nil || !digest.IsValidHour(hour) {
+		return 0, false
+	}
+
+	return hour, true
+}
+
+// formatDeliveryMode formats the delivery mode of the subscription for the user.
+func formatDeliveryMode(state *SubscribeState) string {
+	return digest.Format(ds.DeliveryMode(state.DeliveryMode), state.DigestHour)
+}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func Test_parseDeliveryMode(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   string
+		want   ds.DeliveryMode
+		wantOk bool
+	}{
+		{
+			name:   "instant",
+			data:   "delivery:instant",
+			want:   ds.DeliveryModeInstant,
+			wantOk: true,
+		},
+		{
+			name:   "daily",
+			data:   "delivery:daily",
+			want:   ds.DeliveryModeDaily,
+			wantOk: true,
+		},
+		{
+			name: "unknown mode",
+			data: "delivery:weekly",
+		},
+		{
+			name: "another button",
+			data: "digest_hour:9",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDeliveryMode(tt.data)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseDigestHour(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   string
+		want   int
+		wantOk bool
+	}{
+		{
+			name:   "midnight",
+			data:   "digest_hour:0",
+			want:   0,
+			wantOk: true,
+		},
+		{
+			name:   "evening",
+			data:   "digest_hour:23",
+			want:   23,
+			wantOk: true,
+		},
+		{
+			name: "out of range",
+			data: "digest_hour:24",
+		},
+		{
+			name: "not a number",
+			data: "digest_hour:nine",
+		},
+		{
+			name: "another button",
+			data: "delivery:daily",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDigestHour(tt.data)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
 
@@ -15,18 +16,48 @@ type (
 		GetCfg() *telegram.Config
 		SendMessage(c tgbotapi.Chattable) (tgbotapi.Message, error)
 		SetCommands(commands []tgbotapi.BotCommand) error
+		SetWebhook(link, secretToken string) error
+		DeleteWebhook() error
 	}
 
 	Service interface {
 		RemoveAllSubscriptionsByUserID(ctx context.Context, userID int64) error
 		RemoveSubscriptionByID(ctx context.Context, id string) error
 		CreateSubscription(ctx context.Context, subscription ds.SubscriptionRequest) (ds.SubscriptionResponse, error)
+		UpdateSubscription(ctx context.Context, subscription ds.UpdateSubscriptionRequest) (ds.SubscriptionResponse, error)
 		GetAllSubscriptionsByUserID(ctx context.Context, userID int64) ([]ds.SubscriptionResponse, error)
 		UpsertUser(ctx context.Context, user ds.UserRequest) (ds.UserResponse, error)
+		GetListingPriceHistory(ctx context.Context, listingID string) ([]ds.ListingPriceResponse, error)
+		AddToWatchlist(ctx context.Context, userID int64, listingID string) error
+		RemoveFromWatchlist(ctx context.Context, userID int64, listingID string) error
+		GetWatchlist(ctx context.Context, userID int64) ([]ds.WatchlistItemResponse, error)
+		HideListing(ctx context.Context, userID int64, listingID string) error
+		MuteSubscription(ctx context.Context, userID int64, id string, until time.Time) (ds.SubscriptionResponse, error)
+		SetSubscriptionPriceOnly(
+			ctx context.Context, userID int64, id string, priceOnly bool,
+		) (ds.SubscriptionResponse, error)
+		GetUserSettings(ctx context.Context, userID int64) (ds.UserSettingsResponse, error)
+		UpdateUserSettings(ctx context.Context, request ds.UpdateUserSettingsRequest) (ds.UserSettingsResponse, error)
 
 		GetCarBrandsList() []string
 		GetCarModelsList(brand string) ([]string, bool)
 		GetCarChassisList() map[string]string
 		GetRegionsList() map[string]string
+		GetSearchFilterOptions(filter ds.SearchFilter) map[string]string
+	}
+
+	// StateStore keeps the subscribe state of unfinished conversations.
+	StateStore interface {
+		Get(ctx context.Context, userID int64) (*SubscribeState, bool, error)
+		Set(ctx context.Context, userID int64, state *SubscribeState) error
+		Delete(ctx context.Context, userID int64) error
+		DeleteExpired(ctx context.Context) (int64, error)
+	}
+
+	StateRepository interface {
+		GetConversationState(ctx context.Context, userID int64, now time.Time) (ds.ConversationStateResponse, error)
+		UpsertConversationState(ctx context.Context, state ds.ConversationStateRequest) error
+		DeleteConversationState(ctx context.Context, userID int64) error
+		DeleteExpiredConversationStates(ctx context.Context, now time.Time) (int64, error)
 	}
 )
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+type (
+	// dispatcher processes the updates in parallel across chats, keeping the updates of the same chat in order.
+	// Every chat is assigned to a single shard, and every shard is served by its own worker goroutine.
+	dispatcher struct {
+		shards []chan tgbotapi.Update
+		handle updateHandleFunc
+		wg     sync.WaitGroup
+	}
+
+	updateHandleFunc func(ctx context.Context, update tgbotapi.Update)
+)
+
+// newDispatcher creates a new dispatcher with the given number of workers and the queue size of each worker.
+func newDispatcher(workers, queueSize int, handle updateHandleFunc) *dispatcher {
+	shards := make([]chan tgbotapi.Update, max(workers, 1))
+	for i := range shards {
+		shards[i] = make(chan tgbotapi.Update, max(queueSize, 0))
+	}
+
+	return &dispatcher{
+		shards: shards,
+		handle: handle,
+		wg:     sync.WaitGroup{},
+	}
+}
+
+// start starts the workers, the updates are handled with the given context.
+func (d *dispatcher) start(ctx context.Context) {
+	for _, shard := range d.shards {
+		d.wg.Add(1)
+
+		go func() {
+			defer d.wg.Done()
+
+			for update := range shard {
+				d.handle(ctx, update)
+			}
+		}()
+	}
+}
+
+// dispatch puts the update to the queue of the chat's shard.
+// It blocks while the queue is full and returns an error if the context is canceled before that.
+func (d *dispatcher) dispatch(ctx context.Context, update tgbotapi.Update) error {
+	select {
+	case d.shards[d.shardIndex(updateChatID(update))] <- update:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck,nolintlint
+	}
+}
+
+// stop closes the queues and waits until the workers handle all queued updates.
+// dispatch must not be called after stop.
+func (d *dispatcher) stop() {
+	for _, shard := range d.shards {
+		close(shard)
+	}
+
+	d.wg.Wait()
+}
+
+// shardIndex returns the index of the shard for the chat.
+func (d *dispatcher) shardIndex(chatID int64) int {
+	return int(uint64(chatID) % uint64(len(d.shards))) //nolint:gosec,nolintlint
+}
+
+// updateChatID returns the ID of the chat the update belongs to.
+// Updates without a chat are keyed by the sender, and the rest go to the same shard.
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+
+	return 0
+}
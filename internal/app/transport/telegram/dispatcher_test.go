@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/require"
+)
+
+const dispatcherWaitTimeout = 5 * time.Second
+
+func newChatUpdate(updateID int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{ //nolint:exhaustruct,nolintlint
+		UpdateID: updateID,
+		Message: &tgbotapi.Message{ //nolint:exhaustruct,nolintlint
+			Chat: tgbotapi.Chat{ID: chatID}, //nolint:exhaustruct,nolintlint
+		},
+	}
+}
+
+func TestDispatcher_Order(t *testing.T) {
+	const (
+		chatsCount   = 10
+		updatesCount = 50
+	)
+
+	var (
+		mu      sync.Mutex
+		handled = make(map[int64][]int)
+	)
+
+	d := newDispatcher(3, 1, func(_ context.Context, update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		chatID := update.Message.Chat.ID
+		handled[chatID] = append(handled[chatID], update.UpdateID)
+	})
+	d.start(context.Background())
+
+	want := make(map[int64][]int)
+
+	for i := range updatesCount {
+		for chatID := range int64(chatsCount) {
+			require.NoError(t, d.dispatch(context.Background(), newChatUpdate(i, chatID)))
+			want[chatID] = append(want[chatID], i)
+		}
+	}
+
+	// stop drains all queued updates
+	d.stop()
+
+	require.Equal(t, want, handled)
+}
+
+func TestDispatcher_Parallel(t *testing.T) {
+	const (
+		slowChatID = int64(1)
+		fastChatID = int64(2)
+	)
+
+	release := make(chan struct{})
+	handled := make(chan int64, 2)
+
+	d := newDispatcher(2, 1, func(_ context.Context, update tgbotapi.Update) {
+		if update.Message.Chat.ID == slowChatID {
+			<-release
+		}
+
+		handled <- update.Message.Chat.ID
+	})
+	d.start(context.Background())
+
+	require.NoError(t, d.dispatch(context.Background(), newChatUpdate(1, slowChatID)))
+	require.NoError(t, d.dispatch(context.Background(), newChatUpdate(2, fastChatID)))
+
+	// the slow chat does not block the other chats
+	select {
+	case chatID := <-handled:
+		require.Equal(t, fastChatID, chatID)
+	case <-time.After(dispatcherWaitTimeout):
+		require.FailNow(t, "update of the fast chat was not handled")
+	}
+
+	close(release)
+	d.stop()
+
+	require.Equal(t, slowChatID, <-handled)
+}
+
+func TestDispatcher_Backpressure(t *testing.T) {
+	const chatID = int64(1)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	d := newDispatcher(1, 1, func(_ context.Context, _ tgbotapi.Update) {
+		started <- struct{}{}
+		<-release
+	})
+	d.start(context.Background())
+
+	// the first update is being handled and the second one is queued
+	require.NoError(t, d.dispatch(context.Background(), newChatUpdate(1, chatID)))
+	<-started
+	require.NoError(t, d.dispatch(context.Background(), newChatUpdate(2, chatID)))
+
+	// the queue is full, so dispatch blocks until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := d.dispatch(ctx, newChatUpdate(3, chatID))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	d.stop()
+}
+
+func Test_updateChatID(t *testing.T) {
+	testCases := []struct {
+		name   string
+		update tgbotapi.Update
+		want   int64
+	}{
+		{
+			name:   "message",
+			update: newChatUpdate(1, 10),
+			want:   10,
+		},
+		{
+			name: "callback query",
+			update: tgbotapi.Update{ //nolint:exhaustruct,nolintlint
+				CallbackQuery: &tgbotapi.CallbackQuery{ //nolint:exhaustruct,nolintlint
+					From: &tgbotapi.User{ID: 20}, //nolint:exhaustruct,nolintlint
+					Message: &tgbotapi.Message{ //nolint:exhaustruct,nolintlint
+						Chat: tgbotapi.Chat{ID: 20}, //nolint:exhaustruct,nolintlint
+					},
+				},
+			},
+			want: 20,
+		},
+		{
+			name:   "empty update",
+			update: tgbotapi.Update{}, //nolint:exhaustruct,nolintlint
+			want:   0,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, updateChatID(tt.update))
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+const editMenuButtonsPerRow = 3
+
+// handleEdit handles the /edit command, allowing the user to choose a subscription to edit.
+func (h *BotHandler) handleEdit(ctx context.Context, chatID int64) error {
+	text := "⚠️ An internal error occurred while getting the subscription list. Please try again later."
+
+	subscriptions, err := h.svc.GetAllSubscriptionsByUserID(ctx, chatID)
+	if err != nil {
+		return h.sendMessage(chatID, text, handleNameEdit)
+	}
+
+	if len(subscriptions) == 0 {
+		text = "📋 You have no subscriptions."
+		return h.sendMessage(chatID, text, handleNameEdit)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup()
+	loc := h.userLocation(ctx, chatID)
+
+	for _, sub := range subscriptions {
+		buttonText := strings.ReplaceAll(h.buildMessageWithSubscription(sub, false, loc), ", \n", "\n")
+		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("%s:%s", handleNameEdit, sub.ID))
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(button))
+	}
+
+	text = "✏️ Please choose a subscription to edit:"
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+
+	if _, err = h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("/edit: failed to send message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return nil
+}
+
+// handleEditCallback handles the callback query for choosing a subscription to edit.
+// The subscribe state is pre-filled from the stored subscription.
+func (h *BotHandler) handleEditCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+	subscriptionID := callbackQuery.Data
+
+	subscriptions, err := h.svc.GetAllSubscriptionsByUserID(ctx, callbackQuery.From.ID)
+	if err != nil {
+		text := "⚠️ An internal error occurred while getting the subscription. Please try again later."
+		return h.sendMessage(chatID, text, handleNameEdit)
+	}
+
+	idx := slices.IndexFunc(subscriptions, func(sub ds.SubscriptionResponse) bool {
+		return sub.ID == subscriptionID
+	})
+	if idx == -1 {
+		text := "⚠️ This subscription no longer exists."
+		return h.sendMessage(chatID, text, handleNameEdit)
+	}
+
+	state := stateFromSubscription(subscriptions[idx])
+	state.Step = editMenuStep
+	state.InProgress = true
+	state.EditingSubscriptionID = subscriptions[idx].ID
+
+	if err = h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	return h.sendEditMenuMessage(ctx, chatID)
+}
+
+// sendEditMenuMessage sends a message with the current subscription values and the steps which can be changed.
+func (h *BotHandler) sendEditMenuMessage(ctx context.Context, chatID int64) error {
+	state, exists := h.getState(ctx, chatID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state.Step = editMenuStep
+
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf(`
+	✏️ Editing subscription:
+
+	🚗 Brand: %s
+	🚘 Models: %s
+	🚙 Chassis: %s
+	📍 Regions: %s
+	💰 Price: %s€ - %s€
+	📅 Year: %s - %s
+	🔧 Filters: %s
+	🏷️ Deal: %s
+	🔤 Keywords: %s
+	📬 Delivery: %s
+
+	Please choose what you want to change, then type '✅ confirm' to save the changes or '🚫 cancel' to discard them.`,
+		state.SelectedBrand,
+		strings.Join(state.SelectedModels, ", "),
+		strings.Join(state.SelectedChassis, ", "),
+		strings.Join(state.SelectedRegions, ", "),
+		state.PriceFrom, state.PriceTo,
+		state.YearFrom, state.YearTo,
+		formatSearchFilters(state),
+		formatMinDealPercent(state.MinDealPercent),
+		formatKeywords(state.IncludeKeywords, state.ExcludeKeywords),
+		formatDeliveryMode(state),
+	)
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", handleNameCancel),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", handleNameConfirm),
+	}
+
+	buttons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚘 Models", editStepData(modelSelectionStep)),
+		tgbotapi.NewInlineKeyboardButtonData("🚙 Chassis", editStepData(chassisSelectionStep)),
+		tgbotapi.NewInlineKeyboardButtonData("📍 Regions", editStepData(regionSelectionStep)),
+		tgbotapi.NewInlineKeyboardButtonData("💰 Price", editStepData(priceFromStep)),
+		tgbotapi.NewInlineKeyboardButtonData("📅 Year", editStepData(yearFromStep)),
+		tgbotapi.NewInlineKeyboardButtonData("🔧 Filters", editStepData(filtersMenuStep)),
+		tgbotapi.NewInlineKeyboardButtonData("🏷️ Deal", editStepData(minDealPercentStep)),
+		tgbotapi.NewInlineKeyboardButtonData("🔤 Keywords", editStepData(keywordsStep)),
+		tgbotapi.NewInlineKeyboardButtonData("📬 Delivery", editStepData(deliveryModeStep)),
+	}
+
+	if err := h.sendSubscribeMessageWithButtons(ctx, MessageWithButtonsParams{
+		ChatID:         chatID,
+		Text:           text,
+		Buttons:        buttons,
+		ActionsButtons: actionsButtons,
+		ButtonsPerRow:  editMenuButtonsPerRow,
+		IsNeedEditMsg:  false,
+	}); err != nil {
+		h.l.Error("failed to send edit menu message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send edit menu message")
+	}
+
+	return nil
+}
+
+// handleEditStepCallback handles the callback query for jumping to a step of the edited subscription.
+func (h *BotHandler) handleEditStepCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists || state.EditingSubscriptionID == "" {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	step, err := strconv.Atoi(callbackQuery.Data)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse edit step")
+	}
+
+	state.Step = subscribeStep(step)
+
+	if err = h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	switch state.Step { //nolint:exhaustive,nolintlint
+	case modelSelectionStep:
+		text := fmt.Sprintf(`
+🚗 Selected models: %s
+
+Please choose models to add or remove and type '✅ done' if you are finished:
+You can cancel the process at any time by typing '🚫 cancel'`,
+			strings.Join(state.SelectedModels, ", "))
+
+		return h.sendModelSelectionMessage(ctx, chatID, text, state.SelectedBrand, 0)
+	case chassisSelectionStep:
+		text := fmt.Sprintf(`
+🚙 Selected chassis: %s
+
+Please choose chassis to add or remove and type '✅ done' if you are finished:
+You can cancel the process at any time by sending '🚫 cancel' or clear this filter by typing '⏭️ skip'.`,
+			strings.Join(state.SelectedChassis, ", "))
+
+		return h.sendChassisSelectionMessage(ctx, chatID, text)
+	case regionSelectionStep:
+		text := fmt.Sprintf(`
+📍 Selected regions: %s
+
+Please choose regions to add or remove and type '✅ done' if you are finished:
+You can cancel the process at any time by typing '🚫 cancel' or clear this filter by sending '⏭️ skip'.`,
+			strings.Join(state.SelectedRegions, ", "))
+
+		return h.sendRegionSelectionMessage(ctx, chatID, text)
+	case priceFromStep:
+		return h.sendPriceFromMessage(ctx, chatID)
+	case yearFromStep:
+		return h.sendYearFromMessage(ctx, chatID)
+	case filtersMenuStep:
+		return h.sendFiltersMenuMessage(ctx, chatID)
+	case minDealPercentStep:
+		return h.sendMinDealPercentMessage(ctx, chatID)
+	case keywordsStep:
+		return h.sendKeywordsMessage(ctx, chatID)
+	case deliveryModeStep:
+		return h.sendDeliveryModeMessage(ctx, chatID)
+	default:
+		h.l.Warn("unknown edit step", logger.AnyAttr("step", state.Step))
+		return h.sendEditMenuMessage(ctx, chatID)
+	}
+}
+
+// confirmEdit saves the changes of the edited subscription in place, keeping its listings.
+func (h *BotHandler) confirmEdit(ctx context.Context, chatID int64, state *SubscribeState) error {
+	_, err := h.svc.UpdateSubscription(ctx, ds.UpdateSubscriptionRequest{
+		ID:              state.EditingSubscriptionID,
+		UserID:          chatID,
+		Model:           state.SelectedModels,
+		Chassis:         state.SelectedChassis,
+		PriceFrom:       state.PriceFrom,
+		PriceTo:         state.PriceTo,
+		YearFrom:        state.YearFrom,
+		YearTo:          state.YearTo,
+		Region:          state.SelectedRegions,
+		MinDealPercent:  minDealPercentFromState(state.MinDealPercent),
+		IncludeKeywords: state.IncludeKeywords,
+		ExcludeKeywords: state.ExcludeKeywords,
+		Fuel:            state.SelectedFuel,
+		Gearbox:         state.SelectedGearbox,
+		MileageTo:       state.MileageTo,
+		PowerFrom:       state.PowerFrom,
+		PowerTo:         state.PowerTo,
+		Doors:           state.SelectedDoors,
+		Seats:           state.SelectedSeats,
+		Color:           state.SelectedColors,
+		AirCondition:    state.SelectedAirCondition,
+		Damage:          state.SelectedDamage,
+		ExtraParams:     state.ExtraParams,
+		DeliveryMode:    ds.DeliveryMode(state.DeliveryMode),
+		DigestHour:      state.DigestHour,
+	})
+	if err != nil {
+		text := "⚠️ An internal error occurred while updating your subscription. Please try again later."
+
+		return h.sendMessage(chatID, text, handleNameConfirm)
+	}
+
+	h.deleteState(ctx, chatID)
+
+	text := "✅ Your subscription has been updated successfully!"
+
+	return h.sendMessage(chatID, text, handleNameConfirm)
+}
+
+// stateFromSubscription fills the subscribe state with the values of the stored subscription.
+func stateFromSubscription(sub ds.SubscriptionResponse) *SubscribeState {
+	return &SubscribeState{ //nolint:exhaustruct,nolintlint
+		SelectedBrand:        sub.Brand,
+		SelectedModels:       slices.Clone(sub.Model),
+		SelectedChassis:      slices.Clone(sub.Chassis),
+		SelectedRegions:      slices.Clone(sub.Region),
+		PriceFrom:            sub.PriceFrom,
+		PriceTo:              sub.PriceTo,
+		YearFrom:             sub.YearFrom,
+		YearTo:               sub.YearTo,
+		SelectedFuel:         slices.Clone(sub.Fuel),
+		SelectedGearbox:      slices.Clone(sub.Gearbox),
+		SelectedDoors:        slices.Clone(sub.Doors),
+		SelectedSeats:        slices.Clone(sub.Seats),
+		SelectedColors:       slices.Clone(sub.Color),
+		SelectedAirCondition: slices.Clone(sub.AirCondition),
+		SelectedDamage:       slices.Clone(sub.Damage),
+		MileageTo:            sub.MileageTo,
+		PowerFrom:            sub.PowerFrom,
+		PowerTo:              sub.PowerTo,
+		MinDealPercent:       minDealPercentToState(sub.MinDealPercent),
+		IncludeKeywords:      slices.Clone(sub.IncludeKeywords),
+		ExcludeKeywords:      slices.Clone(sub.ExcludeKeywords),
+		ExtraParams:          maps.Clone(sub.ExtraParams),
+		DeliveryMode:         string(sub.DeliveryMode),
+		DigestHour:           sub.DigestHour,
+	}
+}
+
+// editStepData builds the callback data for the edit step button.
+func editStepData(step subscribeStep) string {
+	return fmt.Sprintf("%s:%d", handleNameEditStep, step)
+}
+
+// isEditStepCompleted checks if the edited step is finished and the user should return to the edit menu.
+// Price and year are entered in two steps, so the menu is shown only after the upper bound.
+func isEditStepCompleted(state *SubscribeState) bool {
+	if state == nil || state.EditingSubscriptionID == "" {
+		return false
+	}
+
+	return state.Step != priceToStep && state.Step != yearToStep
+}
@@ -0,0 +1,434 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// optionFilter is a search filter with the options the user selects with the buttons, e.g. the fuel type.
+type optionFilter struct {
+	step     subscribeStep
+	filter   ds.SearchFilter
+	emoji    string
+	label    string
+	plural   string
+	selected func(state *SubscribeState) *[]string
+}
+
+const (
+	filtersMenuButtonsPerRow = 3
+	optionButtonsPerRow      = 2
+	sendMileageButtonsPerRow = 2
+	sendPowerButtonsPerRow   = 2
+
+	// filterStepPrefix is the prefix of the callback data of the buttons of the filters menu.
+	filterStepPrefix = "filter:"
+)
+
+// optionFilters lists the search filters with the options in the order of their steps.
+var optionFilters = []optionFilter{ //nolint:gochecknoglobals,nolintlint
+	{
+		step: fuelSelectionStep, filter: ds.SearchFilterFuel, emoji: "⛽", label: "Fuel", plural: "fuel types",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedFuel },
+	},
+	{
+		step: gearboxSelectionStep, filter: ds.SearchFilterGearbox, emoji: "⚙️", label: "Gearbox", plural: "gearboxes",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedGearbox },
+	},
+	{
+		step: doorsSelectionStep, filter: ds.SearchFilterDoors, emoji: "🚪", label: "Doors", plural: "doors",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedDoors },
+	},
+	{
+		step: seatsSelectionStep, filter: ds.SearchFilterSeats, emoji: "💺", label: "Seats", plural: "seats",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedSeats },
+	},
+	{
+		step: colorSelectionStep, filter: ds.SearchFilterColor, emoji: "🎨", label: "Color", plural: "colors",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedColors },
+	},
+	{
+		step: airConditionSelectionStep, filter: ds.SearchFilterAirCondition, emoji: "❄️", label: "A/C",
+		plural:   "air conditioning types",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedAirCondition },
+	},
+	{
+		step: damageSelectionStep, filter: ds.SearchFilterDamage, emoji: "🔨", label: "Damage", plural: "damage states",
+		selected: func(state *SubscribeState) *[]string { return &state.SelectedDamage },
+	},
+}
+
+// optionFilterByStep returns the search filter with the options chosen at the step.
+func optionFilterByStep(step subscribeStep) (optionFilter, bool) {
+	for _, f := range optionFilters {
+		if f.step == step {
+			return f, true
+		}
+	}
+
+	return optionFilter{}, false //nolint:exhaustruct,nolintlint
+}
+
+// isFilterStep checks if the step is one of the additional filters, which are chosen in the filters menu.
+// The steps are stored in the conversation state and in the buttons, so they aren't ordered and
+// the filters are checked one by one.
+func isFilterStep(step subscribeStep) bool {
+	if _, ok := optionFilterByStep(step); ok {
+		return true
+	}
+
+	return step == mileageToStep || step == powerFromStep || step == powerToStep
+}
+
+// nextStep returns the step after the current one. The additional filters are chosen in the filters menu,
+// so their steps return to the menu, which is followed by the deal and the keywords steps.
+func nextStep(step subscribeStep) subscribeStep {
+	switch {
+	case step == yearToStep:
+		return filtersMenuStep
+	case step == filtersMenuStep:
+		return minDealPercentStep
+	case step == minDealPercentStep:
+		return keywordsStep
+	case step == keywordsStep:
+		return confirmSelectionStep
+	case step == powerFromStep:
+		return powerToStep
+	case isFilterStep(step):
+		return filtersMenuStep
+	default:
+		return step + 1
+	}
+}
+
+// sendFiltersMenuMessage sends a message with the additional filters and the buttons to change them.
+func (h *BotHandler) sendFiltersMenuMessage(ctx context.Context, chatID int64) error {
+	state, exists := h.getState(ctx, chatID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state.Step = filtersMenuStep
+
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf(`
+🔧 Additional filters: %s
+
+Please choose a filter to change, then type '✅ done' to continue or '⏭️ skip' to clear all the filters.
+You can cancel the process at any time by sending '🚫 cancel'.`,
+		formatSearchFilters(state),
+	)
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Done", "/done"),
+	}
+
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(optionFilters)+2)
+	for _, f := range optionFilters {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(f.emoji+" "+f.label, filterStepData(f.step)))
+	}
+
+	buttons = append(buttons,
+		tgbotapi.NewInlineKeyboardButtonData("🛣️ Mileage", filterStepData(mileageToStep)),
+		tgbotapi.NewInlineKeyboardButtonData("🐎 Power", filterStepData(powerFromStep)),
+	)
+
+	if err := h.sendSubscribeMessageWithButtons(ctx, MessageWithButtonsParams{
+		ChatID:         chatID,
+		Text:           text,
+		Buttons:        buttons,
+		ActionsButtons: actionsButtons,
+		ButtonsPerRow:  filtersMenuButtonsPerRow,
+		IsNeedEditMsg:  false,
+	}); err != nil {
+		h.l.Error("failed to send filters menu message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send filters menu message")
+	}
+
+	return nil
+}
+
+// handleFiltersMenu handles the callback query for choosing a filter in the filters menu.
+func (h *BotHandler) handleFiltersMenu(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	data, ok := strings.CutPrefix(callbackQuery.Data, filterStepPrefix)
+	if !ok {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	step, err := strconv.Atoi(data)
+	if err != nil || !isFilterStep(subscribeStep(step)) {
+		return errors.Errorf("invalid filter step: %s", data)
+	}
+
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	state.Step = subscribeStep(step)
+
+	if err = h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	switch state.Step { //nolint:exhaustive,nolintlint
+	case mileageToStep:
+		return h.sendMileageToMessage(ctx, chatID)
+	case powerFromStep:
+		return h.sendPowerFromMessage(ctx, chatID)
+	default:
+		f, _ := optionFilterByStep(state.Step)
+
+		return h.sendOptionSelectionMessage(ctx, chatID, f)
+	}
+}
+
+// sendOptionSelectionMessage sends a message asking the user to select the options of the search filter.
+func (h *BotHandler) sendOptionSelectionMessage(ctx context.Context, chatID int64, f optionFilter) error {
+	state, exists := h.getState(ctx, chatID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	text := fmt.Sprintf(`
+%s Please choose %s (you can select multiple). When you're done, type '✅ done':
+
+You can cancel the process at any time by sending '🚫 cancel' or skip this step by sending '⏭️ skip'.`,
+		f.emoji, f.plural)
+
+	if selected := *f.selected(state); len(selected) != 0 {
+		text = fmt.Sprintf(`
+%s Selected %s: %s
+
+Please choose %s to add or remove and type '✅ done' if you are finished:
+You can cancel the process at any time by sending '🚫 cancel' or clear this filter by typing '⏭️ skip'.`,
+			f.emoji, f.plural, strings.Join(selected, ", "), f.plural)
+	}
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Done", "/done"),
+	}
+
+	buttons := generateButtons(ctx, h.svc.GetSearchFilterOptions(f.filter))
+
+	if err := h.sendSubscribeMessageWithButtons(ctx, MessageWithButtonsParams{
+		ChatID:         chatID,
+		Text:           text,
+		Buttons:        buttons,
+		ActionsButtons: actionsButtons,
+		ButtonsPerRow:  optionButtonsPerRow,
+		IsNeedEditMsg:  true,
+	}); err != nil {
+		h.l.Error("failed to send option selection message", logger.ErrAttr(err),
+			logger.StringAttr("filter", string(f.filter)))
+
+		return errors.Wrap(err, "failed to send option selection message")
+	}
+
+	return nil
+}
+
+// handleSelectOption handles the selection of an option of the search filter.
+func (h *BotHandler) handleSelectOption(
+	ctx context.Context, callbackQuery *tgbotapi.CallbackQuery, f optionFilter,
+) error {
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID)
+	}
+
+	selected := f.selected(state)
+	*selected = toggle(*selected, callbackQuery.Data)
+
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
+	return h.sendOptionSelectionMessage(ctx, callbackQuery.Message.Chat.ID, f)
+}
+
+// sendMileageToMessage sends a message asking the user to enter the maximum mileage.
+func (h *BotHandler) sendMileageToMessage(ctx context.Context, chatID int64) error {
+	text := `
+🛣️ Please enter the maximum mileage in km or type '️⏭️ skip' to skip this step:
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, sendMileageButtonsPerRow, actionsButtons, nil)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("failed to send mileage to message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send mileage to message")
+	}
+
+	return nil
+}
+
+// handleMileageTo processes the user's input for the maximum mileage.
+func (h *BotHandler) handleMileageTo(ctx context.Context, message *tgbotapi.Message) error {
+	errText := "⚠️ Invalid mileage. Please enter a valid number or type '️⏭️ skip' to skip this step:"
+	if err := h.handleNumericInput(
+		ctx, message, mileageToStep, filtersMenuStep, errText, sendMileageButtonsPerRow,
+	); err != nil {
+		return err
+	}
+
+	return h.sendFiltersMenuMessage(ctx, message.Chat.ID)
+}
+
+// sendPowerFromMessage sends a message asking the user to enter the minimum engine power.
+func (h *BotHandler) sendPowerFromMessage(ctx context.Context, chatID int64) error {
+	text := `
+🐎 Please enter the minimum engine power in kW or type '️⏭️ skip' to skip this step:
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, sendPowerButtonsPerRow, actionsButtons, nil)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("failed to send power from message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send power from message")
+	}
+
+	return nil
+}
+
+// handlePowerFrom processes the user's input for the minimum engine power.
+func (h *BotHandler) handlePowerFrom(ctx context.Context, message *tgbotapi.Message) error {
+	errText := "⚠️ Invalid power from. Please enter a valid number or type '️⏭️ skip' to skip this step:"
+	if err := h.handleNumericInput(
+		ctx, message, powerFromStep, powerToStep, errText, sendPowerButtonsPerRow,
+	); err != nil {
+		return err
+	}
+
+	return h.sendPowerToMessage(ctx, message.Chat.ID)
+}
+
+// sendPowerToMessage sends a message asking the user to enter the maximum engine power.
+func (h *BotHandler) sendPowerToMessage(ctx context.Context, chatID int64) error {
+	text := `
+🐎 Please enter the maximum engine power in kW or type '️⏭️ skip' to skip this step:
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, sendPowerButtonsPerRow, actionsButtons, nil)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("failed to send power to message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send power to message")
+	}
+
+	return nil
+}
+
+// handlePowerTo processes the user's input for the maximum engine power.
+func (h *BotHandler) handlePowerTo(ctx context.Context, message *tgbotapi.Message) error {
+	errText := "⚠️ Invalid power to. Please enter a valid number or type '️⏭️ skip' to skip this step:"
+	if err := h.handleNumericInput(
+		ctx, message, powerToStep, filtersMenuStep, errText, sendPowerButtonsPerRow,
+	); err != nil {
+		return err
+	}
+
+	return h.sendFiltersMenuMessage(ctx, message.Chat.ID)
+}
+
+// clearSearchFilters clears all the additional filters of the state.
+func clearSearchFilters(state *SubscribeState) {
+	for _, f := range optionFilters {
+		*f.selected(state) = []string{}
+	}
+
+	state.MileageTo = ""
+	state.PowerFrom = ""
+	state.PowerTo = ""
+}
+
+// formatSearchFilters formats the additional filters of the state for the user, e.g. "⛽ Dizel; 🛣️ up to 150000 km".
+func formatSearchFilters(state *SubscribeState) string {
+	var parts []string
+
+	for _, f := range optionFilters {
+		if selected := *f.selected(state); len(selected) != 0 {
+			parts = append(parts, f.emoji+" "+strings.Join(selected, ", "))
+		}
+	}
+
+	if mileage := formatMileage(state.MileageTo); mileage != "" {
+		parts = append(parts, "🛣️ "+mileage)
+	}
+
+	if power := formatPower(state.PowerFrom, state.PowerTo); power != "" {
+		parts = append(parts, "🐎 "+power)
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// formatMileage formats the maximum mileage, it's empty if there is no limit.
+func formatMileage(mileageTo string) string {
+	if mileageTo == "" {
+		return ""
+	}
+
+	return "up to " + mileageTo + " km"
+}
+
+// formatPower formats the range of the engine power, it's empty if there is no limit.
+func formatPower(powerFrom, powerTo string) string {
+	switch {
+	case powerFrom == "" && powerTo == "":
+		return ""
+	case powerFrom == "":
+		return "up to " + powerTo + " kW"
+	case powerTo == "":
+		return "from " + powerFrom + " kW"
+	default:
+		return fmt.Sprintf("%s - %s kW", powerFrom, powerTo)
+	}
+}
+
+// filterStepData builds the callback data for the button of the filters menu.
+func filterStepData(step subscribeStep) string {
+	return fmt.Sprintf("%s%d", filterStepPrefix, step)
+}
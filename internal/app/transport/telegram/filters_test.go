@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_nextStep(t *testing.T) {
+	testCases := []struct {
+		name string
+		step subscribeStep
+		want subscribeStep
+	}{
+		{name: "regular step", step: chassisSelectionStep, want: regionSelectionStep},
+		{name: "year to opens the filters menu", step: yearToStep, want: filtersMenuStep},
+		{name: "filters menu is followed by the deal", step: filtersMenuStep, want: minDealPercentStep},
+		{name: "option filter returns to the menu", step: fuelSelectionStep, want: filtersMenuStep},
+		{name: "last option filter returns to the menu", step: damageSelectionStep, want: filtersMenuStep},
+		{name: "power from is followed by power to", step: powerFromStep, want: powerToStep},
+		{name: "power to returns to the menu", step: powerToStep, want: filtersMenuStep},
+		{name: "deal", step: minDealPercentStep, want: keywordsStep},
+		{name: "keywords are followed by the confirmation", step: keywordsStep, want: confirmSelectionStep},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, nextStep(tt.step))
+		})
+	}
+}
+
+func Test_optionFilterByStep(t *testing.T) {
+	for _, f := range optionFilters {
+		got, ok := optionFilterByStep(f.step)
+		require.True(t, ok)
+		require.Equal(t, f.filter, got.filter)
+		require.True(t, isFilterStep(f.step))
+	}
+
+	_, ok := optionFilterByStep(mileageToStep)
+	require.False(t, ok)
+
+	require.True(t, isFilterStep(mileageToStep))
+	require.True(t, isFilterStep(powerToStep))
+	require.False(t, isFilterStep(filtersMenuStep))
+	require.False(t, isFilterStep(minDealPercentStep))
+	require.False(t, isFilterStep(confirmSelectionStep))
+}
+
+func Test_formatSearchFilters(t *testing.T) {
+	testCases := []struct {
+		name  string
+		state *SubscribeState
+		want  string
+	}{
+		{
+			name:  "no filters",
+			state: &SubscribeState{}, //nolint:exhaustruct,nolintlint
+			want:  "none",
+		},
+		{
+			name: "all kinds of filters",
+			state: &SubscribeState{ //nolint:exhaustruct,nolintlint
+				SelectedFuel:    []string{"Dizel", "Benzin"},
+				SelectedGearbox: []string{"Automatski / poluautomatski"},
+				MileageTo:       "150000",
+				PowerTo:         "200",
+			},
+			want: "⛽ Dizel, Benzin; ⚙️ Automatski / poluautomatski; 🛣️ up to 150000 km; 🐎 up to 200 kW",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, formatSearchFilters(tt.state))
+		})
+	}
+}
+
+func Test_formatPower(t *testing.T) {
+	require.Empty(t, formatPower("", ""))
+	require.Equal(t, "from 100 kW", formatPower("100", ""))
+	require.Equal(t, "up to 200 kW", formatPower("", "200"))
+	require.Equal(t, "100 - 200 kW", formatPower("100", "200"))
+}
+
+func Test_clearSearchFilters(t *testing.T) {
+	state := &SubscribeState{ //nolint:exhaustruct,nolintlint
+		SelectedBrand:  "bmw",
+		SelectedFuel:   []string{"Dizel"},
+		SelectedDamage: []string{"Nije oštećen"},
+		MileageTo:      "150000",
+		PowerFrom:      "100",
+	}
+
+	clearSearchFilters(state)
+
+	require.Equal(t, "none", formatSearchFilters(state))
+	require.Equal(t, "bmw", state.SelectedBrand)
+}
@@ -2,19 +2,24 @@ package telegram
 
 import (
 	"context"
+	"net"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
 
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/listingaction"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
 type BotHandler struct {
-	l     *logger.Logger
-	tgBot TgBot
-	svc   Service
-	state map[int64]*SubscribeState
+	l      *logger.Logger
+	tgBot  TgBot
+	svc    Service
+	states StateStore
+	now    func() time.Time
 }
 
 const (
@@ -23,35 +28,76 @@ const (
 	handleNameListSubscriptions = "/list_subscriptions"
 	handleNameSubscribe         = "/subscribe"
 	handleNameUnsubscribe       = "/unsubscribe"
+	handleNameEdit              = "/edit"
+	handleNameEditStep          = "/edit_step"
 	handleNameCancel            = "/cancel"
 	handleNameSkip              = "/skip"
 	handleNameDone              = "/done"
 	handleNameConfirm           = "/confirm"
+	handleNameHistory           = "/history"
+	handleNameWatchlist         = "/watchlist"
+	handleNameSettings          = "/settings"
 	handleNameUnknown           = "unknown command"
+
+	stateCleanupInterval = time.Hour
 )
 
-func NewBotHandler(l *logger.Logger, tgBot TgBot, svc Service) *BotHandler {
+func NewBotHandler(l *logger.Logger, tgBot TgBot, svc Service, states StateStore) *BotHandler {
 	return &BotHandler{
-		l:     l,
-		tgBot: tgBot,
-		svc:   svc,
-		state: make(map[int64]*SubscribeState),
+		l:      l,
+		tgBot:  tgBot,
+		svc:    svc,
+		states: states,
+		now:    time.Now,
 	}
 }
 
 // Start begins processing updates from Telegram.
+// Updates are received with long polling, or with the webhook if it is enabled in the configuration.
 func (h *BotHandler) Start(ctx context.Context) error {
 	h.l.Info("bot handler started")
 
+	cfg := h.tgBot.GetCfg()
+	if cfg.IsWebhookEnabled {
+		listener, err := net.Listen("tcp", cfg.WebhookListenAddr)
+		if err != nil {
+			return errors.Wrap(err, "failed to listen webhook address")
+		}
+
+		return h.serveWebhook(ctx, listener)
+	}
+
 	updateConfig := tgbotapi.NewUpdate(0)
-	updateConfig.Timeout = h.tgBot.GetCfg().UpdateCfgTimeout
+	updateConfig.Timeout = cfg.UpdateCfgTimeout
 
 	updates := h.tgBot.GetAPI().GetUpdatesChan(updateConfig)
 
+	return h.processUpdates(ctx, updates, nil)
+}
+
+// processUpdates handles the updates until the context is canceled or the update source fails.
+// The updates are handled by the dispatcher, the queued updates are drained before return.
+func (h *BotHandler) processUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, errCh <-chan error) error {
+	cfg := h.tgBot.GetCfg()
+
+	d := newDispatcher(cfg.HandlerWorkers, cfg.HandlerQueueSize, h.HandleUpdate)
+	// the queued updates are still handled on shutdown, so the handler context is not canceled
+	d.start(context.WithoutCancel(ctx))
+	defer d.stop()
+
+	cleanupTicker := time.NewTicker(stateCleanupInterval)
+	defer cleanupTicker.Stop()
+
 	for {
 		select {
 		case update := <-updates:
-			h.HandleUpdate(ctx, update)
+			if err := d.dispatch(ctx, update); err != nil {
+				h.l.Warn("update is not dispatched", logger.IntAttr("update_id", update.UpdateID), logger.ErrAttr(err))
+			}
+		case <-cleanupTicker.C:
+			h.deleteExpiredStates(ctx)
+		case err := <-errCh:
+			return err
 		case <-ctx.Done():
 			h.l.Info("bot handler stopped", logger.ErrAttr(ctx.Err()))
 			return nil
@@ -75,6 +121,15 @@ func (h *BotHandler) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
 func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 	var err error
 
+	// the commands with an argument are matched by the command only
+	if command, arg, _ := strings.Cut(message.Text, " "); command == handleNameHistory {
+		if err = h.handleHistory(ctx, message.Chat.ID, arg); err != nil {
+			h.l.Error("failed to send message", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
 	switch message.Text {
 	case handleNameStart:
 		err = h.handleStart(ctx, message.Chat)
@@ -84,6 +139,8 @@ func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Messag
 		err = h.handleSubscribe(ctx, message.Chat.ID)
 	case handleNameUnsubscribe:
 		err = h.handleUnsubscribe(ctx, message.Chat.ID)
+	case handleNameEdit:
+		err = h.handleEdit(ctx, message.Chat.ID)
 	case handleNameListSubscriptions:
 		err = h.handleListSubscriptions(ctx, message.Chat.ID)
 	case handleNameDone:
@@ -94,8 +151,17 @@ func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Messag
 		err = h.handleSkip(ctx, message.Chat.ID)
 	case handleNameConfirm:
 		err = h.handleConfirm(ctx, message.Chat.ID)
+	case handleNameWatchlist:
+		err = h.handleWatchlist(ctx, message.Chat.ID)
+	case handleNameSettings:
+		err = h.handleSettings(ctx, message.Chat.ID)
 	default:
-		state, exists := h.state[message.Chat.ID]
+		if isSearchLink(message.Text) {
+			err = h.handleSearchLink(ctx, message)
+			break
+		}
+
+		state, exists := h.getState(ctx, message.Chat.ID)
 		if exists {
 			switch state.Step { //nolint:exhaustive,nolintlint
 			case priceFromStep:
@@ -106,6 +172,18 @@ func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Messag
 				err = h.handleYearFrom(ctx, message)
 			case yearToStep:
 				err = h.handleYearTo(ctx, message)
+			case mileageToStep:
+				err = h.handleMileageTo(ctx, message)
+			case powerFromStep:
+				err = h.handlePowerFrom(ctx, message)
+			case powerToStep:
+				err = h.handlePowerTo(ctx, message)
+			case minDealPercentStep:
+				err = h.handleMinDealPercent(ctx, message)
+			case keywordsStep:
+				err = h.handleKeywords(ctx, message)
+			case timeZoneStep:
+				err = h.handleTimeZone(ctx, message)
 			default:
 				err = h.sendUnknownCommandMessage(ctx, message.Chat.ID)
 			}
@@ -120,6 +198,14 @@ func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Messag
 }
 
 func (h *BotHandler) handleCallbackQuery(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
+	if listingaction.Match(callbackQuery.Data) {
+		if err := h.handleListingAction(ctx, callbackQuery); err != nil {
+			h.l.Error("failed to handle listing action", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
 	if strings.HasPrefix(callbackQuery.Data, handleNameUnsubscribe+":") {
 		callbackQuery.Data = strings.TrimPrefix(callbackQuery.Data, handleNameUnsubscribe+":")
 		if err := h.handleUnsubscribeCallback(ctx, callbackQuery); err != nil {
@@ -129,6 +215,42 @@ func (h *BotHandler) handleCallbackQuery(ctx context.Context, callbackQuery *tgb
 		return
 	}
 
+	if strings.HasPrefix(callbackQuery.Data, handleNameWatchlist+":") {
+		callbackQuery.Data = strings.TrimPrefix(callbackQuery.Data, handleNameWatchlist+":")
+		if err := h.handleWatchlistCallback(ctx, callbackQuery); err != nil {
+			h.l.Error("failed to handle watchlist callback", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, handleNameSettings+":") {
+		callbackQuery.Data = strings.TrimPrefix(callbackQuery.Data, handleNameSettings+":")
+		if err := h.handleSettingsCallback(ctx, callbackQuery); err != nil {
+			h.l.Error("failed to handle settings callback", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, handleNameEdit+":") {
+		callbackQuery.Data = strings.TrimPrefix(callbackQuery.Data, handleNameEdit+":")
+		if err := h.handleEditCallback(ctx, callbackQuery); err != nil {
+			h.l.Error("failed to handle edit callback", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, handleNameEditStep+":") {
+		callbackQuery.Data = strings.TrimPrefix(callbackQuery.Data, handleNameEditStep+":")
+		if err := h.handleEditStepCallback(ctx, callbackQuery); err != nil {
+			h.l.Error("failed to handle edit step callback", logger.ErrAttr(err))
+		}
+
+		return
+	}
+
 	if isHandled, err := h.handleActionButtons(ctx, callbackQuery); isHandled {
 		if err != nil {
 			h.l.Error("failed to handle action buttons", logger.ErrAttr(err))
@@ -149,6 +271,8 @@ func (h *BotHandler) handleActionButtons(ctx context.Context, callbackQuery *tgb
 		return true, h.handleSubscribe(ctx, callbackQuery.From.ID)
 	case handleNameUnsubscribe:
 		return true, h.handleUnsubscribe(ctx, callbackQuery.From.ID)
+	case handleNameEdit:
+		return true, h.handleEdit(ctx, callbackQuery.From.ID)
 	case handleNameListSubscriptions:
 		return true, h.handleListSubscriptions(ctx, callbackQuery.From.ID)
 	case handleNameStop:
@@ -161,6 +285,10 @@ func (h *BotHandler) handleActionButtons(ctx context.Context, callbackQuery *tgb
 		return true, h.handleSkip(ctx, callbackQuery.From.ID)
 	case handleNameConfirm:
 		return true, h.handleConfirm(ctx, callbackQuery.From.ID)
+	case handleNameWatchlist:
+		return true, h.handleWatchlist(ctx, callbackQuery.From.ID)
+	case handleNameSettings:
+		return true, h.handleSettings(ctx, callbackQuery.From.ID)
 	}
 
 	return false, nil
@@ -168,7 +296,7 @@ func (h *BotHandler) handleActionButtons(ctx context.Context, callbackQuery *tgb
 
 // handleStateSteps handles state steps.
 func (h *BotHandler) handleStateSteps(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
-	state, exists := h.state[callbackQuery.From.ID]
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
 	if !exists {
 		if err := h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID); err != nil {
 			h.l.Error("failed to send unknown command message", logger.ErrAttr(err))
@@ -178,6 +306,10 @@ func (h *BotHandler) handleStateSteps(ctx context.Context, callbackQuery *tgbota
 		return nil
 	}
 
+	if f, ok := optionFilterByStep(state.Step); ok {
+		return h.handleSelectOption(ctx, callbackQuery, f)
+	}
+
 	switch state.Step { //nolint:exhaustive,nolintlint
 	case brandSelectionStep:
 		return h.handleSelectBrand(ctx, callbackQuery)
@@ -195,6 +327,20 @@ func (h *BotHandler) handleStateSteps(ctx context.Context, callbackQuery *tgbota
 		return h.handleYearFrom(ctx, callbackQuery.Message)
 	case yearToStep:
 		return h.handleYearTo(ctx, callbackQuery.Message)
+	case filtersMenuStep:
+		return h.handleFiltersMenu(ctx, callbackQuery)
+	case mileageToStep:
+		return h.handleMileageTo(ctx, callbackQuery.Message)
+	case powerFromStep:
+		return h.handlePowerFrom(ctx, callbackQuery.Message)
+	case powerToStep:
+		return h.handlePowerTo(ctx, callbackQuery.Message)
+	case minDealPercentStep:
+		return h.handleMinDealPercent(ctx, callbackQuery.Message)
+	case deliveryModeStep:
+		return h.handleSelectDeliveryMode(ctx, callbackQuery)
+	case digestHourStep:
+		return h.handleSelectDigestHour(ctx, callbackQuery)
 	default:
 		h.l.Warn("unknown subscription step", logger.AnyAttr("step", state.Step))
 	}
@@ -202,6 +348,48 @@ func (h *BotHandler) handleStateSteps(ctx context.Context, callbackQuery *tgbota
 	return nil
 }
 
+// getState returns the user's subscribe state from the state store.
+// An error is only logged and handled as a missing state.
+func (h *BotHandler) getState(ctx context.Context, userID int64) (*SubscribeState, bool) {
+	state, exists, err := h.states.Get(ctx, userID)
+	if err != nil {
+		h.l.Error("failed to get subscribe state", logger.ErrAttr(err), logger.Int64Attr("user_id", userID))
+		return nil, false
+	}
+
+	return state, exists
+}
+
+// saveState saves the user's subscribe state to the state store.
+func (h *BotHandler) saveState(ctx context.Context, userID int64, state *SubscribeState) error {
+	if err := h.states.Set(ctx, userID, state); err != nil {
+		h.l.Error("failed to save subscribe state", logger.ErrAttr(err), logger.Int64Attr("user_id", userID))
+		return errors.Wrap(err, "failed to save subscribe state")
+	}
+
+	return nil
+}
+
+// deleteState removes the user's subscribe state from the state store.
+func (h *BotHandler) deleteState(ctx context.Context, userID int64) {
+	if err := h.states.Delete(ctx, userID); err != nil {
+		h.l.Error("failed to delete subscribe state", logger.ErrAttr(err), logger.Int64Attr("user_id", userID))
+	}
+}
+
+// deleteExpiredStates removes the abandoned conversations from the state store.
+func (h *BotHandler) deleteExpiredStates(ctx context.Context) {
+	count, err := h.states.DeleteExpired(ctx)
+	if err != nil {
+		h.l.Error("failed to delete expired subscribe states", logger.ErrAttr(err))
+		return
+	}
+
+	if count > 0 {
+		h.l.Info("deleted expired subscribe states", logger.Int64Attr("count", count))
+	}
+}
+
 // recoverPanic recovers from a panic and logs the error.
 func (h *BotHandler) recoverPanic() {
 	if r := recover(); r != nil {
@@ -1,5 +1,28 @@
 package telegram
 
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/keywords"
+)
+
+const (
+	// maxKeywords is the max number of the include and exclude keywords of a subscription.
+	maxKeywords = 20
+	// maxKeywordLength is the max number of characters of a keyword.
+	maxKeywordLength = 50
+)
+
+var (
+	errNoKeywords      = errors.New("no keywords")
+	errTooManyKeywords = errors.New("too many keywords")
+	errKeywordTooLong  = errors.New("keyword is too long")
+)
+
 // contains checks if an item is already in the selected slice.
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -10,3 +33,78 @@ func contains(slice []string, item string) bool {
 
 	return false
 }
+
+// toggle adds an item to the selected slice or removes it if it is already selected.
+func toggle(slice []string, item string) []string {
+	if !contains(slice, item) {
+		return append(slice, item)
+	}
+
+	return slices.DeleteFunc(slice, func(s string) bool {
+		return s == item
+	})
+}
+
+// parseKeywords parses the keywords separated by commas or new lines, a keyword starting with '-' is excluded,
+// e.g. "xdrive, -oštećen" includes "xdrive" and excludes "oštećen".
+// The keywords which are the same after the normalization are only kept once.
+func parseKeywords(input string) ([]string, []string, error) {
+	include, exclude := []string{}, []string{}
+	seen := make(map[string]struct{})
+
+	for _, keyword := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		keyword = strings.TrimSpace(keyword)
+
+		keyword, isExcluded := strings.CutPrefix(keyword, "-")
+		keyword = strings.TrimSpace(keyword)
+
+		normalized := keywords.Normalize(keyword)
+		if normalized == "" {
+			continue
+		}
+
+		if utf8.RuneCountInString(keyword) > maxKeywordLength {
+			return nil, nil, errKeywordTooLong
+		}
+
+		key := normalized
+		if isExcluded {
+			key = "-" + normalized
+		}
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		if isExcluded {
+			exclude = append(exclude, keyword)
+		} else {
+			include = append(include, keyword)
+		}
+	}
+
+	switch {
+	case len(include)+len(exclude) == 0:
+		return nil, nil, errNoKeywords
+	case len(include)+len(exclude) > maxKeywords:
+		return nil, nil, errTooManyKeywords
+	}
+
+	return include, exclude, nil
+}
+
+// formatKeywords formats the keywords the same way the user enters them, the excluded ones start with '-'.
+func formatKeywords(include, exclude []string) string {
+	if len(include) == 0 && len(exclude) == 0 {
+		return "any title"
+	}
+
+	formatted := slices.Clone(include)
+	for _, keyword := range exclude {
+		formatted = append(formatted, "-"+keyword)
+	}
+
+	return strings.Join(formatted, ", ")
+}
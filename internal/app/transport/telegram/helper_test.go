@@ -1,6 +1,7 @@
 package telegram
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,3 +25,93 @@ func Test_contains(t *testing.T) {
 		})
 	}
 }
+
+func Test_toggle(t *testing.T) {
+	testCases := []struct {
+		name  string
+		item  string
+		slice []string
+		want  []string
+	}{
+		{name: "add item", item: "hello", slice: []string{"world"}, want: []string{"world", "hello"}},
+		{name: "remove item", item: "hello", slice: []string{"hello", "world"}, want: []string{"world"}},
+		{name: "add to empty slice", item: "hello", slice: []string{}, want: []string{"hello"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toggle(tt.slice, tt.item)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseKeywords(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		wantInclude []string
+		wantExclude []string
+		wantErr     error
+	}{
+		{
+			name:        "include and exclude",
+			input:       "xdrive, M paket\n-oštećen, - delovi",
+			wantInclude: []string{"xdrive", "M paket"},
+			wantExclude: []string{"oštećen", "delovi"},
+		},
+		{
+			name:        "only exclude",
+			input:       "-ostecen",
+			wantInclude: []string{},
+			wantExclude: []string{"ostecen"},
+		},
+		{
+			name:        "duplicates after normalization",
+			input:       "xDrive, XDRIVE, -oštećen, -ostecen, -Оштећен",
+			wantInclude: []string{"xDrive"},
+			wantExclude: []string{"oštećen"},
+		},
+		{
+			name:        "same keyword included and excluded",
+			input:       "xdrive, -xdrive",
+			wantInclude: []string{"xdrive"},
+			wantExclude: []string{"xdrive"},
+		},
+		{
+			name:        "empty keywords are ignored",
+			input:       "xdrive,, -, !!!",
+			wantInclude: []string{"xdrive"},
+			wantExclude: []string{},
+		},
+		{
+			name:    "no keywords",
+			input:   " , -",
+			wantErr: errNoKeywords,
+		},
+		{
+			name:    "too many keywords",
+			input:   "a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u",
+			wantErr: errTooManyKeywords,
+		},
+		{
+			name:    "keyword too long",
+			input:   strings.Repeat("š", maxKeywordLength+1),
+			wantErr: errKeywordTooLong,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			include, exclude, err := parseKeywords(tt.input)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantInclude, include)
+			require.Equal(t, tt.wantExclude, exclude)
+		})
+	}
+}
+
+func Test_formatKeywords(t *testing.T) {
+	require.Equal(t, "any title", formatKeywords(nil, []string{}))
+	require.Equal(t, "xdrive, m paket, -oštećen", formatKeywords([]string{"xdrive", "m paket"}, []string{"oštećen"}))
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/pricetrend"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+const (
+	// historyMaxRows is the max number of the latest prices shown in the history.
+	historyMaxRows = 20
+	// listingPathSegment is the path segment of a listing link which is followed by the listing ID.
+	listingPathSegment = "auto-oglasi"
+)
+
+// handleHistory handles the /history command, showing the price history of a listing.
+// The listing is given by its ID or link, e.g. "/history 26135927".
+func (h *BotHandler) handleHistory(ctx context.Context, chatID int64, arg string) error {
+	listingID, ok := parseListingID(arg)
+	if !ok {
+		text := "ℹ️ Please send the listing ID or link, e.g. " + handleNameHistory + " 26135927"
+		return h.sendMessage(chatID, text, handleNameHistory)
+	}
+
+	history, err := h.svc.GetListingPriceHistory(ctx, listingID)
+	if err != nil {
+		text := "⚠️ An internal error occurred while getting the price history. Please try again later."
+		return h.sendMessage(chatID, text, handleNameHistory)
+	}
+
+	if len(history) == 0 {
+		text := "🤷 No price history found for listing " + listingID + "."
+		return h.sendMessage(chatID, text, handleNameHistory)
+	}
+
+	text := buildHistoryText(listingID, history)
+
+	img, err := pricetrend.RenderChart(history, nil, h.now())
+	if err != nil {
+		// there is nothing to draw without the typed prices, so only the text is sent
+		return h.sendMessage(chatID, text, handleNameHistory)
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "price_history.png", Bytes: img})
+	photo.Caption = text
+
+	if _, err = h.tgBot.SendMessage(photo); err != nil {
+		h.l.Error(handleNameHistory+": failed to send photo", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send photo")
+	}
+
+	return nil
+}
+
+// buildHistoryText builds the message with the latest prices of a listing and its trend.
+func buildHistoryText(listingID string, history []ds.ListingPriceResponse) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("📈 Price history of listing %s:\n\n", listingID))
+
+	// the repeated observations of the same price are only shown on the chart
+	rows := pricetrend.Changes(history)
+	if len(rows) > historyMaxRows {
+		rows = rows[len(rows)-historyMaxRows:]
+	}
+
+	for _, row := range rows {
+		price := row.Price
+		if row.PriceEUR.Valid {
+			price = pricetrend.FormatEUR(row.PriceEUR.Decimal)
+		}
+
+		sb.WriteString(fmt.Sprintf("%s  %s\n", row.ObservedAt.Format(time.DateOnly), price))
+	}
+
+	if trend := pricetrend.Format(history); trend != "" {
+		sb.WriteString("\n📊 Trend: " + trend)
+	}
+
+	return sb.String()
+}
+
+// parseListingID parses the listing ID from the ID itself or from the listing link.
+func parseListingID(arg string) (string, bool) {
+	arg = strings.TrimSpace(arg)
+	if isDigits(arg) {
+		return arg, true
+	}
+
+	link, err := url.Parse(arg)
+	if err != nil {
+		return "", false
+	}
+
+	segments := strings.Split(strings.Trim(link.Path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == listingPathSegment && isDigits(segments[i+1]) {
+			return segments[i+1], true
+		}
+	}
+
+	return "", false
+}
+
+// isDigits checks if the string is a non-empty string of digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseListingID(t *testing.T) {
+	testCases := []struct {
+		name   string
+		arg    string
+		want   string
+		wantOK bool
+	}{
+		{name: "listing ID", arg: "26135927", want: "26135927", wantOK: true},
+		{name: "listing ID with spaces", arg: " 26135927 ", want: "26135927", wantOK: true},
+		{
+			name:   "listing link",
+			arg:    "https://www.polovniautomobili.com/auto-oglasi/26135927/bmw-320-d?attp=p0_pv0_pc1_pl1_plv0",
+			want:   "26135927",
+			wantOK: true,
+		},
+		{name: "listing link without host", arg: "/auto-oglasi/26135927/bmw-320-d", want: "26135927", wantOK: true},
+		{name: "empty", arg: "", want: "", wantOK: false},
+		{name: "not a number", arg: "bmw", want: "", wantOK: false},
+		{
+			name:   "search link",
+			arg:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw",
+			want:   "",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseListingID(tt.arg)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
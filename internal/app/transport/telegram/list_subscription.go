@@ -3,10 +3,19 @@ package telegram
 import (
 	"context"
 	"strings"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
 
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/searchparams"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
+// maxSearchButtonLength is the max length of the text of the search button, the longer text is cut.
+const maxSearchButtonLength = 40
+
 // handleListSubscriptions handles the /list_subscriptions command, showing the user their current subscriptions.
 func (h *BotHandler) handleListSubscriptions(ctx context.Context, chatID int64) error {
 	subscriptions, err := h.svc.GetAllSubscriptionsByUserID(ctx, chatID)
@@ -20,19 +29,52 @@ func (h *BotHandler) handleListSubscriptions(ctx context.Context, chatID int64)
 		return h.sendMessage(chatID, text, handleNameListSubscriptions)
 	}
 
-	text := h.buildSubscriptionListMessage(subscriptions)
+	msg := tgbotapi.NewMessage(chatID, h.buildSubscriptionListMessage(subscriptions, h.userLocation(ctx, chatID)))
+	msg.ReplyMarkup = h.buildSearchButtons(subscriptions)
+
+	if _, err = h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameListSubscriptions+": failed to send message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return nil
+}
+
+// buildSearchButtons builds a button per subscription, which opens the same search on the site.
+func (h *BotHandler) buildSearchButtons(subscriptions []ds.SubscriptionResponse) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(subscriptions))
+
+	for _, sub := range subscriptions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(searchButtonText(sub), searchparams.URL(sub, h.svc)),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// searchButtonText names the search button by the brand and the models of the subscription.
+func searchButtonText(sub ds.SubscriptionResponse) string {
+	text := "🔎 " + sub.Brand
+	if len(sub.Model) > 0 {
+		text += " " + strings.Join(sub.Model, ", ")
+	}
+
+	if runes := []rune(text); len(runes) > maxSearchButtonLength {
+		text = string(runes[:maxSearchButtonLength-1]) + "…"
+	}
 
-	return h.sendMessage(chatID, text, handleNameListSubscriptions)
+	return text
 }
 
-// buildSubscriptionListMessage builds a message listing all subscriptions.
-func (h *BotHandler) buildSubscriptionListMessage(subscriptions []ds.SubscriptionResponse) string {
+// buildSubscriptionListMessage builds a message listing all subscriptions, the times are in the time zone of the user.
+func (h *BotHandler) buildSubscriptionListMessage(subscriptions []ds.SubscriptionResponse, loc *time.Location) string {
 	var sb strings.Builder
 
 	sb.WriteString("📋 Your current subscriptions:\n")
 
 	for _, sub := range subscriptions {
-		sb.WriteString(h.buildMessageWithSubscription(sub, true))
+		sb.WriteString(h.buildMessageWithSubscription(sub, true, loc))
 	}
 
 	msg := sb.String()
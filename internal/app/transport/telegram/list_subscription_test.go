@@ -0,0 +1,42 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func Test_searchButtonText(t *testing.T) {
+	testCases := []struct {
+		name string
+		sub  ds.SubscriptionResponse
+		want string
+	}{
+		{
+			name: "brand",
+			sub:  ds.SubscriptionResponse{Brand: "bmw"}, //nolint:exhaustruct,nolintlint
+			want: "🔎 bmw",
+		},
+		{
+			name: "models",
+			sub:  ds.SubscriptionResponse{Brand: "bmw", Model: []string{"x3", "x5"}}, //nolint:exhaustruct,nolintlint
+			want: "🔎 bmw x3, x5",
+		},
+		{
+			name: "too long",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand: "mercedes-benz",
+				Model: []string{"c-klasa", "e-klasa", "s-klasa", "glc"},
+			},
+			want: "🔎 mercedes-benz c-klasa, e-klasa, s-kla…",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, searchButtonText(tt.sub))
+		})
+	}
+}
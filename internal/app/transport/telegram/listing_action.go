@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/listingaction"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// handleNameListingAction names the handler of the buttons of the listing notifications in the logs.
+const handleNameListingAction = "listing action"
+
+// handleListingAction handles the buttons of a listing notification: it saves the car to the watchlist,
+// hides the car, mutes the subscription or switches it to the price changes only or back to all the listings.
+// The buttons set the state instead of toggling it, so tapping a button again doesn't undo it.
+func (h *BotHandler) handleListingAction(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	data, err := listingaction.Decode(callbackQuery.Data)
+	if err != nil {
+		h.l.Warn("failed to decode listing action", logger.ErrAttr(err))
+		return h.sendMessage(chatID, listingActionErrorText(err), handleNameListingAction)
+	}
+
+	var sub ds.SubscriptionResponse
+
+	switch data.Action {
+	case listingaction.Save:
+		err = h.svc.AddToWatchlist(ctx, chatID, data.ListingID)
+	case listingaction.Hide:
+		err = h.svc.HideListing(ctx, chatID, data.ListingID)
+	case listingaction.Mute:
+		sub, err = h.svc.MuteSubscription(ctx, chatID, data.SubscriptionID,
+			h.now().UTC().Add(listingaction.MuteDuration))
+	case listingaction.PriceOnly:
+		sub, err = h.svc.SetSubscriptionPriceOnly(ctx, chatID, data.SubscriptionID, true)
+	case listingaction.AllListings:
+		sub, err = h.svc.SetSubscriptionPriceOnly(ctx, chatID, data.SubscriptionID, false)
+	}
+
+	if err != nil {
+		return h.sendMessage(chatID, listingActionErrorText(err), handleNameListingAction)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, listingActionText(data.Action, sub, h.userLocation(ctx, chatID)))
+
+	// the switch to the price changes only is undone with the button of all the listings
+	if data.Action == listingaction.PriceOnly {
+		data.Action = listingaction.AllListings
+
+		if undo, encodeErr := listingaction.Encode(data); encodeErr == nil {
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔔 All listings", undo),
+			))
+		}
+	}
+
+	if _, err = h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameListingAction+": failed to send message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return nil
+}
+
+// listingActionText confirms the action of the button to the user, the end of the mute is in the time zone of the user.
+func listingActionText(action listingaction.Action, sub ds.SubscriptionResponse, loc *time.Location) string {
+	switch action {
+	case listingaction.Save:
+		return "⭐ The car is saved to your watchlist."
+	case listingaction.Hide:
+		return "🙈 You won't be notified about this car anymore."
+	case listingaction.Mute:
+		return "🔕 The subscription is muted until " + formatMutedUntil(sub.MutedUntil.Time, loc) + "."
+	case listingaction.PriceOnly:
+		return "📉 The subscription now notifies only about the price changes."
+	case listingaction.AllListings:
+		return "🔔 The subscription notifies about all the listings again."
+	}
+
+	return ""
+}
+
+// listingActionErrorText explains the user why the action of the button failed.
+func listingActionErrorText(err error) string {
+	switch {
+	case errors.Is(err, listingaction.ErrUnsupportedVersion), errors.Is(err, listingaction.ErrInvalidData):
+		return "⚠️ This button is outdated. Please use the buttons of a newer notification."
+	case errors.Is(err, ds.ErrNotFound):
+		return "🤷 The subscription is not found, it may have been removed. See " + handleNameListSubscriptions + "."
+	default:
+		return "⚠️ An internal error occurred. Please try again later."
+	}
+}
+
+// formatMutedUntil formats the end of the mute of a subscription in the time zone of the user.
+func formatMutedUntil(until time.Time, loc *time.Location) string {
+	return until.In(loc).Format("2006-01-02 15:04") + " " + loc.String()
+}
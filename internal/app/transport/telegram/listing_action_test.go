@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/guregu/null"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/listingaction"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/quiethours"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+	tgCli "github.com/gudimz/polovni-auto-alert/pkg/telegram"
+)
+
+// stubListingActionService records the subscription changes of the listing actions,
+// the other methods of the service are not called.
+type stubListingActionService struct {
+	Service
+
+	mutedUntil time.Time
+	priceOnly  []bool
+	timeZone   string
+}
+
+func (s *stubListingActionService) GetUserSettings(_ context.Context, userID int64) (ds.UserSettingsResponse, error) {
+	return ds.UserSettingsResponse{UserID: userID, TimeZone: s.timeZone}, nil //nolint:exhaustruct,nolintlint
+}
+
+func (s *stubListingActionService) MuteSubscription(
+	_ context.Context, userID int64, id string, until time.Time,
+) (ds.SubscriptionResponse, error) {
+	s.mutedUntil = until
+
+	return ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+		ID:         id,
+		UserID:     userID,
+		MutedUntil: null.TimeFrom(until),
+	}, nil
+}
+
+func (s *stubListingActionService) SetSubscriptionPriceOnly(
+	_ context.Context, userID int64, id string, priceOnly bool,
+) (ds.SubscriptionResponse, error) {
+	s.priceOnly = append(s.priceOnly, priceOnly)
+	return ds.SubscriptionResponse{ID: id, UserID: userID, PriceOnly: priceOnly}, nil //nolint:exhaustruct,nolintlint
+}
+
+func TestBotHandler_handleListingAction(t *testing.T) {
+	const chatID = int64(42)
+
+	now := time.Date(2024, 12, 1, 18, 30, 0, 0, time.UTC)
+	subID := "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	fakeAPI, calls := newFakeBotAPI(t)
+
+	lg := logger.NewLogger()
+
+	bot, err := tgCli.NewBot(lg, &tgCli.Config{ //nolint:exhaustruct,nolintlint
+		BotToken:    "token",
+		APIEndpoint: fakeAPI.URL + "/bot%s/%s",
+	})
+	require.NoError(t, err)
+	waitBotAPICall(t, calls, "getMe")
+
+	svc := &stubListingActionService{timeZone: "Europe/Belgrade"} //nolint:exhaustruct,nolintlint
+
+	h := NewBotHandler(lg, bot, svc, NewMemoryStateStore(time.Hour))
+	h.now = func() time.Time { return now }
+
+	tap := func(action listingaction.Action) fakeBotAPICall {
+		data, err := listingaction.Encode(listingaction.Data{Action: action, ListingID: "1", SubscriptionID: subID})
+		require.NoError(t, err)
+
+		err = h.handleListingAction(context.Background(), &tgbotapi.CallbackQuery{ //nolint:exhaustruct,nolintlint
+			Data:    data,
+			Message: &tgbotapi.Message{Chat: tgbotapi.Chat{ID: chatID}}, //nolint:exhaustruct,nolintlint
+		})
+		require.NoError(t, err)
+
+		return waitBotAPICall(t, calls, "sendMessage")
+	}
+
+	call := tap(listingaction.Mute)
+	require.Equal(t, now.Add(listingaction.MuteDuration), svc.mutedUntil)
+	// the end of the mute is in the time zone of the user
+	require.Equal(t, "🔕 The subscription is muted until 2024-12-02 19:30 Europe/Belgrade.", call.params.Get("text"))
+
+	// tapping the price only button again keeps the subscription price only
+	tap(listingaction.PriceOnly)
+	call = tap(listingaction.PriceOnly)
+	require.Contains(t, call.params.Get("reply_markup"), "la1|a|1|")
+
+	tap(listingaction.AllListings)
+	require.Equal(t, []bool{true, true, false}, svc.priceOnly)
+}
+
+func Test_listingActionText(t *testing.T) {
+	until := time.Date(2024, 12, 1, 18, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		action listingaction.Action
+		sub    ds.SubscriptionResponse
+		loc    *time.Location
+		want   string
+	}{
+		{
+			name:   "save",
+			action: listingaction.Save,
+			want:   "⭐ The car is saved to your watchlist.",
+		},
+		{
+			name:   "hide",
+			action: listingaction.Hide,
+			want:   "🙈 You won't be notified about this car anymore.",
+		},
+		{
+			name:   "mute",
+			action: listingaction.Mute,
+			sub:    ds.SubscriptionResponse{MutedUntil: null.TimeFrom(until)}, //nolint:exhaustruct,nolintlint
+			loc:    time.UTC,
+			want:   "🔕 The subscription is muted until 2024-12-01 18:30 UTC.",
+		},
+		{
+			name:   "mute in the time zone of the user",
+			action: listingaction.Mute,
+			sub:    ds.SubscriptionResponse{MutedUntil: null.TimeFrom(until)}, //nolint:exhaustruct,nolintlint
+			loc:    quiethours.Location("America/New_York"),
+			want:   "🔕 The subscription is muted until 2024-12-01 13:30 America/New_York.",
+		},
+		{
+			name:   "price only",
+			action: listingaction.PriceOnly,
+			sub:    ds.SubscriptionResponse{PriceOnly: true}, //nolint:exhaustruct,nolintlint
+			want:   "📉 The subscription now notifies only about the price changes.",
+		},
+		{
+			name:   "all listings",
+			action: listingaction.AllListings,
+			want:   "🔔 The subscription notifies about all the listings again.",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, listingActionText(tt.action, tt.sub, tt.loc))
+		})
+	}
+}
+
+func Test_listingActionErrorText(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "outdated button",
+			err:  errors.Wrap(listingaction.ErrUnsupportedVersion, "la0"),
+			want: "⚠️ This button is outdated. Please use the buttons of a newer notification.",
+		},
+		{
+			name: "subscription not found",
+			err:  errors.Wrap(ds.ErrNotFound, "failed to mute subscription"),
+			want: "🤷 The subscription is not found, it may have been removed. See /list_subscriptions.",
+		},
+		{
+			name: "internal error",
+			err:  errors.New("common error"),
+			want: "⚠️ An internal error occurred. Please try again later.",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, listingActionErrorText(tt.err))
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/searchparams"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
+)
+
+// isSearchLink checks if the message is a pasted link, it's handled as a search on the site.
+func isSearchLink(text string) bool {
+	text = strings.TrimSpace(text)
+
+	return strings.HasPrefix(text, "https://") || strings.HasPrefix(text, "http://")
+}
+
+// handleSearchLink creates a subscription from the pasted link of a search on the site.
+// The values of the search are checked against the catalog and shown for the confirmation,
+// an unfinished subscription or edit is replaced.
+func (h *BotHandler) handleSearchLink(ctx context.Context, message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+
+	sub, err := searchparams.FromURL(message.Text, h.svc)
+	if err != nil {
+		h.l.Warn("failed to parse search link", logger.ErrAttr(err))
+
+		return h.sendMessage(chatID, searchLinkErrorText(err), handleNameSubscribe)
+	}
+
+	state := stateFromSearch(sub)
+	state.Step = confirmSelectionStep
+	state.InProgress = true
+
+	if err = h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	return h.sendConfirmationMessage(ctx, chatID)
+}
+
+// searchLinkErrorText explains the user why the link can't be used for a subscription.
+func searchLinkErrorText(err error) string {
+	switch {
+	case errors.Is(err, polovniauto.ErrInvalidSearchLink):
+		return "⚠️ It's not a link of a search on polovniautomobili.com. " +
+			"Please open the search on the site and paste the link from the address bar."
+	case errors.Is(err, searchparams.ErrNoBrand):
+		return "⚠️ The search has no brand. Please choose a brand on the site and paste the link again."
+	case errors.Is(err, searchparams.ErrUnknownValue), errors.Is(err, searchparams.ErrInvalidNumber):
+		// the error names the parameter and its value, e.g. "model[]=m3: unknown value"
+		return "⚠️ I can't use this search, " + err.Error() +
+			". Please change the search on the site or use /subscribe to choose the filters."
+	default:
+		return "⚠️ An internal error occurred while reading the link. Please try again later."
+	}
+}
+
+// stateFromSearch fills the subscribe state with the values of the search.
+func stateFromSearch(sub ds.SubscriptionRequest) *SubscribeState {
+	return &SubscribeState{ //nolint:exhaustruct,nolintlint
+		SelectedBrand:        sub.Brand,
+		SelectedModels:       slices.Clone(sub.Model),
+		SelectedChassis:      slices.Clone(sub.Chassis),
+		SelectedRegions:      slices.Clone(sub.Region),
+		PriceFrom:            sub.PriceFrom,
+		PriceTo:              sub.PriceTo,
+		YearFrom:             sub.YearFrom,
+		YearTo:               sub.YearTo,
+		SelectedFuel:         slices.Clone(sub.Fuel),
+		SelectedGearbox:      slices.Clone(sub.Gearbox),
+		SelectedDoors:        slices.Clone(sub.Doors),
+		SelectedSeats:        slices.Clone(sub.Seats),
+		SelectedColors:       slices.Clone(sub.Color),
+		SelectedAirCondition: slices.Clone(sub.AirCondition),
+		SelectedDamage:       slices.Clone(sub.Damage),
+		MileageTo:            sub.MileageTo,
+		PowerFrom:            sub.PowerFrom,
+		PowerTo:              sub.PowerTo,
+		ExtraParams:          maps.Clone(sub.ExtraParams),
+	}
+}
+
+// formatExtraParams formats the parameters of the search the subscription has no fields for.
+func formatExtraParams(params map[string]string) string {
+	if len(params) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		parts = append(parts, key+"="+params[key])
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/searchparams"
+	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
+)
+
+func Test_isSearchLink(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{name: "search link", text: " https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw", want: true},
+		{name: "http link", text: "http://polovniautomobili.com/auto-oglasi/pretraga", want: true},
+		{name: "price", text: "15000", want: false},
+		{name: "keywords", text: "xdrive, -oštećen", want: false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isSearchLink(tt.text))
+		})
+	}
+}
+
+func Test_searchLinkErrorText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		contains string
+	}{
+		{
+			name:     "not a search link",
+			err:      errors.Wrap(polovniauto.ErrInvalidSearchLink, "failed to parse search link"),
+			contains: "not a link of a search",
+		},
+		{name: "no brand", err: searchparams.ErrNoBrand, contains: "no brand"},
+		{
+			name:     "unknown value",
+			err:      errors.Wrapf(searchparams.ErrUnknownValue, "%s=%s", "model[]", "m3"),
+			contains: "model[]=m3",
+		},
+		{name: "internal", err: errors.New("db is down"), contains: "internal error"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Contains(t, searchLinkErrorText(tt.err), tt.contains)
+		})
+	}
+}
+
+func Test_formatExtraParams(t *testing.T) {
+	require.Equal(t, "none", formatExtraParams(nil))
+	require.Equal(t, "showOldNew=new, sort=basic",
+		formatExtraParams(map[string]string{"sort": "basic", "showOldNew": "new"}))
+}
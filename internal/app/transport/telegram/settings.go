@@ -0,0 +1,304 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/guregu/null"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/quiethours"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// The callback data of the buttons of the settings follow handleNameSettings, e.g. "/settings:to:22:8".
+// The chosen values are kept in the data, so the settings don't need a conversation state,
+// except for the time zone which can be typed.
+const (
+	settingsTimeZone     = "tz"
+	settingsQuietHours   = "quiet"
+	settingsQuietFrom    = "from"
+	settingsQuietTo      = "to"
+	settingsQuietOff     = "quiet_off"
+	settingsMuteWeekends = "weekends"
+
+	settingsButtonsPerRow     = 2
+	settingsHourButtonsPerRow = 6
+)
+
+// settingsTimeZones are offered as buttons, any other time zone can be typed.
+var settingsTimeZones = []string{"Europe/Belgrade", "Europe/Berlin", "Europe/London", quiethours.DefaultTimeZone}
+
+// handleSettings handles the /settings command, showing the time zone and the quiet hours of the user
+// with the buttons changing them.
+func (h *BotHandler) handleSettings(ctx context.Context, chatID int64) error {
+	settings, err := h.svc.GetUserSettings(ctx, chatID)
+	if err != nil {
+		return h.sendMessage(chatID, settingsErrorText(err), handleNameSettings)
+	}
+
+	weekendsButton := "🏖️ Mute weekends"
+	if settings.MuteWeekends {
+		weekendsButton = "🔔 Unmute weekends"
+	}
+
+	buttons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🌍 Time zone", settingsData(settingsTimeZone)),
+		tgbotapi.NewInlineKeyboardButtonData("🌙 Quiet hours", settingsData(settingsQuietHours)),
+		tgbotapi.NewInlineKeyboardButtonData(weekendsButton, settingsData(settingsMuteWeekends)),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, buildSettingsText(settings))
+	msg.ReplyMarkup = createKeyboard(ctx, settingsButtonsPerRow, nil, buttons)
+
+	if _, err = h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameSettings+": failed to send message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return nil
+}
+
+// handleSettingsCallback handles the buttons of the settings, the data is without the handleNameSettings prefix.
+func (h *BotHandler) handleSettingsCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+
+	switch callbackQuery.Data {
+	case settingsTimeZone:
+		return h.sendTimeZoneMessage(ctx, chatID)
+	case settingsQuietHours:
+		return h.sendQuietHoursMessage(ctx, chatID, "🌙 Please choose the hour the quiet hours start at:",
+			settingsQuietFrom+":", tgbotapi.NewInlineKeyboardButtonData("🔔 Turn off", settingsData(settingsQuietOff)))
+	}
+
+	if from, ok := strings.CutPrefix(callbackQuery.Data, settingsQuietFrom+":"); ok {
+		return h.sendQuietHoursMessage(ctx, chatID, "🌅 Please choose the hour the quiet hours end at:",
+			settingsQuietTo+":"+from+":", tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", handleNameSettings))
+	}
+
+	settings, err := h.svc.GetUserSettings(ctx, chatID)
+	if err != nil {
+		return h.sendMessage(chatID, settingsErrorText(err), handleNameSettings)
+	}
+
+	request, ok := applySettingsChange(settings, callbackQuery.Data)
+	if !ok {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
+	// the time zone is chosen with a button, so it isn't typed anymore
+	if strings.HasPrefix(callbackQuery.Data, settingsTimeZone+":") && !h.isSubscriptionInProgress(ctx, chatID) {
+		h.deleteState(ctx, chatID)
+	}
+
+	return h.updateSettings(ctx, chatID, request)
+}
+
+// sendTimeZoneMessage sends a message asking the user to choose or type the time zone.
+// The typed time zone needs the conversation state, so the time zone can't be changed while a subscription
+// is created or edited, the unfinished subscription would be lost.
+func (h *BotHandler) sendTimeZoneMessage(ctx context.Context, chatID int64) error {
+	if h.isSubscriptionInProgress(ctx, chatID) {
+		text := "⚠️ Please finish the subscription you are creating or editing or send '🚫 cancel' " +
+			"before changing the time zone."
+
+		return h.sendMessage(chatID, text, handleNameSettings)
+	}
+
+	state := &SubscribeState{ //nolint:exhaustruct,nolintlint
+		Step:       timeZoneStep,
+		InProgress: true,
+	}
+
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	text := `
+🌍 Please choose your time zone or type its name, e.g. 'Europe/Belgrade' or 'America/New_York'.
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(settingsTimeZones))
+	for _, tz := range settingsTimeZones {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(tz, settingsData(settingsTimeZone+":"+tz)))
+	}
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", handleNameCancel),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, settingsButtonsPerRow, actionsButtons, buttons)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameSettings+": failed to send time zone message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send time zone message")
+	}
+
+	return nil
+}
+
+// userLocation returns the time zone of the user. The times are shown in UTC if the settings can't be loaded,
+// so an error is only logged.
+func (h *BotHandler) userLocation(ctx context.Context, chatID int64) *time.Location {
+	settings, err := h.svc.GetUserSettings(ctx, chatID)
+	if err != nil {
+		if !errors.Is(err, ds.ErrNotFound) {
+			h.l.Warn("failed to get user settings, the times are shown in UTC",
+				logger.ErrAttr(err),
+				logger.Int64Attr("user_id", chatID),
+			)
+		}
+
+		return time.UTC
+	}
+
+	return quiethours.Location(settings.TimeZone)
+}
+
+// isSubscriptionInProgress checks if the user is creating or editing a subscription.
+func (h *BotHandler) isSubscriptionInProgress(ctx context.Context, chatID int64) bool {
+	state, exists := h.getState(ctx, chatID)
+	return exists && state.InProgress && state.Step != timeZoneStep
+}
+
+// handleTimeZone handles the typed time zone.
+func (h *BotHandler) handleTimeZone(ctx context.Context, message *tgbotapi.Message) error {
+	chatID := message.Chat.ID
+	timeZone := strings.TrimSpace(message.Text)
+
+	if !quiethours.IsValidTimeZone(timeZone) {
+		text := "⚠️ Unknown time zone. Please type a name like 'Europe/Belgrade'."
+		return h.sendMessage(chatID, text, handleNameSettings)
+	}
+
+	settings, err := h.svc.GetUserSettings(ctx, chatID)
+	if err != nil {
+		return h.sendMessage(chatID, settingsErrorText(err), handleNameSettings)
+	}
+
+	h.deleteState(ctx, chatID)
+
+	request, _ := applySettingsChange(settings, settingsTimeZone+":"+timeZone)
+
+	return h.updateSettings(ctx, chatID, request)
+}
+
+// sendQuietHoursMessage sends a message asking the user to choose an hour of the quiet hours,
+// the hour is appended to the callback data of the buttons.
+func (h *BotHandler) sendQuietHoursMessage(
+	ctx context.Context, chatID int64, text, data string, action tgbotapi.InlineKeyboardButton,
+) error {
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, ds.HoursInDay)
+	for hour := range ds.HoursInDay {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%02d:00", hour), settingsData(data+strconv.Itoa(hour))),
+		)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text+"\n\nThe hours are in your time zone.")
+	msg.ReplyMarkup = createKeyboard(ctx, settingsHourButtonsPerRow, []tgbotapi.InlineKeyboardButton{action}, buttons)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameSettings+": failed to send quiet hours message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send quiet hours message")
+	}
+
+	return nil
+}
+
+// updateSettings saves the settings and sends them to the user.
+func (h *BotHandler) updateSettings(ctx context.Context, chatID int64, request ds.UpdateUserSettingsRequest) error {
+	if _, err := h.svc.UpdateUserSettings(ctx, request); err != nil {
+		return h.sendMessage(chatID, settingsErrorText(err), handleNameSettings)
+	}
+
+	if err := h.sendMessage(chatID, "🟢 Your settings are saved.", handleNameSettings); err != nil {
+		return err
+	}
+
+	return h.handleSettings(ctx, chatID)
+}
+
+// applySettingsChange applies the change of the button to the settings, false is returned for invalid data.
+func applySettingsChange(settings ds.UserSettingsResponse, data string) (ds.UpdateUserSettingsRequest, bool) {
+	request := ds.UpdateUserSettingsRequest{
+		UserID:         settings.UserID,
+		TimeZone:       settings.TimeZone,
+		QuietHoursFrom: settings.QuietHoursFrom,
+		QuietHoursTo:   settings.QuietHoursTo,
+		MuteWeekends:   settings.MuteWeekends,
+	}
+
+	if timeZone, ok := strings.CutPrefix(data, settingsTimeZone+":"); ok {
+		request.TimeZone = timeZone
+		return request, quiethours.IsValidTimeZone(timeZone)
+	}
+
+	if hours, ok := strings.CutPrefix(data, settingsQuietTo+":"); ok {
+		fromValue, toValue, _ := strings.Cut(hours, ":")
+
+		from, fromErr := strconv.Atoi(fromValue)
+		to, toErr := strconv.Atoi(toValue)
+
+		if fromErr != nil || toErr != nil || !quiethours.IsValidHour(from) || !quiethours.IsValidHour(to) || from == to {
+			return ds.UpdateUserSettingsRequest{}, false
+		}
+
+		request.QuietHoursFrom, request.QuietHoursTo = null.IntFrom(int64(from)), null.IntFrom(int64(to))
+
+		return request, true
+	}
+
+	switch data {
+	case settingsQuietOff:
+		request.QuietHoursFrom, request.QuietHoursTo = null.Int{}, null.Int{}
+	case settingsMuteWeekends:
+		request.MuteWeekends = !request.MuteWeekends
+	default:
+		return ds.UpdateUserSettingsRequest{}, false
+	}
+
+	return request, true
+}
+
+// buildSettingsText builds the message with the settings of the user.
+func buildSettingsText(settings ds.UserSettingsResponse) string {
+	weekends := "no"
+	if settings.MuteWeekends {
+		weekends = "yes"
+	}
+
+	return fmt.Sprintf(`⚙️ Your settings:
+
+🌍 Time zone: %s
+🌙 Quiet hours: %s
+🏖️ Weekends muted: %s
+
+The notifications are held during the quiet hours and the muted weekends and sent when they end.
+The dates of the listings and the hour of the daily digest are in your time zone.`,
+		settings.TimeZone,
+		quiethours.Format(settings),
+		weekends,
+	)
+}
+
+// settingsErrorText explains the user why the settings can't be shown or saved.
+func settingsErrorText(err error) string {
+	if errors.Is(err, ds.ErrNotFound) {
+		return "🤷 You are not registered yet. Please send " + handleNameStart + " first."
+	}
+
+	return "⚠️ An internal error occurred while saving the settings. Please try again later."
+}
+
+// settingsData returns the callback data of a button of the settings.
+func settingsData(data string) string {
+	return handleNameSettings + ":" + data
+}
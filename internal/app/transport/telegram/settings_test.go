@@ -0,0 +1,167 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+	tgCli "github.com/gudimz/polovni-auto-alert/pkg/telegram"
+)
+
+func Test_applySettingsChange(t *testing.T) {
+	settings := ds.UserSettingsResponse{
+		UserID:         1,
+		TimeZone:       "UTC",
+		QuietHoursFrom: null.IntFrom(22),
+		QuietHoursTo:   null.IntFrom(8),
+		MuteWeekends:   false,
+	}
+
+	request := ds.UpdateUserSettingsRequest{
+		UserID:         settings.UserID,
+		TimeZone:       settings.TimeZone,
+		QuietHoursFrom: settings.QuietHoursFrom,
+		QuietHoursTo:   settings.QuietHoursTo,
+		MuteWeekends:   settings.MuteWeekends,
+	}
+
+	testCases := []struct {
+		name   string
+		data   string
+		want   func(ds.UpdateUserSettingsRequest) ds.UpdateUserSettingsRequest
+		wantOk bool
+	}{
+		{
+			name: "time zone",
+			data: "tz:Europe/Belgrade",
+			want: func(r ds.UpdateUserSettingsRequest) ds.UpdateUserSettingsRequest {
+				r.TimeZone = "Europe/Belgrade"
+				return r
+			},
+			wantOk: true,
+		},
+		{
+			name: "unknown time zone",
+			data: "tz:Belgrade",
+		},
+		{
+			name: "quiet hours",
+			data: "to:23:7",
+			want: func(r ds.UpdateUserSettingsRequest) ds.UpdateUserSettingsRequest {
+				r.QuietHoursFrom, r.QuietHoursTo = null.IntFrom(23), null.IntFrom(7)
+				return r
+			},
+			wantOk: true,
+		},
+		{
+			name: "quiet hours start and end at the same hour",
+			data: "to:7:7",
+		},
+		{
+			name: "quiet hours out of range",
+			data: "to:22:24",
+		},
+		{
+			name: "quiet hours are not numbers",
+			data: "to:night",
+		},
+		{
+			name: "quiet hours off",
+			data: "quiet_off",
+			want: func(r ds.UpdateUserSettingsRequest) ds.UpdateUserSettingsRequest {
+				r.QuietHoursFrom, r.QuietHoursTo = null.Int{}, null.Int{}
+				return r
+			},
+			wantOk: true,
+		},
+		{
+			name: "mute weekends",
+			data: "weekends",
+			want: func(r ds.UpdateUserSettingsRequest) ds.UpdateUserSettingsRequest {
+				r.MuteWeekends = true
+				return r
+			},
+			wantOk: true,
+		},
+		{
+			name: "unknown button",
+			data: "snooze",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := applySettingsChange(settings, tt.data)
+			require.Equal(t, tt.wantOk, ok)
+
+			if tt.want != nil {
+				require.Equal(t, tt.want(request), got)
+			}
+		})
+	}
+}
+
+func Test_buildSettingsText(t *testing.T) {
+	text := buildSettingsText(ds.UserSettingsResponse{
+		UserID:         1,
+		TimeZone:       "Europe/Belgrade",
+		QuietHoursFrom: null.IntFrom(22),
+		QuietHoursTo:   null.IntFrom(8),
+		MuteWeekends:   true,
+	})
+
+	require.Contains(t, text, "🌍 Time zone: Europe/Belgrade\n")
+	require.Contains(t, text, "🌙 Quiet hours: 22:00 - 08:00\n")
+	require.Contains(t, text, "🏖️ Weekends muted: yes\n")
+}
+
+func TestBotHandler_sendTimeZoneMessage(t *testing.T) {
+	const chatID = int64(42)
+
+	ctx := context.Background()
+
+	fakeAPI, calls := newFakeBotAPI(t)
+
+	lg := logger.NewLogger()
+
+	bot, err := tgCli.NewBot(lg, &tgCli.Config{ //nolint:exhaustruct,nolintlint
+		BotToken:    "token",
+		APIEndpoint: fakeAPI.URL + "/bot%s/%s",
+	})
+	require.NoError(t, err)
+	waitBotAPICall(t, calls, "getMe")
+
+	states := NewMemoryStateStore(time.Hour)
+	h := NewBotHandler(lg, bot, nil, states)
+
+	// the unfinished subscription is kept
+	wizard := &SubscribeState{ //nolint:exhaustruct,nolintlint
+		Step:          modelSelectionStep,
+		InProgress:    true,
+		SelectedBrand: "bmw",
+	}
+	require.NoError(t, states.Set(ctx, chatID, wizard))
+
+	require.NoError(t, h.sendTimeZoneMessage(ctx, chatID))
+	require.Contains(t, waitBotAPICall(t, calls, "sendMessage").params.Get("text"), "before changing the time zone")
+
+	state, exists, err := states.Get(ctx, chatID)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, wizard, state)
+
+	// the time zone is typed when no subscription is in progress
+	require.NoError(t, states.Delete(ctx, chatID))
+	require.NoError(t, h.sendTimeZoneMessage(ctx, chatID))
+	require.Contains(t, waitBotAPICall(t, calls, "sendMessage").params.Get("text"), "Please choose your time zone")
+
+	state, exists, err = states.Get(ctx, chatID)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, timeZoneStep, state.Step)
+}
@@ -23,7 +23,11 @@ Here’s what you can do:
 
 📬 subscribe - Subscribe to new car listings alerts
 ❌ unsubscribe - Unsubscribe from a car listings alert
+✏️ edit - Edit one of your subscriptions
 📋 list_subscriptions - List all your current subscriptions
+📈 history <listing> - Show the price history of a listing
+⭐ watchlist - Show your saved cars and their prices
+⚙️ settings - Set your time zone, quiet hours and weekend muting
 🚫 stop - Stop receiving notifications
 
 Just select the desired command or type it in the chat to get started.
@@ -42,7 +46,10 @@ Just select the desired command or type it in the chat to get started.
 	buttons := []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("📬 Subscribe", handleNameSubscribe),
 		tgbotapi.NewInlineKeyboardButtonData("❌ Unsubscribe", handleNameUnsubscribe),
+		tgbotapi.NewInlineKeyboardButtonData("✏️ Edit", handleNameEdit),
 		tgbotapi.NewInlineKeyboardButtonData("📋 List Subscriptions", handleNameListSubscriptions),
+		tgbotapi.NewInlineKeyboardButtonData("⭐ Watchlist", handleNameWatchlist),
+		tgbotapi.NewInlineKeyboardButtonData("⚙️ Settings", handleNameSettings),
 		tgbotapi.NewInlineKeyboardButtonData("🚫 Stop", handleNameStop),
 	}
 
@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+type (
+	// MemoryStateStore keeps the subscribe states in memory, it is used in tests and for local runs.
+	MemoryStateStore struct {
+		mu     sync.Mutex
+		ttl    time.Duration
+		now    func() time.Time
+		states map[int64]memoryState
+	}
+
+	memoryState struct {
+		state     SubscribeState
+		expiresAt time.Time
+	}
+
+	// PostgresStateStore keeps the subscribe states in the conversation_state table,
+	// so unfinished conversations survive a restart of the notifier.
+	PostgresStateStore struct {
+		repo StateRepository
+		ttl  time.Duration
+		now  func() time.Time
+	}
+)
+
+// NewMemoryStateStore creates a new in-memory state store with the given TTL.
+func NewMemoryStateStore(ttl time.Duration) *MemoryStateStore {
+	return &MemoryStateStore{
+		mu:     sync.Mutex{},
+		ttl:    ttl,
+		now:    time.Now,
+		states: make(map[int64]memoryState),
+	}
+}
+
+// Get returns a copy of the user's state if it exists and is not expired.
+func (s *MemoryStateStore) Get(_ context.Context, userID int64) (*SubscribeState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, exists := s.states[userID]
+	if !exists {
+		return nil, false, nil
+	}
+
+	if !s.now().Before(st.expiresAt) {
+		delete(s.states, userID)
+		return nil, false, nil
+	}
+
+	return st.state.clone(), true, nil
+}
+
+// Set saves a copy of the user's state and extends its expiration.
+func (s *MemoryStateStore) Set(_ context.Context, userID int64, state *SubscribeState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[userID] = memoryState{
+		state:     *state.clone(),
+		expiresAt: s.now().Add(s.ttl),
+	}
+
+	return nil
+}
+
+// Delete removes the user's state.
+func (s *MemoryStateStore) Delete(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, userID)
+
+	return nil
+}
+
+// DeleteExpired removes all expired states and returns their count.
+func (s *MemoryStateStore) DeleteExpired(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+
+	now := s.now()
+
+	for userID, st := range s.states {
+		if !now.Before(st.expiresAt) {
+			delete(s.states, userID)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// NewPostgresStateStore creates a new state store backed by PostgreSQL with the given TTL.
+func NewPostgresStateStore(repo StateRepository, ttl time.Duration) *PostgresStateStore {
+	return &PostgresStateStore{
+		repo: repo,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// Get returns the user's state if it exists and is not expired.
+func (s *PostgresStateStore) Get(ctx context.Context, userID int64) (*SubscribeState, bool, error) {
+	row, err := s.repo.GetConversationState(ctx, userID, s.now().UTC())
+	if err != nil {
+		if errors.Is(err, ds.ErrNotFound) {
+			return nil, false, nil
+		}
+
+		return nil, false, errors.Wrap(err, "failed to get conversation state")
+	}
+
+	state := new(SubscribeState)
+	if err = json.Unmarshal(row.State, state); err != nil {
+		return nil, false, errors.Wrap(err, "failed to unmarshal conversation state")
+	}
+
+	return state, true, nil
+}
+
+// Set saves the user's state and extends its expiration.
+func (s *PostgresStateStore) Set(ctx context.Context, userID int64, state *SubscribeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal conversation state")
+	}
+
+	if err = s.repo.UpsertConversationState(ctx, ds.ConversationStateRequest{
+		UserID:    userID,
+		State:     data,
+		ExpiresAt: s.now().UTC().Add(s.ttl),
+	}); err != nil {
+		return errors.Wrap(err, "failed to save conversation state")
+	}
+
+	return nil
+}
+
+// Delete removes the user's state.
+func (s *PostgresStateStore) Delete(ctx context.Context, userID int64) error {
+	if err := s.repo.DeleteConversationState(ctx, userID); err != nil {
+		return errors.Wrap(err, "failed to delete conversation state")
+	}
+
+	return nil
+}
+
+// DeleteExpired removes all expired states and returns their count.
+func (s *PostgresStateStore) DeleteExpired(ctx context.Context) (int64, error) {
+	count, err := s.repo.DeleteExpiredConversationStates(ctx, s.now().UTC())
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired conversation states")
+	}
+
+	return count, nil
+}
+
+// clone returns a deep copy of the state, so the stored state is not changed until it is saved.
+func (s SubscribeState) clone() *SubscribeState {
+	s.SelectedModels = slices.Clone(s.SelectedModels)
+	s.SelectedChassis = slices.Clone(s.SelectedChassis)
+	s.SelectedRegions = slices.Clone(s.SelectedRegions)
+	s.SelectedFuel = slices.Clone(s.SelectedFuel)
+	s.SelectedGearbox = slices.Clone(s.SelectedGearbox)
+	s.SelectedDoors = slices.Clone(s.SelectedDoors)
+	s.SelectedSeats = slices.Clone(s.SelectedSeats)
+	s.SelectedColors = slices.Clone(s.SelectedColors)
+	s.SelectedAirCondition = slices.Clone(s.SelectedAirCondition)
+	s.SelectedDamage = slices.Clone(s.SelectedDamage)
+	s.IncludeKeywords = slices.Clone(s.IncludeKeywords)
+	s.ExcludeKeywords = slices.Clone(s.ExcludeKeywords)
+	s.ExtraParams = maps.Clone(s.ExtraParams)
+
+	return &s
+}
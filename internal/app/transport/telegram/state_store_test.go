@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+type stubStateRepository struct {
+	rows map[int64]ds.ConversationStateRequest
+}
+
+func (r *stubStateRepository) GetConversationState(
+	_ context.Context, userID int64, now time.Time,
+) (ds.ConversationStateResponse, error) {
+	row, exists := r.rows[userID]
+	if !exists || !now.Before(row.ExpiresAt) {
+		return ds.ConversationStateResponse{}, ds.ErrNotFound
+	}
+
+	return ds.ConversationStateResponse{UserID: row.UserID, State: row.State, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func (r *stubStateRepository) UpsertConversationState(_ context.Context, state ds.ConversationStateRequest) error {
+	r.rows[state.UserID] = state
+	return nil
+}
+
+func (r *stubStateRepository) DeleteConversationState(_ context.Context, userID int64) error {
+	delete(r.rows, userID)
+	return nil
+}
+
+func (r *stubStateRepository) DeleteExpiredConversationStates(_ context.Context, now time.Time) (int64, error) {
+	var count int64
+
+	for userID, row := range r.rows {
+		if !now.Before(row.ExpiresAt) {
+			delete(r.rows, userID)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func TestStateStore(t *testing.T) {
+	const (
+		userID = int64(1)
+		ttl    = time.Hour
+	)
+
+	now := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		build func(now func() time.Time) StateStore
+	}{
+		{
+			name: "memory",
+			build: func(now func() time.Time) StateStore {
+				store := NewMemoryStateStore(ttl)
+				store.now = now
+
+				return store
+			},
+		},
+		{
+			name: "postgres",
+			build: func(now func() time.Time) StateStore {
+				store := NewPostgresStateStore(&stubStateRepository{rows: make(map[int64]ds.ConversationStateRequest)}, ttl)
+				store.now = now
+
+				return store
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			current := now
+			store := tc.build(func() time.Time { return current })
+
+			_, exists, err := store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.False(t, exists)
+
+			state := &SubscribeState{ //nolint:exhaustruct,nolintlint
+				Step:            modelSelectionStep,
+				InProgress:      true,
+				SelectedBrand:   "bmw",
+				SelectedModels:  []string{"m3"},
+				SelectedChassis: []string{},
+				SelectedRegions: []string{},
+				PriceFrom:       "1000",
+				SelectedFuel:    []string{"Benzin"},
+				IncludeKeywords: []string{"xenon"},
+				ExtraParams:     map[string]string{"engine_volume_from": "1598"},
+			}
+			require.NoError(t, store.Set(ctx, userID, state))
+
+			// changes are not visible until the state is saved
+			state.SelectedModels[0] = "m5"
+
+			got, exists, err := store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.Equal(t, []string{"m3"}, got.SelectedModels)
+			require.Equal(t, modelSelectionStep, got.Step)
+			require.Equal(t, "1000", got.PriceFrom)
+
+			// changes of the returned state are not visible until it is saved either
+			got.SelectedFuel[0] = "Dizel"
+			got.IncludeKeywords[0] = "panorama"
+			got.ExtraParams["engine_volume_from"] = "1998"
+
+			got, exists, err = store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.Equal(t, []string{"Benzin"}, got.SelectedFuel)
+			require.Equal(t, []string{"xenon"}, got.IncludeKeywords)
+			require.Equal(t, map[string]string{"engine_volume_from": "1598"}, got.ExtraParams)
+
+			// the state expires after TTL
+			current = now.Add(ttl)
+
+			_, exists, err = store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.False(t, exists)
+
+			// saving extends the expiration
+			require.NoError(t, store.Set(ctx, userID, state))
+
+			current = current.Add(ttl - time.Minute)
+
+			_, exists, err = store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.True(t, exists)
+
+			current = current.Add(time.Minute)
+
+			count, err := store.DeleteExpired(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), count)
+
+			require.NoError(t, store.Set(ctx, userID, state))
+			require.NoError(t, store.Delete(ctx, userID))
+
+			_, exists, err = store.Get(ctx, userID)
+			require.NoError(t, err)
+			require.False(t, exists)
+		})
+	}
+}
+
+// TestStoredStepNumbers guards the numbers of the steps, which are stored in the conversation state
+// and in the buttons, so a changed number breaks the conversations and the buttons sent before a deploy.
+func TestStoredStepNumbers(t *testing.T) {
+	steps := []subscribeStep{
+		brandSelectionStep, modelSelectionStep, chassisSelectionStep, regionSelectionStep,
+		priceFromStep, priceToStep, yearFromStep, yearToStep,
+		confirmSelectionStep, editMenuStep,
+		minDealPercentStep, keywordsStep,
+		filtersMenuStep, fuelSelectionStep, gearboxSelectionStep, doorsSelectionStep, seatsSelectionStep,
+		colorSelectionStep, airConditionSelectionStep, damageSelectionStep, mileageToStep, powerFromStep, powerToStep,
+		deliveryModeStep, digestHourStep, timeZoneStep,
+	}
+
+	for i, step := range steps {
+		require.Equal(t, subscribeStep(i+1), step)
+	}
+}
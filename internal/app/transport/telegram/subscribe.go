@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
 
 	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
@@ -18,17 +19,34 @@ type (
 	subscribeStep int
 
 	SubscribeState struct {
-		Step            subscribeStep
-		InProgress      bool
-		SelectedBrand   string
-		SelectedModels  []string
-		SelectedChassis []string
-		SelectedRegions []string
-		PriceFrom       string
-		PriceTo         string
-		YearFrom        string
-		YearTo          string
-		LastMessageID   int
+		Step                  subscribeStep     `json:"step"`
+		InProgress            bool              `json:"in_progress"`
+		SelectedBrand         string            `json:"selected_brand"`
+		SelectedModels        []string          `json:"selected_models"`
+		SelectedChassis       []string          `json:"selected_chassis"`
+		SelectedRegions       []string          `json:"selected_regions"`
+		PriceFrom             string            `json:"price_from"`
+		PriceTo               string            `json:"price_to"`
+		YearFrom              string            `json:"year_from"`
+		YearTo                string            `json:"year_to"`
+		SelectedFuel          []string          `json:"selected_fuel"`
+		SelectedGearbox       []string          `json:"selected_gearbox"`
+		SelectedDoors         []string          `json:"selected_doors"`
+		SelectedSeats         []string          `json:"selected_seats"`
+		SelectedColors        []string          `json:"selected_colors"`
+		SelectedAirCondition  []string          `json:"selected_air_condition"`
+		SelectedDamage        []string          `json:"selected_damage"`
+		MileageTo             string            `json:"mileage_to"`
+		PowerFrom             string            `json:"power_from"`
+		PowerTo               string            `json:"power_to"`
+		MinDealPercent        string            `json:"min_deal_percent"`
+		IncludeKeywords       []string          `json:"include_keywords"`
+		ExcludeKeywords       []string          `json:"exclude_keywords"`
+		ExtraParams           map[string]string `json:"extra_params"`
+		LastMessageID         int               `json:"last_message_id"`
+		EditingSubscriptionID string            `json:"editing_subscription_id"`
+		DeliveryMode          string            `json:"delivery_mode"`
+		DigestHour            int               `json:"digest_hour"`
 	}
 
 	MessageWithButtonsParams struct {
@@ -51,6 +69,28 @@ const (
 	yearFromStep         subscribeStep = 7
 	yearToStep           subscribeStep = 8
 	confirmSelectionStep subscribeStep = 9
+	editMenuStep         subscribeStep = 10
+	// the steps are stored in the conversation state and in the buttons, so a new step gets the next number
+	// and the numbers of the existing steps never change, nextStep orders them
+	minDealPercentStep subscribeStep = 11
+	keywordsStep       subscribeStep = 12
+	// filtersMenuStep is the menu of the additional filters, each of them is chosen at its own step below
+	filtersMenuStep           subscribeStep = 13
+	fuelSelectionStep         subscribeStep = 14
+	gearboxSelectionStep      subscribeStep = 15
+	doorsSelectionStep        subscribeStep = 16
+	seatsSelectionStep        subscribeStep = 17
+	colorSelectionStep        subscribeStep = 18
+	airConditionSelectionStep subscribeStep = 19
+	damageSelectionStep       subscribeStep = 20
+	mileageToStep             subscribeStep = 21
+	powerFromStep             subscribeStep = 22
+	powerToStep               subscribeStep = 23
+	// deliveryModeStep and digestHourStep are only reached from the edit menu
+	deliveryModeStep subscribeStep = 24
+	digestHourStep   subscribeStep = 25
+	// timeZoneStep is reached from the settings, the time zone can be typed
+	timeZoneStep subscribeStep = 26
 
 	brandButtonsPerRow     = 3
 	modelButtonsPerRow     = 3
@@ -58,18 +98,25 @@ const (
 	regionButtonsPerRow    = 3
 	sendPriceButtonsPerRow = 2
 	sendYearButtonsPerRow  = 2
+	sendDealButtonsPerRow  = 2
+	sendKeywordsPerRow     = 2
+
+	// maxMinDealPercent is the max percent below the market price, a listing can't be cheaper than free.
+	maxMinDealPercent = 99
 
 	maxModelsPerPage = 36
 	maxBrandsPerPage = 36
 )
 
+var errInvalidMinDealPercent = errors.New("min deal percent is out of range")
+
 // handleSubscribe handles the /subscribe command, starting the subscription process.
 func (h *BotHandler) handleSubscribe(ctx context.Context, chatID int64) error {
 	return h.startSubscription(ctx, chatID)
 }
 
 func (h *BotHandler) startSubscription(ctx context.Context, chatID int64) error {
-	h.state[chatID] = &SubscribeState{ //nolint:exhaustruct,nolintlint
+	state := &SubscribeState{ //nolint:exhaustruct,nolintlint
 		Step:            brandSelectionStep,
 		InProgress:      true,
 		SelectedModels:  []string{},
@@ -77,6 +124,10 @@ func (h *BotHandler) startSubscription(ctx context.Context, chatID int64) error
 		SelectedRegions: []string{},
 	}
 
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
 	return h.sendBrandSelectionMessage(ctx, chatID, 0)
 }
 
@@ -125,7 +176,7 @@ You can cancel the process at any time by typing '🚫 cancel'.`
 
 	// if it first times send message else edit this message
 	isNeedEditMsg := false
-	if _, exists := h.state[chatID]; exists {
+	if _, exists := h.getState(ctx, chatID); exists {
 		isNeedEditMsg = true
 	}
 
@@ -148,10 +199,18 @@ func (h *BotHandler) handleSelectBrand(ctx context.Context, callbackQuery *tgbot
 		return h.sendBrandSelectionMessage(ctx, callbackQuery.Message.Chat.ID, page)
 	}
 
-	state := h.state[callbackQuery.From.ID]
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID)
+	}
+
 	state.SelectedBrand = data
 	state.Step = modelSelectionStep
 
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
 	text := `
 🚗 Please choose car models (you can select multiple). When you're done, type '✅ done':
 
@@ -235,17 +294,19 @@ func generatePaginationButtons(currentPage, totalPages int) []tgbotapi.InlineKey
 
 // handleSelectModels handles the model selection step.
 func (h *BotHandler) handleSelectModels(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
-	state := h.state[callbackQuery.From.ID]
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID)
+	}
+
 	data := callbackQuery.Data
 
 	isPagination := strings.HasPrefix(data, "prev_page_") || strings.HasPrefix(data, "next_page_")
 
 	var text string
 
-	if !isPagination { // Selecting a model
-		if !contains(state.SelectedModels, data) {
-			state.SelectedModels = append(state.SelectedModels, data)
-		}
+	if !isPagination { // Selecting or deselecting a model
+		state.SelectedModels = toggle(state.SelectedModels, data)
 
 		text = fmt.Sprintf(`
 🚗 Selected models: %s
@@ -257,6 +318,10 @@ You can cancel the process at any time by typing '🚫 cancel'`,
 		text = callbackQuery.Message.Text
 	}
 
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
+	}
+
 	page := 0
 
 	if isPagination {
@@ -293,11 +358,17 @@ func (h *BotHandler) sendChassisSelectionMessage(ctx context.Context, chatID int
 
 // handleSelectChassis handles the chassis selection step.
 func (h *BotHandler) handleSelectChassis(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
-	state := h.state[callbackQuery.From.ID]
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID)
+	}
+
 	chassis := callbackQuery.Data
 
-	if !contains(state.SelectedChassis, chassis) {
-		state.SelectedChassis = append(state.SelectedChassis, chassis)
+	state.SelectedChassis = toggle(state.SelectedChassis, chassis)
+
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
 	}
 
 	text := fmt.Sprintf(`
@@ -337,11 +408,17 @@ func (h *BotHandler) sendRegionSelectionMessage(ctx context.Context, chatID int6
 
 // handleSelectRegions handles the region selection step.
 func (h *BotHandler) handleSelectRegions(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
-	state := h.state[callbackQuery.From.ID]
+	state, exists := h.getState(ctx, callbackQuery.From.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, callbackQuery.Message.Chat.ID)
+	}
+
 	region := callbackQuery.Data
 
-	if !contains(state.SelectedRegions, region) {
-		state.SelectedRegions = append(state.SelectedRegions, region)
+	state.SelectedRegions = toggle(state.SelectedRegions, region)
+
+	if err := h.saveState(ctx, callbackQuery.From.ID, state); err != nil {
+		return err
 	}
 
 	text := fmt.Sprintf(`
@@ -422,6 +499,10 @@ func (h *BotHandler) handlePriceTo(ctx context.Context, message *tgbotapi.Messag
 		return err
 	}
 
+	if state, _ := h.getState(ctx, message.Chat.ID); isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, message.Chat.ID)
+	}
+
 	return h.sendYearFromMessage(ctx, message.Chat.ID)
 }
 
@@ -485,11 +566,134 @@ You can cancel the process at any time by sending '🚫 cancel'.`
 func (h *BotHandler) handleYearTo(ctx context.Context, message *tgbotapi.Message) error {
 	errText := "⚠️ Invalid year to. Please enter a valid number or type '️⏭️ skip' to skip this step:"
 	if err := h.handleNumericInput(
-		ctx, message, yearToStep, confirmSelectionStep, errText, sendYearButtonsPerRow,
+		ctx, message, yearToStep, filtersMenuStep, errText, sendYearButtonsPerRow,
+	); err != nil {
+		return err
+	}
+
+	if state, _ := h.getState(ctx, message.Chat.ID); isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, message.Chat.ID)
+	}
+
+	return h.sendFiltersMenuMessage(ctx, message.Chat.ID)
+}
+
+// sendMinDealPercentMessage sends a message asking the user to enter the minimum percent below the market price.
+func (h *BotHandler) sendMinDealPercentMessage(ctx context.Context, chatID int64) error {
+	text := `
+🏷️ Please enter how many percent below the market price a listing must be to notify you, e.g. 15,
+or type '️⏭️ skip' to get all listings:
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, sendDealButtonsPerRow, actionsButtons, nil)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("failed to send min deal percent message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send min deal percent message")
+	}
+
+	return nil
+}
+
+// handleMinDealPercent processes the user's input for the minimum percent below the market price.
+func (h *BotHandler) handleMinDealPercent(ctx context.Context, message *tgbotapi.Message) error {
+	errText := fmt.Sprintf(
+		"⚠️ Invalid percent. Please enter a number from 1 to %d or type '️⏭️ skip' to get all listings:",
+		maxMinDealPercent,
+	)
+	if err := h.handleNumericInput(
+		ctx, message, minDealPercentStep, keywordsStep, errText, sendDealButtonsPerRow,
 	); err != nil {
 		return err
 	}
 
+	if state, _ := h.getState(ctx, message.Chat.ID); isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, message.Chat.ID)
+	}
+
+	return h.sendKeywordsMessage(ctx, message.Chat.ID)
+}
+
+// sendKeywordsMessage sends a message asking the user to enter the keywords of the listing title.
+func (h *BotHandler) sendKeywordsMessage(ctx context.Context, chatID int64) error {
+	text := `
+🔤 Please enter the keywords the listing title must contain, separated by commas, e.g. xdrive, m paket.
+Start a keyword with '-' to skip the listings containing it, e.g. -oštećen, -delovi.
+The case and the alphabet don't matter. Type '️⏭️ skip' to get listings with any title:
+
+You can cancel the process at any time by sending '🚫 cancel'.`
+
+	if state, exists := h.getState(ctx, chatID); exists &&
+		(len(state.IncludeKeywords) != 0 || len(state.ExcludeKeywords) != 0) {
+		text = "\n🔤 Current keywords: " + formatKeywords(state.IncludeKeywords, state.ExcludeKeywords) + "\n" + text
+	}
+
+	actionsButtons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+		tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createKeyboard(ctx, sendKeywordsPerRow, actionsButtons, nil)
+
+	if _, err := h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error("failed to send keywords message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send keywords message")
+	}
+
+	return nil
+}
+
+// handleKeywords processes the user's input for the include and exclude keywords of the listing title.
+func (h *BotHandler) handleKeywords(ctx context.Context, message *tgbotapi.Message) error {
+	state, exists := h.getState(ctx, message.Chat.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, message.Chat.ID)
+	}
+
+	include, exclude, parseErr := parseKeywords(message.Text)
+	if parseErr != nil {
+		errText := fmt.Sprintf(
+			"⚠️ Invalid keywords. Please enter up to %d keywords of up to %d characters separated by commas "+
+				"or type '️⏭️ skip' to get listings with any title:",
+			maxKeywords, maxKeywordLength,
+		)
+
+		actionsButtons := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
+			tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
+		}
+
+		msg := tgbotapi.NewMessage(message.Chat.ID, errText)
+		msg.ReplyMarkup = createKeyboard(ctx, sendKeywordsPerRow, actionsButtons, nil)
+
+		if _, err := h.tgBot.SendMessage(msg); err != nil {
+			h.l.Error("failed to send validation message", logger.ErrAttr(err))
+			return errors.Wrap(err, "failed to send validation message")
+		}
+
+		return parseErr
+	}
+
+	state.IncludeKeywords = include
+	state.ExcludeKeywords = exclude
+	state.Step = confirmSelectionStep
+
+	if err := h.saveState(ctx, message.Chat.ID, state); err != nil {
+		return err
+	}
+
+	if isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, message.Chat.ID)
+	}
+
 	return h.sendConfirmationMessage(ctx, message.Chat.ID)
 }
 
@@ -502,10 +706,19 @@ func (h *BotHandler) handleNumericInput(
 	errorMessage string,
 	buttonsPerRow int, //nolint:unparam,nolintlint
 ) error {
-	state := h.state[message.Chat.ID]
+	state, exists := h.getState(ctx, message.Chat.ID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, message.Chat.ID)
+	}
+
 	input := message.Text
 
-	if _, atoiErr := strconv.Atoi(input); atoiErr != nil {
+	value, atoiErr := strconv.Atoi(input)
+	if atoiErr == nil && currStep == minDealPercentStep && (value < 1 || value > maxMinDealPercent) {
+		atoiErr = errInvalidMinDealPercent
+	}
+
+	if atoiErr != nil {
 		actionsButtons := []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("🚫 Cancel", "/cancel"),
 			tgbotapi.NewInlineKeyboardButtonData("⏭️ Skip", "/skip"),
@@ -531,30 +744,41 @@ func (h *BotHandler) handleNumericInput(
 		state.YearFrom = input
 	case yearToStep:
 		state.YearTo = input
+	case mileageToStep:
+		state.MileageTo = input
+	case powerFromStep:
+		state.PowerFrom = input
+	case powerToStep:
+		state.PowerTo = input
+	case minDealPercentStep:
+		state.MinDealPercent = input
 	}
 
 	state.Step = nextStep
 
-	return nil
+	return h.saveState(ctx, message.Chat.ID, state)
 }
 
 // handleCancel handles the /cancel command, canceling the subscription process.
-func (h *BotHandler) handleCancel(_ context.Context, chatID int64) error {
-	state, exists := h.state[chatID]
+func (h *BotHandler) handleCancel(ctx context.Context, chatID int64) error {
+	state, exists := h.getState(ctx, chatID)
 	if !exists || !state.InProgress {
 		return nil // Ignore if subscription process is not in progress
 	}
 
-	delete(h.state, chatID)
+	h.deleteState(ctx, chatID)
 
 	text := "🚫 Subscription process has been cancelled."
+	if state.Step == timeZoneStep {
+		text = "🚫 The change of the settings has been cancelled."
+	}
 
 	return h.sendMessage(chatID, text, handleNameCancel)
 }
 
 // handleDone handles the /done command, moving to the next step.
 func (h *BotHandler) handleDone(ctx context.Context, chatID int64) error {
-	state, exists := h.state[chatID]
+	state, exists := h.getState(ctx, chatID)
 	if !exists {
 		return h.sendUnknownCommandMessage(ctx, chatID)
 	}
@@ -563,7 +787,21 @@ func (h *BotHandler) handleDone(ctx context.Context, chatID int64) error {
 		return h.sendUnknownCommandMessage(ctx, chatID)
 	}
 
-	state.Step++
+	doneStep := state.Step
+	state.Step = nextStep(state.Step)
+
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	// the additional filters return to the filters menu, also when editing
+	if isFilterStep(doneStep) {
+		return h.sendFiltersMenuMessage(ctx, chatID)
+	}
+
+	if isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, chatID)
+	}
 
 	switch state.Step {
 	case brandSelectionStep:
@@ -592,6 +830,12 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 		return h.sendYearFromMessage(ctx, chatID)
 	case yearToStep:
 		return h.sendYearToMessage(ctx, chatID)
+	case filtersMenuStep:
+		return h.sendFiltersMenuMessage(ctx, chatID)
+	case minDealPercentStep:
+		return h.sendMinDealPercentMessage(ctx, chatID)
+	case keywordsStep:
+		return h.sendKeywordsMessage(ctx, chatID)
 	case confirmSelectionStep:
 		return h.sendConfirmationMessage(ctx, chatID)
 	default:
@@ -603,7 +847,7 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 			h.l.Error("failed to send cancellation message", logger.ErrAttr(err))
 		}
 
-		delete(h.state, chatID)
+		h.deleteState(ctx, chatID)
 
 		return nil
 	}
@@ -611,16 +855,58 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 
 // handleSkip handles the /skip command, allowing the user to skip optional steps.
 func (h *BotHandler) handleSkip(ctx context.Context, chatID int64) error {
-	state, exists := h.state[chatID]
+	state, exists := h.getState(ctx, chatID)
 	if !exists || !state.InProgress {
 		return nil // Ignore if subscription process is not in progress
 	}
 
-	state.Step++
+	skippedStep := state.Step
+	state.Step = nextStep(state.Step)
+
+	// Clear the state for the skipped optional step
+	switch skippedStep { //nolint:exhaustive,nolintlint
+	case chassisSelectionStep:
+		state.SelectedChassis = state.SelectedChassis[:0]
+	case regionSelectionStep:
+		state.SelectedRegions = state.SelectedRegions[:0]
+	case filtersMenuStep:
+		clearSearchFilters(state)
+	case mileageToStep:
+		state.MileageTo = ""
+	case powerFromStep:
+		state.PowerFrom = ""
+	case powerToStep:
+		state.PowerTo = ""
+	case minDealPercentStep:
+		state.MinDealPercent = ""
+	case keywordsStep:
+		state.IncludeKeywords = nil
+		state.ExcludeKeywords = nil
+	default:
+		if f, ok := optionFilterByStep(skippedStep); ok {
+			*f.selected(state) = []string{}
+		}
+	}
+
+	if err := h.saveState(ctx, chatID, state); err != nil {
+		return err
+	}
+
+	// the additional filters return to the filters menu, also when editing
+	if isFilterStep(skippedStep) {
+		if state.Step == powerToStep {
+			return h.sendPowerToMessage(ctx, chatID)
+		}
+
+		return h.sendFiltersMenuMessage(ctx, chatID)
+	}
+
+	if isEditStepCompleted(state) {
+		return h.sendEditMenuMessage(ctx, chatID)
+	}
+
 	switch state.Step { //nolint:exhaustive,nolintlint
 	case regionSelectionStep:
-		// Clear the state for the previous step for the chassis and move on to the next step
-		state.SelectedChassis = state.SelectedChassis[:0]
 		text := `
 📍 Please choose regions (you can select multiple). When you're done, type '️✅ done':
 
@@ -628,8 +914,6 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 
 		return h.sendRegionSelectionMessage(ctx, chatID, text)
 	case priceFromStep:
-		// Clear the state for the previous step for the regions and move on to the next step
-		state.SelectedRegions = state.SelectedRegions[:0]
 		return h.sendPriceFromMessage(ctx, chatID)
 	case priceToStep:
 		return h.sendPriceToMessage(ctx, chatID)
@@ -637,6 +921,12 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 		return h.sendYearFromMessage(ctx, chatID)
 	case yearToStep:
 		return h.sendYearToMessage(ctx, chatID)
+	case filtersMenuStep:
+		return h.sendFiltersMenuMessage(ctx, chatID)
+	case minDealPercentStep:
+		return h.sendMinDealPercentMessage(ctx, chatID)
+	case keywordsStep:
+		return h.sendKeywordsMessage(ctx, chatID)
 	case confirmSelectionStep:
 		return h.sendConfirmationMessage(ctx, chatID)
 	default:
@@ -648,7 +938,11 @@ You can cancel the process at any time by sending '🚫 cancel' or skip this ste
 
 // sendConfirmationMessage sends a message asking the user to confirm their subscription.
 func (h *BotHandler) sendConfirmationMessage(ctx context.Context, chatID int64) error {
-	state := h.state[chatID]
+	state, exists := h.getState(ctx, chatID)
+	if !exists {
+		return h.sendUnknownCommandMessage(ctx, chatID)
+	}
+
 	text := fmt.Sprintf(`
 	🚗 Brand: %s
 	🚘 Models: %s
@@ -656,6 +950,10 @@ func (h *BotHandler) sendConfirmationMessage(ctx context.Context, chatID int64)
 	📍 Regions: %s
 	💰 Price: %s€ - %s€
 	📅 Year: %s - %s
+	🔧 Filters: %s
+	🏷️ Deal: %s
+	🔤 Keywords: %s
+	🔗 Other parameters: %s
 
 	Please type '✅ confirm' to save this subscription or '🚫 cancel' to discard it.`,
 		state.SelectedBrand,
@@ -664,6 +962,10 @@ func (h *BotHandler) sendConfirmationMessage(ctx context.Context, chatID int64)
 		strings.Join(state.SelectedRegions, ", "),
 		state.PriceFrom, state.PriceTo,
 		state.YearFrom, state.YearTo,
+		formatSearchFilters(state),
+		formatMinDealPercent(state.MinDealPercent),
+		formatKeywords(state.IncludeKeywords, state.ExcludeKeywords),
+		formatExtraParams(state.ExtraParams),
 	)
 
 	actionsButtons := []tgbotapi.InlineKeyboardButton{
@@ -684,21 +986,39 @@ func (h *BotHandler) sendConfirmationMessage(ctx context.Context, chatID int64)
 
 // handleConfirm handles the /confirm command, saving the subscription to the database.
 func (h *BotHandler) handleConfirm(ctx context.Context, chatID int64) error {
-	state, exists := h.state[chatID]
+	state, exists := h.getState(ctx, chatID)
 	if !exists {
 		return h.sendUnknownCommandMessage(ctx, chatID)
 	}
 
+	if state.EditingSubscriptionID != "" {
+		return h.confirmEdit(ctx, chatID, state)
+	}
+
 	subscription := ds.SubscriptionRequest{
-		UserID:    chatID,
-		Brand:     state.SelectedBrand,
-		Model:     state.SelectedModels,
-		Chassis:   state.SelectedChassis,
-		Region:    state.SelectedRegions,
-		PriceFrom: state.PriceFrom,
-		PriceTo:   state.PriceTo,
-		YearFrom:  state.YearFrom,
-		YearTo:    state.YearTo,
+		UserID:          chatID,
+		Brand:           state.SelectedBrand,
+		Model:           state.SelectedModels,
+		Chassis:         state.SelectedChassis,
+		Region:          state.SelectedRegions,
+		PriceFrom:       state.PriceFrom,
+		PriceTo:         state.PriceTo,
+		YearFrom:        state.YearFrom,
+		YearTo:          state.YearTo,
+		MinDealPercent:  minDealPercentFromState(state.MinDealPercent),
+		IncludeKeywords: state.IncludeKeywords,
+		ExcludeKeywords: state.ExcludeKeywords,
+		Fuel:            state.SelectedFuel,
+		Gearbox:         state.SelectedGearbox,
+		MileageTo:       state.MileageTo,
+		PowerFrom:       state.PowerFrom,
+		PowerTo:         state.PowerTo,
+		Doors:           state.SelectedDoors,
+		Seats:           state.SelectedSeats,
+		Color:           state.SelectedColors,
+		AirCondition:    state.SelectedAirCondition,
+		Damage:          state.SelectedDamage,
+		ExtraParams:     state.ExtraParams,
 	}
 
 	_, err := h.svc.CreateSubscription(ctx, subscription)
@@ -708,7 +1028,7 @@ func (h *BotHandler) handleConfirm(ctx context.Context, chatID int64) error {
 		return h.sendMessage(chatID, text, handleNameConfirm)
 	}
 
-	delete(h.state, chatID)
+	h.deleteState(ctx, chatID)
 
 	text := "✅ Your subscription has been saved successfully!"
 
@@ -717,14 +1037,14 @@ func (h *BotHandler) handleConfirm(ctx context.Context, chatID int64) error {
 
 // sendSubscribeMessage sends a message for subscribe new listings.
 func (h *BotHandler) sendSubscribeMessage(
-	_ context.Context,
+	ctx context.Context,
 	chatID int64,
 	msg tgbotapi.MessageConfig,
 	isNeedEditMsg bool,
 ) error {
-	state := h.state[chatID]
+	state, exists := h.getState(ctx, chatID)
 
-	if state != nil && state.LastMessageID != 0 && isNeedEditMsg {
+	if exists && state.LastMessageID != 0 && isNeedEditMsg {
 		replyMarkup, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
 		if !ok {
 			return errors.New("failed to cast reply markup to InlineKeyboardMarkup")
@@ -742,7 +1062,11 @@ func (h *BotHandler) sendSubscribeMessage(
 			return errors.Wrap(err, "failed to send brand selection message")
 		}
 
-		h.state[chatID].LastMessageID = sendMsg.MessageID
+		if exists {
+			state.LastMessageID = sendMsg.MessageID
+
+			return h.saveState(ctx, chatID, state)
+		}
 	}
 
 	return nil
@@ -755,3 +1079,31 @@ func (h *BotHandler) sendSubscribeMessageWithButtons(ctx context.Context, params
 
 	return h.sendSubscribeMessage(ctx, params.ChatID, msg, params.IsNeedEditMsg)
 }
+
+// minDealPercentFromState converts the percent entered by the user, it's null if the step was skipped.
+func minDealPercentFromState(percent string) null.Int {
+	value, err := strconv.Atoi(percent)
+	if err != nil {
+		return null.Int{}
+	}
+
+	return null.IntFrom(int64(value))
+}
+
+// minDealPercentToState converts the percent of the subscription to the state, it's empty if there is no threshold.
+func minDealPercentToState(percent null.Int) string {
+	if !percent.Valid {
+		return ""
+	}
+
+	return strconv.FormatInt(percent.Int64, 10)
+}
+
+// formatMinDealPercent formats the threshold of the deals for the user.
+func formatMinDealPercent(percent string) string {
+	if percent == "" {
+		return "any price"
+	}
+
+	return "at least " + percent + "% below market"
+}
@@ -27,8 +27,10 @@ func (h *BotHandler) handleUnsubscribe(ctx context.Context, chatID int64) error
 
 	var buttons []tgbotapi.InlineKeyboardButton
 
+	loc := h.userLocation(ctx, chatID)
+
 	for _, sub := range subscriptions {
-		buttonText := strings.ReplaceAll(h.buildMessageWithSubscription(sub, false), ", \n", "\n")
+		buttonText := strings.ReplaceAll(h.buildMessageWithSubscription(sub, false, loc), ", \n", "\n")
 		// handleNameUnsubscribe name is used in the callback. TODO: need refactoring
 		addPrefixForData := fmt.Sprintf("%s:%s", handleNameUnsubscribe, sub.ID)
 		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, addPrefixForData)
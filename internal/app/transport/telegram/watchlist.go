@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/pricetrend"
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// handleWatchlist handles the /watchlist command, showing the saved cars with their current prices
+// and the buttons removing them from the watchlist.
+func (h *BotHandler) handleWatchlist(ctx context.Context, chatID int64) error {
+	items, err := h.svc.GetWatchlist(ctx, chatID)
+	if err != nil {
+		text := "⚠️ An internal error occurred while getting the watchlist. Please try again later."
+		return h.sendMessage(chatID, text, handleNameWatchlist)
+	}
+
+	if len(items) == 0 {
+		text := "⭐ Your watchlist is empty. Tap ⭐ Save under a listing notification to watch the price of the car."
+		return h.sendMessage(chatID, text, handleNameWatchlist)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup()
+
+	for i, item := range items {
+		// handleNameWatchlist name is used in the callback, like in the unsubscribe buttons
+		data := fmt.Sprintf("%s:%s", handleNameWatchlist, item.ListingID)
+		button := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Remove %d. %s", i+1, item.Title), data)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(button))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, buildWatchlistText(items))
+	msg.ReplyMarkup = keyboard
+
+	if _, err = h.tgBot.SendMessage(msg); err != nil {
+		h.l.Error(handleNameWatchlist+": failed to send message", logger.ErrAttr(err))
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return nil
+}
+
+// handleWatchlistCallback handles the callback query for removing a car from the watchlist.
+func (h *BotHandler) handleWatchlistCallback(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) error {
+	chatID := callbackQuery.Message.Chat.ID
+	text := "🟢 The car is removed from your watchlist."
+
+	if err := h.svc.RemoveFromWatchlist(ctx, chatID, callbackQuery.Data); err != nil {
+		text = "⚠️ An internal error occurred while removing the car from the watchlist. Please try again later."
+		if errors.Is(err, ds.ErrNotFound) {
+			text = "🤷 The car is not on your watchlist anymore."
+		}
+	}
+
+	if err := h.sendMessage(chatID, text, handleNameWatchlist); err != nil {
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	// send an updated watchlist.
+	return h.handleWatchlist(ctx, chatID)
+}
+
+// buildWatchlistText builds the message with the saved cars, their current prices and links.
+func buildWatchlistText(items []ds.WatchlistItemResponse) string {
+	var sb strings.Builder
+
+	sb.WriteString("⭐ Your watchlist:\n")
+
+	for i, item := range items {
+		price := item.Price
+		if item.PriceEUR.Valid {
+			price = pricetrend.FormatEUR(item.PriceEUR.Decimal)
+		}
+
+		if price == "" {
+			price = "unknown"
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n", i+1, item.Title))
+
+		if item.RemovedAt.Valid {
+			sb.WriteString(fmt.Sprintf("🗑️ Removed on %s, the last price was %s\n",
+				item.RemovedAt.Time.Format(time.DateOnly), price))
+		} else {
+			sb.WriteString("💶 " + price + "\n")
+		}
+
+		sb.WriteString("🔗 " + item.Link + "\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func Test_buildWatchlistText(t *testing.T) {
+	removedAt := time.Date(2024, 12, 1, 18, 30, 0, 0, time.UTC)
+
+	items := []ds.WatchlistItemResponse{
+		{ //nolint:exhaustruct,nolintlint
+			ListingID: "26135927",
+			Title:     "BMW 320d",
+			Price:     "12500€",
+			PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(12500)),
+			Link:      "https://www.polovniautomobili.com/auto-oglasi/26135927/bmw-320d",
+		},
+		{ //nolint:exhaustruct,nolintlint
+			ListingID: "26135928",
+			Title:     "Audi A4",
+			Price:     "Na upit",
+			Link:      "https://www.polovniautomobili.com/auto-oglasi/26135928/audi-a4",
+		},
+		{ //nolint:exhaustruct,nolintlint
+			ListingID: "26135929",
+			Title:     "VW Golf",
+			PriceEUR:  decimal.NewNullDecimal(decimal.NewFromInt(7000)),
+			Link:      "https://www.polovniautomobili.com/auto-oglasi/26135929/vw-golf",
+			RemovedAt: null.TimeFrom(removedAt),
+		},
+		{ //nolint:exhaustruct,nolintlint
+			ListingID: "26135930",
+			Title:     "Opel Astra",
+			Link:      "https://www.polovniautomobili.com/auto-oglasi/26135930/opel-astra",
+		},
+	}
+
+	want := "⭐ Your watchlist:\n" +
+		"\n1. BMW 320d\n💶 12 500 €\n🔗 https://www.polovniautomobili.com/auto-oglasi/26135927/bmw-320d\n" +
+		"\n2. Audi A4\n💶 Na upit\n🔗 https://www.polovniautomobili.com/auto-oglasi/26135928/audi-a4\n" +
+		"\n3. VW Golf\n🗑️ Removed on 2024-12-01, the last price was 7 000 €\n" +
+		"🔗 https://www.polovniautomobili.com/auto-oglasi/26135929/vw-golf\n" +
+		"\n4. Opel Astra\n💶 unknown\n🔗 https://www.polovniautomobili.com/auto-oglasi/26135930/opel-astra\n"
+
+	require.Equal(t, want, buildWatchlistText(items))
+}
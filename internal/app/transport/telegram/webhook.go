@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+const (
+	webhookSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token" //nolint:gosec,nolintlint
+	webhookUpdatesBufferSize = 100
+	webhookReadHeaderTimeout = 10 * time.Second
+	webhookShutdownTimeout   = 10 * time.Second
+)
+
+// serveWebhook registers the webhook, serves the webhook requests on the listener and passes the updates
+// to the handler. The webhook is removed on shutdown, so the bot can be switched back to long polling.
+func (h *BotHandler) serveWebhook(ctx context.Context, listener net.Listener) error {
+	cfg := h.tgBot.GetCfg()
+
+	if cfg.WebhookURL == "" || cfg.WebhookSecretToken == "" {
+		_ = listener.Close()
+		return errors.New("webhook url and secret token are required")
+	}
+
+	updates := make(chan tgbotapi.Update, webhookUpdatesBufferSize)
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.WebhookPath, h.webhookHandler(cfg.WebhookSecretToken, updates))
+
+	srv := &http.Server{ //nolint:exhaustruct,nolintlint
+		Handler:           mux,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+		// pending requests are canceled together with the handler
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- errors.Wrap(err, "webhook server failed")
+		}
+	}()
+
+	defer h.shutdownWebhook(srv)
+
+	if err := h.tgBot.SetWebhook(cfg.WebhookURL, cfg.WebhookSecretToken); err != nil {
+		return errors.Wrap(err, "failed to register webhook")
+	}
+
+	h.l.Info("webhook registered", logger.StringAttr("addr", listener.Addr().String()))
+
+	return h.processUpdates(ctx, updates, errCh)
+}
+
+// shutdownWebhook removes the webhook and stops the webhook server.
+func (h *BotHandler) shutdownWebhook(srv *http.Server) {
+	if err := h.tgBot.DeleteWebhook(); err != nil {
+		h.l.Error("failed to delete webhook", logger.ErrAttr(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		h.l.Error("failed to shutdown webhook server", logger.ErrAttr(err))
+	}
+}
+
+// webhookHandler accepts the webhook requests from Telegram and passes the updates to the channel.
+// Requests without the valid secret token are rejected.
+func (h *BotHandler) webhookHandler(secretToken string, updates chan<- tgbotapi.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(webhookSecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secretToken)) != 1 {
+			h.l.Warn("webhook request with invalid secret token", logger.StringAttr("remote_addr", r.RemoteAddr))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			h.l.Warn("failed to decode webhook update", logger.ErrAttr(err))
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		select {
+		case updates <- update:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
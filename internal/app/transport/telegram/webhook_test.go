@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+	tgCli "github.com/gudimz/polovni-auto-alert/pkg/telegram"
+)
+
+const fakeBotAPIWaitTimeout = 5 * time.Second
+
+type fakeBotAPICall struct {
+	method string
+	params url.Values
+}
+
+// newFakeBotAPI starts a fake Telegram Bot API server which records the called methods.
+func newFakeBotAPI(t *testing.T) (*httptest.Server, <-chan fakeBotAPICall) {
+	t.Helper()
+
+	calls := make(chan fakeBotAPICall, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		var result string
+
+		switch method {
+		case "getMe":
+			result = `{"id":1,"is_bot":true,"first_name":"bot","username":"test_bot"}`
+		case "sendMessage":
+			result = fmt.Sprintf(`{"message_id":1,"date":0,"chat":{"id":%s,"type":"private"}}`, r.PostForm.Get("chat_id"))
+		default:
+			result = "true"
+		}
+
+		calls <- fakeBotAPICall{method: method, params: r.PostForm}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, calls
+}
+
+func waitBotAPICall(t *testing.T, calls <-chan fakeBotAPICall, method string) fakeBotAPICall {
+	t.Helper()
+
+	for {
+		select {
+		case call := <-calls:
+			if call.method == method {
+				return call
+			}
+		case <-time.After(fakeBotAPIWaitTimeout):
+			require.FailNow(t, "bot api method was not called", method)
+		}
+	}
+}
+
+func TestBotHandler_serveWebhook(t *testing.T) {
+	const (
+		secretToken = "secret"
+		webhookPath = "/telegram/webhook"
+		webhookURL  = "https://example.com/telegram/webhook"
+		chatID      = int64(42)
+	)
+
+	fakeAPI, calls := newFakeBotAPI(t)
+
+	cfg := &tgCli.Config{ //nolint:exhaustruct,nolintlint
+		BotToken:           "token",
+		APIEndpoint:        fakeAPI.URL + "/bot%s/%s",
+		IsWebhookEnabled:   true,
+		WebhookURL:         webhookURL,
+		WebhookPath:        webhookPath,
+		WebhookSecretToken: secretToken,
+	}
+
+	lg := logger.NewLogger()
+
+	bot, err := tgCli.NewBot(lg, cfg)
+	require.NoError(t, err)
+	waitBotAPICall(t, calls, "getMe")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := NewBotHandler(lg, bot, nil, NewMemoryStateStore(time.Hour))
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- h.serveWebhook(ctx, listener)
+	}()
+
+	call := waitBotAPICall(t, calls, "setWebhook")
+	require.Equal(t, webhookURL, call.params.Get("url"))
+	require.Equal(t, secretToken, call.params.Get("secret_token"))
+
+	endpoint := "http://" + listener.Addr().String() + webhookPath
+	update := fmt.Sprintf(`{"update_id":1,"message":{"message_id":1,"date":0,"text":"hello",`+
+		`"chat":{"id":%d,"type":"private"},"from":{"id":%d,"is_bot":false,"first_name":"user"}}}`, chatID, chatID)
+
+	testCases := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			token:      secretToken,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "missing secret token",
+			method:     http.MethodPost,
+			body:       update,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid secret token",
+			method:     http.MethodPost,
+			token:      "wrong",
+			body:       update,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid body",
+			method:     http.MethodPost,
+			token:      secretToken,
+			body:       "{",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "success",
+			method:     http.MethodPost,
+			token:      secretToken,
+			body:       update,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tc.method, endpoint, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			if tc.token != "" {
+				req.Header.Set(webhookSecretTokenHeader, tc.token)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, tc.wantStatus, resp.StatusCode)
+		})
+	}
+
+	// only the valid update is handled, the unknown text is answered with the help message
+	call = waitBotAPICall(t, calls, "sendMessage")
+	require.Equal(t, fmt.Sprint(chatID), call.params.Get("chat_id"))
+	require.Empty(t, calls)
+
+	cancel()
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(fakeBotAPIWaitTimeout):
+		require.FailNow(t, "webhook server was not stopped")
+	}
+
+	waitBotAPICall(t, calls, "deleteWebhook")
+}
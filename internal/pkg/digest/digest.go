@@ -0,0 +1,97 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+const (
+	// MaxMessageLength is the limit of Telegram for the length of a text message in UTF-16 code units.
+	MaxMessageLength = 4096
+	// MaxPageItems is the max number of the listings on a page of the digest, so the page stays compact.
+	MaxPageItems = 20
+)
+
+// IsDigest checks if the listings of the subscription with the delivery mode are collected into a digest.
+func IsDigest(mode ds.DeliveryMode) bool {
+	return mode == ds.DeliveryModeHourly || mode == ds.DeliveryModeDaily
+}
+
+// IsValidHour checks if the daily digest can be sent at the hour.
+func IsValidHour(hour int) bool {
+	return hour >= 0 && hour < ds.HoursInDay
+}
+
+// NextAt returns when the digest following the one sent at sentAt is due: at the start of the next hour
+// for the hourly digest or at the digest hour of the next day for the daily digest, the digest hour is
+// in the time zone of the user. The time is returned in UTC.
+// The digest is due at once if it's been more than a period since the previous one.
+// sentAt is returned for a mode without a digest.
+func NextAt(mode ds.DeliveryMode, hour int, sentAt time.Time, loc *time.Location) time.Time {
+	sentAt = sentAt.UTC()
+
+	switch mode { //nolint:exhaustive,nolintlint
+	case ds.DeliveryModeHourly:
+		return sentAt.Truncate(time.Hour).Add(time.Hour)
+	case ds.DeliveryModeDaily:
+		local := sentAt.In(loc)
+
+		next := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+		if !next.After(local) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, 0, 0, 0, loc)
+		}
+
+		return next.UTC()
+	}
+
+	return sentAt
+}
+
+// Format describes the delivery mode to the user, e.g. "daily digest at 09:00".
+func Format(mode ds.DeliveryMode, hour int) string {
+	switch mode { //nolint:exhaustive,nolintlint
+	case ds.DeliveryModeHourly:
+		return "hourly digest"
+	case ds.DeliveryModeDaily:
+		return fmt.Sprintf("daily digest at %02d:00", hour)
+	}
+
+	return "instant"
+}
+
+// Paginate splits the items of the digest into pages of up to MaxPageItems items, the items of a page
+// joined by newlines fit the limit, which is counted in UTF-16 code units as Telegram does.
+// An item which is longer than the limit is put on a page alone.
+func Paginate(items []string, limit int) [][]string {
+	var (
+		pages  [][]string
+		page   []string
+		length int
+	)
+
+	for _, item := range items {
+		itemLength := len(utf16.Encode([]rune(item)))
+
+		// the item is separated from the previous one by a newline
+		if len(page) > 0 && (len(page) == MaxPageItems || length+1+itemLength > limit) {
+			pages = append(pages, page)
+			page, length = nil, 0
+		}
+
+		if len(page) > 0 {
+			length++
+		}
+
+		page = append(page, item)
+		length += itemLength
+	}
+
+	if len(page) > 0 {
+		pages = append(pages, page)
+	}
+
+	return pages
+}
@@ -0,0 +1,163 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func TestNextAt(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mode   ds.DeliveryMode
+		hour   int
+		sentAt time.Time
+		loc    *time.Location
+		expect time.Time
+	}{
+		{
+			name:   "hourly",
+			mode:   ds.DeliveryModeHourly,
+			sentAt: time.Date(2024, 12, 1, 10, 20, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "hourly sent at the start of the hour",
+			mode:   ds.DeliveryModeHourly,
+			sentAt: time.Date(2024, 12, 1, 11, 0, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily later today",
+			mode:   ds.DeliveryModeDaily,
+			hour:   18,
+			sentAt: time.Date(2024, 12, 1, 10, 20, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily tomorrow",
+			mode:   ds.DeliveryModeDaily,
+			hour:   9,
+			sentAt: time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily next month",
+			mode:   ds.DeliveryModeDaily,
+			hour:   0,
+			sentAt: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC),
+			expect: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily in another zone",
+			mode:   ds.DeliveryModeDaily,
+			hour:   9,
+			sentAt: time.Date(2024, 12, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600)),
+			expect: time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily in the time zone of the user",
+			mode:   ds.DeliveryModeDaily,
+			hour:   9,
+			sentAt: time.Date(2024, 12, 1, 7, 30, 0, 0, time.UTC),
+			loc:    time.FixedZone("CET", 3600),
+			expect: time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily in the time zone of the user tomorrow",
+			mode:   ds.DeliveryModeDaily,
+			hour:   9,
+			sentAt: time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC),
+			loc:    time.FixedZone("CET", 3600),
+			expect: time.Date(2024, 12, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "instant",
+			mode:   ds.DeliveryModeInstant,
+			sentAt: time.Date(2024, 12, 1, 10, 20, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 1, 10, 20, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc := tc.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+
+			assert.Equal(t, tc.expect, NextAt(tc.mode, tc.hour, tc.sentAt, loc))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "instant", Format(ds.DeliveryModeInstant, 9))
+	assert.Equal(t, "instant", Format("", 9))
+	assert.Equal(t, "hourly digest", Format(ds.DeliveryModeHourly, 9))
+	assert.Equal(t, "daily digest at 09:00", Format(ds.DeliveryModeDaily, 9))
+}
+
+func TestPaginate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		items  []string
+		limit  int
+		expect [][]string
+	}{
+		{
+			name:   "no items",
+			items:  nil,
+			limit:  10,
+			expect: nil,
+		},
+		{
+			name:   "one page",
+			items:  []string{"aaa", "bbb"},
+			limit:  7,
+			expect: [][]string{{"aaa", "bbb"}},
+		},
+		{
+			name:   "split by length",
+			items:  []string{"aaa", "bbb", "ccc"},
+			limit:  6,
+			expect: [][]string{{"aaa"}, {"bbb"}, {"ccc"}},
+		},
+		{
+			name:   "emoji are counted in UTF-16",
+			items:  []string{"🚗🚗", "🚗"},
+			limit:  6,
+			expect: [][]string{{"🚗🚗"}, {"🚗"}},
+		},
+		{
+			name:   "item over the limit",
+			items:  []string{"a", strings.Repeat("b", 20), "c"},
+			limit:  10,
+			expect: [][]string{{"a"}, {strings.Repeat("b", 20)}, {"c"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Paginate(tc.items, tc.limit))
+		})
+	}
+}
+
+func TestPaginate_MaxPageItems(t *testing.T) {
+	items := make([]string, MaxPageItems*2+1)
+	for i := range items {
+		items[i] = "a"
+	}
+
+	pages := Paginate(items, MaxMessageLength)
+	require.Len(t, pages, 3)
+	assert.Len(t, pages[0], MaxPageItems)
+	assert.Len(t, pages[1], MaxPageItems)
+	assert.Len(t, pages[2], 1)
+}
@@ -0,0 +1,24 @@
+package ds
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type (
+	ConversationStateRequest struct {
+		UserID    int64     `json:"user_id"`
+		State     []byte    `json:"state"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	ConversationStateResponse struct {
+		UserID    int64     `json:"user_id"`
+		State     []byte    `json:"state"`
+		ExpiresAt time.Time `json:"expires_at"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
 )
 
 type (
@@ -21,65 +22,317 @@ type (
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 	}
+	UpdateUserSettingsRequest struct {
+		UserID         int64    `json:"user_id"`
+		TimeZone       string   `json:"time_zone"`
+		QuietHoursFrom null.Int `json:"quiet_hours_from"`
+		QuietHoursTo   null.Int `json:"quiet_hours_to"`
+		MuteWeekends   bool     `json:"mute_weekends"`
+	}
+	UserSettingsResponse struct {
+		UserID         int64    `json:"user_id"`
+		TimeZone       string   `json:"time_zone"`
+		QuietHoursFrom null.Int `json:"quiet_hours_from"`
+		QuietHoursTo   null.Int `json:"quiet_hours_to"`
+		MuteWeekends   bool     `json:"mute_weekends"`
+	}
 	SubscriptionRequest struct {
-		UserID    int64    `json:"user_id"`
-		Brand     string   `json:"brand"`
-		Model     []string `json:"model"`
-		Chassis   []string `json:"chassis"`
-		PriceFrom string   `json:"price_from"`
-		PriceTo   string   `json:"price_to"`
-		YearFrom  string   `json:"year_from"`
-		YearTo    string   `json:"year_to"`
-		Region    []string `json:"region"`
+		UserID          int64             `json:"user_id"`
+		Brand           string            `json:"brand"`
+		Model           []string          `json:"model"`
+		Chassis         []string          `json:"chassis"`
+		PriceFrom       string            `json:"price_from"`
+		PriceTo         string            `json:"price_to"`
+		YearFrom        string            `json:"year_from"`
+		YearTo          string            `json:"year_to"`
+		Region          []string          `json:"region"`
+		MinDealPercent  null.Int          `json:"min_deal_percent"`
+		IncludeKeywords []string          `json:"include_keywords"`
+		ExcludeKeywords []string          `json:"exclude_keywords"`
+		Fuel            []string          `json:"fuel"`
+		Gearbox         []string          `json:"gearbox"`
+		MileageTo       string            `json:"mileage_to"`
+		PowerFrom       string            `json:"power_from"`
+		PowerTo         string            `json:"power_to"`
+		Doors           []string          `json:"doors"`
+		Seats           []string          `json:"seats"`
+		Color           []string          `json:"color"`
+		AirCondition    []string          `json:"air_condition"`
+		Damage          []string          `json:"damage"`
+		ExtraParams     map[string]string `json:"extra_params"`
+	}
+
+	UpdateSubscriptionRequest struct {
+		ID              string            `json:"id"`
+		UserID          int64             `json:"user_id"`
+		Model           []string          `json:"model"`
+		Chassis         []string          `json:"chassis"`
+		PriceFrom       string            `json:"price_from"`
+		PriceTo         string            `json:"price_to"`
+		YearFrom        string            `json:"year_from"`
+		YearTo          string            `json:"year_to"`
+		Region          []string          `json:"region"`
+		MinDealPercent  null.Int          `json:"min_deal_percent"`
+		IncludeKeywords []string          `json:"include_keywords"`
+		ExcludeKeywords []string          `json:"exclude_keywords"`
+		Fuel            []string          `json:"fuel"`
+		Gearbox         []string          `json:"gearbox"`
+		MileageTo       string            `json:"mileage_to"`
+		PowerFrom       string            `json:"power_from"`
+		PowerTo         string            `json:"power_to"`
+		Doors           []string          `json:"doors"`
+		Seats           []string          `json:"seats"`
+		Color           []string          `json:"color"`
+		AirCondition    []string          `json:"air_condition"`
+		Damage          []string          `json:"damage"`
+		ExtraParams     map[string]string `json:"extra_params"`
+		DeliveryMode    DeliveryMode      `json:"delivery_mode"`
+		DigestHour      int               `json:"digest_hour"`
 	}
 
 	SubscriptionResponse struct {
-		ID        string    `json:"id"`
-		UserID    int64     `json:"user_id"`
-		Brand     string    `json:"brand"`
-		Model     []string  `json:"model"`
-		Chassis   []string  `json:"chassis"`
-		PriceFrom string    `json:"price_from"`
-		PriceTo   string    `json:"price_to"`
-		YearFrom  string    `json:"year_from"`
-		YearTo    string    `json:"year_to"`
-		Region    []string  `json:"region"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
+		ID              string            `json:"id"`
+		UserID          int64             `json:"user_id"`
+		Brand           string            `json:"brand"`
+		Model           []string          `json:"model"`
+		Chassis         []string          `json:"chassis"`
+		PriceFrom       string            `json:"price_from"`
+		PriceTo         string            `json:"price_to"`
+		YearFrom        string            `json:"year_from"`
+		YearTo          string            `json:"year_to"`
+		Region          []string          `json:"region"`
+		MinDealPercent  null.Int          `json:"min_deal_percent"`
+		IncludeKeywords []string          `json:"include_keywords"`
+		ExcludeKeywords []string          `json:"exclude_keywords"`
+		Fuel            []string          `json:"fuel"`
+		Gearbox         []string          `json:"gearbox"`
+		MileageTo       string            `json:"mileage_to"`
+		PowerFrom       string            `json:"power_from"`
+		PowerTo         string            `json:"power_to"`
+		Doors           []string          `json:"doors"`
+		Seats           []string          `json:"seats"`
+		Color           []string          `json:"color"`
+		AirCondition    []string          `json:"air_condition"`
+		Damage          []string          `json:"damage"`
+		ExtraParams     map[string]string `json:"extra_params"`
+		MutedUntil      null.Time         `json:"muted_until"`
+		PriceOnly       bool              `json:"price_only"`
+		DeliveryMode    DeliveryMode      `json:"delivery_mode"`
+		DigestHour      int               `json:"digest_hour"`
+		DigestSentAt    null.Time         `json:"digest_sent_at"`
+		CreatedAt       time.Time         `json:"created_at"`
+		UpdatedAt       time.Time         `json:"updated_at"`
 	}
 
-	UpsertListingRequest struct {
-		ListingID      string      `json:"listing_id"`
-		SubscriptionID string      `json:"subscription_id"`
-		Title          string      `json:"title"`
-		Price          string      `json:"price"`
-		NewPrice       null.String `json:"new_price"`
-		EngineVolume   string      `json:"engine_volume"`
-		Transmission   string      `json:"transmission"`
-		BodyType       string      `json:"body_type"`
-		Mileage        string      `json:"mileage"`
-		Location       string      `json:"location"`
-		Link           string      `json:"link"`
-		Date           time.Time   `json:"date"`
-		IsNeedSend     bool        `json:"is_need_send"`
+	// DeliveryMode is how the listings of the subscription are sent: each of them at once or collected
+	// into a digest, which is sent every hour or every day at the digest hour.
+	DeliveryMode string
+
+	// SearchFilter is the name of a search filter of the site with a list of options, e.g. the fuel type.
+	SearchFilter string
+
+	UpsertCarRequest struct {
+		ListingID       string              `json:"listing_id"`
+		Title           string              `json:"title"`
+		Price           string              `json:"price"`
+		EngineVolume    string              `json:"engine_volume"`
+		Transmission    string              `json:"transmission"`
+		BodyType        string              `json:"body_type"`
+		Mileage         string              `json:"mileage"`
+		Location        string              `json:"location"`
+		Link            string              `json:"link"`
+		Date            time.Time           `json:"date"`
+		Details         ListingDetails      `json:"details"`
+		PriceEUR        decimal.NullDecimal `json:"price_eur"`
+		MileageKM       null.Int            `json:"mileage_km"`
+		EngineVolumeCM3 null.Int            `json:"engine_volume_cm3"`
+		Year            null.Int            `json:"year"`
+		Brand           string              `json:"brand"`
+		Model           string              `json:"model"`
+		ImageHash       null.Int            `json:"image_hash"`
+		ImageURL        string              `json:"image_url"`
+	}
+
+	UpsertSubscriptionMatchRequest struct {
+		ListingID      string              `json:"listing_id"`
+		SubscriptionID string              `json:"subscription_id"`
+		Price          string              `json:"price"`
+		NewPrice       null.String         `json:"new_price"`
+		PriceEUR       decimal.NullDecimal `json:"price_eur"`
+		NewPriceEUR    decimal.NullDecimal `json:"new_price_eur"`
+		IsNeedSend     bool                `json:"is_need_send"`
+		RelistedFrom   null.String         `json:"relisted_from"`
+		RelistedPrice  null.String         `json:"relisted_price"`
 	}
 
 	ListingResponse struct {
-		ID             string      `json:"id"`
-		ListingID      string      `json:"listing_id"`
-		SubscriptionID string      `json:"subscription_id"`
-		Title          string      `json:"title"`
-		Price          string      `json:"price"`
-		NewPrice       null.String `json:"new_price"`
-		EngineVolume   string      `json:"engine_volume"`
-		Transmission   string      `json:"transmission"`
-		BodyType       string      `json:"body_type"`
-		Mileage        string      `json:"mileage"`
-		Location       string      `json:"location"`
-		Link           string      `json:"link"`
-		Date           time.Time   `json:"date"`
-		IsNeedSend     bool        `json:"is_need_send"`
-		CreatedAt      time.Time   `json:"created_at"`
-		UpdatedAt      time.Time   `json:"updated_at"`
+		ID              string              `json:"id"`
+		ListingID       string              `json:"listing_id"`
+		SubscriptionID  string              `json:"subscription_id"`
+		Title           string              `json:"title"`
+		Price           string              `json:"price"`
+		NewPrice        null.String         `json:"new_price"`
+		EngineVolume    string              `json:"engine_volume"`
+		Transmission    string              `json:"transmission"`
+		BodyType        string              `json:"body_type"`
+		Mileage         string              `json:"mileage"`
+		Location        string              `json:"location"`
+		Link            string              `json:"link"`
+		Date            time.Time           `json:"date"`
+		IsNeedSend      bool                `json:"is_need_send"`
+		Details         ListingDetails      `json:"details"`
+		PriceEUR        decimal.NullDecimal `json:"price_eur"`
+		NewPriceEUR     decimal.NullDecimal `json:"new_price_eur"`
+		MileageKM       null.Int            `json:"mileage_km"`
+		EngineVolumeCM3 null.Int            `json:"engine_volume_cm3"`
+		Year            null.Int            `json:"year"`
+		Brand           string              `json:"brand"`
+		Model           string              `json:"model"`
+		FirstSeenAt     time.Time           `json:"first_seen_at"`
+		RemovedAt       null.Time           `json:"removed_at"`
+		LastSeenAt      time.Time           `json:"last_seen_at"`
+		ImageHash       null.Int            `json:"image_hash"`
+		ImageURL        string              `json:"image_url"`
+		RelistedFrom    null.String         `json:"relisted_from"`
+		RelistedPrice   null.String         `json:"relisted_price"`
+		CreatedAt       time.Time           `json:"created_at"`
+		UpdatedAt       time.Time           `json:"updated_at"`
+	}
+
+	ListingPriceRequest struct {
+		ListingID   string              `json:"listing_id"`
+		Price       string              `json:"price"`
+		PriceEUR    decimal.NullDecimal `json:"price_eur"`
+		ObservedAt  time.Time           `json:"observed_at"`
+		StaleBefore time.Time           `json:"stale_before"`
+	}
+
+	ListingPriceResponse struct {
+		ID         string              `json:"id"`
+		ListingID  string              `json:"listing_id"`
+		Price      string              `json:"price"`
+		PriceEUR   decimal.NullDecimal `json:"price_eur"`
+		ObservedAt time.Time           `json:"observed_at"`
+	}
+
+	MedianPriceResponse struct {
+		Day      time.Time       `json:"day"`
+		PriceEUR decimal.Decimal `json:"price_eur"`
+	}
+
+	MarketStatsRequest struct {
+		Brand       string `json:"brand"`
+		Model       string `json:"model"`
+		Year        int    `json:"year"`
+		MileageBand int    `json:"mileage_band"`
+	}
+
+	MarketStatsResponse struct {
+		Brand              string              `json:"brand"`
+		Model              string              `json:"model"`
+		Year               int                 `json:"year"`
+		MileageBand        int                 `json:"mileage_band"`
+		SampleSize         int                 `json:"sample_size"`
+		P25PriceEUR        decimal.Decimal     `json:"p25_price_eur"`
+		MedianPriceEUR     decimal.Decimal     `json:"median_price_eur"`
+		P75PriceEUR        decimal.Decimal     `json:"p75_price_eur"`
+		RemovedCount       int                 `json:"removed_count"`
+		MedianDaysOnMarket decimal.NullDecimal `json:"median_days_on_market"`
+		UpdatedAt          time.Time           `json:"updated_at"`
+	}
+
+	RefreshMarketStatsRequest struct {
+		MileageBandKM int       `json:"mileage_band_km"`
+		SeenAfter     time.Time `json:"seen_after"`
+	}
+
+	MarkCarsSeenRequest struct {
+		ListingIDs []string  `json:"listing_ids"`
+		SeenAt     time.Time `json:"seen_at"`
+	}
+
+	MarkCarsMissingRequest struct {
+		SubscriptionIDs []string `json:"subscription_ids"`
+		SeenListingIDs  []string `json:"seen_listing_ids"`
+	}
+
+	MissingCarResponse struct {
+		ListingID    string `json:"listing_id"`
+		Link         string `json:"link"`
+		MissingCount int    `json:"missing_count"`
+	}
+
+	MarkCarRemovedRequest struct {
+		ListingID string    `json:"listing_id"`
+		RemovedAt time.Time `json:"removed_at"`
+		Notify    bool      `json:"notify"`
+	}
+
+	WatchedCarResponse struct {
+		ListingID string `json:"listing_id"`
+		Link      string `json:"link"`
+	}
+
+	UpdateCarPriceRequest struct {
+		ListingID string              `json:"listing_id"`
+		Price     string              `json:"price"`
+		PriceEUR  decimal.NullDecimal `json:"price_eur"`
+		SeenAt    time.Time           `json:"seen_at"`
+	}
+
+	WatchlistItemResponse struct {
+		ListingID string              `json:"listing_id"`
+		Title     string              `json:"title"`
+		Price     string              `json:"price"`
+		PriceEUR  decimal.NullDecimal `json:"price_eur"`
+		Link      string              `json:"link"`
+		RemovedAt null.Time           `json:"removed_at"`
+		CreatedAt time.Time           `json:"created_at"`
+	}
+
+	ListingDetails struct {
+		FuelType        string   `json:"fuel_type"`
+		PowerKW         int      `json:"power_kw"`
+		PowerHP         int      `json:"power_hp"`
+		Color           string   `json:"color"`
+		Drive           string   `json:"drive"`
+		Doors           string   `json:"doors"`
+		RegisteredUntil string   `json:"registered_until"`
+		DamageStatus    string   `json:"damage_status"`
+		SellerType      string   `json:"seller_type"`
+		GalleryURLs     []string `json:"gallery_urls"`
 	}
 )
+
+// The search filters with the options are named the same as their query parameters of the site without "[]",
+// the options are stored by their names and mapped to the IDs of the site when searching.
+const (
+	SearchFilterFuel         = SearchFilter("fuel")
+	SearchFilterGearbox      = SearchFilter("gearbox")
+	SearchFilterDoors        = SearchFilter("door_num")
+	SearchFilterSeats        = SearchFilter("seat_num")
+	SearchFilterColor        = SearchFilter("color")
+	SearchFilterAirCondition = SearchFilter("air_condition")
+	SearchFilterDamage       = SearchFilter("damaged")
+)
+
+const (
+	DeliveryModeInstant = DeliveryMode("instant")
+	DeliveryModeHourly  = DeliveryMode("hourly")
+	DeliveryModeDaily   = DeliveryMode("daily")
+)
+
+// HoursInDay is the number of the hours in a day, the hour of the daily digest and the hours the quiet hours
+// start and end at are from 0 to 23.
+const HoursInDay = 24
+
+// SearchFilters lists the search filters with the options in the order they are shown to the user.
+var SearchFilters = []SearchFilter{ //nolint:gochecknoglobals,nolintlint
+	SearchFilterFuel,
+	SearchFilterGearbox,
+	SearchFilterDoors,
+	SearchFilterSeats,
+	SearchFilterColor,
+	SearchFilterAirCondition,
+	SearchFilterDamage,
+}
@@ -2,6 +2,9 @@ package ds
 
 import (
 	"time"
+
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
 )
 
 type (
@@ -21,10 +24,65 @@ type (
 		UpdatedAt time.Time          `json:"updated_at"`
 	}
 
+	OutboxNotificationResponse struct {
+		ID             string           `json:"id"`
+		ListingID      string           `json:"listing_id"`
+		SubscriptionID string           `json:"subscription_id"`
+		Kind           NotificationKind `json:"kind"`
+		Attempts       int              `json:"attempts"`
+		NextAttemptAt  time.Time        `json:"next_attempt_at"`
+		LastError      string           `json:"last_error"`
+		PhotosSent     bool             `json:"photos_sent"`
+		CreatedAt      time.Time        `json:"created_at"`
+		UpdatedAt      time.Time        `json:"updated_at"`
+	}
+
+	UpdateOutboxNotificationRequest struct {
+		ID            string    `json:"id"`
+		Attempts      int       `json:"attempts"`
+		NextAttemptAt time.Time `json:"next_attempt_at"`
+		LastError     string    `json:"last_error"`
+		PhotosSent    bool      `json:"photos_sent"`
+	}
+
+	// WatchlistChangeResponse is a car of the watchlist of the user whose price differs from the price
+	// the user knows or which was removed since the user was notified.
+	WatchlistChangeResponse struct {
+		UserID           int64               `json:"user_id"`
+		ListingID        string              `json:"listing_id"`
+		Title            string              `json:"title"`
+		Link             string              `json:"link"`
+		Price            string              `json:"price"`
+		PriceEUR         decimal.NullDecimal `json:"price_eur"`
+		NotifiedPrice    string              `json:"notified_price"`
+		NotifiedPriceEUR decimal.NullDecimal `json:"notified_price_eur"`
+		FirstSeenAt      time.Time           `json:"first_seen_at"`
+		RemovedAt        null.Time           `json:"removed_at"`
+	}
+
+	UpdateWatchlistNotifiedRequest struct {
+		UserID    int64               `json:"user_id"`
+		ListingID string              `json:"listing_id"`
+		Price     string              `json:"price"`
+		PriceEUR  decimal.NullDecimal `json:"price_eur"`
+		RemovedAt null.Time           `json:"removed_at"`
+	}
+
 	NotificationStatus string
+
+	NotificationKind string
 )
 
 const (
 	StatusSent   = NotificationStatus("SENT")
 	StatusFailed = NotificationStatus("FAILED")
+	// StatusDead is set when the notification will not be retried anymore.
+	StatusDead = NotificationStatus("DEAD")
+)
+
+const (
+	// NotificationKindListing is the notification about a new listing or a changed price.
+	NotificationKindListing = NotificationKind("LISTING")
+	// NotificationKindRemoved is the notification about a sent listing which was removed from the site.
+	NotificationKindRemoved = NotificationKind("REMOVED")
 )
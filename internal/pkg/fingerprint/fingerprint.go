@@ -0,0 +1,117 @@
+package fingerprint
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/keywords"
+	"github.com/gudimz/polovni-auto-alert/pkg/imagehash"
+)
+
+const (
+	// WindowDays is the number of days a listing is compared with the new listings after it was last seen.
+	WindowDays = 30
+	// MaxMileageDiffKM is the difference of the mileage which is still the same car, e.g. it was driven or rounded.
+	MaxMileageDiffKM = 2000
+	// NewCarMileageKM is the mileage below which a dealer often sells several identical cars,
+	// so such cars are only matched by the photo.
+	NewCarMileageKM = 1000
+	// MaxImageDistance is the maximum number of the different bits of the photo hashes of the same photo.
+	MaxImageDistance = 10
+)
+
+// Fingerprint holds the attributes of a car which don't change when the car is relisted under a new listing ID.
+type Fingerprint struct {
+	Title           string
+	Year            null.Int
+	MileageKM       null.Int
+	EngineVolumeCM3 null.Int
+	Location        string
+	ImageHash       null.Int
+}
+
+// FromListing returns the fingerprint of the stored listing.
+func FromListing(listing ds.ListingResponse) Fingerprint {
+	return Fingerprint{
+		Title:           listing.Title,
+		Year:            listing.Year,
+		MileageKM:       listing.MileageKM,
+		EngineVolumeCM3: listing.EngineVolumeCM3,
+		Location:        listing.Location,
+		ImageHash:       listing.ImageHash,
+	}
+}
+
+// Matches checks if the fingerprints probably belong to the same car.
+// The year, the location and the mileage must match and the engine volume too if both are known.
+// If both photos are known, they decide, otherwise the titles must match,
+// which is not enough for the new cars, as the dealers list identical cars.
+func (f Fingerprint) Matches(other Fingerprint) bool {
+	if !f.Year.Valid || !other.Year.Valid || f.Year.Int64 != other.Year.Int64 {
+		return false
+	}
+
+	if f.EngineVolumeCM3.Valid && other.EngineVolumeCM3.Valid && f.EngineVolumeCM3.Int64 != other.EngineVolumeCM3.Int64 {
+		return false
+	}
+
+	if location := keywords.Normalize(f.Location); location == "" || location != keywords.Normalize(other.Location) {
+		return false
+	}
+
+	if !f.MileageKM.Valid || !other.MileageKM.Valid || abs(f.MileageKM.Int64-other.MileageKM.Int64) > MaxMileageDiffKM {
+		return false
+	}
+
+	if f.ImageHash.Valid && other.ImageHash.Valid {
+		distance := imagehash.Distance(uint64(f.ImageHash.Int64), uint64(other.ImageHash.Int64)) //nolint:gosec,nolintlint
+		return distance <= MaxImageDistance
+	}
+
+	if min(f.MileageKM.Int64, other.MileageKM.Int64) < NewCarMileageKM {
+		return false
+	}
+
+	title := keywords.Normalize(f.Title)
+
+	return title != "" && title == keywords.Normalize(other.Title)
+}
+
+// FindRelist returns the listing the new listing is a probable relist of, the most recently seen one if there are
+// several. Only the other listings seen in the last WindowDays are compared, ok is false if there is no such listing.
+func FindRelist(
+	f Fingerprint, listingID string, listings []ds.ListingResponse, now time.Time,
+) (ds.ListingResponse, bool) {
+	var (
+		relist ds.ListingResponse
+		found  bool
+	)
+
+	seenAfter := now.AddDate(0, 0, -WindowDays)
+
+	for _, listing := range listings {
+		if listing.ListingID == listingID || listing.LastSeenAt.Before(seenAfter) {
+			continue
+		}
+
+		if found && !listing.LastSeenAt.After(relist.LastSeenAt) {
+			continue
+		}
+
+		if f.Matches(FromListing(listing)) {
+			relist, found = listing, true
+		}
+	}
+
+	return relist, found
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
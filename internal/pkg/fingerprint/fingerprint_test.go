@@ -0,0 +1,249 @@
+package fingerprint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+// golf is the fingerprint of the previous listing the new listings are compared with.
+func golf() Fingerprint {
+	return Fingerprint{
+		Title:           "Volkswagen Golf 7 1.6 TDI",
+		Year:            null.IntFrom(2016),
+		MileageKM:       null.IntFrom(182000),
+		EngineVolumeCM3: null.IntFrom(1598),
+		Location:        "Novi Sad",
+		ImageHash:       null.NewInt(0, false),
+	}
+}
+
+func TestFingerprint_Matches(t *testing.T) {
+	const photo = int64(0x0f0f_3c3c_f0f0_aaaa)
+
+	testCases := []struct {
+		name   string
+		prev   func(f *Fingerprint)
+		next   func(f *Fingerprint)
+		expect bool
+	}{
+		{
+			name:   "same car",
+			expect: true,
+		},
+		{
+			name: "title written differently",
+			next: func(f *Fingerprint) {
+				f.Title = "VOLKSWAGEN golf 7  1.6 tdi!"
+			},
+			expect: true,
+		},
+		{
+			name: "title without diacritics",
+			prev: func(f *Fingerprint) {
+				f.Title = "Škoda Octavia 1.6 TDI"
+			},
+			next: func(f *Fingerprint) {
+				f.Title = "Skoda Octavia 1.6 TDI"
+			},
+			expect: true,
+		},
+		{
+			name: "driven since the previous listing",
+			next: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(183500)
+			},
+			expect: true,
+		},
+		{
+			name: "mileage too different",
+			next: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(190000)
+			},
+			expect: false,
+		},
+		{
+			name: "unknown mileage",
+			next: func(f *Fingerprint) {
+				f.MileageKM = null.NewInt(0, false)
+			},
+			expect: false,
+		},
+		{
+			name: "different year",
+			next: func(f *Fingerprint) {
+				f.Year = null.IntFrom(2017)
+			},
+			expect: false,
+		},
+		{
+			name: "different engine",
+			next: func(f *Fingerprint) {
+				f.EngineVolumeCM3 = null.IntFrom(1968)
+			},
+			expect: false,
+		},
+		{
+			name: "unknown engine",
+			next: func(f *Fingerprint) {
+				f.EngineVolumeCM3 = null.NewInt(0, false)
+			},
+			expect: true,
+		},
+		{
+			name: "different location",
+			next: func(f *Fingerprint) {
+				f.Location = "Beograd"
+			},
+			expect: false,
+		},
+		{
+			name: "different title",
+			next: func(f *Fingerprint) {
+				f.Title = "Volkswagen Golf 7 1.6 TDI Highline"
+			},
+			expect: false,
+		},
+		{
+			name: "different title, same photo",
+			prev: func(f *Fingerprint) {
+				f.ImageHash = null.IntFrom(photo)
+			},
+			next: func(f *Fingerprint) {
+				f.Title = "Volkswagen Golf 7 1.6 TDI Highline"
+				f.ImageHash = null.IntFrom(photo ^ 0b1011)
+			},
+			expect: true,
+		},
+		{
+			name: "same title, different photo",
+			prev: func(f *Fingerprint) {
+				f.ImageHash = null.IntFrom(photo)
+			},
+			next: func(f *Fingerprint) {
+				f.ImageHash = null.IntFrom(^photo)
+			},
+			expect: false,
+		},
+		{
+			name: "photo of one listing only",
+			next: func(f *Fingerprint) {
+				f.ImageHash = null.IntFrom(photo)
+			},
+			expect: true,
+		},
+		{
+			name: "new cars of a dealer",
+			prev: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(10)
+			},
+			next: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(5)
+			},
+			expect: false,
+		},
+		{
+			name: "new car with the same photo",
+			prev: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(10)
+				f.ImageHash = null.IntFrom(photo)
+			},
+			next: func(f *Fingerprint) {
+				f.MileageKM = null.IntFrom(10)
+				f.ImageHash = null.IntFrom(photo)
+			},
+			expect: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prev, next := golf(), golf()
+
+			if tc.prev != nil {
+				tc.prev(&prev)
+			}
+
+			if tc.next != nil {
+				tc.next(&next)
+			}
+
+			assert.Equal(t, tc.expect, next.Matches(prev))
+			assert.Equal(t, tc.expect, prev.Matches(next))
+		})
+	}
+}
+
+func TestFindRelist(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+
+	listing := func(listingID, title string, lastSeenAt time.Time) ds.ListingResponse {
+		return ds.ListingResponse{ //nolint:exhaustruct,nolintlint
+			ListingID:       listingID,
+			Title:           title,
+			Year:            null.IntFrom(2016),
+			MileageKM:       null.IntFrom(182000),
+			EngineVolumeCM3: null.IntFrom(1598),
+			Location:        "Novi Sad",
+			LastSeenAt:      lastSeenAt,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		listings []ds.ListingResponse
+		expectID string
+		ok       bool
+	}{
+		{
+			name: "relist",
+			listings: []ds.ListingResponse{
+				listing("1", "Audi A4 2.0 TDI", now.Add(-time.Hour)),
+				listing("2", "Volkswagen Golf 7 1.6 TDI", now.AddDate(0, 0, -3)),
+			},
+			expectID: "2",
+			ok:       true,
+		},
+		{
+			name: "most recently seen relist",
+			listings: []ds.ListingResponse{
+				listing("1", "Volkswagen Golf 7 1.6 TDI", now.AddDate(0, 0, -20)),
+				listing("2", "Volkswagen Golf 7 1.6 TDI", now.AddDate(0, 0, -2)),
+				listing("3", "Volkswagen Golf 7 1.6 TDI", now.AddDate(0, 0, -10)),
+			},
+			expectID: "2",
+			ok:       true,
+		},
+		{
+			name: "seen too long ago",
+			listings: []ds.ListingResponse{
+				listing("1", "Volkswagen Golf 7 1.6 TDI", now.AddDate(0, 0, -WindowDays-1)),
+			},
+			ok: false,
+		},
+		{
+			name: "same listing",
+			listings: []ds.ListingResponse{
+				listing("100", "Volkswagen Golf 7 1.6 TDI", now),
+			},
+			ok: false,
+		},
+		{
+			name:     "no listings",
+			listings: nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			relist, ok := FindRelist(golf(), "100", tc.listings, now)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expectID, relist.ListingID)
+		})
+	}
+}
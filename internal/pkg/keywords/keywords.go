@@ -0,0 +1,51 @@
+package keywords
+
+import (
+	"strings"
+	"unicode"
+)
+
+// transliteration maps the Serbian cyrillic letters and the latin letters with diacritics to plain latin,
+// so "Оштећен", "oštećen" and "ostecen" are the same word.
+var transliteration = strings.NewReplacer(
+	"а", "a", "б", "b", "в", "v", "г", "g", "д", "d", "ђ", "dj", "е", "e", "ж", "z", "з", "z", "и", "i",
+	"ј", "j", "к", "k", "л", "l", "љ", "lj", "м", "m", "н", "n", "њ", "nj", "о", "o", "п", "p", "р", "r",
+	"с", "s", "т", "t", "ћ", "c", "у", "u", "ф", "f", "х", "h", "ц", "c", "ч", "c", "џ", "dz", "ш", "s",
+	"č", "c", "ć", "c", "đ", "dj", "š", "s", "ž", "z",
+)
+
+// Normalize lowercases the text, transliterates it to plain latin and keeps only the words of letters and digits
+// separated by a single space, e.g. "BMW 320d, Оштећен!" becomes "bmw 320d ostecen".
+func Normalize(text string) string {
+	words := strings.FieldsFunc(transliteration.Replace(strings.ToLower(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(words, " ")
+}
+
+// Match checks if the text contains all the included keywords and none of the excluded ones.
+// A keyword matches the start of a word, so "ostecen" matches "oštećena", but not "neoštećen".
+// The keywords may be phrases, e.g. "uvoz u toku", the case and the alphabet don't matter.
+func Match(text string, include, exclude []string) bool {
+	normalized := " " + Normalize(text)
+
+	contains := func(keyword string) bool {
+		keyword = Normalize(keyword)
+		return keyword != "" && strings.Contains(normalized, " "+keyword)
+	}
+
+	for _, keyword := range include {
+		if !contains(keyword) {
+			return false
+		}
+	}
+
+	for _, keyword := range exclude {
+		if contains(keyword) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,57 @@
+package keywords
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name   string
+		text   string
+		expect string
+	}{
+		{name: "punctuation and case", text: "BMW 320d, M-Paket!", expect: "bmw 320d m paket"},
+		{name: "latin diacritics", text: "Oštećen, uvoz u toku", expect: "ostecen uvoz u toku"},
+		{name: "cyrillic", text: "Оштећен ЏИП Љубичасти", expect: "ostecen dzip ljubicasti"},
+		{name: "spaces", text: "  xDrive   M  Sport ", expect: "xdrive m sport"},
+		{name: "empty", text: " - ", expect: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Normalize(tc.text))
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		text    string
+		include []string
+		exclude []string
+		expect  bool
+	}{
+		{name: "no keywords", text: "BMW X3 xDrive20d", expect: true},
+		{name: "included", text: "BMW X3 xDrive20d", include: []string{"XDRIVE"}, expect: true},
+		{name: "not included", text: "BMW X3 sDrive18d", include: []string{"xdrive"}, expect: false},
+		{name: "all included", text: "BMW X3 xDrive20d M Sport", include: []string{"xdrive", "m sport"}, expect: true},
+		{name: "one not included", text: "BMW X3 xDrive20d", include: []string{"xdrive", "m sport"}, expect: false},
+		{name: "excluded", text: "Golf 7 oštećen", exclude: []string{"ostecen"}, expect: false},
+		{name: "excluded word form", text: "Golf 7 oštećena hauba", exclude: []string{"Oštećen"}, expect: false},
+		{name: "excluded cyrillic", text: "Голф 7 ОШТЕЋЕН", exclude: []string{"oštećen"}, expect: false},
+		{name: "excluded phrase", text: "Audi A4 - uvoz u toku", exclude: []string{"uvoz u toku"}, expect: false},
+		{name: "not a word start", text: "Golf 7 neoštećen", exclude: []string{"oštećen"}, expect: true},
+		{name: "included and excluded", text: "BMW X3 xDrive delovi", include: []string{"xdrive"},
+			exclude: []string{"delovi"}, expect: false},
+		{name: "empty keyword", text: "BMW X3", include: []string{" - "}, expect: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Match(tc.text, tc.include, tc.exclude))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package listingaction
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Action is what the user does with the car of a listing notification by tapping its button.
+type Action string
+
+// Data is the callback data of a button of a listing notification.
+type Data struct {
+	Action         Action
+	ListingID      string
+	SubscriptionID string
+}
+
+const (
+	// Save adds the car to the watchlist of the user.
+	Save Action = "s"
+	// Hide stops the notifications about the car.
+	Hide Action = "h"
+	// Mute stops the notifications of the subscription for MuteDuration.
+	Mute Action = "m"
+	// PriceOnly switches the subscription to the notifications about the price changes only.
+	PriceOnly Action = "p"
+	// AllListings switches the subscription back to the notifications about all the listings.
+	AllListings Action = "a"
+)
+
+const (
+	// prefix starts the callback data of the buttons, the version of the format follows it,
+	// so the buttons of the notifications sent before the format changed are still recognized.
+	prefix  = "la"
+	version = "1"
+	// separator is not used in the other callback data of the bot, e.g. in the names of the brands.
+	separator = "|"
+
+	// MaxDataLength is the limit of Telegram for the callback data of a button in bytes.
+	MaxDataLength = 64
+
+	// MuteDuration is how long the subscription is muted for.
+	MuteDuration = 24 * time.Hour
+)
+
+var (
+	ErrInvalidData        = errors.New("invalid listing action data")
+	ErrUnsupportedVersion = errors.New("unsupported listing action version")
+)
+
+// Encode encodes the data of the button as "la1|<action>|<listing ID>|<subscription ID>",
+// the subscription UUID is shortened to 22 characters, so the data fits the limit of Telegram.
+func Encode(data Data) (string, error) {
+	if !isAction(data.Action) || data.ListingID == "" || strings.Contains(data.ListingID, separator) {
+		return "", errors.Wrapf(ErrInvalidData, "action %q, listing %q", data.Action, data.ListingID)
+	}
+
+	id, err := uuid.Parse(data.SubscriptionID)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidData, "subscription %q", data.SubscriptionID)
+	}
+
+	encoded := strings.Join([]string{
+		prefix + version, string(data.Action), data.ListingID, base64.RawURLEncoding.EncodeToString(id[:]),
+	}, separator)
+
+	if len(encoded) > MaxDataLength {
+		return "", errors.Wrapf(ErrInvalidData, "%d bytes are over the limit", len(encoded))
+	}
+
+	return encoded, nil
+}
+
+// Decode decodes the callback data of the button, see Encode.
+// ErrUnsupportedVersion is returned for the data of another version of the format.
+func Decode(encoded string) (Data, error) {
+	if !Match(encoded) {
+		return Data{}, ErrInvalidData
+	}
+
+	parts := strings.Split(encoded, separator)
+	if parts[0] != prefix+version {
+		return Data{}, errors.Wrap(ErrUnsupportedVersion, parts[0])
+	}
+
+	if len(parts) != 4 { //nolint:mnd,nolintlint
+		return Data{}, ErrInvalidData
+	}
+
+	action, listingID := Action(parts[1]), parts[2]
+	if !isAction(action) || listingID == "" {
+		return Data{}, ErrInvalidData
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return Data{}, errors.Wrap(ErrInvalidData, err.Error())
+	}
+
+	id, err := uuid.FromBytes(raw)
+	if err != nil {
+		return Data{}, errors.Wrap(ErrInvalidData, err.Error())
+	}
+
+	return Data{
+		Action:         action,
+		ListingID:      listingID,
+		SubscriptionID: id.String(),
+	}, nil
+}
+
+// Match checks if the callback data belongs to a button of a listing notification of any version.
+func Match(encoded string) bool {
+	head, _, found := strings.Cut(encoded, separator)
+	return found && strings.HasPrefix(head, prefix)
+}
+
+func isAction(action Action) bool {
+	switch action {
+	case Save, Hide, Mute, PriceOnly, AllListings:
+		return true
+	}
+
+	return false
+}
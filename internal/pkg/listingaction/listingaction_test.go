@@ -0,0 +1,98 @@
+package listingaction
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   Data
+		expect string
+	}{
+		{
+			name:   "save",
+			data:   Data{Action: Save, ListingID: "25000001", SubscriptionID: "0f8fad5b-d9cb-469f-a165-70867728950e"},
+			expect: "la1|s|25000001|D4-tW9nLRp-hZXCGdyiVDg",
+		},
+		{
+			name:   "price only",
+			data:   Data{Action: PriceOnly, ListingID: "7", SubscriptionID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"},
+			expect: "la1|p|7|fJ5meXQlQN6US-B_wfkK5w",
+		},
+		{
+			name:   "all listings",
+			data:   Data{Action: AllListings, ListingID: "7", SubscriptionID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"},
+			expect: "la1|a|7|fJ5meXQlQN6US-B_wfkK5w",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := Encode(tc.data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, encoded)
+			assert.LessOrEqual(t, len(encoded), MaxDataLength)
+
+			decoded, err := Decode(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, tc.data, decoded)
+		})
+	}
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	subID := "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	testCases := []struct {
+		name string
+		data Data
+	}{
+		{name: "unknown action", data: Data{Action: "x", ListingID: "1", SubscriptionID: subID}},
+		{name: "no listing", data: Data{Action: Save, SubscriptionID: subID}},
+		{name: "separator in listing", data: Data{Action: Save, ListingID: "1|2", SubscriptionID: subID}},
+		{name: "invalid subscription", data: Data{Action: Save, ListingID: "1", SubscriptionID: "sub"}},
+		{name: "too long", data: Data{Action: Save, ListingID: strings.Repeat("1", 40), SubscriptionID: subID}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Encode(tc.data)
+			require.ErrorIs(t, err, ErrInvalidData)
+		})
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	testCases := []struct {
+		name      string
+		encoded   string
+		expectErr error
+	}{
+		{name: "other callback", encoded: "lada", expectErr: ErrInvalidData},
+		{name: "other version", encoded: "la2|s|1|D4-tW9nLRp-hZXCGdyiVDg|x", expectErr: ErrUnsupportedVersion},
+		{name: "missing part", encoded: "la1|s|1", expectErr: ErrInvalidData},
+		{name: "unknown action", encoded: "la1|x|1|D4-tW9nLRp-hZXCGdyiVDg", expectErr: ErrInvalidData},
+		{name: "no listing", encoded: "la1|s||D4-tW9nLRp-hZXCGdyiVDg", expectErr: ErrInvalidData},
+		{name: "invalid subscription", encoded: "la1|s|1|D4-tW9nL", expectErr: ErrInvalidData},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(tc.encoded)
+			require.ErrorIs(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("la1|s|1|D4-tW9nLRp-hZXCGdyiVDg"))
+	assert.True(t, Match("la2|anything"))
+	assert.False(t, Match("lada"))
+	assert.False(t, Match("land-rover"))
+	assert.False(t, Match("/subscribe"))
+}
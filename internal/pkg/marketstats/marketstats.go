@@ -0,0 +1,80 @@
+package marketstats
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+const (
+	// MileageBandKM is the width of the mileage bands the cars are grouped by, e.g. 0-49 999 km is the band 0.
+	MileageBandKM = 50000
+	// MinSampleSize is the minimum number of cars in a group for its market price to be trusted.
+	MinSampleSize = 5
+	// WindowDays is the number of days the cars are kept in the stats after they were last seen.
+	WindowDays = 90
+)
+
+var hundred = decimal.NewFromInt(100) //nolint:mnd,nolintlint
+
+// MileageBand returns the mileage band of the mileage in km.
+func MileageBand(mileageKM int) int {
+	return mileageKM / MileageBandKM
+}
+
+// Key returns the key of the market stats group of the listing,
+// ok is false if the brand, the model, the year or the mileage of the listing is unknown.
+func Key(listing ds.ListingResponse) (ds.MarketStatsRequest, bool) {
+	if listing.Brand == "" || listing.Model == "" || !listing.Year.Valid || !listing.MileageKM.Valid {
+		return ds.MarketStatsRequest{}, false
+	}
+
+	return ds.MarketStatsRequest{
+		Brand:       listing.Brand,
+		Model:       listing.Model,
+		Year:        int(listing.Year.Int64),
+		MileageBand: MileageBand(int(listing.MileageKM.Int64)),
+	}, true
+}
+
+// DealPercent returns how many percent the price is below the median market price, rounded to an integer.
+// The percent is negative if the price is above the market, ok is false if the median is not positive.
+func DealPercent(price, median decimal.Decimal) (int, bool) {
+	if !median.IsPositive() {
+		return 0, false
+	}
+
+	return int(median.Sub(price).Mul(hundred).Div(median).Round(0).IntPart()), true
+}
+
+// FormatDeal formats the deal percent, e.g. "18% below market".
+func FormatDeal(percent int) string {
+	switch {
+	case percent > 0:
+		return strconv.Itoa(percent) + "% below market"
+	case percent < 0:
+		return strconv.Itoa(-percent) + "% above market"
+	default:
+		return "at market price"
+	}
+}
+
+// DaysOnMarket returns the number of the whole days between the first time the car was seen and its removal.
+func DaysOnMarket(firstSeenAt, removedAt time.Time) int {
+	return max(int(removedAt.Sub(firstSeenAt).Hours()/ds.HoursInDay), 0)
+}
+
+// FormatDays formats the number of days on market, e.g. "12 days".
+func FormatDays(days int) string {
+	switch days {
+	case 0:
+		return "less than a day"
+	case 1:
+		return "1 day"
+	default:
+		return strconv.Itoa(days) + " days"
+	}
+}
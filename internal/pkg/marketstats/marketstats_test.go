@@ -0,0 +1,116 @@
+package marketstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func TestKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		listing ds.ListingResponse
+		expect  ds.MarketStatsRequest
+		ok      bool
+	}{
+		{
+			name: "known car",
+			listing: ds.ListingResponse{ //nolint:exhaustruct,nolintlint
+				Brand:     "bmw",
+				Model:     "320",
+				Year:      null.IntFrom(2015),
+				MileageKM: null.IntFrom(180000),
+			},
+			expect: ds.MarketStatsRequest{Brand: "bmw", Model: "320", Year: 2015, MileageBand: 3},
+			ok:     true,
+		},
+		{
+			name: "first band",
+			listing: ds.ListingResponse{ //nolint:exhaustruct,nolintlint
+				Brand:     "bmw",
+				Model:     "320",
+				Year:      null.IntFrom(2023),
+				MileageKM: null.IntFrom(49999),
+			},
+			expect: ds.MarketStatsRequest{Brand: "bmw", Model: "320", Year: 2023, MileageBand: 0},
+			ok:     true,
+		},
+		{
+			name: "unknown model",
+			listing: ds.ListingResponse{ //nolint:exhaustruct,nolintlint
+				Brand:     "bmw",
+				Year:      null.IntFrom(2015),
+				MileageKM: null.IntFrom(180000),
+			},
+			expect: ds.MarketStatsRequest{}, //nolint:exhaustruct,nolintlint
+			ok:     false,
+		},
+		{
+			name: "unknown mileage",
+			listing: ds.ListingResponse{ //nolint:exhaustruct,nolintlint
+				Brand: "bmw",
+				Model: "320",
+				Year:  null.IntFrom(2015),
+			},
+			expect: ds.MarketStatsRequest{}, //nolint:exhaustruct,nolintlint
+			ok:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, ok := Key(tc.listing)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expect, key)
+		})
+	}
+}
+
+func TestDealPercent(t *testing.T) {
+	testCases := []struct {
+		name   string
+		price  int64
+		median int64
+		expect int
+		ok     bool
+	}{
+		{name: "below market", price: 8200, median: 10000, expect: 18, ok: true},
+		{name: "above market", price: 10550, median: 10000, expect: -6, ok: true},
+		{name: "at market", price: 10000, median: 10000, expect: 0, ok: true},
+		{name: "rounded", price: 9333, median: 10000, expect: 7, ok: true},
+		{name: "zero median", price: 10000, median: 0, expect: 0, ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			percent, ok := DealPercent(decimal.NewFromInt(tc.price), decimal.NewFromInt(tc.median))
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expect, percent)
+		})
+	}
+}
+
+func TestFormatDeal(t *testing.T) {
+	assert.Equal(t, "18% below market", FormatDeal(18))
+	assert.Equal(t, "6% above market", FormatDeal(-6))
+	assert.Equal(t, "at market price", FormatDeal(0))
+}
+
+func TestDaysOnMarket(t *testing.T) {
+	firstSeenAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 12, DaysOnMarket(firstSeenAt, firstSeenAt.Add(12*24*time.Hour+5*time.Hour)))
+	assert.Equal(t, 0, DaysOnMarket(firstSeenAt, firstSeenAt.Add(23*time.Hour)))
+	assert.Equal(t, 0, DaysOnMarket(firstSeenAt, firstSeenAt.Add(-time.Hour)))
+}
+
+func TestFormatDays(t *testing.T) {
+	assert.Equal(t, "12 days", FormatDays(12))
+	assert.Equal(t, "1 day", FormatDays(1))
+	assert.Equal(t, "less than a day", FormatDays(0))
+}
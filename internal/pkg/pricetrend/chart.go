@@ -0,0 +1,62 @@
+package pricetrend
+
+import (
+	"bytes"
+	"image/color"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/chart"
+)
+
+var (
+	listingColor = color.RGBA{R: 33, G: 150, B: 243, A: 255}
+	medianColor  = color.RGBA{R: 255, G: 152, B: 0, A: 255}
+)
+
+// RenderChart renders the typed prices of the history as a PNG line chart with the market median if it is given.
+// The last price is extended to now, so the chart shows how long the price holds.
+// chart.ErrNoData is returned if there are no typed prices in the history.
+func RenderChart(history []ds.ListingPriceResponse, median []ds.MedianPriceResponse, now time.Time) ([]byte, error) {
+	listing := make([]chart.Point, 0, len(history)+1)
+
+	for _, point := range history {
+		if point.PriceEUR.Valid {
+			listing = append(listing, chart.Point{Time: point.ObservedAt, Value: point.PriceEUR.Decimal.InexactFloat64()})
+		}
+	}
+
+	if len(listing) == 0 {
+		return nil, chart.ErrNoData
+	}
+
+	if last := listing[len(listing)-1]; now.After(last.Time) {
+		listing = append(listing, chart.Point{Time: now, Value: last.Value})
+	}
+
+	series := []chart.Series{{Name: "Listing", Color: listingColor, Points: listing}}
+
+	if len(median) > 0 {
+		points := make([]chart.Point, 0, len(median))
+		for _, point := range median {
+			points = append(points, chart.Point{Time: point.Day, Value: point.PriceEUR.InexactFloat64()})
+		}
+
+		series = append(series, chart.Series{Name: "Market median", Color: medianColor, Points: points})
+	}
+
+	var buf bytes.Buffer
+
+	if err := chart.RenderPNG(&buf, chart.Config{ //nolint:exhaustruct,nolintlint
+		Title: "Price, EUR",
+		FormatValue: func(value float64) string {
+			return formatNumber(decimal.NewFromFloat(value))
+		},
+	}, series...); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package pricetrend
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+const (
+	// maxPoints is the max number of the latest prices shown in a trend.
+	maxPoints = 10
+	separator = " → "
+)
+
+// Format formats the price changes of the history as a trend, e.g. "12 500 → 11 900 → 11 200 € over 9 days".
+// The history is expected to be ordered by the observation time, an empty string is returned
+// if there is no price change in the history.
+func Format(history []ds.ListingPriceResponse) string {
+	changes := Changes(history)
+	if len(changes) < 2 { //nolint:mnd,nolintlint
+		return ""
+	}
+
+	points := changes
+	if len(points) > maxPoints {
+		points = points[len(points)-maxPoints:]
+	}
+
+	var sb strings.Builder
+
+	if len(points) < len(changes) {
+		sb.WriteString("…" + separator)
+	}
+
+	sb.WriteString(formatPrices(points))
+
+	if period := formatPeriod(changes[len(changes)-1].ObservedAt.Sub(changes[0].ObservedAt)); period != "" {
+		sb.WriteString(" over " + period)
+	}
+
+	return sb.String()
+}
+
+// Changes returns the observations of the history where the price changed, the repeated observations
+// of the same price are skipped. Typed prices are compared when both are known, otherwise the raw strings.
+func Changes(history []ds.ListingPriceResponse) []ds.ListingPriceResponse {
+	changes := make([]ds.ListingPriceResponse, 0, len(history))
+
+	for _, point := range history {
+		if len(changes) > 0 && isSamePrice(changes[len(changes)-1], point) {
+			continue
+		}
+
+		changes = append(changes, point)
+	}
+
+	return changes
+}
+
+// FormatEUR formats the price in EUR with a space as the thousands separator, e.g. "12 500 €".
+func FormatEUR(price decimal.Decimal) string {
+	return formatNumber(price) + " €"
+}
+
+// formatPrices joins the prices, the typed prices are used only if all of them are known,
+// otherwise it falls back to the raw price strings.
+func formatPrices(points []ds.ListingPriceResponse) string {
+	prices := make([]string, 0, len(points))
+
+	for _, point := range points {
+		if !point.PriceEUR.Valid {
+			prices = prices[:0]
+			break
+		}
+
+		prices = append(prices, formatNumber(point.PriceEUR.Decimal))
+	}
+
+	if len(prices) == len(points) {
+		return strings.Join(prices, separator) + " €"
+	}
+
+	for _, point := range points {
+		prices = append(prices, point.Price)
+	}
+
+	return strings.Join(prices, separator)
+}
+
+// formatPeriod formats the period in days, an empty string is returned for a period shorter than a day.
+func formatPeriod(period time.Duration) string {
+	days := int(period.Hours() / ds.HoursInDay)
+
+	switch {
+	case days < 1:
+		return ""
+	case days == 1:
+		return "1 day"
+	default:
+		return strconv.Itoa(days) + " days"
+	}
+}
+
+// isSamePrice checks if both observations have the same price.
+func isSamePrice(a, b ds.ListingPriceResponse) bool {
+	if a.PriceEUR.Valid && b.PriceEUR.Valid {
+		return a.PriceEUR.Decimal.Equal(b.PriceEUR.Decimal)
+	}
+
+	return a.Price == b.Price
+}
+
+// formatNumber rounds the price to an integer and separates its thousands with spaces.
+func formatNumber(price decimal.Decimal) string {
+	return groupThousands(price.Round(0).StringFixed(0))
+}
+
+// groupThousands separates the thousands of an integer number with spaces.
+func groupThousands(number string) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	var sb strings.Builder
+
+	for i, digit := range number {
+		if i > 0 && (len(number)-i)%3 == 0 {
+			sb.WriteByte(' ')
+		}
+
+		sb.WriteRune(digit)
+	}
+
+	return sign + sb.String()
+}
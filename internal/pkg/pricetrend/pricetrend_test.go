@@ -0,0 +1,190 @@
+package pricetrend
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/chart"
+)
+
+func TestFormat(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	price := func(raw string, eur int64, daysAgo int) ds.ListingPriceResponse {
+		priceEUR := decimal.NullDecimal{Decimal: decimal.NewFromInt(eur), Valid: eur != 0}
+
+		return ds.ListingPriceResponse{ //nolint:exhaustruct,nolintlint
+			Price:      raw,
+			PriceEUR:   priceEUR,
+			ObservedAt: now.AddDate(0, 0, -daysAgo),
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		history []ds.ListingPriceResponse
+		expect  string
+	}{
+		{
+			name:    "empty history",
+			history: nil,
+			expect:  "",
+		},
+		{
+			name:    "single price",
+			history: []ds.ListingPriceResponse{price("12.500 €", 12500, 0)},
+			expect:  "",
+		},
+		{
+			name: "typed prices",
+			history: []ds.ListingPriceResponse{
+				price("12.500 €", 12500, 9),
+				price("11.900 €", 11900, 4),
+				price("11.200 €", 11200, 0),
+			},
+			expect: "12 500 → 11 900 → 11 200 € over 9 days",
+		},
+		{
+			name: "repeated prices are skipped",
+			history: []ds.ListingPriceResponse{
+				price("12.500 €", 12500, 9),
+				price("12.500 €", 12500, 8),
+				price("11.900 €", 11900, 4),
+				price("11.900 €", 11900, 3),
+				price("11.900 €", 11900, 0),
+			},
+			expect: "12 500 → 11 900 € over 5 days",
+		},
+		{
+			name: "one day",
+			history: []ds.ListingPriceResponse{
+				price("950 €", 950, 1),
+				price("1.000.000 €", 1000000, 0),
+			},
+			expect: "950 → 1 000 000 € over 1 day",
+		},
+		{
+			name: "same day",
+			history: []ds.ListingPriceResponse{
+				price("2.000 €", 2000, 0),
+				price("1.900 €", 1900, 0),
+			},
+			expect: "2 000 → 1 900 €",
+		},
+		{
+			name: "unknown typed price falls back to raw prices",
+			history: []ds.ListingPriceResponse{
+				price("Na upit", 0, 3),
+				price("1.900 €", 1900, 0),
+			},
+			expect: "Na upit → 1.900 € over 3 days",
+		},
+		{
+			name: "only the latest prices are shown",
+			history: func() []ds.ListingPriceResponse {
+				history := make([]ds.ListingPriceResponse, 0, maxPoints+2)
+				for i := maxPoints + 1; i >= 0; i-- {
+					history = append(history, price("", int64(1000+i*100), i))
+				}
+
+				return history
+			}(),
+			expect: "… → 1 900 → 1 800 → 1 700 → 1 600 → 1 500 → 1 400 → 1 300 → 1 200 → 1 100 → 1 000 €" +
+				" over 11 days",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Format(tc.history))
+		})
+	}
+}
+
+func TestFormatEUR(t *testing.T) {
+	testCases := []struct {
+		name   string
+		price  decimal.Decimal
+		expect string
+	}{
+		{name: "hundreds", price: decimal.NewFromInt(950), expect: "950 €"},
+		{name: "thousands", price: decimal.NewFromInt(12500), expect: "12 500 €"},
+		{name: "millions", price: decimal.NewFromInt(1250000), expect: "1 250 000 €"},
+		{name: "rounded", price: decimal.RequireFromString("11999.5"), expect: "12 000 €"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, FormatEUR(tc.price))
+		})
+	}
+}
+
+func TestRenderChart(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		history   []ds.ListingPriceResponse
+		median    []ds.MedianPriceResponse
+		expectErr error
+	}{
+		{
+			name: "listing with market median",
+			history: []ds.ListingPriceResponse{
+				{
+					Price:      "12.500 €",
+					PriceEUR:   decimal.NewNullDecimal(decimal.NewFromInt(12500)),
+					ObservedAt: now.AddDate(0, 0, -9),
+				},
+				{
+					Price:      "11.200 €",
+					PriceEUR:   decimal.NewNullDecimal(decimal.NewFromInt(11200)),
+					ObservedAt: now,
+				},
+			},
+			median: []ds.MedianPriceResponse{
+				{Day: now.AddDate(0, 0, -9), PriceEUR: decimal.NewFromInt(11900)},
+				{Day: now, PriceEUR: decimal.NewFromInt(11700)},
+			},
+		},
+		{
+			name: "single price is extended to now",
+			history: []ds.ListingPriceResponse{
+				{
+					Price:      "12.500 €",
+					PriceEUR:   decimal.NewNullDecimal(decimal.NewFromInt(12500)),
+					ObservedAt: now.AddDate(0, 0, -3),
+				},
+			},
+		},
+		{
+			name: "no typed prices",
+			history: []ds.ListingPriceResponse{
+				{Price: "Na upit", ObservedAt: now}, //nolint:exhaustruct,nolintlint
+			},
+			expectErr: chart.ErrNoData,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RenderChart(tc.history, tc.median, now)
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			_, err = png.Decode(bytes.NewReader(got))
+			assert.NoError(t, err)
+		})
+	}
+}
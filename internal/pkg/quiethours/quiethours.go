@@ -0,0 +1,117 @@
+package quiethours
+
+import (
+	"fmt"
+	"time"
+	// the time zones are embedded, the images of the services don't have them.
+	_ "time/tzdata"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+const (
+	// DefaultTimeZone is the time zone of the user until they choose another one.
+	DefaultTimeZone = "UTC"
+
+	// maxPeriods is the max number of the quiet periods which follow one another, e.g. the weekend
+	// is followed by the quiet hours of Monday night.
+	maxPeriods = 3
+)
+
+// Location returns the time zone of the user, UTC is returned for an unknown time zone.
+func Location(timeZone string) *time.Location {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil || timeZone == "" {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// IsValidTimeZone checks if the name is a time zone of the IANA database, e.g. "Europe/Belgrade".
+func IsValidTimeZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(name)
+
+	return err == nil
+}
+
+// IsValidHour checks if the quiet hours can start or end at the hour.
+func IsValidHour(hour int) bool {
+	return hour >= 0 && hour < ds.HoursInDay
+}
+
+// EndAt checks if the notifications to the user are held at the time and returns in UTC when they can be sent,
+// i.e. the end of the quiet hours or of the weekend, whichever is later if one follows the other.
+// The quiet hours are from the hour they start at to the hour they end at in the time zone of the user,
+// they can go past midnight, e.g. from 22:00 to 08:00.
+func EndAt(settings ds.UserSettingsResponse, now time.Time) (time.Time, bool) {
+	t := now.In(Location(settings.TimeZone))
+	isQuiet := false
+
+	for range maxPeriods {
+		end, ok := periodEnd(settings, t)
+		if !ok {
+			break
+		}
+
+		t, isQuiet = end, true
+	}
+
+	return t.UTC(), isQuiet
+}
+
+// periodEnd returns the end of the quiet period the local time is in.
+func periodEnd(settings ds.UserSettingsResponse, t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+
+	if settings.MuteWeekends {
+		switch t.Weekday() { //nolint:exhaustive,nolintlint
+		case time.Saturday:
+			return time.Date(year, month, day+2, 0, 0, 0, 0, t.Location()), true //nolint:mnd,nolintlint
+		case time.Sunday:
+			return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location()), true
+		}
+	}
+
+	if !IsSet(settings) {
+		return time.Time{}, false
+	}
+
+	from, to := int(settings.QuietHoursFrom.Int64), int(settings.QuietHoursTo.Int64)
+	hour := t.Hour()
+
+	inWindow := hour >= from && hour < to
+	if from > to {
+		inWindow = hour >= from || hour < to
+	}
+
+	if !inWindow {
+		return time.Time{}, false
+	}
+
+	end := time.Date(year, month, day, to, 0, 0, 0, t.Location())
+	if !end.After(t) {
+		end = time.Date(year, month, day+1, to, 0, 0, 0, t.Location())
+	}
+
+	return end, true
+}
+
+// IsSet checks if the user has the quiet hours.
+func IsSet(settings ds.UserSettingsResponse) bool {
+	return settings.QuietHoursFrom.Valid && settings.QuietHoursTo.Valid &&
+		settings.QuietHoursFrom.Int64 != settings.QuietHoursTo.Int64
+}
+
+// Format describes the quiet hours to the user, e.g. "22:00 - 08:00".
+func Format(settings ds.UserSettingsResponse) string {
+	if !IsSet(settings) {
+		return "off"
+	}
+
+	return fmt.Sprintf("%02d:00 - %02d:00", settings.QuietHoursFrom.Int64, settings.QuietHoursTo.Int64)
+}
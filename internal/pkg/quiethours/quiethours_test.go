@@ -0,0 +1,133 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+)
+
+func TestEndAt(t *testing.T) {
+	nightly := ds.UserSettingsResponse{ //nolint:exhaustruct,nolintlint
+		TimeZone:       "Europe/Belgrade",
+		QuietHoursFrom: null.IntFrom(22),
+		QuietHoursTo:   null.IntFrom(8),
+	}
+
+	daytime := nightly
+	daytime.QuietHoursFrom, daytime.QuietHoursTo = null.IntFrom(9), null.IntFrom(17)
+
+	weekends := nightly
+	weekends.MuteWeekends = true
+
+	unknownZone := nightly
+	unknownZone.TimeZone = "Mars/Olympus"
+
+	testCases := []struct {
+		name       string
+		settings   ds.UserSettingsResponse
+		now        time.Time
+		expect     time.Time
+		expectHeld bool
+	}{
+		{
+			name:     "no quiet hours",
+			settings: ds.UserSettingsResponse{TimeZone: "UTC"}, //nolint:exhaustruct,nolintlint
+			now:      time.Date(2024, 12, 4, 23, 0, 0, 0, time.UTC),
+			expect:   time.Date(2024, 12, 4, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "before quiet hours",
+			settings: nightly,
+			// 20:30 in Belgrade
+			now:    time.Date(2024, 12, 4, 19, 30, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 4, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "quiet hours before midnight",
+			settings: nightly,
+			// 23:00 in Belgrade, the quiet hours end at 08:00 in Belgrade
+			now:        time.Date(2024, 12, 4, 22, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 5, 7, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+		{
+			name:     "quiet hours after midnight",
+			settings: nightly,
+			// 03:00 in Belgrade
+			now:        time.Date(2024, 12, 5, 2, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 5, 7, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+		{
+			name:     "end of quiet hours",
+			settings: nightly,
+			now:      time.Date(2024, 12, 5, 7, 0, 0, 0, time.UTC),
+			expect:   time.Date(2024, 12, 5, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "quiet hours within a day",
+			settings:   daytime,
+			now:        time.Date(2024, 12, 4, 12, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 4, 16, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+		{
+			name:     "weekends aren't muted",
+			settings: nightly,
+			// Saturday 12:00 in Belgrade
+			now:    time.Date(2024, 12, 7, 11, 0, 0, 0, time.UTC),
+			expect: time.Date(2024, 12, 7, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekend is followed by quiet hours",
+			settings: weekends,
+			// Saturday 12:00 in Belgrade, Monday starts with the quiet hours until 08:00
+			now:        time.Date(2024, 12, 7, 11, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 9, 7, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+		{
+			name:     "quiet hours of Friday night are followed by weekend",
+			settings: weekends,
+			// Friday 23:00 in Belgrade
+			now:        time.Date(2024, 12, 6, 22, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 9, 7, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+		{
+			name:       "unknown time zone is UTC",
+			settings:   unknownZone,
+			now:        time.Date(2024, 12, 4, 22, 0, 0, 0, time.UTC),
+			expect:     time.Date(2024, 12, 5, 8, 0, 0, 0, time.UTC),
+			expectHeld: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			end, held := EndAt(tc.settings, tc.now)
+			assert.Equal(t, tc.expectHeld, held)
+			assert.Equal(t, tc.expect, end)
+		})
+	}
+}
+
+func TestIsValidTimeZone(t *testing.T) {
+	assert.True(t, IsValidTimeZone("Europe/Belgrade"))
+	assert.True(t, IsValidTimeZone("UTC"))
+	assert.False(t, IsValidTimeZone(""))
+	assert.False(t, IsValidTimeZone("Local"))
+	assert.False(t, IsValidTimeZone("Belgrade"))
+}
+
+func TestFormat(t *testing.T) {
+	settings := ds.UserSettingsResponse{} //nolint:exhaustruct,nolintlint
+	assert.Equal(t, "off", Format(settings))
+
+	settings.QuietHoursFrom, settings.QuietHoursTo = null.IntFrom(22), null.IntFrom(8)
+	assert.Equal(t, "22:00 - 08:00", Format(settings))
+}
@@ -0,0 +1,300 @@
+package searchparams
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
+)
+
+type (
+	// IDCatalog maps the names of the chassis and of the options of the search filters to the IDs of the site.
+	IDCatalog interface {
+		GetCarChassisList() map[string]string
+		GetSearchFilterOptions(filter ds.SearchFilter) map[string]string
+	}
+
+	// Catalog is the catalog of the site saved by the fetcher, the values of a search are checked against it.
+	Catalog interface {
+		IDCatalog
+		GetCarModelsList(brand string) ([]string, bool)
+		GetRegionsList() map[string]string
+	}
+)
+
+var (
+	ErrNoBrand       = errors.New("brand is not set")
+	ErrUnknownValue  = errors.New("unknown value")
+	ErrInvalidNumber = errors.New("invalid number")
+)
+
+// The query parameters of the search the subscription has fields for.
+const (
+	paramBrand     = "brand"
+	paramModel     = "model[]"
+	paramChassis   = "chassis[]"
+	paramRegion    = "region[]"
+	paramPriceFrom = "price_from"
+	paramPriceTo   = "price_to"
+	paramYearFrom  = "year_from"
+	paramYearTo    = "year_to"
+	paramMileageTo = "mileage_to"
+	paramPowerFrom = "power_from"
+	paramPowerTo   = "power_to"
+
+	// paramShowOldNew chooses between the new and the used cars, all of them are searched by default.
+	paramShowOldNew   = "showOldNew"
+	defaultShowOldNew = "all"
+)
+
+// URL returns the link of the search of the subscription on the site.
+func URL(sub ds.SubscriptionResponse, catalog IDCatalog) string {
+	return polovniauto.SearchURL(FromSubscription(sub, catalog))
+}
+
+// FromSubscription converts a subscription to the query parameters of the search, it's the reverse of ToSubscription.
+// The extra parameters of the subscription are passed as is, unless the subscription has a field for them.
+// The names of the chassis and of the options are mapped to the IDs of the site,
+// the ones which are not on the site anymore are ignored.
+func FromSubscription(sub ds.SubscriptionResponse, catalog IDCatalog) map[string]string {
+	params := make(map[string]string, len(sub.ExtraParams))
+	maps.Copy(params, sub.ExtraParams)
+
+	if _, exists := params[paramShowOldNew]; !exists {
+		params[paramShowOldNew] = defaultShowOldNew
+	}
+
+	params[paramBrand] = sub.Brand
+	params[paramPriceFrom] = sub.PriceFrom
+	params[paramPriceTo] = sub.PriceTo
+	params[paramYearFrom] = sub.YearFrom
+	params[paramYearTo] = sub.YearTo
+
+	if len(sub.Model) > 0 {
+		params[paramModel] = strings.Join(sub.Model, ",")
+	}
+
+	if len(sub.Region) > 0 {
+		params[paramRegion] = strings.Join(sub.Region, ",")
+	}
+
+	if len(sub.Chassis) > 0 {
+		params[paramChassis] = strings.Join(mapNames(sub.Chassis, catalog.GetCarChassisList()), ",")
+	}
+
+	if sub.MileageTo != "" {
+		params[paramMileageTo] = sub.MileageTo
+	}
+
+	if sub.PowerFrom != "" {
+		params[paramPowerFrom] = sub.PowerFrom
+	}
+
+	if sub.PowerTo != "" {
+		params[paramPowerTo] = sub.PowerTo
+	}
+
+	for filter, selected := range searchFilterOptions(sub) {
+		if options := mapNames(selected, catalog.GetSearchFilterOptions(filter)); len(options) > 0 {
+			params[string(filter)+"[]"] = strings.Join(options, ",")
+		}
+	}
+
+	return params
+}
+
+// searchFilterOptions returns the selected options of the search filters of the subscription.
+func searchFilterOptions(sub ds.SubscriptionResponse) map[ds.SearchFilter][]string {
+	return map[ds.SearchFilter][]string{
+		ds.SearchFilterFuel:         sub.Fuel,
+		ds.SearchFilterGearbox:      sub.Gearbox,
+		ds.SearchFilterDoors:        sub.Doors,
+		ds.SearchFilterSeats:        sub.Seats,
+		ds.SearchFilterColor:        sub.Color,
+		ds.SearchFilterAirCondition: sub.AirCondition,
+		ds.SearchFilterDamage:       sub.Damage,
+	}
+}
+
+// FromURL converts the link of a search on the site to a subscription, see ToSubscription.
+func FromURL(link string, catalog Catalog) (ds.SubscriptionRequest, error) {
+	params, err := polovniauto.ParseSearchURL(link)
+	if err != nil {
+		return ds.SubscriptionRequest{}, errors.Wrap(err, "failed to parse search link")
+	}
+
+	return ToSubscription(params, catalog)
+}
+
+// ToSubscription converts the query parameters of a search to a subscription, the user ID is not set.
+// The brand is required, the brand, the models, the chassis, the regions and the options of the search filters
+// must be in the catalog, the IDs of the site are mapped to the names the subscription stores.
+// The parameters the subscription has no fields for are kept as the extra parameters.
+func ToSubscription(params map[string]string, catalog Catalog) (ds.SubscriptionRequest, error) {
+	sub := ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+		Brand:       params[paramBrand],
+		ExtraParams: make(map[string]string),
+	}
+
+	if sub.Brand == "" {
+		return ds.SubscriptionRequest{}, ErrNoBrand
+	}
+
+	models, ok := catalog.GetCarModelsList(sub.Brand)
+	if !ok {
+		return ds.SubscriptionRequest{}, errors.Wrapf(ErrUnknownValue, "%s=%s", paramBrand, sub.Brand)
+	}
+
+	var err error
+
+	if sub.Model, err = mapValues(paramModel, params[paramModel], sameValues(models)); err != nil {
+		return ds.SubscriptionRequest{}, err
+	}
+
+	if sub.Chassis, err = mapValues(paramChassis, params[paramChassis], invert(catalog.GetCarChassisList())); err != nil {
+		return ds.SubscriptionRequest{}, err
+	}
+
+	if sub.Region, err = mapValues(paramRegion, params[paramRegion], invert(catalog.GetRegionsList())); err != nil {
+		return ds.SubscriptionRequest{}, err
+	}
+
+	numbers := map[string]*string{
+		paramPriceFrom: &sub.PriceFrom,
+		paramPriceTo:   &sub.PriceTo,
+		paramYearFrom:  &sub.YearFrom,
+		paramYearTo:    &sub.YearTo,
+		paramMileageTo: &sub.MileageTo,
+		paramPowerFrom: &sub.PowerFrom,
+		paramPowerTo:   &sub.PowerTo,
+	}
+
+	for param, field := range numbers {
+		value := params[param]
+		if value == "" {
+			continue
+		}
+
+		if _, err = strconv.Atoi(value); err != nil {
+			return ds.SubscriptionRequest{}, errors.Wrapf(ErrInvalidNumber, "%s=%s", param, value)
+		}
+
+		*field = value
+	}
+
+	filters := filterFields(&sub)
+
+	for _, filter := range ds.SearchFilters {
+		param := string(filter) + "[]"
+
+		var options []string
+		if options, err = mapValues(param, params[param], invert(catalog.GetSearchFilterOptions(filter))); err != nil {
+			return ds.SubscriptionRequest{}, err
+		}
+
+		if len(options) > 0 {
+			*filters[filter] = options
+		}
+	}
+
+	for param, value := range params {
+		if _, known := numbers[param]; known || isFieldParam(param) {
+			continue
+		}
+
+		// the default is set when searching, so the subscription doesn't keep it
+		if param == paramShowOldNew && value == defaultShowOldNew {
+			continue
+		}
+
+		sub.ExtraParams[param] = value
+	}
+
+	return sub, nil
+}
+
+// filterFields returns the fields of the subscription with the options of the search filters.
+func filterFields(sub *ds.SubscriptionRequest) map[ds.SearchFilter]*[]string {
+	return map[ds.SearchFilter]*[]string{
+		ds.SearchFilterFuel:         &sub.Fuel,
+		ds.SearchFilterGearbox:      &sub.Gearbox,
+		ds.SearchFilterDoors:        &sub.Doors,
+		ds.SearchFilterSeats:        &sub.Seats,
+		ds.SearchFilterColor:        &sub.Color,
+		ds.SearchFilterAirCondition: &sub.AirCondition,
+		ds.SearchFilterDamage:       &sub.Damage,
+	}
+}
+
+// isFieldParam checks if the subscription has a field with a list of values for the parameter.
+func isFieldParam(param string) bool {
+	switch param {
+	case paramBrand, paramModel, paramChassis, paramRegion:
+		return true
+	}
+
+	for _, filter := range ds.SearchFilters {
+		if param == string(filter)+"[]" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapNames maps the names to the IDs of the catalog, the names the catalog doesn't have are ignored.
+func mapNames(names []string, ids map[string]string) []string {
+	mapped := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if id, exists := ids[name]; exists {
+			mapped = append(mapped, id)
+		}
+	}
+
+	return mapped
+}
+
+// mapValues maps the comma separated values of the parameter to the names of the catalog,
+// it fails on the first value the catalog doesn't have.
+func mapValues(param, values string, names map[string]string) ([]string, error) {
+	mapped := []string{}
+	if values == "" {
+		return mapped, nil
+	}
+
+	for _, value := range strings.Split(values, ",") {
+		name, ok := names[value]
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownValue, "%s=%s", param, value)
+		}
+
+		mapped = append(mapped, name)
+	}
+
+	return mapped, nil
+}
+
+// invert maps the IDs of the catalog, e.g. of the chassis, to their names.
+func invert(catalog map[string]string) map[string]string {
+	inverted := make(map[string]string, len(catalog))
+	for name, id := range catalog {
+		inverted[id] = name
+	}
+
+	return inverted
+}
+
+// sameValues maps each of the values to itself, e.g. the models are stored as they are in the search.
+func sameValues(values []string) map[string]string {
+	same := make(map[string]string, len(values))
+	for _, value := range values {
+		same[value] = value
+	}
+
+	return same
+}
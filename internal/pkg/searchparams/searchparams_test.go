@@ -0,0 +1,202 @@
+package searchparams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/internal/pkg/ds"
+	"github.com/gudimz/polovni-auto-alert/pkg/polovniauto"
+)
+
+type catalog struct{}
+
+func (catalog) GetCarModelsList(brand string) ([]string, bool) {
+	models, ok := map[string][]string{"bmw": {"x3", "x5"}}[brand]
+	return models, ok
+}
+
+func (catalog) GetCarChassisList() map[string]string {
+	return map[string]string{"Limuzina": "277", "Džip/SUV": "2632"}
+}
+
+func (catalog) GetRegionsList() map[string]string {
+	return map[string]string{"Beograd": "Beograd", "Vojvodina": "Vojvodina"}
+}
+
+func (catalog) GetSearchFilterOptions(filter ds.SearchFilter) map[string]string {
+	return map[ds.SearchFilter]map[string]string{
+		ds.SearchFilterFuel:    {"Benzin": "45", "Dizel": "2309"},
+		ds.SearchFilterGearbox: {"Automatski / poluautomatski": "3212"},
+	}[filter]
+}
+
+func TestFromURL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		link    string
+		want    ds.SubscriptionRequest
+		wantErr error
+	}{
+		{
+			name: "all parameters",
+			link: "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw&model%5B%5D=x5&model%5B%5D=x3" +
+				"&chassis%5B%5D=2632&region%5B%5D=Beograd&price_from=10000&price_to=30000&year_from=2015&year_to=" +
+				"&mileage_to=150000&power_from=100&fuel%5B%5D=2309&gearbox%5B%5D=3212&sort=basic&without_price=1",
+			want: ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+				Brand:       "bmw",
+				Model:       []string{"x5", "x3"},
+				Chassis:     []string{"Džip/SUV"},
+				Region:      []string{"Beograd"},
+				PriceFrom:   "10000",
+				PriceTo:     "30000",
+				YearFrom:    "2015",
+				MileageTo:   "150000",
+				PowerFrom:   "100",
+				Fuel:        []string{"Dizel"},
+				Gearbox:     []string{"Automatski / poluautomatski"},
+				ExtraParams: map[string]string{"sort": "basic", "without_price": "1"},
+			},
+		},
+		{
+			name: "brand only",
+			link: "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw",
+			want: ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+				Brand:       "bmw",
+				Model:       []string{},
+				Chassis:     []string{},
+				Region:      []string{},
+				ExtraParams: map[string]string{},
+			},
+		},
+		{
+			name:    "not a search link",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-320-d",
+			wantErr: polovniauto.ErrInvalidSearchLink,
+		},
+		{
+			name:    "no brand",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?price_to=30000",
+			wantErr: ErrNoBrand,
+		},
+		{
+			name:    "unknown brand",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=lada",
+			wantErr: ErrUnknownValue,
+		},
+		{
+			name:    "unknown model",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw&model%5B%5D=m3",
+			wantErr: ErrUnknownValue,
+		},
+		{
+			name:    "unknown option",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw&fuel%5B%5D=1",
+			wantErr: ErrUnknownValue,
+		},
+		{
+			name:    "invalid number",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/pretraga?brand=bmw&price_to=30k",
+			wantErr: ErrInvalidNumber,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FromURL(tc.link, catalog{})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestURL_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		sub  ds.SubscriptionResponse
+	}{
+		{
+			name: "all fields",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand:       "bmw",
+				Model:       []string{"x5", "x3"},
+				Chassis:     []string{"Limuzina", "Džip/SUV"},
+				Region:      []string{"Vojvodina", "Beograd"},
+				PriceFrom:   "10000",
+				PriceTo:     "30000",
+				YearFrom:    "2015",
+				YearTo:      "2020",
+				MileageTo:   "150000",
+				PowerFrom:   "100",
+				PowerTo:     "250",
+				Fuel:        []string{"Dizel", "Benzin"},
+				Gearbox:     []string{"Automatski / poluautomatski"},
+				ExtraParams: map[string]string{"showOldNew": "old", "without_price": "1"},
+			},
+		},
+		{
+			name: "brand only",
+			sub: ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+				Brand:       "bmw",
+				Model:       []string{},
+				Chassis:     []string{},
+				Region:      []string{},
+				ExtraParams: map[string]string{},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FromURL(URL(tc.sub, catalog{}), catalog{})
+			require.NoError(t, err)
+
+			require.Equal(t, ds.SubscriptionRequest{ //nolint:exhaustruct,nolintlint
+				Brand:        tc.sub.Brand,
+				Model:        tc.sub.Model,
+				Chassis:      tc.sub.Chassis,
+				Region:       tc.sub.Region,
+				PriceFrom:    tc.sub.PriceFrom,
+				PriceTo:      tc.sub.PriceTo,
+				YearFrom:     tc.sub.YearFrom,
+				YearTo:       tc.sub.YearTo,
+				MileageTo:    tc.sub.MileageTo,
+				PowerFrom:    tc.sub.PowerFrom,
+				PowerTo:      tc.sub.PowerTo,
+				Fuel:         tc.sub.Fuel,
+				Gearbox:      tc.sub.Gearbox,
+				Doors:        tc.sub.Doors,
+				Seats:        tc.sub.Seats,
+				Color:        tc.sub.Color,
+				AirCondition: tc.sub.AirCondition,
+				Damage:       tc.sub.Damage,
+				ExtraParams:  tc.sub.ExtraParams,
+			}, got)
+		})
+	}
+}
+
+func TestFromSubscription(t *testing.T) {
+	sub := ds.SubscriptionResponse{ //nolint:exhaustruct,nolintlint
+		Brand:   "bmw",
+		Chassis: []string{"Limuzina", "Kupe"}, // not in the catalog
+		Fuel:    []string{"Dizel"},
+		Color:   []string{"Crna"}, // not in the catalog
+	}
+
+	require.Equal(t, map[string]string{
+		"brand":      "bmw",
+		"chassis[]":  "277",
+		"fuel[]":     "2309",
+		"price_from": "",
+		"price_to":   "",
+		"year_from":  "",
+		"year_to":    "",
+		"showOldNew": "all",
+	}, FromSubscription(sub, catalog{}))
+}
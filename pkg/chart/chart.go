@@ -0,0 +1,394 @@
+package chart
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	defaultWidth  = 800
+	defaultHeight = 400
+
+	marginLeft   = 80
+	marginRight  = 24
+	marginTop    = 40
+	marginBottom = 32
+
+	yTicks        = 5
+	xTicks        = 5
+	hoursInDay    = 24
+	tickLabelGap  = 6
+	legendLineLen = 16
+	legendGap     = 12
+	lineWidth     = 2
+	markerRadius  = 3
+	// valuePadding is the share of the value range added above and below the series.
+	valuePadding = 0.1
+)
+
+var (
+	// ErrNoData is returned if there are no points to render.
+	ErrNoData = errors.New("no data to render")
+
+	background = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gridColor  = color.RGBA{R: 230, G: 230, B: 230, A: 255}
+	axisColor  = color.RGBA{R: 160, G: 160, B: 160, A: 255}
+	textColor  = color.RGBA{R: 60, G: 60, B: 60, A: 255}
+
+	// palette is used for the series without a color.
+	palette = []color.RGBA{
+		{R: 33, G: 150, B: 243, A: 255},
+		{R: 255, G: 152, B: 0, A: 255},
+		{R: 76, G: 175, B: 80, A: 255},
+		{R: 233, G: 30, B: 99, A: 255},
+	}
+)
+
+type (
+	// Point is a value of a series at a time.
+	Point struct {
+		Time  time.Time
+		Value float64
+	}
+
+	// Series is a line of the chart, the points are expected to be ordered by time.
+	Series struct {
+		Name   string
+		Color  color.RGBA
+		Points []Point
+	}
+
+	// Config holds the options of the chart, zero values are replaced with the defaults.
+	Config struct {
+		Title       string
+		Width       int
+		Height      int
+		FormatValue func(value float64) string
+		FormatTime  func(t time.Time) string
+	}
+)
+
+// RenderPNG renders the series as a line chart and writes it as a PNG image.
+func RenderPNG(w io.Writer, cfg Config, series ...Series) error {
+	img, err := Render(cfg, series...)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// Render renders the series as a line chart.
+func Render(cfg Config, series ...Series) (*image.RGBA, error) {
+	cfg = withDefaults(cfg)
+
+	minTime, maxTime, minValue, maxValue, ok := bounds(series)
+	if !ok {
+		return nil, ErrNoData
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	c := canvas{
+		img:  img,
+		plot: image.Rect(marginLeft, marginTop, cfg.Width-marginRight, cfg.Height-marginBottom),
+	}
+	c.minTime, c.maxTime = padTime(minTime, maxTime)
+	c.minValue, c.maxValue, c.valueStep = valueRange(minValue, maxValue)
+
+	c.drawGrid(cfg)
+	c.drawText(cfg.Title, marginLeft, marginTop/2+basicfont.Face7x13.Ascent/2, textColor)
+	c.drawLegend(series)
+
+	for i, s := range series {
+		clr := s.Color
+		if clr.A == 0 {
+			clr = palette[i%len(palette)]
+		}
+
+		c.drawSeries(s.Points, clr)
+	}
+
+	return img, nil
+}
+
+// withDefaults replaces the zero values of the config with the defaults.
+func withDefaults(cfg Config) Config {
+	if cfg.Width <= 0 {
+		cfg.Width = defaultWidth
+	}
+
+	if cfg.Height <= 0 {
+		cfg.Height = defaultHeight
+	}
+
+	if cfg.FormatValue == nil {
+		cfg.FormatValue = func(value float64) string {
+			return strconv.FormatFloat(value, 'f', 0, 64)
+		}
+	}
+
+	if cfg.FormatTime == nil {
+		cfg.FormatTime = func(t time.Time) string {
+			return t.Format("02 Jan")
+		}
+	}
+
+	return cfg
+}
+
+// bounds returns the time and value ranges of all points, ok is false if there are no points.
+func bounds(series []Series) (minTime, maxTime time.Time, minValue, maxValue float64, ok bool) {
+	for _, s := range series {
+		for _, p := range s.Points {
+			if !ok {
+				minTime, maxTime, minValue, maxValue, ok = p.Time, p.Time, p.Value, p.Value, true
+				continue
+			}
+
+			if p.Time.Before(minTime) {
+				minTime = p.Time
+			}
+
+			if p.Time.After(maxTime) {
+				maxTime = p.Time
+			}
+
+			minValue = math.Min(minValue, p.Value)
+			maxValue = math.Max(maxValue, p.Value)
+		}
+	}
+
+	return minTime, maxTime, minValue, maxValue, ok
+}
+
+// padTime widens the time range of a single moment to a day.
+func padTime(minTime, maxTime time.Time) (time.Time, time.Time) {
+	if !maxTime.After(minTime) {
+		return minTime.Add(-12 * time.Hour), maxTime.Add(12 * time.Hour)
+	}
+
+	return minTime, maxTime
+}
+
+// valueRange adds the padding to the value range, so the lines don't touch the plot border,
+// and rounds the range to the step of the value ticks.
+func valueRange(minValue, maxValue float64) (float64, float64, float64) {
+	padding := (maxValue - minValue) * valuePadding
+	if padding == 0 {
+		padding = math.Max(math.Abs(maxValue)*valuePadding, 1)
+	}
+
+	step := niceStep((maxValue - minValue + 2*padding) / yTicks)
+
+	return math.Floor((minValue-padding)/step) * step, math.Ceil((maxValue+padding)/step) * step, step
+}
+
+// niceStep rounds the step up to 1, 2, 2.5 or 5 multiplied by a power of 10.
+func niceStep(step float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+
+	for _, multiplier := range []float64{1, 2, 2.5, 5} {
+		if step <= multiplier*magnitude {
+			return multiplier * magnitude
+		}
+	}
+
+	return 10 * magnitude //nolint:mnd,nolintlint
+}
+
+// canvas draws the chart parts in the plot area scaled to the time and value ranges.
+type canvas struct {
+	img       *image.RGBA
+	plot      image.Rectangle
+	minTime   time.Time
+	maxTime   time.Time
+	minValue  float64
+	maxValue  float64
+	valueStep float64
+}
+
+// x returns the horizontal position of the time.
+func (c *canvas) x(t time.Time) int {
+	share := float64(t.Sub(c.minTime)) / float64(c.maxTime.Sub(c.minTime))
+	return c.plot.Min.X + int(math.Round(share*float64(c.plot.Dx())))
+}
+
+// y returns the vertical position of the value.
+func (c *canvas) y(value float64) int {
+	share := (value - c.minValue) / (c.maxValue - c.minValue)
+	return c.plot.Max.Y - int(math.Round(share*float64(c.plot.Dy())))
+}
+
+// drawGrid draws the grid lines, the axes and their labels.
+func (c *canvas) drawGrid(cfg Config) {
+	face := basicfont.Face7x13
+
+	step := c.valueStep
+	for i := 0; ; i++ {
+		value := c.minValue + step*float64(i)
+		if value > c.maxValue+step/2 {
+			break
+		}
+
+		y := c.y(value)
+
+		c.drawHLine(c.plot.Min.X, c.plot.Max.X, y, gridColor)
+
+		label := cfg.FormatValue(value)
+		c.drawText(label, c.plot.Min.X-tickLabelGap-textWidth(label), y+face.Ascent/2-1, textColor)
+	}
+
+	days := max(int(math.Ceil(c.maxTime.Sub(c.minTime).Hours()/hoursInDay/xTicks)), 1)
+
+	// the time ticks are at the start of the days
+	tick := time.Date(c.minTime.Year(), c.minTime.Month(), c.minTime.Day(), 0, 0, 0, 0, c.minTime.Location())
+	if tick.Before(c.minTime) {
+		tick = tick.AddDate(0, 0, 1)
+	}
+
+	for ; !tick.After(c.maxTime); tick = tick.AddDate(0, 0, days) {
+		x := c.x(tick)
+
+		c.drawVLine(x, c.plot.Min.Y, c.plot.Max.Y, gridColor)
+
+		label := cfg.FormatTime(tick)
+		labelX := min(max(x-textWidth(label)/2, 0), c.img.Bounds().Dx()-textWidth(label))
+		c.drawText(label, labelX, c.plot.Max.Y+tickLabelGap+face.Ascent, textColor)
+	}
+
+	c.drawHLine(c.plot.Min.X, c.plot.Max.X, c.plot.Max.Y, axisColor)
+	c.drawVLine(c.plot.Min.X, c.plot.Min.Y, c.plot.Max.Y, axisColor)
+}
+
+// drawLegend draws the names of the series aligned to the right of the plot.
+func (c *canvas) drawLegend(series []Series) {
+	x := c.plot.Max.X
+	y := marginTop / 2
+
+	for i := len(series) - 1; i >= 0; i-- {
+		if series[i].Name == "" {
+			continue
+		}
+
+		clr := series[i].Color
+		if clr.A == 0 {
+			clr = palette[i%len(palette)]
+		}
+
+		x -= textWidth(series[i].Name)
+		c.drawText(series[i].Name, x, y+basicfont.Face7x13.Ascent/2, textColor)
+
+		x -= tickLabelGap + legendLineLen
+		c.drawLine(x, y, x+legendLineLen, y, clr)
+
+		x -= legendGap
+	}
+}
+
+// drawSeries draws the line through the points with a marker on each point.
+func (c *canvas) drawSeries(points []Point, clr color.RGBA) {
+	for i := 1; i < len(points); i++ {
+		c.drawLine(c.x(points[i-1].Time), c.y(points[i-1].Value), c.x(points[i].Time), c.y(points[i].Value), clr)
+	}
+
+	for _, p := range points {
+		c.drawMarker(c.x(p.Time), c.y(p.Value), clr)
+	}
+}
+
+// drawLine draws a line with the Bresenham's algorithm.
+func (c *canvas) drawLine(x0, y0, x1, y1 int, clr color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+
+	for {
+		c.fillRect(x0, y0, x0+lineWidth, y0+lineWidth, clr)
+
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x0 += sx
+		} else {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// drawMarker draws a filled circle centered on the point.
+func (c *canvas) drawMarker(x, y int, clr color.RGBA) {
+	for dy := -markerRadius; dy <= markerRadius; dy++ {
+		for dx := -markerRadius; dx <= markerRadius; dx++ {
+			if dx*dx+dy*dy <= markerRadius*markerRadius {
+				c.img.SetRGBA(x+dx, y+dy, clr)
+			}
+		}
+	}
+}
+
+// drawHLine draws a horizontal line of one pixel width.
+func (c *canvas) drawHLine(x0, x1, y int, clr color.RGBA) {
+	c.fillRect(x0, y, x1+1, y+1, clr)
+}
+
+// drawVLine draws a vertical line of one pixel width.
+func (c *canvas) drawVLine(x, y0, y1 int, clr color.RGBA) {
+	c.fillRect(x, y0, x+1, y1+1, clr)
+}
+
+// fillRect fills the rectangle, the parts out of the image are skipped.
+func (c *canvas) fillRect(x0, y0, x1, y1 int, clr color.RGBA) {
+	draw.Draw(c.img, image.Rect(x0, y0, x1, y1), image.NewUniform(clr), image.Point{}, draw.Src)
+}
+
+// drawText draws the text with the baseline starting at the point.
+func (c *canvas) drawText(text string, x, y int, clr color.RGBA) {
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(clr),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// textWidth returns the width of the text in pixels.
+func textWidth(text string) int {
+	return font.MeasureString(basicfont.Face7x13, text).Ceil()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
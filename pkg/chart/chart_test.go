@@ -0,0 +1,128 @@
+package chart
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden images: go test ./pkg/chart -update.
+var update = flag.Bool("update", false, "update the golden images")
+
+func TestRenderPNG(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	day := func(n int) time.Time {
+		return start.AddDate(0, 0, n)
+	}
+
+	testCases := []struct {
+		name   string
+		golden string
+		cfg    Config
+		series []Series
+	}{
+		{
+			name:   "single series",
+			golden: "single_series.png",
+			cfg:    Config{Title: "Price, EUR"}, //nolint:exhaustruct,nolintlint
+			series: []Series{
+				{
+					Name: "Listing",
+					Points: []Point{
+						{Time: day(0), Value: 12500},
+						{Time: day(4), Value: 11900},
+						{Time: day(9), Value: 11200},
+					},
+				},
+			},
+		},
+		{
+			name:   "two series with colors",
+			golden: "two_series.png",
+			cfg:    Config{Title: "Price, EUR", Width: 640, Height: 320}, //nolint:exhaustruct,nolintlint
+			series: []Series{
+				{
+					Name:  "Listing",
+					Color: color.RGBA{R: 220, G: 40, B: 40, A: 255},
+					Points: []Point{
+						{Time: day(0), Value: 12500},
+						{Time: day(6), Value: 10900},
+					},
+				},
+				{
+					Name: "Market median",
+					Points: []Point{
+						{Time: day(0), Value: 11800},
+						{Time: day(2), Value: 11650},
+						{Time: day(4), Value: 11900},
+						{Time: day(6), Value: 11400},
+					},
+				},
+			},
+		},
+		{
+			name:   "single point",
+			golden: "single_point.png",
+			cfg:    Config{}, //nolint:exhaustruct,nolintlint
+			series: []Series{
+				{Points: []Point{{Time: day(0), Value: 2000}}}, //nolint:exhaustruct,nolintlint
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			require.NoError(t, RenderPNG(&buf, tc.cfg, tc.series...))
+
+			path := filepath.Join("testdata", tc.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+			}
+
+			got, err := png.Decode(&buf)
+			require.NoError(t, err)
+
+			want := readGolden(t, path)
+			require.Equal(t, want.Bounds(), got.Bounds())
+
+			for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y++ {
+				for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x++ {
+					require.Equal(t, want.At(x, y), got.At(x, y), "pixel (%d, %d) differs from %s", x, y, path)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPNG_NoData(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := RenderPNG(&buf, Config{}, Series{Name: "Listing"}) //nolint:exhaustruct,nolintlint
+	require.ErrorIs(t, err, ErrNoData)
+	require.Zero(t, buf.Len())
+}
+
+func readGolden(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	require.NoError(t, err)
+
+	return img
+}
@@ -0,0 +1,72 @@
+package imagehash
+
+import (
+	"image"
+	"math/bits"
+)
+
+const (
+	// hashWidth is the width of the grid the image is reduced to, one column more than the compared pairs.
+	hashWidth  = 9
+	hashHeight = 8
+)
+
+// DHash returns the difference hash of the image: the image is reduced to a 9x8 grayscale grid
+// and every bit tells if a cell is brighter than its right neighbour.
+// The hash survives resizing and recompression, so the same photo uploaded twice has a close hash.
+func DHash(img image.Image) uint64 {
+	grid := grayGrid(img)
+
+	var hash uint64
+
+	for y := range hashHeight {
+		for x := range hashWidth - 1 {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// Distance returns the number of the different bits of the hashes, 0 is the same image.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// grayGrid reduces the image to the grid of the average luminance of its cells.
+func grayGrid(img image.Image) [hashHeight][hashWidth]float64 {
+	var (
+		grid   [hashHeight][hashWidth]float64
+		counts [hashHeight][hashWidth]int
+	)
+
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return grid
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := (y - bounds.Min.Y) * hashHeight / bounds.Dy()
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := (x - bounds.Min.X) * hashWidth / bounds.Dx()
+
+			r, g, b, _ := img.At(x, y).RGBA()
+			grid[row][col] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[row][col]++
+		}
+	}
+
+	for y := range hashHeight {
+		for x := range hashWidth {
+			if counts[y][x] > 0 {
+				grid[y][x] /= float64(counts[y][x])
+			}
+		}
+	}
+
+	return grid
+}
@@ -0,0 +1,84 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/draw"
+)
+
+// photo draws a synthetic photo: a horizontal gradient with a dark block, brighter by the offset.
+func photo(width, height int, offset uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			v := uint8(x*200/width) + offset
+			if x > width/3 && x < width/2 && y > height/4 && y < height*3/4 {
+				v = 10
+			}
+
+			img.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	return img
+}
+
+// scaled resizes the image, like the same photo uploaded in a different size.
+func scaled(img image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	return dst
+}
+
+// flipped mirrors the image horizontally, so its gradient goes the other way.
+func flipped(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dst.Set(bounds.Max.X-1-x+bounds.Min.X, y, img.At(x, y))
+		}
+	}
+
+	return dst
+}
+
+func TestDHash(t *testing.T) {
+	original := photo(320, 240, 0)
+
+	testCases := []struct {
+		name        string
+		other       image.Image
+		maxDistance int
+		minDistance int
+	}{
+		{name: "same image", other: original, maxDistance: 0},
+		{name: "resized", other: scaled(original, 160, 120), maxDistance: 4},
+		{name: "brighter", other: photo(320, 240, 30), maxDistance: 4},
+		{name: "different image", other: flipped(original), minDistance: 32, maxDistance: 64},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			distance := Distance(DHash(original), DHash(tc.other))
+			assert.GreaterOrEqual(t, distance, tc.minDistance)
+			assert.LessOrEqual(t, distance, tc.maxDistance)
+		})
+	}
+}
+
+func TestDHash_Empty(t *testing.T) {
+	assert.Equal(t, uint64(0), DHash(image.NewRGBA(image.Rectangle{})))
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, Distance(0b1011, 0b1011))
+	assert.Equal(t, 2, Distance(0b1011, 0b0001))
+	assert.Equal(t, 64, Distance(0, ^uint64(0)))
+}
@@ -6,18 +6,28 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"image"
+	_ "image/jpeg" // the listing photos are decoded by image.Decode
+	_ "image/png"
 	"io"
 	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // the dates of the site are in its time zone, which may be missing on the host
+	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/chromedp"
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
+	_ "golang.org/x/image/webp"
 
+	"github.com/gudimz/polovni-auto-alert/pkg/imagehash"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 	"github.com/gudimz/polovni-auto-alert/pkg/utils"
 )
@@ -31,6 +41,27 @@ type Client struct {
 
 var (
 	ErrUnexpectedStatusCode = errors.New("unexpected status code")
+	ErrInvalidListingLink   = errors.New("invalid listing link")
+	ErrInvalidImageLink     = errors.New("invalid image link")
+	ErrInvalidSearchLink    = errors.New("invalid search link")
+	ErrListingRemoved       = errors.New("listing is removed")
+
+	// errPageNotFound is returned with ErrUnexpectedStatusCode if the page is not found or gone.
+	errPageNotFound = errors.New("page not found")
+
+	// splitParams are the multi-value parameters which are sent as a value per item, their values are
+	// joined by commas, e.g. "m3,m5" of "model[]". The other parameters, e.g. "chassis[]", are sent as is.
+	splitParams = map[string]struct{}{ //nolint:gochecknoglobals,nolintlint
+		"model[]":         {},
+		"region[]":        {},
+		"fuel[]":          {},
+		"gearbox[]":       {},
+		"door_num[]":      {},
+		"seat_num[]":      {},
+		"color[]":         {},
+		"air_condition[]": {},
+		"damaged[]":       {},
+	}
 )
 
 const (
@@ -48,6 +79,25 @@ const (
 
 	delay          = 1 * time.Second
 	maxRandomDelay = 3
+
+	// dateLayout is the layout of the renew date of the listings, it's the wall clock time in siteTimeZone.
+	dateLayout   = "2006-01-02 15:04:05"
+	siteTimeZone = "Europe/Belgrade"
+
+	listingPathPrefix = "/auto-oglasi/"
+	searchPath        = "/auto-oglasi/pretraga"
+	siteHost          = "polovniautomobili.com"
+
+	maxListingRedirects = 5
+
+	// maxImageSize limits the size of the downloaded listing photo, the thumbnails are much smaller.
+	maxImageSize = 10 << 20
+
+	// MaxGalleryPhotos limits the number of the gallery photos of the listing, it's the size of a Telegram album.
+	MaxGalleryPhotos = 10
+
+	SellerTypeDealer  = "dealer"
+	SellerTypePrivate = "private"
 )
 
 func NewClient(l *logger.Logger, cfg *Config) *Client {
@@ -76,18 +126,43 @@ func NewClient(l *logger.Logger, cfg *Config) *Client {
 }
 
 // Listing represents a car listing.
+// Price, Year, EngineVolume and Mileage are kept as displayed on the site,
+// the typed fields hold the same values normalized for comparison.
 type Listing struct {
-	ID           string
-	Title        string
-	Price        string
-	Year         string
-	EngineVolume string
-	Transmission string
-	BodyType     string
-	Mileage      string
-	Location     string
-	Link         string
-	Date         time.Time
+	ID              string
+	Title           string
+	Price           string
+	Year            string
+	EngineVolume    string
+	Transmission    string
+	BodyType        string
+	Mileage         string
+	Location        string
+	Link            string
+	ImageURL        string
+	Date            time.Time
+	PriceEUR        decimal.NullDecimal
+	ProductionYear  null.Int
+	EngineVolumeCM3 null.Int
+	MileageKM       null.Int
+}
+
+// ListingDetails represents the full vehicle specs from the listing detail page.
+type ListingDetails struct {
+	// Price is the current price in the format of the search results, e.g. "2400€",
+	// it's empty if the price is not set, e.g. "Na upit"
+	Price           string
+	PriceEUR        decimal.NullDecimal
+	FuelType        string
+	PowerKW         int
+	PowerHP         int
+	Color           string
+	Drive           string
+	Doors           string
+	RegisteredUntil string
+	DamageStatus    string
+	SellerType      string
+	GalleryURLs     []string
 }
 
 // GetNewListings retrieves new car listings based on the provided parameters.
@@ -102,7 +177,7 @@ func (c *Client) GetNewListings(ctx context.Context, params map[string]string) (
 		uri := c.buildURL(params)
 		c.l.Debug("visit: " + uri.String())
 
-		bodyStr, err := c.fetchPage(ctx, uri)
+		bodyStr, err := c.fetchPage(ctx, c.httpClient, uri)
 		if err != nil {
 			return []Listing{}, err
 		}
@@ -127,6 +202,132 @@ func (c *Client) GetNewListings(ctx context.Context, params map[string]string) (
 	return allListings, nil
 }
 
+// GetListingDetails retrieves the full vehicle specs from the listing detail page.
+// ErrListingRemoved is returned if the page is not found or redirects to another page, the same as IsListingRemoved
+// checks it, so the removal of the listing is known without fetching its page again.
+func (c *Client) GetListingDetails(ctx context.Context, link string) (ListingDetails, error) {
+	uri, err := c.buildListingURL(link)
+	if err != nil {
+		return ListingDetails{}, err
+	}
+
+	listingID := listingIDFromPath(uri.Path)
+
+	client := *c.httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if listingIDFromPath(req.URL.Path) != listingID {
+			return ErrListingRemoved
+		}
+
+		if len(via) >= maxListingRedirects {
+			return fmt.Errorf("%w: too many redirects", ErrUnexpectedStatusCode)
+		}
+
+		return nil
+	}
+
+	c.l.Debug("visit: " + uri.String())
+
+	bodyStr, err := c.fetchPage(ctx, &client, uri)
+	if errors.Is(err, errPageNotFound) {
+		return ListingDetails{}, fmt.Errorf("%w: %w", ErrListingRemoved, err)
+	}
+
+	if err != nil {
+		return ListingDetails{}, err
+	}
+
+	return c.parseListingDetails(bodyStr)
+}
+
+// IsListingRemoved checks if the listing was removed from the site.
+// The detail page of a removed listing is not found or redirects to another page,
+// a redirect to the same listing, e.g. after its title was changed, is followed.
+func (c *Client) IsListingRemoved(ctx context.Context, link string) (bool, error) {
+	uri, err := c.buildListingURL(link)
+	if err != nil {
+		return false, err
+	}
+
+	listingID := listingIDFromPath(uri.Path)
+
+	// the redirects are checked by us, so they are not followed by the client
+	client := *c.httpClient
+	client.CheckRedirect = func(_ *http.Request, _ []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for range maxListingRedirects {
+		c.l.Debug("visit: " + uri.String())
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+		if err != nil {
+			return false, fmt.Errorf("error creating request: %w", err)
+		}
+
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("error making request: %w", err)
+		}
+
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return false, nil
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			return true, nil
+		case resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode < http.StatusBadRequest:
+			location, err := resp.Location()
+			if err != nil || listingIDFromPath(location.Path) != listingID {
+				return true, nil
+			}
+
+			uri = location
+		default:
+			return false, fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, resp.StatusCode)
+		}
+	}
+
+	return false, fmt.Errorf("%w: too many redirects", ErrUnexpectedStatusCode)
+}
+
+// GetImageHash downloads the image and returns its difference hash, see imagehash.DHash.
+func (c *Client) GetImageHash(ctx context.Context, imageURL string) (uint64, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidImageLink, imageURL)
+	}
+
+	c.l.Debug("visit: " + u.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", getRandomUserAgent())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxImageSize))
+	if err != nil {
+		return 0, fmt.Errorf("error decoding image: %w", err)
+	}
+
+	return imagehash.DHash(img), nil
+}
+
 // GetCarsList retrieves the list of car brands and models.
 func (c *Client) GetCarsList(ctx context.Context) (map[string][]string, error) {
 	ctx, cancel := chromedp.NewRemoteAllocator(ctx, c.cfg.ChromeWSURL)
@@ -296,25 +497,88 @@ func (c *Client) GetRegionsList(ctx context.Context) (map[string]string, error)
 	return regions, nil
 }
 
+// GetSearchFilters retrieves the options of the search filters, the filters are the IDs of the select elements
+// of the search form, e.g. "fuel". The options of a filter map the names to the IDs, a filter without
+// the options on the site is omitted.
+func (c *Client) GetSearchFilters(ctx context.Context, filters []string) (map[string]map[string]string, error) {
+	ctx, cancel := chromedp.NewRemoteAllocator(ctx, c.cfg.ChromeWSURL)
+	defer cancel()
+
+	ctxTask, taskCancel := chromedp.NewContext(ctx)
+	defer taskCancel()
+
+	c.l.Info("loading the site for search filters")
+
+	if err := chromedp.Run(
+		ctxTask,
+		chromedp.Navigate(urlPA),
+		chromedp.WaitReady(`#brand`, chromedp.ByID),
+		chromedp.Sleep(delay),
+	); err != nil {
+		return nil, fmt.Errorf("error loading the site for search filters: %w", err)
+	}
+
+	searchFilters := make(map[string]map[string]string, len(filters))
+
+	for _, filter := range filters {
+		var options map[string]string
+		if err := chromedp.Run(
+			ctxTask,
+			chromedp.Evaluate(fmt.Sprintf(`
+				(function() {
+					const result = {};
+					document.querySelectorAll('#%s option').forEach(option => {
+						if (option.value) {
+							result[option.textContent.trim()] = option.value;
+						}
+					});
+					return result;
+				})()
+			`, filter), &options),
+		); err != nil {
+			return nil, fmt.Errorf("error getting options of search filter %s: %w", filter, err)
+		}
+
+		if len(options) == 0 {
+			c.l.Warn("no options found for search filter", logger.StringAttr("filter", filter))
+			continue
+		}
+
+		searchFilters[filter] = options
+	}
+
+	c.l.Info(fmt.Sprintf("found search filters: %d and success finished", len(searchFilters)))
+
+	return searchFilters, nil
+}
+
+// SearchURL returns the link of the search on the site with the query parameters, the same as the client searches.
+func SearchURL(params map[string]string) string {
+	baseURL, _ := url.Parse(urlPA)
+
+	return buildSearchURL(baseURL, params).String()
+}
+
 // buildURL constructs the URL with query parameters.
 func (c *Client) buildURL(params map[string]string) *url.URL {
-	rel := &url.URL{Path: "/auto-oglasi/pretraga"}
-	u := c.baseURL.ResolveReference(rel)
+	return buildSearchURL(c.baseURL, params)
+}
+
+// buildSearchURL constructs the URL of the search with query parameters relative to the base URL.
+func buildSearchURL(baseURL *url.URL, params map[string]string) *url.URL {
+	rel := &url.URL{Path: searchPath}
+	u := baseURL.ResolveReference(rel)
 
 	q := u.Query()
 
 	for key, value := range params {
-		switch key {
-		case "model[]":
-			for _, model := range strings.Split(value, ",") {
-				q.Add("model[]", model)
-			}
-		case "region[]":
-			for _, region := range strings.Split(value, ",") {
-				q.Add("region[]", region)
-			}
-		default:
+		if _, split := splitParams[key]; !split {
 			q.Add(key, value)
+			continue
+		}
+
+		for _, item := range strings.Split(value, ",") {
+			q.Add(key, item)
 		}
 	}
 
@@ -323,8 +587,95 @@ func (c *Client) buildURL(params map[string]string) *url.URL {
 	return u
 }
 
-// fetchPage retrieves the HTML content of the given URL.
-func (c *Client) fetchPage(ctx context.Context, u *url.URL) (string, error) {
+// ParseSearchURL parses the link of a search on the site to the query parameters, it's the reverse of buildURL.
+// The values of the multi-value parameters, e.g. "model[]", are joined by commas,
+// the empty values and the page of the results are dropped.
+func ParseSearchURL(link string) (map[string]string, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSearchLink, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") ||
+		strings.TrimPrefix(u.Hostname(), "www.") != siteHost ||
+		strings.TrimSuffix(u.Path, "/") != searchPath {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSearchLink, link)
+	}
+
+	params := make(map[string]string)
+
+	for key, values := range u.Query() {
+		if key == "page" {
+			continue
+		}
+
+		nonEmpty := make([]string, 0, len(values))
+
+		for _, value := range values {
+			if value = strings.TrimSpace(value); value != "" {
+				nonEmpty = append(nonEmpty, value)
+			}
+		}
+
+		switch {
+		case len(nonEmpty) == 0:
+			continue
+		case strings.HasSuffix(key, "[]"):
+			params[key] = strings.Join(nonEmpty, ",")
+		default:
+			params[key] = nonEmpty[0]
+		}
+	}
+
+	return params, nil
+}
+
+// parseListingDate parses the renew date of the listing, which is the wall clock time of the site,
+// the date is returned in UTC. The zero time is returned if the date can't be parsed.
+func parseListingDate(value string) time.Time {
+	loc, err := time.LoadLocation(siteTimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	date, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return date.UTC()
+}
+
+// buildListingURL validates the listing link and resolves it against the base URL.
+func (c *Client) buildListingURL(link string) (*url.URL, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidListingLink, err)
+	}
+
+	if !strings.HasPrefix(u.Path, listingPathPrefix) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidListingLink, link)
+	}
+
+	// always visit our base URL, only the path of the link is used
+	return c.baseURL.ResolveReference(&url.URL{Path: u.Path}), nil
+}
+
+// listingIDFromPath returns the listing ID from the path of the listing link,
+// e.g. "25000001" for "/auto-oglasi/25000001/bmw-320-d", it's empty if the path is not a listing.
+func listingIDFromPath(p string) string {
+	rest, ok := strings.CutPrefix(p, listingPathPrefix)
+	if !ok {
+		return ""
+	}
+
+	id, _, _ := strings.Cut(rest, "/")
+
+	return id
+}
+
+// fetchPage retrieves the HTML content of the given URL with the client.
+func (c *Client) fetchPage(ctx context.Context, client *http.Client, u *url.URL) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", fmt.Errorf("error creating request: %w", err)
@@ -332,12 +683,16 @@ func (c *Client) fetchPage(ctx context.Context, u *url.URL) (string, error) {
 
 	req.Header.Set("User-Agent", getRandomUserAgent())
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return "", fmt.Errorf("%w: %w: %d", ErrUnexpectedStatusCode, errPageNotFound, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, resp.StatusCode)
 	}
@@ -371,14 +726,9 @@ func (c *Client) parseListings(bodyStr string) ([]Listing, error) {
 		mileage := strings.TrimSpace(s.Find("div.setInfo div.top").Eq(1).Text())
 		location := strings.TrimSpace(s.Find("div.city").Text())
 		link, _ := s.Find("a.ga-title").Attr("href")
+		imageURL := c.parseImageURL(s.Find("img").First())
 
-		// Parse date string to time.Time
-		var date time.Time
-
-		date, err = time.Parse("2006-01-02 15:04:05", dateStr)
-		if err != nil {
-			date = time.Time{} // Default to zero value if parsing fails
-		}
+		date := parseListingDate(dateStr)
 
 		// Split year and body type
 		year := "N/A"
@@ -396,23 +746,198 @@ func (c *Client) parseListings(bodyStr string) ([]Listing, error) {
 
 		// Append parsed listing to the slice
 		listings = append(listings, Listing{
-			ID:           id,
-			Title:        title,
-			Price:        price,
-			Year:         year,
-			EngineVolume: engineVolume,
-			Transmission: transmission,
-			BodyType:     bodyType,
-			Mileage:      mileage,
-			Location:     location,
-			Link:         link,
-			Date:         date,
+			ID:              id,
+			Title:           title,
+			Price:           price,
+			Year:            year,
+			EngineVolume:    engineVolume,
+			Transmission:    transmission,
+			BodyType:        bodyType,
+			Mileage:         mileage,
+			Location:        location,
+			Link:            link,
+			ImageURL:        imageURL,
+			Date:            date,
+			PriceEUR:        parsePrice(price),
+			ProductionYear:  parseNumber(year),
+			EngineVolumeCM3: parseNumber(engineVolume),
+			MileageKM:       parseNumber(mileage),
 		})
 	})
 
 	return listings, nil
 }
 
+// parseImageURL returns the full URL of the listing photo, the photos are lazy loaded, so the real URL is in data-src.
+// It's empty if the listing has no photo or only a placeholder.
+func (c *Client) parseImageURL(img *goquery.Selection) string {
+	src := strings.TrimSpace(img.AttrOr("data-src", ""))
+	if src == "" {
+		src = strings.TrimSpace(img.AttrOr("src", ""))
+	}
+
+	u, err := url.Parse(src)
+	if err != nil || src == "" {
+		return ""
+	}
+
+	if c.baseURL != nil {
+		u = c.baseURL.ResolveReference(u)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+
+	return u.String()
+}
+
+// parseListingDetails parses the vehicle specs from the listing detail page HTML.
+func (c *Client) parseListingDetails(bodyStr string) (ListingDetails, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(bodyStr)))
+	if err != nil {
+		return ListingDetails{}, fmt.Errorf("error parsing HTML: %w", err)
+	}
+
+	// collect all "label: value" rows from the general and additional info sections
+	specs := make(map[string]string)
+
+	doc.Find("div.divider div.uk-grid").Each(func(_ int, s *goquery.Selection) {
+		cells := s.Find("div.uk-width-1-2")
+		if cells.Length() != 2 { //nolint:nolintlint,mnd
+			return
+		}
+
+		label := strings.TrimSuffix(strings.TrimSpace(cells.Eq(0).Text()), ":")
+		specs[label] = strings.TrimSpace(cells.Eq(1).Text())
+	})
+
+	powerKW, powerHP := parsePower(specs["Snaga motora"])
+	priceEUR := parsePrice(doc.Find("div.price-item").First().Text())
+
+	sellerType := SellerTypePrivate
+	if doc.Find("a[href*='/auto-kuca/']").Length() > 0 {
+		sellerType = SellerTypeDealer
+	}
+
+	return ListingDetails{
+		Price:           formatPrice(priceEUR),
+		PriceEUR:        priceEUR,
+		FuelType:        specs["Gorivo"],
+		PowerKW:         powerKW,
+		PowerHP:         powerHP,
+		Color:           specs["Boja"],
+		Drive:           specs["Pogon"],
+		Doors:           specs["Broj vrata"],
+		RegisteredUntil: strings.TrimSuffix(specs["Registrovan do"], "."),
+		DamageStatus:    specs["Oštećenje"],
+		SellerType:      sellerType,
+		GalleryURLs:     c.parseGalleryURLs(doc),
+	}, nil
+}
+
+// parseGalleryURLs returns the full URLs of the photos of the listing gallery in their order,
+// at most MaxGalleryPhotos, the duplicates and the placeholders are skipped.
+func (c *Client) parseGalleryURLs(doc *goquery.Document) []string {
+	var urls []string
+
+	doc.Find("#image-gallery li").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		imageURL := c.parseImageURL(s)
+		if imageURL == "" {
+			imageURL = c.parseImageURL(s.Find("img").First())
+		}
+
+		if imageURL != "" && !slices.Contains(urls, imageURL) {
+			urls = append(urls, imageURL)
+		}
+
+		return len(urls) < MaxGalleryPhotos
+	})
+
+	return urls
+}
+
+// parsePower parses the engine power in format "110/150 (kW/KS)" to kW and HP.
+func parsePower(power string) (int, int) {
+	value, _, _ := strings.Cut(power, "(")
+
+	kwStr, hpStr, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return 0, 0
+	}
+
+	kw, err := strconv.Atoi(strings.TrimSpace(kwStr))
+	if err != nil {
+		return 0, 0
+	}
+
+	hp, err := strconv.Atoi(strings.TrimSpace(hpStr))
+	if err != nil {
+		return 0, 0
+	}
+
+	return kw, hp
+}
+
+// parsePrice parses the price in format "2.400 €" to a decimal value in EUR.
+// It returns an invalid value if the price is not set, e.g. "Na upit" or "N/A".
+func parsePrice(price string) decimal.NullDecimal {
+	digits := onlyDigits(price)
+	if digits == "" {
+		return decimal.NullDecimal{}
+	}
+
+	value, err := decimal.NewFromString(digits)
+	if err != nil {
+		return decimal.NullDecimal{}
+	}
+
+	return decimal.NewNullDecimal(value)
+}
+
+// formatPrice formats the price in EUR as the search results do, e.g. "2400€", it's empty if the price is unknown.
+func formatPrice(price decimal.NullDecimal) string {
+	if !price.Valid {
+		return ""
+	}
+
+	return price.Decimal.String() + "€"
+}
+
+// parseNumber parses the leading number of a value like "150.000 km", "1995 cm3" or "2015." to int.
+// Dots and spaces are treated as thousands separators.
+func parseNumber(value string) null.Int {
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ' '
+	})
+	if end == -1 {
+		end = len(value)
+	}
+
+	digits := onlyDigits(value[:end])
+	if digits == "" {
+		return null.Int{}
+	}
+
+	number, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return null.Int{}
+	}
+
+	return null.IntFrom(number)
+}
+
+// onlyDigits removes all non-digit characters from the string.
+func onlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, value)
+}
+
 // getRandomUserAgent returns a random user agent string.
 func getRandomUserAgent() string {
 	userAgents := []string{
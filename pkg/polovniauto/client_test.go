@@ -1,15 +1,25 @@
 package polovniauto
 
 import (
+	"bytes"
 	"context"
+	"image"
+	"image/color"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/guregu/null"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/gudimz/polovni-auto-alert/pkg/imagehash"
 	"github.com/gudimz/polovni-auto-alert/pkg/logger"
 )
 
@@ -77,6 +87,10 @@ func (s *ClientTestSuite) TestClient_GetNewListings() {
 					_, err := w.Write([]byte(`
 						<article class="classified" data-classifiedid="1" data-price="2000€" data-renewdate="2023-10-10 10:10:10">
 							<a class="ga-title" title="Best bmw" href="/auto-oglasi/1"></a>
+							<div class="image">
+								<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="
+									data-src="https://images.polovniautomobili.com/user-images/thumbs/1/1_bmw.jpg">
+							</div>
 							<div class="setInfo">
 								<div class="top">2001. Limuzina</div>
 								<div class="top">100000 km</div>
@@ -93,17 +107,22 @@ func (s *ClientTestSuite) TestClient_GetNewListings() {
 			},
 			want: []Listing{
 				{
-					ID:           "1",
-					Title:        "Best bmw",
-					Price:        "2000€",
-					Year:         "2001",
-					EngineVolume: "2000 cm3",
-					Transmission: "Manual",
-					BodyType:     "Limuzina",
-					Mileage:      "100000 km",
-					Location:     "Belgrade",
-					Link:         s.server.URL + "/auto-oglasi/1",
-					Date:         time.Date(2023, 10, 10, 10, 10, 10, 0, time.UTC),
+					ID:              "1",
+					Title:           "Best bmw",
+					Price:           "2000€",
+					Year:            "2001",
+					EngineVolume:    "2000 cm3",
+					Transmission:    "Manual",
+					BodyType:        "Limuzina",
+					Mileage:         "100000 km",
+					Location:        "Belgrade",
+					Link:            s.server.URL + "/auto-oglasi/1",
+					ImageURL:        "https://images.polovniautomobili.com/user-images/thumbs/1/1_bmw.jpg",
+					Date:            time.Date(2023, 10, 10, 8, 10, 10, 0, time.UTC), // 10:10:10 in Belgrade
+					PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(2000)),
+					ProductionYear:  null.IntFrom(2001),
+					EngineVolumeCM3: null.IntFrom(2000),
+					MileageKM:       null.IntFrom(100000),
 				},
 			},
 		},
@@ -163,6 +182,448 @@ func (s *ClientTestSuite) TestClient_GetNewListings() {
 	}
 }
 
+func (s *ClientTestSuite) TestClient_GetListingDetails() {
+	testCases := []struct {
+		name      string
+		link      string
+		fixture   string
+		status    int
+		redirect  string
+		want      ListingDetails
+		expectErr error
+	}{
+		{
+			name:    "success: private seller",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-320-d",
+			fixture: "listing_details_private.html",
+			status:  http.StatusOK,
+			want: ListingDetails{
+				Price:           "2400€",
+				PriceEUR:        decimal.NewNullDecimal(decimal.NewFromInt(2400)),
+				FuelType:        "Dizel",
+				PowerKW:         135,
+				PowerHP:         184,
+				Color:           "Crna",
+				Drive:           "Zadnji",
+				Doors:           "4/5 vrata",
+				RegisteredUntil: "05.2025",
+				DamageStatus:    "Nije oštećen",
+				SellerType:      SellerTypePrivate,
+				GalleryURLs: []string{
+					"https://images.polovniautomobili.com/user-images/big/25000001/1_front.jpg",
+					"https://images.polovniautomobili.com/user-images/big/25000001/2_side.jpg",
+				},
+			},
+		},
+		{
+			name:    "success: dealer",
+			link:    "/auto-oglasi/25000002/volkswagen-golf-7-16-tdi",
+			fixture: "listing_details_dealer.html",
+			status:  http.StatusOK,
+			want: ListingDetails{
+				FuelType:        "Dizel",
+				PowerKW:         77,
+				PowerHP:         105,
+				Color:           "Siva",
+				Drive:           "Prednji",
+				Doors:           "4/5 vrata",
+				RegisteredUntil: "Nije registrovan",
+				DamageStatus:    "Oštećen - u voznom stanju",
+				SellerType:      SellerTypeDealer,
+			},
+		},
+		{
+			name:     "success: redirect to the same listing",
+			link:     "/auto-oglasi/25000002/volkswagen-golf",
+			fixture:  "listing_details_dealer.html",
+			status:   http.StatusOK,
+			redirect: "/auto-oglasi/25000002/volkswagen-golf-7-16-tdi",
+			want: ListingDetails{
+				FuelType:        "Dizel",
+				PowerKW:         77,
+				PowerHP:         105,
+				Color:           "Siva",
+				Drive:           "Prednji",
+				Doors:           "4/5 vrata",
+				RegisteredUntil: "Nije registrovan",
+				DamageStatus:    "Oštećen - u voznom stanju",
+				SellerType:      SellerTypeDealer,
+			},
+		},
+		{
+			name:      "invalid listing link",
+			link:      "https://www.polovniautomobili.com/auto-kuca/1234/auto-centar",
+			expectErr: ErrInvalidListingLink,
+		},
+		{
+			name:      "listing not found",
+			link:      "/auto-oglasi/25000003/audi-a4",
+			status:    http.StatusNotFound,
+			expectErr: ErrListingRemoved,
+		},
+		{
+			name:      "listing gone",
+			link:      "/auto-oglasi/25000003/audi-a4",
+			status:    http.StatusGone,
+			expectErr: ErrListingRemoved,
+		},
+		{
+			name:      "redirect to the search page",
+			link:      "/auto-oglasi/25000003/audi-a4",
+			status:    http.StatusOK,
+			redirect:  "/auto-oglasi/pretraga",
+			expectErr: ErrListingRemoved,
+		},
+		{
+			name:      "unexpected status code",
+			link:      "/auto-oglasi/25000003/audi-a4",
+			status:    http.StatusServiceUnavailable,
+			expectErr: ErrUnexpectedStatusCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var body []byte
+
+			if tc.fixture != "" {
+				var err error
+
+				body, err = os.ReadFile(filepath.Join("testdata", tc.fixture))
+				s.Require().NoError(err)
+			}
+
+			s.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.redirect != "" && r.URL.Path != tc.redirect {
+					http.Redirect(w, r, tc.redirect, http.StatusMovedPermanently)
+					return
+				}
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write(body)
+			})
+
+			got, err := s.client.GetListingDetails(ctx, tc.link)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestClient_IsListingRemoved() {
+	testCases := []struct {
+		name      string
+		link      string
+		handler   http.HandlerFunc
+		want      bool
+		expectErr error
+	}{
+		{
+			name: "listing exists",
+			link: "/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			want: false,
+		},
+		{
+			name: "listing not found",
+			link: "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			want: true,
+		},
+		{
+			name: "listing gone",
+			link: "/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusGone)
+			},
+			want: true,
+		},
+		{
+			name: "redirect to the search page",
+			link: "/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/auto-oglasi/pretraga?brand=bmw", http.StatusFound)
+			},
+			want: true,
+		},
+		{
+			name: "redirect to the same listing",
+			link: "/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/auto-oglasi/25000001/bmw-320-d" {
+					http.Redirect(w, r, "/auto-oglasi/25000001/bmw-320-d-m-sport", http.StatusMovedPermanently)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+			},
+			want: false,
+		},
+		{
+			name:      "invalid listing link",
+			link:      "https://www.polovniautomobili.com/auto-kuca/1234/auto-centar",
+			expectErr: ErrInvalidListingLink,
+		},
+		{
+			name: "unexpected status code",
+			link: "/auto-oglasi/25000001/bmw-320-d",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectErr: ErrUnexpectedStatusCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			s.server.Config.Handler = tc.handler
+
+			got, err := s.client.IsListingRemoved(ctx, tc.link)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestClient_GetImageHash() {
+	img := image.NewRGBA(image.Rect(0, 0, 90, 80))
+	for x := range 90 {
+		for y := range 80 {
+			img.Set(x, y, color.Gray{Y: uint8(x * 2)}) //nolint:gosec,nolintlint
+		}
+	}
+
+	var photo bytes.Buffer
+	s.Require().NoError(png.Encode(&photo, img))
+
+	testCases := []struct {
+		name      string
+		path      string
+		imageURL  string
+		status    int
+		body      []byte
+		want      uint64
+		expectErr error
+	}{
+		{
+			name:   "success",
+			path:   "/user-images/thumbs/1/1_bmw.png",
+			status: http.StatusOK,
+			body:   photo.Bytes(),
+			want:   imagehash.DHash(img),
+		},
+		{
+			name:      "invalid image link",
+			imageURL:  "data:image/gif;base64,R0lGODlhAQABAAAAACw=",
+			expectErr: ErrInvalidImageLink,
+		},
+		{
+			name:      "unexpected status code",
+			path:      "/user-images/thumbs/1/1_bmw.png",
+			status:    http.StatusNotFound,
+			expectErr: ErrUnexpectedStatusCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			s.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write(tc.body)
+			})
+
+			imageURL := tc.imageURL
+			if imageURL == "" {
+				imageURL = s.server.URL + tc.path
+			}
+
+			got, err := s.client.GetImageHash(ctx, imageURL)
+
+			switch {
+			case tc.expectErr != nil:
+				s.Require().Error(err)
+				s.Require().ErrorIs(err, tc.expectErr, "expected error: %v, got: %v", tc.expectErr, err)
+			default:
+				s.Require().NoError(err)
+				s.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestClient_buildURL() {
+	// the chassis are sent as a single value joined by commas, the models and the search filters by an item
+	u := s.client.buildURL(map[string]string{
+		"brand":      "bmw",
+		"model[]":    "m3,m5",
+		"chassis[]":  "277,2635",
+		"fuel[]":     "2309,2310",
+		"mileage_to": "150000",
+	})
+
+	s.Equal("/auto-oglasi/pretraga", u.Path)
+	s.Equal(url.Values{
+		"brand":      {"bmw"},
+		"model[]":    {"m3", "m5"},
+		"chassis[]":  {"277,2635"},
+		"fuel[]":     {"2309", "2310"},
+		"mileage_to": {"150000"},
+	}, u.Query())
+}
+
+func TestParseSearchURL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		link    string
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name: "search",
+			link: "https://www.polovniautomobili.com/auto-oglasi/pretraga?page=2&sort=basic&brand=bmw" +
+				"&model%5B%5D=m3&model%5B%5D=m5&price_from=&price_to=30000&chassis%5B%5D=277&showOldNew=all",
+			want: map[string]string{
+				"brand":      "bmw",
+				"model[]":    "m3,m5",
+				"price_to":   "30000",
+				"chassis[]":  "277",
+				"sort":       "basic",
+				"showOldNew": "all",
+			},
+		},
+		{
+			name: "without www and with a trailing slash",
+			link: " http://polovniautomobili.com/auto-oglasi/pretraga/?brand=audi\n",
+			want: map[string]string{"brand": "audi"},
+		},
+		{
+			name:    "listing link",
+			link:    "https://www.polovniautomobili.com/auto-oglasi/25000001/bmw-320-d",
+			wantErr: ErrInvalidSearchLink,
+		},
+		{
+			name:    "other site",
+			link:    "https://www.example.com/auto-oglasi/pretraga?brand=bmw",
+			wantErr: ErrInvalidSearchLink,
+		},
+		{
+			name:    "not a link",
+			link:    "bmw m3",
+			wantErr: ErrInvalidSearchLink,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseSearchURL(tc.link)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_parsePrice(t *testing.T) {
+	testCases := []struct {
+		name  string
+		price string
+		want  decimal.NullDecimal
+	}{
+		{name: "plain", price: "2000€", want: decimal.NewNullDecimal(decimal.NewFromInt(2000))},
+		{name: "thousands separator", price: "12.500 €", want: decimal.NewNullDecimal(decimal.NewFromInt(12500))},
+		{name: "on request", price: "Na upit", want: decimal.NullDecimal{}},
+		{name: "not available", price: "N/A", want: decimal.NullDecimal{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parsePrice(tc.price)
+			require.Equal(t, tc.want.Valid, got.Valid)
+			require.True(t, tc.want.Decimal.Equal(got.Decimal), "want: %s, got: %s", tc.want.Decimal, got.Decimal)
+		})
+	}
+}
+
+func Test_parseListingDate(t *testing.T) {
+	belgrade, err := time.LoadLocation("Europe/Belgrade")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{name: "summer time", value: "2023-10-10 10:10:10", want: time.Date(2023, 10, 10, 8, 10, 10, 0, time.UTC)},
+		{name: "winter time", value: "2024-01-15 00:30:00", want: time.Date(2024, 1, 14, 23, 30, 0, 0, time.UTC)},
+		{name: "not available", value: "N/A", want: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseListingDate(tc.value)
+			require.Equal(t, tc.want, got)
+
+			if !got.IsZero() {
+				// it's the same wall clock time in the time zone of the site
+				require.Equal(t, tc.value, got.In(belgrade).Format(time.DateTime))
+			}
+		})
+	}
+}
+
+func Test_parseNumber(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		want  null.Int
+	}{
+		{name: "mileage", value: "150.000 km", want: null.IntFrom(150000)},
+		{name: "mileage without separator", value: "100000 km", want: null.IntFrom(100000)},
+		{name: "engine volume", value: "1995 cm3", want: null.IntFrom(1995)},
+		{name: "year", value: "2015.", want: null.IntFrom(2015)},
+		{name: "zero mileage", value: "0 km", want: null.IntFrom(0)},
+		{name: "not available", value: "N/A", want: null.Int{}},
+		{name: "empty", value: "", want: null.Int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, parseNumber(tc.value))
+		})
+	}
+}
+
 func TestClientTestSuite(t *testing.T) {
 	suite.Run(t, new(ClientTestSuite))
 }
@@ -15,7 +15,7 @@ type Bot struct {
 
 // NewBot creates a new Bot instance with the configuration.
 func NewBot(l *logger.Logger, cfg *Config) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.BotToken, cfg.APIEndpoint)
 	if err != nil {
 		return nil, errors.Wrap(err, "new bot failed")
 	}
@@ -42,8 +42,19 @@ func (b *Bot) GetCfg() *Config {
 }
 
 // SendMessage sends a message using the bot's API.
+// Telegram returns all the messages of a media group, the first one is returned for it.
 func (b *Bot) SendMessage(c tgbotapi.Chattable) (tgbotapi.Message, error) {
-	return b.API.Send(c) //nolint:wrapcheck,nolintlint
+	mediaGroup, ok := c.(tgbotapi.MediaGroupConfig)
+	if !ok {
+		return b.API.Send(c) //nolint:wrapcheck,nolintlint
+	}
+
+	messages, err := b.API.SendMediaGroup(mediaGroup)
+	if err != nil || len(messages) == 0 {
+		return tgbotapi.Message{}, err //nolint:wrapcheck,nolintlint
+	}
+
+	return messages[0], nil
 }
 
 // SetCommands sets the bot commands that will be shown in the UI.
@@ -57,3 +68,28 @@ func (b *Bot) SetCommands(commands []tgbotapi.BotCommand) error {
 
 	return nil
 }
+
+// SetWebhook registers the webhook URL, Telegram sends the secret token in every webhook request.
+func (b *Bot) SetWebhook(link, secretToken string) error {
+	cfg, err := tgbotapi.NewWebhook(link)
+	if err != nil {
+		return errors.Wrap(err, "failed to create webhook config")
+	}
+
+	cfg.SecretToken = secretToken
+
+	if _, err = b.API.Request(cfg); err != nil {
+		return errors.Wrap(err, "failed to set webhook")
+	}
+
+	return nil
+}
+
+// DeleteWebhook removes the webhook, so updates can be received with long polling again.
+func (b *Bot) DeleteWebhook() error {
+	if _, err := b.API.Request(tgbotapi.DeleteWebhookConfig{DropPendingUpdates: false}); err != nil {
+		return errors.Wrap(err, "failed to delete webhook")
+	}
+
+	return nil
+}
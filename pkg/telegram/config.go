@@ -1,14 +1,32 @@
 package telegram
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 // Config holds the configuration for the Telegram bot.
 type Config struct {
-	BotToken         string `envconfig:"TELEGRAM_API_TOKEN" required:"true"`
-	UpdateCfgTimeout int    `envconfig:"TELEGRAM_UPDATE_CONFIG_TIMEOUT" default:"60"`
-	IsDebug          bool   `envconfig:"TELEGRAM_DEBUG" default:"false"`
+	BotToken             string        `envconfig:"TELEGRAM_API_TOKEN" required:"true"`
+	APIEndpoint          string        `envconfig:"TELEGRAM_API_ENDPOINT" default:"https://api.telegram.org/bot%s/%s"`
+	UpdateCfgTimeout     int           `envconfig:"TELEGRAM_UPDATE_CONFIG_TIMEOUT" default:"60"`
+	IsDebug              bool          `envconfig:"TELEGRAM_DEBUG" default:"false"`
+	ConversationStateTTL time.Duration `envconfig:"TELEGRAM_CONVERSATION_STATE_TTL" default:"24h"`
+	HandlerWorkers       int           `envconfig:"TELEGRAM_HANDLER_WORKERS" default:"8"`
+	HandlerQueueSize     int           `envconfig:"TELEGRAM_HANDLER_QUEUE_SIZE" default:"100"`
+
+	// Limits of the outgoing messages per second, globally and per chat, as recommended in the Telegram bots FAQ.
+	GlobalRateLimit     int `envconfig:"TELEGRAM_GLOBAL_RATE_LIMIT" default:"30"`
+	ChatRateLimit       int `envconfig:"TELEGRAM_CHAT_RATE_LIMIT" default:"1"`
+	RateLimitMaxRetries int `envconfig:"TELEGRAM_RATE_LIMIT_MAX_RETRIES" default:"5"`
+
+	// Webhook settings, updates are received with long polling unless the webhook is enabled.
+	IsWebhookEnabled   bool   `envconfig:"TELEGRAM_WEBHOOK_ENABLED" default:"false"`
+	WebhookURL         string `envconfig:"TELEGRAM_WEBHOOK_URL"`
+	WebhookListenAddr  string `envconfig:"TELEGRAM_WEBHOOK_LISTEN_ADDR" default:":8443"`
+	WebhookPath        string `envconfig:"TELEGRAM_WEBHOOK_PATH" default:"/telegram/webhook"`
+	WebhookSecretToken string `envconfig:"TELEGRAM_WEBHOOK_SECRET_TOKEN"`
 }
 
 func NewConfig() *Config {
@@ -0,0 +1,23 @@
+package telegram
+
+import (
+	"net/http"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+)
+
+// IsPermanentError checks if the request was rejected by Telegram for a reason which retries will not fix,
+// like a missing chat or a malformed message. Network errors, 429 Too Many Requests and 5xx are transient.
+func IsPermanentError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	if apiErr.Code == http.StatusTooManyRequests || apiErr.RetryAfter != 0 {
+		return false
+	}
+
+	return apiErr.Code >= http.StatusBadRequest && apiErr.Code < http.StatusInternalServerError
+}
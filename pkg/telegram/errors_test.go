@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"net/http"
+	"testing"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPermanentError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "chat not found",
+			err:  &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request: chat not found"}, //nolint:exhaustruct,nolintlint
+			want: true,
+		},
+		{
+			name: "bad markup",
+			err: errors.Wrap(&tgbotapi.Error{ //nolint:exhaustruct,nolintlint
+				Code:    http.StatusBadRequest,
+				Message: "Bad Request: can't parse entities",
+			}, "failed to send message"),
+			want: true,
+		},
+		{
+			name: "too many requests",
+			err: &tgbotapi.Error{
+				Code:               http.StatusTooManyRequests,
+				Message:            "Too Many Requests: retry after 5",
+				ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}, //nolint:exhaustruct,nolintlint
+			},
+			want: false,
+		},
+		{
+			name: "server error",
+			err:  &tgbotapi.Error{Code: http.StatusBadGateway, Message: "Bad Gateway"}, //nolint:exhaustruct,nolintlint
+			want: false,
+		},
+		{
+			name: "network error",
+			err:  errors.New("connection reset by peer"),
+			want: false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsPermanentError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+// chatNextCleanupSize is the number of tracked chats after which the chats without pending messages are forgotten.
+const chatNextCleanupSize = 1000
+
+type (
+	// Clock provides the current time and timers, it is replaced in tests.
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+	}
+
+	realClock struct{}
+
+	// RateLimitedBot is a Bot which keeps the outgoing messages within the global and per chat limits.
+	// Messages over the limits wait for their turn in order instead of failing, and messages rejected
+	// with 429 Too Many Requests are resent after the retry_after returned by Telegram.
+	// Telegram counts every media of a media group as a message, so the media group takes a slot for each.
+	RateLimitedBot struct {
+		*Bot
+		clock          Clock
+		globalInterval time.Duration
+		chatInterval   time.Duration
+		maxRetries     int
+
+		mu          sync.Mutex
+		pausedUntil time.Time
+		globalSlots []time.Time
+		chatNext    map[int64]time.Time
+	}
+)
+
+// Now returns the current time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewRateLimitedBot creates a new RateLimitedBot with the limits from the bot's configuration.
+func NewRateLimitedBot(bot *Bot) *RateLimitedBot {
+	return newRateLimitedBot(bot, realClock{})
+}
+
+func newRateLimitedBot(bot *Bot, clock Clock) *RateLimitedBot {
+	return &RateLimitedBot{
+		Bot:            bot,
+		clock:          clock,
+		globalInterval: rateInterval(bot.cfg.GlobalRateLimit),
+		chatInterval:   rateInterval(bot.cfg.ChatRateLimit),
+		maxRetries:     bot.cfg.RateLimitMaxRetries,
+		mu:             sync.Mutex{},
+		pausedUntil:    time.Time{},
+		globalSlots:    nil,
+		chatNext:       make(map[int64]time.Time),
+	}
+}
+
+// SendMessage sends a message within the rate limits.
+func (b *RateLimitedBot) SendMessage(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return b.SendMessageWithContext(context.Background(), c)
+}
+
+// SendMessageWithContext sends a message within the rate limits, waiting for its turn until the context is done.
+func (b *RateLimitedBot) SendMessageWithContext(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	chatID := chatIDOf(c)
+	weight := weightOf(c)
+
+	for attempt := 0; ; attempt++ {
+		if err := b.wait(ctx, chatID, weight); err != nil {
+			return tgbotapi.Message{}, err
+		}
+
+		msg, err := b.Bot.SendMessage(c)
+
+		retryAfter, isTooManyRequests := retryAfterOf(err)
+		if !isTooManyRequests || attempt >= b.maxRetries {
+			return msg, err
+		}
+
+		b.l.Warn("telegram rate limit exceeded, message will be resent",
+			logger.Int64Attr("chat_id", chatID),
+			logger.DurationAttr("retry_after", retryAfter),
+			logger.IntAttr("attempt", attempt+1),
+		)
+
+		b.pause(retryAfter)
+	}
+}
+
+// wait reserves the next free slots for the chat and waits until the first of them comes.
+func (b *RateLimitedBot) wait(ctx context.Context, chatID int64, weight int) error {
+	delay := b.reserve(chatID, weight)
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-b.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed to wait for rate limit")
+	}
+}
+
+// reserve takes the earliest slot allowed by both the global and the chat limits and returns the delay until it.
+// A message weighing more than one slot takes the following slots too, so the next messages wait for them.
+// Slots of the same chat are reserved in the call order, so the waiting messages are sent in order,
+// and a chat waiting for its limit does not hold back the other chats.
+func (b *RateLimitedBot) reserve(chatID int64, weight int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+
+	sendAt := now
+	if b.pausedUntil.After(sendAt) {
+		sendAt = b.pausedUntil
+	}
+
+	if next, exists := b.chatNext[chatID]; exists && next.After(sendAt) {
+		sendAt = next
+	}
+
+	sendAt = b.reserveGlobalSlot(now, sendAt)
+
+	for range weight - 1 {
+		b.reserveGlobalSlot(now, sendAt)
+	}
+
+	if chatID == 0 {
+		return sendAt.Sub(now)
+	}
+
+	if len(b.chatNext) >= chatNextCleanupSize {
+		for id, next := range b.chatNext {
+			if !next.After(now) {
+				delete(b.chatNext, id)
+			}
+		}
+	}
+
+	b.chatNext[chatID] = sendAt.Add(time.Duration(max(weight, 1)) * b.chatInterval)
+
+	return sendAt.Sub(now)
+}
+
+// reserveGlobalSlot finds the earliest time not before the given one which is at least the global interval
+// away from the other reserved slots, and reserves it.
+func (b *RateLimitedBot) reserveGlobalSlot(now, earliest time.Time) time.Time {
+	// the slots in the past do not limit the new ones anymore
+	past := 0
+	for past < len(b.globalSlots) && !b.globalSlots[past].Add(b.globalInterval).After(now) {
+		past++
+	}
+
+	b.globalSlots = b.globalSlots[past:]
+
+	sendAt := earliest
+	idx := 0
+
+	for ; idx < len(b.globalSlots); idx++ {
+		slot := b.globalSlots[idx]
+
+		if !slot.Add(b.globalInterval).After(sendAt) {
+			continue
+		}
+
+		if !sendAt.Add(b.globalInterval).After(slot) {
+			break
+		}
+
+		sendAt = slot.Add(b.globalInterval)
+	}
+
+	b.globalSlots = slices.Insert(b.globalSlots, idx, sendAt)
+
+	return sendAt
+}
+
+// pause delays all messages until retryAfter elapses, Telegram applies flood control to the whole bot.
+func (b *RateLimitedBot) pause(retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := b.clock.Now().Add(retryAfter)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// rateInterval returns the interval between messages for the limit per second, zero means no limit.
+func rateInterval(limit int) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Second / time.Duration(limit)
+}
+
+// chatIDOf returns the chat ID of the message, messages of other types are limited only globally.
+func chatIDOf(c tgbotapi.Chattable) int64 {
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		return cfg.ChatID
+	case tgbotapi.PhotoConfig:
+		return cfg.ChatID
+	case tgbotapi.MediaGroupConfig:
+		return cfg.ChatID
+	default:
+		return 0
+	}
+}
+
+// weightOf returns the number of slots the message takes, it's the number of media of a media group.
+func weightOf(c tgbotapi.Chattable) int {
+	if cfg, ok := c.(tgbotapi.MediaGroupConfig); ok && len(cfg.Media) > 1 {
+		return len(cfg.Media)
+	}
+
+	return 1
+}
+
+// retryAfterOf checks if the error is 429 Too Many Requests and returns the time to wait before retry.
+func retryAfterOf(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || (apiErr.Code != http.StatusTooManyRequests && apiErr.RetryAfter == 0) {
+		return 0, false
+	}
+
+	return time.Duration(apiErr.RetryAfter) * time.Second, true
+}
@@ -0,0 +1,343 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gudimz/polovni-auto-alert/pkg/logger"
+)
+
+const fakeWaitTimeout = 5 * time.Second
+
+type (
+	fakeClock struct {
+		mu      sync.Mutex
+		now     time.Time
+		timers  []fakeTimer
+		waiting chan time.Duration
+	}
+
+	fakeTimer struct {
+		at time.Time
+		ch chan time.Time
+	}
+)
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{
+		mu:      sync.Mutex{},
+		now:     now,
+		timers:  nil,
+		waiting: make(chan time.Duration, 10),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	c.waiting <- d
+
+	return ch
+}
+
+// Advance moves the clock forward and fires the expired timers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	timers := c.timers[:0]
+
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			timers = append(timers, timer)
+			continue
+		}
+
+		timer.ch <- c.now
+	}
+
+	c.timers = timers
+}
+
+// waitTimer waits until somebody waits for the clock and returns the duration.
+func (c *fakeClock) waitTimer(t *testing.T) time.Duration {
+	t.Helper()
+
+	select {
+	case d := <-c.waiting:
+		return d
+	case <-time.After(fakeWaitTimeout):
+		require.FailNow(t, "clock timer was not started")
+		return 0
+	}
+}
+
+// newFakeBotAPI starts a fake Telegram Bot API server, sendMessage responds with the given responses in order.
+func newFakeBotAPI(t *testing.T, responses ...string) *httptest.Server {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/bottoken/getMe" {
+			_, _ = fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"test_bot"}}`)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		require.Less(t, calls, len(responses), "unexpected bot api call")
+		_, _ = fmt.Fprint(w, responses[calls])
+		calls++
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestRateLimitedBot(t *testing.T, clock Clock, cfg *Config, responses ...string) *RateLimitedBot {
+	t.Helper()
+
+	cfg.BotToken = "token"
+	cfg.APIEndpoint = newFakeBotAPI(t, responses...).URL + "/bot%s/%s"
+
+	bot, err := NewBot(logger.NewLogger(), cfg)
+	require.NoError(t, err)
+
+	return newRateLimitedBot(bot, clock)
+}
+
+func TestRateLimitedBot_reserve(t *testing.T) {
+	type reservation struct {
+		chatID int64
+		weight int
+		want   time.Duration
+	}
+
+	testCases := []struct {
+		name         string
+		cfg          *Config
+		reservations []reservation
+	}{
+		{
+			name: "chat limit",
+			cfg:  &Config{GlobalRateLimit: 30, ChatRateLimit: 1}, //nolint:exhaustruct,nolintlint
+			reservations: []reservation{
+				{chatID: 1, want: 0},
+				{chatID: 1, want: time.Second},
+				{chatID: 1, want: 2 * time.Second},
+				{chatID: 2, want: time.Second / 30},
+			},
+		},
+		{
+			name: "global limit",
+			cfg:  &Config{GlobalRateLimit: 2, ChatRateLimit: 1}, //nolint:exhaustruct,nolintlint
+			reservations: []reservation{
+				{chatID: 1, want: 0},
+				{chatID: 2, want: 500 * time.Millisecond},
+				{chatID: 3, want: time.Second},
+				{chatID: 1, want: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name: "media group",
+			cfg:  &Config{GlobalRateLimit: 10, ChatRateLimit: 1}, //nolint:exhaustruct,nolintlint
+			reservations: []reservation{
+				{chatID: 1, weight: 3, want: 0},
+				{chatID: 2, want: 300 * time.Millisecond},
+				{chatID: 1, want: 3 * time.Second},
+			},
+		},
+		{
+			name: "without chat",
+			cfg:  &Config{GlobalRateLimit: 10, ChatRateLimit: 1}, //nolint:exhaustruct,nolintlint
+			reservations: []reservation{
+				{chatID: 0, want: 0},
+				{chatID: 0, want: 100 * time.Millisecond},
+				{chatID: 0, want: 200 * time.Millisecond},
+			},
+		},
+		{
+			name: "no limits",
+			cfg:  &Config{}, //nolint:exhaustruct,nolintlint
+			reservations: []reservation{
+				{chatID: 1, want: 0},
+				{chatID: 1, want: 0},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC))
+			b := newRateLimitedBot(&Bot{l: logger.NewLogger(), cfg: tt.cfg, API: nil}, clock)
+
+			for _, r := range tt.reservations {
+				require.Equal(t, r.want, b.reserve(r.chatID, max(r.weight, 1)))
+			}
+		})
+	}
+}
+
+func TestRateLimitedBot_SendMessageWithContext(t *testing.T) {
+	const (
+		chatID          = int64(42)
+		okResponse      = `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":42,"type":"private"}}}`
+		tooManyResponse = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 3",` +
+			`"parameters":{"retry_after":3}}`
+		forbiddenResponse = `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`
+	)
+
+	testCases := []struct {
+		name        string
+		maxRetries  int
+		responses   []string
+		wantWaits   []time.Duration
+		wantErrCode int
+	}{
+		{
+			name:       "success",
+			maxRetries: 1,
+			responses:  []string{okResponse},
+		},
+		{
+			name:       "success after retry_after",
+			maxRetries: 1,
+			responses:  []string{tooManyResponse, okResponse},
+			wantWaits:  []time.Duration{3 * time.Second},
+		},
+		{
+			name:        "retries are exceeded",
+			maxRetries:  2,
+			responses:   []string{tooManyResponse, tooManyResponse, tooManyResponse},
+			wantWaits:   []time.Duration{3 * time.Second, 3 * time.Second},
+			wantErrCode: http.StatusTooManyRequests,
+		},
+		{
+			name:        "other error is not retried",
+			maxRetries:  1,
+			responses:   []string{forbiddenResponse},
+			wantErrCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC))
+			cfg := &Config{ //nolint:exhaustruct,nolintlint
+				GlobalRateLimit:     30,
+				ChatRateLimit:       1,
+				RateLimitMaxRetries: tt.maxRetries,
+			}
+			b := newTestRateLimitedBot(t, clock, cfg, tt.responses...)
+
+			done := make(chan error, 1)
+
+			go func() {
+				_, err := b.SendMessageWithContext(context.Background(), tgbotapi.NewMessage(chatID, "hello"))
+				done <- err
+			}()
+
+			for _, want := range tt.wantWaits {
+				require.Equal(t, want, clock.waitTimer(t))
+				clock.Advance(want)
+			}
+
+			var err error
+
+			select {
+			case err = <-done:
+			case <-time.After(fakeWaitTimeout):
+				require.FailNow(t, "message was not sent")
+			}
+
+			if tt.wantErrCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var apiErr *tgbotapi.Error
+			require.ErrorAs(t, err, &apiErr)
+			require.Equal(t, tt.wantErrCode, apiErr.Code)
+		})
+	}
+}
+
+func TestRateLimitedBot_SendMessageWithContext_Canceled(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC))
+	cfg := &Config{GlobalRateLimit: 30, ChatRateLimit: 1, RateLimitMaxRetries: 1} //nolint:exhaustruct,nolintlint
+	b := newTestRateLimitedBot(t, clock, cfg,
+		`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`)
+
+	_, err := b.SendMessageWithContext(context.Background(), tgbotapi.NewMessage(1, "first"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := b.SendMessageWithContext(ctx, tgbotapi.NewMessage(1, "second"))
+		done <- err
+	}()
+
+	// the second message waits for the chat limit until the context is canceled
+	require.Equal(t, time.Second, clock.waitTimer(t))
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRateLimitedBot_SendMessageWithContext_MediaGroup(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC))
+	cfg := &Config{GlobalRateLimit: 30, ChatRateLimit: 1, RateLimitMaxRetries: 1} //nolint:exhaustruct,nolintlint
+	b := newTestRateLimitedBot(t, clock, cfg,
+		`{"ok":true,"result":[{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}},`+
+			`{"message_id":2,"date":0,"chat":{"id":1,"type":"private"}}]}`)
+
+	photo := tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL("https://example.com/1.jpg"))
+	photo.Caption = "caption"
+
+	// Telegram returns all the messages of the album, the first one holds the caption
+	msg, err := b.SendMessageWithContext(context.Background(), tgbotapi.NewMediaGroup(1, []any{
+		photo,
+		tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL("https://example.com/2.jpg")),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.MessageID)
+}
+
+func Test_weightOf(t *testing.T) {
+	photos := []any{
+		tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL("https://example.com/1.jpg")),
+		tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL("https://example.com/2.jpg")),
+	}
+
+	require.Equal(t, 1, weightOf(tgbotapi.NewMessage(1, "hello")))
+	require.Equal(t, 2, weightOf(tgbotapi.NewMediaGroup(1, photos)))
+}